@@ -0,0 +1,80 @@
+package analyze
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/spf13/cobra"
+)
+
+// Workspaces are projected this many days ahead when looking for states that
+// are growing towards the size limit
+const projectionDays = 30
+
+var orgName string
+var prefix string
+var tags []string
+var samples int
+var maxSizeMB int64
+var deadline time.Duration
+
+// AnalyzeCmd &
+var AnalyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Reports state sizes and growth of all workspaces in an organization",
+	Long: `Reports the state size, resource count and growth per day of every workspace in an
+organization, sampled from recent state versions. Workspaces whose state is larger than
+--max-size-mb are flagged LARGE, and those projected to reach it within 30 days GROWING.
+Sizes come from the state version metadata, so states are only downloaded from TFE
+releases that don't report it. Workspaces that can't be analyzed are listed with the error.
+With --deadline, the workspaces analyzed before the budget ran out are listed and the
+rest are reported as remaining.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		return config.ValidateReadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if orgName == "" {
+			orgName = config.GetConfig().TerraformOrgName
+		}
+		stats, err := api.AnalyzeWorkspaces(orgName, api.WorkspaceFilter{Prefix: prefix, Tags: tags}, samples, deadline)
+		if stats == nil {
+			return err
+		}
+
+		maxSize := maxSizeMB << 20
+		w := tabwriter.NewWriter(console.Out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "WORKSPACE\tSERIAL\tSIZE\tRESOURCES\tGROWTH/DAY\t")
+		for _, s := range stats {
+			if s.Err != nil {
+				fmt.Fprintf(w, "%s\t-\t-\t-\t-\t%s\n", s.Workspace, console.Failure("ERROR: "+s.Err.Error()))
+				continue
+			}
+			flag := ""
+			switch {
+			case s.Size >= maxSize:
+				flag = console.Failure("LARGE")
+			case float64(s.Size)+s.GrowthPerDay*projectionDays >= float64(maxSize):
+				flag = console.Warning("GROWING")
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.0f\t%s\n", s.Workspace, s.Serial, s.Size, s.Resources, s.GrowthPerDay, flag)
+		}
+		if flushErr := w.Flush(); flushErr != nil {
+			return flushErr
+		}
+		// Workspaces that failed are listed above, err says how many
+		return err
+	},
+}
+
+func init() {
+	AnalyzeCmd.PersistentFlags().StringVar(&orgName, "org", "", "organization to analyze, defaults to tf_org_name")
+	AnalyzeCmd.PersistentFlags().StringVarP(&prefix, "prefix", "p", "", "only analyze workspaces whose name starts with this prefix")
+	AnalyzeCmd.PersistentFlags().StringSliceVar(&tags, "tag", nil, "only analyze workspaces that have all of these tags, can be repeated")
+	AnalyzeCmd.PersistentFlags().IntVar(&samples, "samples", 10, "number of newest state versions to sample per workspace")
+	AnalyzeCmd.PersistentFlags().Int64Var(&maxSizeMB, "max-size-mb", 50, "state size in MB above which a workspace is flagged")
+	AnalyzeCmd.PersistentFlags().DurationVar(&deadline, "deadline", 0, "stop analyzing workspaces once this time budget is nearly used up, e.g. 30m")
+}
@@ -0,0 +1,72 @@
+package approve
+
+import (
+	"errors"
+	"io/ioutil"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/approval"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/spf13/cobra"
+)
+
+var workspaces []string
+var keyFile string
+var approver string
+var orgName string
+var valid time.Duration
+
+// ApproveCmd &
+var ApproveCmd = &cobra.Command{
+	Use:   "approve",
+	Short: "Signs an approval for restores to critical workspaces",
+	Long: `Prints an approval token for restoring state to the given workspaces, signed with the
+approver's private key. The person running the restore passes it to 'state copy --approval'.
+Only needed for workspaces with the dual_control tag, tier:critical by default.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(workspaces) == 0 {
+			return errors.New("workspace is required")
+		}
+		if len(keyFile) == 0 {
+			return errors.New("key is required")
+		}
+		if len(approver) == 0 {
+			return errors.New("approver is required")
+		}
+		if len(orgName) == 0 && len(config.GetConfig().TerraformOrgName) == 0 {
+			return config.ErrTFOrgNameRequired
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return err
+		}
+		org := orgName
+		if org == "" {
+			org = config.GetConfig().TerraformOrgName
+		}
+		token, err := approval.Sign(string(key), approval.Approval{
+			Approver:     approver,
+			Organization: org,
+			Workspaces:   workspaces,
+			ExpiresAt:    time.Now().Add(valid).UTC(),
+		})
+		if err != nil {
+			return err
+		}
+		console.Println(token)
+		return nil
+	},
+}
+
+func init() {
+	ApproveCmd.Flags().StringArrayVarP(&workspaces, "workspace", "w", nil, "workspace the restore is approved for, can be repeated")
+	ApproveCmd.Flags().StringVar(&keyFile, "key", "", "approver's private key file from 'approve keygen'")
+	ApproveCmd.Flags().StringVar(&approver, "approver", "", "approver's name as listed in dual_control.approvers")
+	ApproveCmd.Flags().StringVar(&orgName, "org", "", "organization of the workspaces, defaults to tf_org_name")
+	ApproveCmd.Flags().DurationVar(&valid, "valid", 4*time.Hour, "how long the approval can be used")
+	ApproveCmd.AddCommand(keygenCmd)
+}
@@ -0,0 +1,40 @@
+package approve
+
+import (
+	"errors"
+
+	"github.com/mupuri/go-tfdr/internal/approval"
+	"github.com/mupuri/go-tfdr/internal/atomicfile"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/spf13/cobra"
+)
+
+var outputFile string
+
+var keygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Creates an approver key pair",
+	Long: `Writes a new private key for signing approvals to --output and prints the public key
+to add under dual_control.approvers in the config of whoever runs restores.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(outputFile) == 0 {
+			return errors.New("output is required")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		public, private, err := approval.GenerateKey()
+		if err != nil {
+			return err
+		}
+		if err := atomicfile.WriteFile(outputFile, []byte(private+"\n"), 0600); err != nil {
+			return err
+		}
+		console.Println(public)
+		return nil
+	},
+}
+
+func init() {
+	keygenCmd.PersistentFlags().StringVarP(&outputFile, "output", "o", "", "file to write the private key to")
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/spf13/cobra"
+)
+
+var auditCommands []string
+var auditPrefix string
+var auditTags []string
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Compares the configured tokens' permissions with what tfdr needs",
+	Long: `Lists the permissions the configured tokens have on the organization and on the
+workspaces matching --prefix and --tag, and compares them with what tfdr's commands need:
+the commands given with --command, else the steps of the configured runbooks, else every
+command. Permissions a token lacks fail the audit; permissions it doesn't need are listed
+with a recommended narrower team access level.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		return config.ValidateReadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		commands := auditCommands
+		if len(commands) == 0 {
+			commands = runbookSteps(config.GetConfig())
+		}
+		if len(commands) == 0 {
+			commands = api.AuditedOperations()
+		}
+
+		audits, err := api.AuditTokens(commands, api.WorkspaceFilter{Prefix: auditPrefix, Tags: auditTags})
+		if err != nil {
+			return err
+		}
+		missing := 0
+		for _, a := range audits {
+			kind := "user"
+			if a.ServiceAccount {
+				kind = "service account"
+			}
+			console.Printf("%s token: %s (%s), %d workspaces\n", console.Bold(a.Role), a.Identity, kind, a.Workspaces)
+			console.Printf("  Required: %s\n", permissionList(a.Required))
+			if len(a.Missing) > 0 {
+				console.Printf("  Missing:  %s\n", console.Failure(permissionList(a.Missing)))
+				missing += len(a.Missing)
+			}
+			if len(a.Unneeded) > 0 {
+				console.Printf("  Unneeded: %s\n", console.Warning(permissionList(a.Unneeded)))
+			}
+			for _, r := range a.Recommendations {
+				console.Printf("  - %s\n", r)
+			}
+		}
+		if missing > 0 {
+			return fmt.Errorf("the configured tokens are missing %d permission(s) tfdr needs", missing)
+		}
+		return nil
+	},
+}
+
+// runbookSteps returns the steps of every configured runbook
+func runbookSteps(c *config.Configuration) []string {
+	names := make([]string, 0, len(c.Runbooks))
+	for name := range c.Runbooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	steps := make([]string, 0)
+	for _, name := range names {
+		steps = append(steps, c.Runbooks[name].Steps...)
+	}
+	return steps
+}
+
+func permissionList(permissions []api.Permission) string {
+	if len(permissions) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		names = append(names, string(p))
+	}
+	return strings.Join(names, ", ")
+}
+
+func init() {
+	auditCmd.PersistentFlags().StringArrayVar(&auditCommands, "command", nil, "tfdr command line to audit for, without the leading tfdr, e.g. \"state copy --create-missing\", can be repeated")
+	auditCmd.PersistentFlags().StringVarP(&auditPrefix, "prefix", "p", "", "only check workspaces whose name starts with this prefix")
+	auditCmd.PersistentFlags().StringSliceVar(&auditTags, "tag", nil, "only check workspaces that have all of these tags, can be repeated")
+}
@@ -0,0 +1,17 @@
+package auth
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// AuthCmd &
+var AuthCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manages Terraform Cloud API tokens",
+	Long:  `Manages Terraform Cloud API tokens`,
+}
+
+func init() {
+	AuthCmd.AddCommand(auditCmd)
+	AuthCmd.AddCommand(rotateCmd)
+}
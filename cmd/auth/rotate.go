@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/atomicfile"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/config/file"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/spf13/cobra"
+)
+
+var teamID string
+
+var rotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Replaces the configured team token with a newly generated one",
+	Long: `Generates a new token for the team, which revokes the old one, and stores it
+in the config file in place of the token tfdr writes with: tf_write_token when it is
+set, tf_team_token otherwise, and every other token setting with the same value. The configured token must be allowed to manage the team's token.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(teamID) == 0 {
+			teamID = config.GetConfig().TerraformTeamID
+		}
+		if len(teamID) == 0 {
+			return errors.New("team-id or tf_team_id is required")
+		}
+		return config.ValidateConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		old := config.GetConfig().WriteToken()
+		token, err := api.RotateTeamToken(teamID)
+		if err != nil {
+			return err
+		}
+		if err := config.ReplaceToken(old, token); err != nil {
+			// Never print the token, the output may end up in CI logs
+			saved, saveErr := saveRotatedToken(token)
+			if saveErr != nil {
+				return fmt.Errorf("The old token has been revoked and the new token could not be saved. Error: %v", err)
+			}
+			console.Printf("The old token has been revoked and the new token could not be saved to the config file. It was written to %s instead\n", saved)
+			return err
+		}
+		console.Println("Team token rotated. The previous token is no longer valid.")
+		return nil
+	},
+}
+
+// saveRotatedToken writes a token that couldn't be stored in the config file
+// to a file only the user can read, next to the config file or else in the
+// temp directory
+func saveRotatedToken(token string) (string, error) {
+	name := filepath.Join(filepath.Dir(file.Path()), "rotated-token")
+	if err := atomicfile.WriteFile(name, []byte(token+"\n"), 0600); err == nil {
+		return name, nil
+	}
+	f, err := ioutil.TempFile("", "tfdr-rotated-token-")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(token + "\n"); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func init() {
+	rotateCmd.PersistentFlags().StringVar(&teamID, "team-id", "", "ID of the team whose token is rotated (defaults to tf_team_id)")
+}
@@ -1,9 +1,8 @@
 package config
 
 import (
-	"fmt"
-
 	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 )
@@ -14,7 +13,7 @@ var getConfigCmd = &cobra.Command{
 	Long:  `Display currently configured options`,
 	Run: func(cmd *cobra.Command, args []string) {
 		bytes, _ := yaml.Marshal(config.GetConfig())
-		fmt.Println(string(bytes))
+		console.Println(string(bytes))
 	},
 }
 
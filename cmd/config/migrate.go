@@ -0,0 +1,30 @@
+package config
+
+import (
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/spf13/cobra"
+)
+
+var migrateConfigCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Rewrites the config file in the current layout",
+	Long: `Rewrites the config file in the current layout. Older config files are migrated
+automatically when read, this makes the change permanent.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgFile, changed, err := config.MigrateFile()
+		if err != nil {
+			return err
+		}
+		if changed {
+			console.Printf("Migrated %s to config version %d\n", cfgFile, config.CurrentVersion)
+		} else {
+			console.Printf("%s is already at config version %d\n", cfgFile, config.CurrentVersion)
+		}
+		return nil
+	},
+}
+
+func init() {
+	ConfigCmd.AddCommand(migrateConfigCmd)
+}
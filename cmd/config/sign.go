@@ -0,0 +1,55 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+
+	"github.com/mupuri/go-tfdr/internal/atomicfile"
+	"github.com/mupuri/go-tfdr/internal/config/remote"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/spf13/cobra"
+)
+
+var keyFile string
+
+var signConfigCmd = &cobra.Command{
+	Use:   "sign FILE",
+	Short: "Signs a config file for runners that fetch it remotely",
+	Long: `Writes the signature of a config file to FILE.sig, made with a private key from
+'tfdr approve keygen'. Upload both files to the same location. Runners started with
+--config s3://... or --config https://... and the matching public key in TF_CONFIG_PUBLIC_KEY
+refuse config files that are not signed with the key.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+			return err
+		}
+		if len(keyFile) == 0 {
+			return errors.New("key is required")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		sig, err := remote.Sign(string(key), data)
+		if err != nil {
+			return err
+		}
+		if err := atomicfile.WriteFile(args[0]+remote.SignatureSuffix, []byte(sig+"\n"), 0644); err != nil {
+			return err
+		}
+		console.Printf("Wrote %s\n", args[0]+remote.SignatureSuffix)
+		return nil
+	},
+}
+
+func init() {
+	signConfigCmd.PersistentFlags().StringVar(&keyFile, "key", "", "private key file from 'tfdr approve keygen'")
+	ConfigCmd.AddCommand(signConfigCmd)
+}
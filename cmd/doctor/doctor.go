@@ -0,0 +1,47 @@
+package doctor
+
+import (
+	"fmt"
+
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/mupuri/go-tfdr/internal/doctor"
+	"github.com/spf13/cobra"
+)
+
+// DoctorCmd &
+var DoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Checks the environment tfdr runs in",
+	Long: `Checks DNS and connectivity to the API, proxy settings, clock skew, token validity,
+optional API features missing on older TFE releases, config file permissions and free disk
+space, and prints hints for anything that needs fixing.
+Run it on a DR runner before you need it.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		failed := 0
+		for _, r := range doctor.Run() {
+			console.Printf("[%s] %s: %s\n", status(r.Status), r.Name, r.Message)
+			if r.Hint != "" && r.Status != doctor.OK {
+				console.Printf("       %s\n", r.Hint)
+			}
+			if r.Status == doctor.Fail {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d checks failed", failed)
+		}
+		return nil
+	},
+}
+
+func status(s doctor.Status) string {
+	padded := fmt.Sprintf("%-4s", s)
+	switch s {
+	case doctor.OK:
+		return console.Success(padded)
+	case doctor.Warn:
+		return console.Warning(padded)
+	default:
+		return console.Failure(padded)
+	}
+}
@@ -0,0 +1,113 @@
+package importlocal
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/mupuri/go-tfdr/internal/models"
+	"github.com/mupuri/go-tfdr/internal/statefile"
+	"github.com/spf13/cobra"
+)
+
+var glob string
+var push bool
+var workspacePrefix string
+var createMissing bool
+var approvals []string
+
+// ImportLocalCmd &
+var ImportLocalCmd = &cobra.Command{
+	Use:   "import-local DIR",
+	Short: "Finds local terraform state files in a directory tree and pushes them to workspaces",
+	Long: `Walks a directory tree for state files of the terraform CLI's local backend, such as
+checkouts of legacy projects, and lists them with the workspace each would be pushed to and
+any problems that would stop terraform from accepting it. Workspace names are taken from the
+file's directory relative to DIR, e.g. network/prod/terraform.tfstate becomes network-prod,
+after --workspace-prefix. Hidden directories such as .terraform are skipped.
+
+With --push, every state without problems is uploaded to its workspace, keeping its serial
+and lineage. Workspaces must have no state yet. Run 'tfdr state upgrade' on states written by
+terraform 0.11 or older first.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+			return err
+		}
+		if push {
+			return config.ValidateConfig()
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := args[0]
+		files, err := statefile.Discover(root, glob)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			console.Printf("No state files matching %s in %s\n", glob, root)
+			return nil
+		}
+
+		states := make([]api.LocalState, 0, len(files))
+		invalid := 0
+		w := tabwriter.NewWriter(console.Out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "FILE\tWORKSPACE\tTERRAFORM\tSERIAL\tRESOURCES\tSTATUS\t")
+		for _, file := range files {
+			rel, err := filepath.Rel(root, file)
+			if err != nil {
+				return err
+			}
+			name := workspacePrefix + statefile.WorkspaceName(root, filepath.ToSlash(rel))
+
+			data, err := statefile.Read(file)
+			if err != nil {
+				return err
+			}
+			var state models.State
+			problems := statefile.Lint(data)
+			if len(problems) == 0 {
+				if err := json.Unmarshal(data, &state); err != nil {
+					problems = append(problems, statefile.Problem{Message: err.Error()})
+				}
+			}
+			status := console.Success("OK")
+			if len(problems) > 0 {
+				invalid++
+				status = console.Failure(problems[0].String())
+				if len(problems) > 1 {
+					status = console.Failure(fmt.Sprintf("%s (and %d more)", problems[0], len(problems)-1))
+				}
+			} else {
+				states = append(states, api.LocalState{File: file, Workspace: name, State: &state})
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\n", rel, name, state.TerraformVersion, state.Serial, len(state.Resources), status)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if push && len(states) > 0 {
+			if err := api.PushLocalStates(states, createMissing, approvals); err != nil {
+				return err
+			}
+			console.Println(console.Success(fmt.Sprintf("Pushed %d local states", len(states))))
+		}
+		if invalid > 0 {
+			return fmt.Errorf("%d of %d state files have problems, run 'tfdr state lint' on them for details", invalid, len(files))
+		}
+		return nil
+	},
+}
+
+func init() {
+	ImportLocalCmd.PersistentFlags().StringVar(&glob, "glob", "**/terraform.tfstate", "pattern of state file paths relative to DIR, where ** matches any number of directories")
+	ImportLocalCmd.PersistentFlags().BoolVar(&push, "push", false, "upload the states to their workspaces")
+	ImportLocalCmd.PersistentFlags().StringVar(&workspacePrefix, "workspace-prefix", "", "prefix for the workspace names derived from the state file paths")
+	ImportLocalCmd.PersistentFlags().BoolVar(&createMissing, "create-missing", false, "with --push, create missing workspaces from workspace_template")
+	ImportLocalCmd.PersistentFlags().StringArrayVar(&approvals, "approval", nil, "approval token from 'tfdr approve', needed for workspaces with the dual_control tag. Can be repeated")
+}
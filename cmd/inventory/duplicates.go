@@ -0,0 +1,51 @@
+package inventory
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/mupuri/go-tfdr/internal/inventory"
+	"github.com/spf13/cobra"
+)
+
+var duplicatesCmd = &cobra.Command{
+	Use:   "duplicates",
+	Short: "Finds resources managed from more than one workspace",
+	Long: `Finds resources whose provider id appears in the state of more than one workspace,
+as can happen after copying state during DR. Exits with an error when any are found.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		return config.ValidateReadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		idx, err := loadIndex()
+		if err != nil {
+			return err
+		}
+
+		duplicates := inventory.Duplicates(idx.Items)
+		if len(duplicates) == 0 {
+			console.Println("No resources are managed from more than one workspace")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(console.Out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "TYPE\tID\tWORKSPACE\tADDRESS\t")
+		for _, d := range duplicates {
+			for _, i := range d.Items {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", d.Type, d.ID, i.Workspace, i.Address())
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		return fmt.Errorf("%d resources are managed from more than one workspace", len(duplicates))
+	},
+}
+
+func init() {
+	duplicatesCmd.PersistentFlags().BoolVar(&refresh, "refresh", false, "rebuild the index before checking")
+	duplicatesCmd.PersistentFlags().DurationVar(&maxAge, "max-age", time.Hour, "rebuild the index when it is older than this")
+}
@@ -0,0 +1,65 @@
+package inventory
+
+import (
+	"fmt"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/atomicfile"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/mupuri/go-tfdr/internal/inventory"
+	"github.com/spf13/cobra"
+)
+
+var format string
+var outputFile string
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exports all resources in the organization's workspace states",
+	Long: `Walks the current state of every workspace in the organization and writes one
+record per resource instance with its workspace, module, type, name, provider and id.
+A workspace whose state can't be read is written as a record with only its workspace
+and error set, and the command exits with an error once the output is written.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if format != "csv" && format != "json" {
+			return fmt.Errorf("unsupported format %q, expected csv or json", format)
+		}
+		return config.ValidateReadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		items, collectErr := api.CollectInventory(deadline)
+		if items == nil {
+			return collectErr
+		}
+
+		write := inventory.WriteCSV
+		if format == "json" {
+			write = inventory.WriteJSON
+		}
+		if outputFile == "" {
+			if err := write(console.Out, items); err != nil {
+				return err
+			}
+			return collectErr
+		}
+
+		f, err := atomicfile.Create(outputFile, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Abort()
+		if err := write(f, items); err != nil {
+			return err
+		}
+		if err := f.Commit(); err != nil {
+			return err
+		}
+		return collectErr
+	},
+}
+
+func init() {
+	exportCmd.PersistentFlags().StringVar(&format, "format", "csv", "output format, csv or json")
+	exportCmd.PersistentFlags().StringVarP(&outputFile, "output", "o", "", "file to write to, defaults to stdout")
+}
@@ -0,0 +1,23 @@
+package inventory
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var deadline time.Duration
+
+// InventoryCmd &
+var InventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Inventory of resources across all workspace states",
+	Long:  `Inventory of resources across all workspace states in the organization`,
+}
+
+func init() {
+	InventoryCmd.AddCommand(exportCmd)
+	InventoryCmd.AddCommand(searchCmd)
+	InventoryCmd.AddCommand(duplicatesCmd)
+	InventoryCmd.PersistentFlags().DurationVar(&deadline, "deadline", 0, "stop reading workspace states once this time budget is nearly used up, e.g. 30m")
+}
@@ -0,0 +1,92 @@
+package inventory
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/mupuri/go-tfdr/internal/inventory"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var refresh bool
+var maxAge time.Duration
+
+var searchCmd = &cobra.Command{
+	Use:   "search QUERY",
+	Short: "Finds which workspaces manage matching resources",
+	Long: `Finds which workspaces' states contain resources matching QUERY, written as
+type.field~value for a substring match or type.field=value for an exact match, e.g.
+'aws_s3_bucket.name~prod-logs'. Fields are workspace, module, name, index, provider,
+id and address. A bare type matches every resource of that type.
+
+Results come from a cached index that is rebuilt when older than --max-age. Workspaces
+whose state can't be read are left out of the results, the index isn't saved, and the
+command exits with an error once the results are printed.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("expected one search query")
+		}
+		return config.ValidateReadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		q, err := inventory.ParseQuery(args[0])
+		if err != nil {
+			return err
+		}
+
+		idx, loadErr := loadIndex()
+		if idx == nil {
+			return loadErr
+		}
+
+		w := tabwriter.NewWriter(console.Out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "WORKSPACE\tADDRESS\tID\t")
+		for _, i := range inventory.Search(idx.Items, q) {
+			fmt.Fprintf(w, "%s\t%s\t%s\t\n", i.Workspace, i.Address(), i.ID)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		return loadErr
+	},
+}
+
+func loadIndex() (*inventory.Index, error) {
+	org := config.GetConfig().TerraformOrgName
+	path := inventory.IndexPath()
+
+	if !refresh {
+		idx, err := inventory.LoadIndex(path)
+		if err != nil {
+			logrus.Warnf("Unable to read inventory index %s, rebuilding it. Error: %v", path, err)
+		}
+		if idx.Fresh(org, maxAge, time.Now()) {
+			logrus.Debugf("Using inventory index from %s", idx.UpdatedAt.Format(time.RFC3339))
+			return idx, nil
+		}
+	}
+
+	items, err := api.CollectInventory(deadline)
+	if items == nil {
+		return nil, err
+	}
+	idx := &inventory.Index{Organization: org, UpdatedAt: time.Now(), Items: items}
+	if err != nil {
+		// An incomplete index would hide the failed workspaces until it expires
+		return idx, err
+	}
+	if err := inventory.SaveIndex(path, idx); err != nil {
+		logrus.Warnf("Unable to save inventory index %s. Error: %v", path, err)
+	}
+	return idx, nil
+}
+
+func init() {
+	searchCmd.PersistentFlags().BoolVar(&refresh, "refresh", false, "rebuild the index before searching")
+	searchCmd.PersistentFlags().DurationVar(&maxAge, "max-age", time.Hour, "rebuild the index when it is older than this")
+}
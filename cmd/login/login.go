@@ -0,0 +1,65 @@
+package login
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/spf13/cobra"
+)
+
+var noBrowser bool
+
+// LoginCmd &
+var LoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Stores a Terraform Cloud API token in the config file",
+	Long: `Opens the Terraform Cloud token page, reads the new token without echoing it,
+verifies it against the API and stores it in the config file`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		console.Printf("Create an API token at %s\n", api.TokenPageURL)
+		if !noBrowser {
+			openBrowser(api.TokenPageURL)
+		}
+
+		token, err := config.PromptSecret("Enter Terraform token: ")
+		if err != nil {
+			return err
+		}
+		token = strings.TrimSpace(token)
+		if len(token) == 0 {
+			return errors.New("token is required")
+		}
+
+		name, err := api.ValidateToken(token)
+		if err != nil {
+			return err
+		}
+		if err := config.SaveToken(token); err != nil {
+			return err
+		}
+		console.Printf("Logged in as %s. Use `tfdr config get` to view your configuration.\n", name)
+		return nil
+	},
+}
+
+func openBrowser(url string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	_ = cmd.Start()
+}
+
+func init() {
+	LoginCmd.PersistentFlags().BoolVar(&noBrowser, "no-browser", false, "print the token page URL without opening a browser")
+}
@@ -0,0 +1,16 @@
+package modules
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// ModulesCmd &
+var ModulesCmd = &cobra.Command{
+	Use:   "modules",
+	Short: "Checks the module sources of a terraform configuration",
+	Long:  `Checks the module sources of a terraform configuration`,
+}
+
+func init() {
+	ModulesCmd.AddCommand(reportCmd)
+}
@@ -0,0 +1,57 @@
+package modules
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/mupuri/go-tfdr/internal/modules"
+	"github.com/spf13/cobra"
+)
+
+var unreachableHosts []string
+
+var reportCmd = &cobra.Command{
+	Use:   "report [dir]",
+	Short: "Flags module sources that are unpinned or unreachable in DR",
+	Long: `Scans the .tf files of a terraform configuration (the current directory by default)
+and its local modules for module calls, and flags sources that are not pinned to a single
+release or ref, or that are hosted on one of the --unreachable-host hosts. A restored
+workspace cannot plan if its module sources changed or went down with the primary region.
+Terraform state does not record module sources, so point this at the workspace's
+configuration checkout.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "."
+		if len(args) == 1 {
+			dir = args[0]
+		}
+		found, err := modules.Scan(dir)
+		if err != nil {
+			return err
+		}
+
+		flagged := 0
+		w := tabwriter.NewWriter(console.Out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "MODULE\tLOCATION\tSOURCE\tPROBLEMS\t")
+		for _, m := range found {
+			problems := modules.Check(m, unreachableHosts)
+			if len(problems) > 0 {
+				flagged++
+			}
+			fmt.Fprintf(w, "%s\t%s:%d\t%s\t%s\t\n", m.Name, m.File, m.Line, m.Source, console.Failure(strings.Join(problems, "; ")))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if flagged > 0 {
+			return fmt.Errorf("%d of %d module sources are unpinned or unreachable in DR", flagged, len(found))
+		}
+		return nil
+	},
+}
+
+func init() {
+	reportCmd.PersistentFlags().StringSliceVar(&unreachableHosts, "unreachable-host", nil, "host that is unavailable during DR, e.g. a git server in the primary region")
+}
@@ -2,11 +2,30 @@ package cmd
 
 import (
 	"log"
+	"time"
 
+	"github.com/mupuri/go-tfdr/cmd/analyze"
+	"github.com/mupuri/go-tfdr/cmd/approve"
+	"github.com/mupuri/go-tfdr/cmd/auth"
 	cfg "github.com/mupuri/go-tfdr/cmd/config"
+	"github.com/mupuri/go-tfdr/cmd/doctor"
+	"github.com/mupuri/go-tfdr/cmd/importlocal"
+	"github.com/mupuri/go-tfdr/cmd/inventory"
+	"github.com/mupuri/go-tfdr/cmd/login"
+	"github.com/mupuri/go-tfdr/cmd/modules"
+	"github.com/mupuri/go-tfdr/cmd/runbook"
+	"github.com/mupuri/go-tfdr/cmd/schema"
+	"github.com/mupuri/go-tfdr/cmd/serve"
 	state "github.com/mupuri/go-tfdr/cmd/state"
+	"github.com/mupuri/go-tfdr/cmd/tui"
+	"github.com/mupuri/go-tfdr/cmd/version"
+	"github.com/mupuri/go-tfdr/cmd/workspace"
+	"github.com/mupuri/go-tfdr/internal/api"
 	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
 	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/mupuri/go-tfdr/internal/notify"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/cobra/doc"
 )
@@ -31,21 +50,91 @@ var docCmd = &cobra.Command{
 // Execute will run the cli command
 func Execute(version string) error {
 	rootCmd.Version = version
-	return rootCmd.Execute()
+	api.SetVersion(version)
+	err := rootCmd.Execute()
+	if calls := api.Usage(); len(calls) > 0 {
+		logrus.Debugf("Made %d API requests:\n%s", api.TotalCalls(calls), api.FormatUsage(calls))
+	}
+	return err
 }
 
 var cfgFile string
+var logLevel string
+var httpTraceFile string
+var colorMode string
+var injectFailures float64
+var onBehalfOf string
 
 func init() {
 	cobra.OnInitialize(initConfig)
 	rootCmd.DisableAutoGenTag = true
-	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file")
+	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file, or an s3:// or https:// URL of a centrally managed one")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "log level, overrides tf_state_copy_log_level. trace logs every API request")
+	rootCmd.PersistentFlags().StringVar(&httpTraceFile, "http-trace-file", "", "with --log-level trace, append full API request and response bodies to this file. Bodies contain state, handle the file accordingly")
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", console.ColorAuto, "color output: auto, always or never. auto colors terminals unless NO_COLOR is set")
+	rootCmd.PersistentFlags().StringVar(&onBehalfOf, "on-behalf-of", "", "person to attribute this run to in logs, notifications, workspace locks and API request headers, overrides on_behalf_of")
+	rootCmd.PersistentFlags().Float64Var(&injectFailures, "inject-failures", 0, "for testing automation around tfdr, fail this fraction of API requests on purpose, e.g. 0.1")
 	rootCmd.AddCommand(cfg.ConfigCmd)
 	rootCmd.AddCommand(state.StateCmd)
+	rootCmd.AddCommand(login.LoginCmd)
+	rootCmd.AddCommand(auth.AuthCmd)
+	rootCmd.AddCommand(workspace.WorkspaceCmd)
+	rootCmd.AddCommand(analyze.AnalyzeCmd)
+	rootCmd.AddCommand(inventory.InventoryCmd)
+	rootCmd.AddCommand(importlocal.ImportLocalCmd)
+	rootCmd.AddCommand(tui.TuiCmd)
+	rootCmd.AddCommand(modules.ModulesCmd)
+	rootCmd.AddCommand(doctor.DoctorCmd)
+	rootCmd.AddCommand(approve.ApproveCmd)
+	rootCmd.AddCommand(runbook.RunbookCmd)
+	rootCmd.AddCommand(schema.SchemaCmd)
+	rootCmd.AddCommand(serve.ServeCmd)
+	rootCmd.AddCommand(version.VersionCmd)
 	rootCmd.AddCommand(docCmd)
 }
 
 func initConfig() {
+	if err := console.SetColorMode(colorMode); err != nil {
+		log.Fatal(err)
+	}
 	config.InitConfig(cfgFile)
+	if logLevel != "" {
+		config.Override(func(c *config.Configuration) {
+			c.LogLevel = logLevel
+		})
+	}
+	if onBehalfOf != "" {
+		config.Override(func(c *config.Configuration) {
+			c.OnBehalfOf = onBehalfOf
+		})
+	}
 	logging.InitLogger()
+	if err := api.SetOnBehalfOf(config.GetConfig().OnBehalfOf); err != nil {
+		log.Fatal(err)
+	}
+	if err := api.ConfigureTransport(config.GetConfig().HTTP); err != nil {
+		log.Fatal(err)
+	}
+	if httpTraceFile != "" {
+		if err := api.EnableHTTPTrace(httpTraceFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if injectFailures > 0 {
+		if err := api.SetFailureInjection(injectFailures); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if email := config.GetConfig().Notifications.Email; email != nil && email.Host != "" {
+		n, err := notify.NewEmail(*email)
+		if err != nil {
+			log.Fatal(err)
+		}
+		api.SetEventHandler(n.Handler(func(err error) {
+			logrus.Warnf("Unable to send notification email. Error: %v", err)
+		}))
+	}
+	if warning := config.TokenAgeWarning(time.Now()); warning != "" {
+		logrus.Warn(warning)
+	}
 }
@@ -0,0 +1,36 @@
+package runbook
+
+import (
+	"fmt"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the runbooks in the config",
+	Long:  `Lists the runbooks in the config with their description and number of steps`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runbooks := config.GetConfig().Runbooks
+		if len(runbooks) == 0 {
+			console.Println("No runbooks configured")
+			return nil
+		}
+		names := make([]string, 0, len(runbooks))
+		for name := range runbooks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		w := tabwriter.NewWriter(console.Out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSTEPS\tDESCRIPTION\t")
+		for _, name := range names {
+			fmt.Fprintf(w, "%s\t%d\t%s\t\n", name, len(runbooks[name].Steps), runbooks[name].Description)
+		}
+		return w.Flush()
+	},
+}
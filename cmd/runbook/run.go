@@ -0,0 +1,127 @@
+package runbook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// maxTerminationMessage is the most Kubernetes reads from a termination
+// message file
+const maxTerminationMessage = 4096
+
+var dryRun bool
+var terminationLog string
+
+// terminationMessage summarizes a runbook run for Kubernetes, which shows it
+// as the container's termination message
+type terminationMessage struct {
+	Runbook    string `json:"runbook"`
+	Steps      int    `json:"steps"`
+	Completed  int    `json:"completed"`
+	FailedStep string `json:"failed_step,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run NAME",
+	Short: "Runs the steps of a runbook",
+	Long: `Runs the steps of the named runbook one after another, stopping at the first step
+that fails. Each step runs as a separate tfdr process with the same config file and global
+flags as this one.
+
+When run as a Kubernetes Job or CronJob, pass --termination-log=/dev/termination-log so the
+outcome is shown as the pod's termination message.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		runbook, err := config.GetConfig().GetRunbook(name)
+		if err != nil {
+			return err
+		}
+
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		global := globalArgs(cmd)
+		msg := terminationMessage{Runbook: name, Steps: len(runbook.Steps)}
+
+		for i, step := range runbook.Steps {
+			console.Println(console.Bold(fmt.Sprintf("Step %d/%d: tfdr %s", i+1, len(runbook.Steps), step)))
+			if dryRun {
+				continue
+			}
+			c := exec.Command(exe, append(global, strings.Fields(step)...)...)
+			c.Stdin = os.Stdin
+			c.Stdout = os.Stdout
+			c.Stderr = os.Stderr
+			if err := c.Run(); err != nil {
+				err = fmt.Errorf("Runbook %s failed at step %d (%s). Error: %v", name, i+1, step, err)
+				msg.FailedStep = step
+				msg.Error = err.Error()
+				writeTerminationLog(msg)
+				return err
+			}
+			msg.Completed++
+		}
+		if !dryRun {
+			console.Println(console.Success(fmt.Sprintf("Runbook %s completed %d steps", name, len(runbook.Steps))))
+			writeTerminationLog(msg)
+		}
+		return nil
+	},
+}
+
+// writeTerminationLog writes the outcome to --termination-log, if given. A
+// failure to write it only warns so it doesn't hide the runbook's outcome.
+func writeTerminationLog(msg terminationMessage) {
+	if terminationLog == "" {
+		return
+	}
+	data, err := json.Marshal(msg)
+	if err == nil && len(data) > maxTerminationMessage {
+		keep := len(msg.Error) - (len(data) - maxTerminationMessage) - len("...")
+		if keep < 0 {
+			keep = 0
+		}
+		msg.Error = msg.Error[:keep] + "..."
+		data, err = json.Marshal(msg)
+	}
+	if err == nil {
+		// Written in place rather than renamed over, as Kubernetes mounts
+		// the termination log as a single file
+		err = ioutil.WriteFile(terminationLog, data, 0644)
+	}
+	if err != nil {
+		console.Println(console.Warning(fmt.Sprintf("Unable to write the termination log %s. Error: %v", terminationLog, err)))
+	}
+}
+
+// globalArgs passes the config file in use and the global flags given to
+// this command on to each step
+func globalArgs(cmd *cobra.Command) []string {
+	args := make([]string, 0)
+	configSet := false
+	cmd.InheritedFlags().Visit(func(f *pflag.Flag) {
+		configSet = configSet || f.Name == "config"
+		args = append(args, fmt.Sprintf("--%s=%s", f.Name, f.Value.String()))
+	})
+	if file := config.FileUsed(); !configSet && file != "" {
+		args = append(args, "--config="+file)
+	}
+	return args
+}
+
+func init() {
+	runCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "print the steps without running them")
+	runCmd.PersistentFlags().StringVar(&terminationLog, "termination-log", "", "file to write the outcome to as JSON, e.g. /dev/termination-log in Kubernetes")
+}
@@ -0,0 +1,17 @@
+package runbook
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// RunbookCmd &
+var RunbookCmd = &cobra.Command{
+	Use:   "runbook",
+	Short: "Runs named sequences of tfdr commands from the config",
+	Long:  `Runs named sequences of tfdr commands from the config`,
+}
+
+func init() {
+	RunbookCmd.AddCommand(runCmd)
+	RunbookCmd.AddCommand(listCmd)
+}
@@ -0,0 +1,47 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/mupuri/go-tfdr/internal/models"
+	"github.com/mupuri/go-tfdr/internal/schema"
+	"github.com/mupuri/go-tfdr/internal/variables"
+	"github.com/spf13/cobra"
+)
+
+var schemas = map[string]func() schema.Schema{
+	"config": func() schema.Schema {
+		return schema.Generate(config.Configuration{}, "tfdr config", "yaml")
+	},
+	"filters": func() schema.Schema {
+		return schema.Generate(models.FilterConfig{}, "tfdr state filters", "json")
+	},
+	"variables": func() schema.Schema {
+		return schema.Generate(struct {
+			Variables []variables.Variable `yaml:"variables"`
+		}{}, "tfdr variables file", "yaml")
+	},
+}
+
+// SchemaCmd &
+var SchemaCmd = &cobra.Command{
+	Use:       "schema config|filters|variables",
+	Short:     "Prints the JSON Schema of a tfdr input file",
+	ValidArgs: []string{"config", "filters", "variables"},
+	Long: `Prints the JSON Schema of the config file, the filter file used by 'state copy' and
+'state delete', or the variables file used by 'state copy --variables-file', so editors and CI
+can validate them before tfdr runs.`,
+	Args: cobra.ExactValidArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		generate, ok := schemas[args[0]]
+		if !ok {
+			return fmt.Errorf("unknown schema %q", args[0])
+		}
+		enc := json.NewEncoder(console.Out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(generate())
+	},
+}
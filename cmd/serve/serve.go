@@ -0,0 +1,135 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/mupuri/go-tfdr/internal/server"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// shutdownTimeout is how long running requests get to finish on shutdown
+const shutdownTimeout = 30 * time.Second
+
+// Request bodies are small JSON documents, so slow clients are cut off
+// instead of holding connections open
+const (
+	readHeaderTimeout = 10 * time.Second
+	readTimeout       = 30 * time.Second
+	idleTimeout       = 2 * time.Minute
+)
+
+var listen string
+var tlsCert string
+var tlsKey string
+
+// ServeCmd &
+var ServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Runs tfdr as a long running server",
+	Long:  `Runs tfdr as a long running server`,
+}
+
+var apiCmd = &cobra.Command{
+	Use:   "api",
+	Short: "Serves an HTTP API for starting restores and checking their status",
+	Long: `Serves an HTTP API so portals and chat bots can start restores and check on them.
+Clients authenticate with a bearer token from api_server.tokens. Restores run one at a time
+in the background:
+
+  POST /v1/restores         start a restore, with source, destinations, filters,
+                            create_missing, approvals, execution_mode and
+                            agent_pool_id
+  GET  /v1/operations       list operations, newest first
+  GET  /v1/operations/ID    get an operation's status and per-workspace results
+
+When api_server.webhooks is configured, POST /v1/webhooks/restores takes the same body as
+/v1/restores, signed with one of the webhook secrets for incident tooling such as PagerDuty.
+
+When api_server.slack is configured, POST /v1/slack/commands serves the /tfdr Slack
+slash command, authenticated with the Slack app's signing secret:
+
+  /tfdr status [ID]   list operations or show one, for api_server.slack.viewers
+
+GET /healthz and GET /readyz are unauthenticated probes for Kubernetes. /readyz checks the
+config, the tokens and that the lock directory is writable, and caches the result for 30s.
+
+The config is reloaded on SIGHUP, which also picks up changed tokens and Slack users. Operation history is
+kept in memory and lost on restart.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if (tlsCert == "") != (tlsKey == "") {
+			return errors.New("tls-cert and tls-key must be given together")
+		}
+		a := config.GetConfig().APIServer
+		if len(a.Tokens) == 0 && len(a.Webhooks) == 0 && (a.Slack == nil || a.Slack.SigningSecret == "") {
+			return errors.New("api_server.tokens, api_server.webhooks or api_server.slack.signing_secret is required")
+		}
+		return config.ValidateConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c := config.GetConfig()
+		if listen == "" {
+			listen = c.APIServer.Listen
+		}
+		if listen == "" {
+			listen = "127.0.0.1:8080"
+		}
+
+		s := server.New(c.APIServer, logging.Default())
+		defer s.Close()
+		stopReload := config.ReloadOnHangup(func(c *config.Configuration, err error) {
+			if err != nil {
+				logrus.Errorf("Unable to reload config, keeping the previous one. Error: %v", err)
+				return
+			}
+			s.SetConfig(c.APIServer)
+			logrus.Info("Reloaded config")
+		})
+		defer stopReload()
+
+		srv := &http.Server{
+			Addr:              listen,
+			Handler:           s.Handler(),
+			ReadHeaderTimeout: readHeaderTimeout,
+			ReadTimeout:       readTimeout,
+			IdleTimeout:       idleTimeout,
+		}
+		errs := make(chan error, 1)
+		go func() {
+			logrus.Infof("Serving the tfdr API on %s", listen)
+			if tlsCert != "" {
+				errs <- srv.ListenAndServeTLS(tlsCert, tlsKey)
+			} else {
+				errs <- srv.ListenAndServe()
+			}
+		}()
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(signals)
+		select {
+		case err := <-errs:
+			return err
+		case sig := <-signals:
+			logrus.Infof("Received %s, shutting down", sig)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return srv.Shutdown(ctx)
+	},
+}
+
+func init() {
+	apiCmd.PersistentFlags().StringVar(&listen, "listen", "", "address to listen on, defaults to api_server.listen or 127.0.0.1:8080")
+	apiCmd.PersistentFlags().StringVar(&tlsCert, "tls-cert", "", "TLS certificate file, serves plain HTTP when not set")
+	apiCmd.PersistentFlags().StringVar(&tlsKey, "tls-key", "", "TLS private key file")
+	ServeCmd.AddCommand(apiCmd)
+}
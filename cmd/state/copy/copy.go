@@ -2,15 +2,34 @@ package copy
 
 import (
 	"errors"
+	"time"
 
 	"github.com/mupuri/go-tfdr/internal/api"
 	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/variables"
 	"github.com/spf13/cobra"
 )
 
 var originalWorkspaceName string
 var newWorkspaceName string
+var destinations []string
 var filterConfigFile string
+var checkCredentials bool
+var alignTFVersion bool
+var createMissing bool
+var suppressRuns bool
+var redactProfile string
+var variablesFile string
+var approvals []string
+var copyStateSharing bool
+var copyNotifications bool
+var notificationURLs map[string]string
+var executionMode string
+var agentPoolID string
+var cleanDeposed bool
+var untaint bool
+var tagStatus bool
+var deadline time.Duration
 
 var CopyStateCmd = &cobra.Command{
 	Use:   "copy",
@@ -23,18 +42,72 @@ var CopyStateCmd = &cobra.Command{
 		if len(originalWorkspaceName) == 0 {
 			return errors.New("originalWorkspaceName is required")
 		}
-		if len(newWorkspaceName) == 0 {
-			return errors.New("newWorkspaceName is required")
+		if len(newWorkspaceName) == 0 && len(destinations) == 0 {
+			return errors.New("newWorkspaceName or dest is required")
+		}
+		if err := api.ValidateExecutionMode(executionMode, agentPoolID); err != nil {
+			return err
 		}
 		return config.ValidateConfig()
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return api.CopyTFState(originalWorkspaceName, newWorkspaceName, filterConfigFile)
+		dests := destinations
+		if newWorkspaceName != "" {
+			dests = append([]string{newWorkspaceName}, dests...)
+		}
+		opts := api.CopyOptions{
+			CheckCredentials:      checkCredentials,
+			AlignTerraformVersion: alignTFVersion,
+			CreateMissing:         createMissing,
+			SuppressRuns:          suppressRuns,
+			CopyStateSharing:      copyStateSharing,
+			CopyNotifications:     copyNotifications,
+			NotificationURLs:      notificationURLs,
+			Approvals:             approvals,
+			ExecutionMode:         executionMode,
+			AgentPoolID:           agentPoolID,
+			CleanDeposed:          cleanDeposed,
+			Untaint:               untaint,
+			TagStatus:             tagStatus,
+			Deadline:              deadline,
+		}
+		if redactProfile != "" {
+			profile, err := config.GetConfig().GetRedactionProfile(redactProfile)
+			if err != nil {
+				return err
+			}
+			opts.Redaction = &profile
+		}
+		if variablesFile != "" {
+			vars, err := variables.Load(variablesFile)
+			if err != nil {
+				return err
+			}
+			opts.Variables = vars
+		}
+		return api.CopyTFStateToMany(originalWorkspaceName, dests, filterConfigFile, opts)
 	},
 }
 
 func init() {
 	CopyStateCmd.PersistentFlags().StringVarP(&originalWorkspaceName, "originalWorkspaceName", "o", "", "workspace to copy state from")
 	CopyStateCmd.PersistentFlags().StringVarP(&newWorkspaceName, "newWorkspaceName", "n", "", "workspace to copy state to")
+	CopyStateCmd.PersistentFlags().StringArrayVar(&destinations, "dest", nil, "additional workspace to copy state to, can be repeated. The source state is downloaded once")
 	CopyStateCmd.PersistentFlags().StringVarP(&filterConfigFile, "filterConfigFile", "f", "", "file with filter config with resources to copy")
+	CopyStateCmd.PersistentFlags().BoolVar(&createMissing, "create-missing", false, "create the new workspace from the configured workspace_template if it does not exist")
+	CopyStateCmd.PersistentFlags().BoolVar(&alignTFVersion, "align-tf-version", false, "update the new workspace's terraform version when it is too old to read the copied state")
+	CopyStateCmd.PersistentFlags().BoolVar(&suppressRuns, "suppress-runs", false, "turn off auto-apply on the new workspace while copying and discard runs queued meanwhile, e.g. by VCS pushes, restoring auto-apply afterwards")
+	CopyStateCmd.PersistentFlags().BoolVar(&copyStateSharing, "copy-state-sharing", false, "share the new workspace's state with the same workspaces as the original, so terraform_remote_state reads keep working")
+	CopyStateCmd.PersistentFlags().BoolVar(&copyNotifications, "copy-notifications", false, "create the original workspace's notification configurations on the new workspace")
+	CopyStateCmd.PersistentFlags().StringToStringVar(&notificationURLs, "notification-url", nil, "with --copy-notifications, replace this notification URL prefix, given as old=new. Can be repeated")
+	CopyStateCmd.PersistentFlags().StringVar(&variablesFile, "variables-file", "", "set the variables in this file on the new workspace, with values from env, files, vault or sops")
+	CopyStateCmd.PersistentFlags().StringVar(&redactProfile, "redact", "", "replace the attributes listed in this redaction profile with placeholders, for seeding lower environments")
+	CopyStateCmd.PersistentFlags().StringArrayVar(&approvals, "approval", nil, "approval token from 'tfdr approve', needed for workspaces with the dual_control tag. Can be repeated")
+	CopyStateCmd.PersistentFlags().StringVar(&executionMode, "execution-mode", "", "switch the new workspace to remote, local or agent execution, e.g. when the primary region's agents are unavailable")
+	CopyStateCmd.PersistentFlags().StringVar(&agentPoolID, "agent-pool-id", "", "with --execution-mode agent, the agent pool the new workspace runs on")
+	CopyStateCmd.PersistentFlags().BoolVar(&cleanDeposed, "clean-deposed", false, "leave deposed instances out of the copied state, so the first apply doesn't destroy them")
+	CopyStateCmd.PersistentFlags().BoolVar(&untaint, "untaint", false, "clear the tainted status of copied instances, so the first apply doesn't replace them")
+	CopyStateCmd.PersistentFlags().BoolVar(&tagStatus, "tag-status", false, "tag the new workspace with the restore date and source workspace, e.g. tfdr:restored-2024-06-01 and tfdr:source:ws-prod-app")
+	CopyStateCmd.PersistentFlags().DurationVar(&deadline, "deadline", 0, "with several destinations, stop copying to further workspaces once this time budget is nearly used up, e.g. 30m")
+	CopyStateCmd.PersistentFlags().BoolVar(&checkCredentials, "check-credentials", false, "warn if the new workspace has no credentials for the providers in the copied state")
 }
@@ -0,0 +1,60 @@
+package format
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mupuri/go-tfdr/internal/atomicfile"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/mupuri/go-tfdr/internal/statefile"
+	"github.com/spf13/cobra"
+)
+
+var check bool
+
+// FmtStateCmd &
+var FmtStateCmd = &cobra.Command{
+	Use:   "fmt [file...]",
+	Short: "Rewrites local state files in a canonical format",
+	Long: `Rewrites local state files with sorted keys and consistent indentation so
+snapshots of the same state diff cleanly`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		unformatted := 0
+		for _, fileName := range args {
+			data, err := statefile.Read(fileName)
+			if err != nil {
+				return err
+			}
+			formatted, err := statefile.Format(data)
+			if err != nil {
+				return fmt.Errorf("%s: %v", fileName, err)
+			}
+			if bytes.Equal(data, formatted) {
+				continue
+			}
+			unformatted++
+			console.Println(fileName)
+			if check {
+				continue
+			}
+			info, err := os.Stat(fileName)
+			if err != nil {
+				return err
+			}
+			if err := atomicfile.WriteFile(fileName, formatted, info.Mode().Perm()); err != nil {
+				return fmt.Errorf("Unable to write state file %s. Err: %v", fileName, err)
+			}
+		}
+		if check && unformatted > 0 {
+			return errors.New("some state files are not formatted")
+		}
+		return nil
+	},
+}
+
+func init() {
+	FmtStateCmd.PersistentFlags().BoolVar(&check, "check", false, "only list files that are not formatted and exit non-zero")
+}
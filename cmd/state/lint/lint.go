@@ -0,0 +1,35 @@
+package lint
+
+import (
+	"fmt"
+
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/mupuri/go-tfdr/internal/statefile"
+	"github.com/spf13/cobra"
+)
+
+// LintStateCmd &
+var LintStateCmd = &cobra.Command{
+	Use:   "lint [file...]",
+	Short: "Validates local state files",
+	Long: `Validates local state files before they are restored, checking the format
+version, serial, lineage and resource structure`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		total := 0
+		for _, fileName := range args {
+			data, err := statefile.Read(fileName)
+			if err != nil {
+				return err
+			}
+			for _, p := range statefile.Lint(data) {
+				console.Printf("%s: %s\n", console.Bold(fileName), console.Failure(p.String()))
+				total++
+			}
+		}
+		if total > 0 {
+			return fmt.Errorf("found %d problem(s)", total)
+		}
+		return nil
+	},
+}
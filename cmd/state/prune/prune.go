@@ -0,0 +1,39 @@
+package prune
+
+import (
+	"errors"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var workspaceName string
+var keep int
+var dryRun bool
+
+// PruneVersionsCmd &
+var PruneVersionsCmd = &cobra.Command{
+	Use:   "prune-versions",
+	Short: "Deletes old state versions from a TF cloud workspace",
+	Long: `Deletes all but the newest state versions from a TF cloud workspace.
+Requires a Terraform Cloud/Enterprise instance that supports state version deletion.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(workspaceName) == 0 {
+			return errors.New("workspace is required")
+		}
+		if keep < 1 {
+			return errors.New("keep must be at least 1")
+		}
+		return config.ValidateConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return api.PruneStateVersions(workspaceName, keep, dryRun)
+	},
+}
+
+func init() {
+	PruneVersionsCmd.PersistentFlags().StringVarP(&workspaceName, "workspace", "w", "", "workspace name")
+	PruneVersionsCmd.PersistentFlags().IntVarP(&keep, "keep", "k", 50, "number of newest state versions to keep")
+	PruneVersionsCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "list the state versions that would be deleted without deleting them")
+}
@@ -0,0 +1,83 @@
+package show
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/mupuri/go-tfdr/internal/statefile"
+	"github.com/spf13/cobra"
+)
+
+var format string
+
+// ShowStateCmd &
+var ShowStateCmd = &cobra.Command{
+	Use:   "show WORKSPACE|FILE [ADDRESS]",
+	Short: "Shows the attributes of resources in a workspace or local state",
+	Long: `Prints the attributes of the resource instances in the current state of a workspace, or a
+local state file when such a file exists, in the style of terraform state show. ADDRESS selects an
+instance such as module.app.aws_instance.web[0], every instance of a resource, or a module;
+without it every instance is shown.
+
+Attributes the state marks sensitive, and strings in attributes named like passwords, secrets,
+tokens or private keys, are shown as (sensitive value). Nothing is written.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.RangeArgs(1, 2)(cmd, args); err != nil {
+			return err
+		}
+		if format != "text" && format != "json" {
+			return fmt.Errorf("unsupported format %q, expected text or json", format)
+		}
+		if _, err := os.Stat(args[0]); err == nil {
+			return nil
+		}
+		return config.ValidateReadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var data []byte
+		var err error
+		if _, statErr := os.Stat(args[0]); statErr == nil {
+			data, err = statefile.Read(args[0])
+		} else {
+			data, err = api.CurrentStateData(args[0])
+			if err == nil && data == nil {
+				err = errors.New("the workspace has no state")
+			}
+		}
+		if err != nil {
+			return err
+		}
+		address := ""
+		if len(args) == 2 {
+			address = args[1]
+		}
+		instances, err := statefile.Show(data, address)
+		if err != nil {
+			return err
+		}
+
+		if format == "json" {
+			enc := json.NewEncoder(console.Out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(instances)
+		}
+		for i, instance := range instances {
+			if i > 0 {
+				console.Println("")
+			}
+			if err := statefile.WriteInstance(console.Out, instance); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	ShowStateCmd.PersistentFlags().StringVar(&format, "format", "text", "output format, text or json")
+}
@@ -3,6 +3,12 @@ package state
 import (
 	"github.com/mupuri/go-tfdr/cmd/state/copy"
 	"github.com/mupuri/go-tfdr/cmd/state/delete"
+	"github.com/mupuri/go-tfdr/cmd/state/format"
+	"github.com/mupuri/go-tfdr/cmd/state/lint"
+	"github.com/mupuri/go-tfdr/cmd/state/prune"
+	"github.com/mupuri/go-tfdr/cmd/state/show"
+	"github.com/mupuri/go-tfdr/cmd/state/stats"
+	"github.com/mupuri/go-tfdr/cmd/state/upgrade"
 	"github.com/spf13/cobra"
 )
 
@@ -15,4 +21,10 @@ var StateCmd = &cobra.Command{
 func init() {
 	StateCmd.AddCommand(copy.CopyStateCmd)
 	StateCmd.AddCommand(delete.DeleteStateCmd)
+	StateCmd.AddCommand(prune.PruneVersionsCmd)
+	StateCmd.AddCommand(format.FmtStateCmd)
+	StateCmd.AddCommand(lint.LintStateCmd)
+	StateCmd.AddCommand(upgrade.UpgradeStateCmd)
+	StateCmd.AddCommand(stats.StatsStateCmd)
+	StateCmd.AddCommand(show.ShowStateCmd)
 }
@@ -0,0 +1,125 @@
+package stats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/mupuri/go-tfdr/internal/statefile"
+	"github.com/spf13/cobra"
+)
+
+var format string
+var top int
+
+// StatsStateCmd &
+var StatsStateCmd = &cobra.Command{
+	Use:   "stats WORKSPACE|FILE",
+	Short: "Reports resource counts and anomalies of a workspace or local state",
+	Long: `Reports the size of a state, its resource instance counts by type, provider and module,
+how deeply its modules are nested and how many instances are tainted or deposed, for capacity
+planning and to spot anomalies before a restore. The argument is read as a local state file
+when such a file exists, otherwise as the name of a workspace whose current state is used.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+			return err
+		}
+		if format != "text" && format != "json" {
+			return fmt.Errorf("unsupported format %q, expected text or json", format)
+		}
+		if _, err := os.Stat(args[0]); err == nil {
+			return nil
+		}
+		return config.ValidateReadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var data []byte
+		var err error
+		if _, statErr := os.Stat(args[0]); statErr == nil {
+			data, err = statefile.Read(args[0])
+		} else {
+			data, err = api.CurrentStateData(args[0])
+			if err == nil && data == nil {
+				err = errors.New("the workspace has no state")
+			}
+		}
+		if err != nil {
+			return err
+		}
+		stats, err := statefile.GetStats(data)
+		if err != nil {
+			return err
+		}
+
+		if format == "json" {
+			enc := json.NewEncoder(console.Out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(stats)
+		}
+
+		console.Printf("Size:             %d bytes\n", stats.Size)
+		console.Printf("Terraform:        %s, serial %d\n", stats.TerraformVersion, stats.Serial)
+		console.Printf("Resources:        %d managed, %d data sources, %d instances\n", stats.Resources, stats.DataSources, stats.Instances)
+		console.Printf("Outputs:          %d\n", stats.Outputs)
+		console.Printf("Max module depth: %d\n", stats.MaxModuleDepth)
+		console.Printf("Tainted:          %s\n", anomaly(stats.Tainted))
+		console.Printf("Deposed:          %s\n", anomaly(stats.Deposed))
+		for _, c := range []struct {
+			title  string
+			counts map[string]int
+		}{{"TYPE", stats.ByType}, {"PROVIDER", stats.ByProvider}, {"MODULE", stats.ByModule}} {
+			console.Println("")
+			if err := printCounts(c.title, c.counts); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func anomaly(n int) string {
+	if n == 0 {
+		return "0"
+	}
+	return console.Warning(fmt.Sprintf("%d instances", n))
+}
+
+// printCounts prints the top counts, largest first
+func printCounts(title string, counts map[string]int) error {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	w := tabwriter.NewWriter(console.Out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tINSTANCES\t\n", title)
+	for i, k := range keys {
+		if top > 0 && i == top {
+			fmt.Fprintf(w, "(%d more)\t\t\n", len(keys)-top)
+			break
+		}
+		label := k
+		if label == "" {
+			label = "(root)"
+		}
+		fmt.Fprintf(w, "%s\t%d\t\n", label, counts[k])
+	}
+	return w.Flush()
+}
+
+func init() {
+	StatsStateCmd.PersistentFlags().StringVar(&format, "format", "text", "output format, text or json")
+	StatsStateCmd.PersistentFlags().IntVar(&top, "top", 10, "number of types, providers and modules to list, 0 for all")
+}
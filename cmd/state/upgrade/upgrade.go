@@ -0,0 +1,48 @@
+package upgrade
+
+import (
+	"fmt"
+
+	"github.com/mupuri/go-tfdr/internal/atomicfile"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/mupuri/go-tfdr/internal/statefile"
+	"github.com/spf13/cobra"
+)
+
+var from int
+var to int
+var outputFile string
+
+// UpgradeStateCmd &
+var UpgradeStateCmd = &cobra.Command{
+	Use:   "upgrade file",
+	Short: "Upgrades a local state file to a newer state format version",
+	Long: `Upgrades a local state file to a newer state format version without contacting
+terraform cloud, so snapshots written by old terraform releases can be restored into
+workspaces running terraform 0.12 or later. Only upgrading from version 3 to 4 is supported.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := statefile.Read(args[0])
+		if err != nil {
+			return err
+		}
+		upgraded, err := statefile.Upgrade(data, from, to)
+		if err != nil {
+			return fmt.Errorf("%s: %v", args[0], err)
+		}
+		if outputFile == "" {
+			_, err = console.Out.Write(upgraded)
+			return err
+		}
+		if err := atomicfile.WriteFile(outputFile, upgraded, 0600); err != nil {
+			return fmt.Errorf("Unable to write state file %s. Err: %v", outputFile, err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	UpgradeStateCmd.PersistentFlags().IntVar(&from, "from", 3, "state format version of the file")
+	UpgradeStateCmd.PersistentFlags().IntVar(&to, "to", 4, "state format version to upgrade to")
+	UpgradeStateCmd.PersistentFlags().StringVarP(&outputFile, "output", "o", "", "file to write the upgraded state to, defaults to stdout")
+}
@@ -0,0 +1,81 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/mupuri/go-tfdr/internal/statefile"
+	"github.com/mupuri/go-tfdr/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+var prefix string
+var tags []string
+var pageSize int
+var verifyTimeout time.Duration
+
+// TuiCmd &
+var TuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Shows an interactive dashboard of the organization's workspaces",
+	Long: `Shows a keyboard driven dashboard of the workspaces matching --prefix and --tag, a page
+at a time. Filter the list with /, press enter to show a workspace's current state (serial,
+resource counts, tainted and deposed instances), v to queue a plan-only run that checks the
+workspace has no changes, and r to reload the list. Operations tfdr runs are listed as they
+progress. Log lines are not shown while the dashboard is open.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if pageSize < 1 {
+			return fmt.Errorf("page-size must be at least 1")
+		}
+		return config.ValidateReadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := api.WorkspaceFilter{Prefix: prefix, Tags: tags}
+		d := tui.New("tfdr: "+config.GetConfig().TerraformOrgName, pageSize, tui.Actions{
+			List: func() ([]string, error) {
+				return api.ListWorkspaces(filter)
+			},
+			Details: stateDetails,
+			Verify: func(name string) (string, error) {
+				results, err := api.VerifyWorkspaces([]string{name}, 0, verifyTimeout)
+				if len(results) == 0 {
+					return "", err
+				}
+				if results[0].Err != nil {
+					return results[0].Outcome + ": " + results[0].Err.Error(), nil
+				}
+				return results[0].Outcome, nil
+			},
+		})
+		defer api.AddEventHandler(d.HandleEvent)()
+		api.SetLogger(logging.Discard())
+		return tui.Run(d, console.Out)
+	},
+}
+
+func stateDetails(name string) (string, error) {
+	data, err := api.CurrentStateData(name)
+	if err != nil {
+		return "", err
+	}
+	if data == nil {
+		return "no state", nil
+	}
+	s, err := statefile.GetStats(data)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("serial %d, terraform %s, %d resources, %d instances, %d tainted, %d deposed, %d bytes",
+		s.Serial, s.TerraformVersion, s.Resources, s.Instances, s.Tainted, s.Deposed, s.Size), nil
+}
+
+func init() {
+	TuiCmd.PersistentFlags().StringVarP(&prefix, "prefix", "p", "", "only show workspaces whose name starts with this prefix")
+	TuiCmd.PersistentFlags().StringSliceVar(&tags, "tag", nil, "only show workspaces that have all of these tags, can be repeated")
+	TuiCmd.PersistentFlags().IntVar(&pageSize, "page-size", 20, "workspaces shown per page")
+	TuiCmd.PersistentFlags().DurationVar(&verifyTimeout, "verify-timeout", 30*time.Minute, "how long to wait for a verification plan")
+}
@@ -0,0 +1,38 @@
+package version
+
+import (
+	"encoding/json"
+
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/mupuri/go-tfdr/version"
+	"github.com/spf13/cobra"
+)
+
+var jsonOutput bool
+
+// VersionCmd &
+var VersionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Prints the tfdr version and build information",
+	Long: `Prints the tfdr version, commit, build date, go version and API level. The API level
+changes only when commands or output formats change incompatibly, so scripts can check it
+before running.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		info := version.Get()
+		if jsonOutput {
+			enc := json.NewEncoder(console.Out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(info)
+		}
+		console.Printf("tfdr %s\n", info.Version)
+		console.Printf("commit:    %s\n", info.Commit)
+		console.Printf("built:     %s\n", info.Date)
+		console.Printf("go:        %s\n", info.GoVersion)
+		console.Printf("api level: %d\n", info.APILevel)
+		return nil
+	},
+}
+
+func init() {
+	VersionCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "print the build information as JSON")
+}
@@ -0,0 +1,63 @@
+package workspace
+
+import (
+	"errors"
+	"time"
+
+	"github.com/eiannone/keyboard"
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/spf13/cobra"
+)
+
+var prefix string
+var tags []string
+var safeDelete bool
+var yes bool
+var deadline time.Duration
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Deletes all TF cloud workspaces whose name starts with a prefix",
+	Long: `Deletes all TF cloud workspaces whose name starts with a prefix, for cleaning up
+after DR rehearsals. With --safe-delete, workspaces that still manage resources are kept.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(prefix) == 0 {
+			return errors.New("prefix is required")
+		}
+		return config.ValidateConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names, err := api.ListWorkspaces(api.WorkspaceFilter{Prefix: prefix, Tags: tags})
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			console.Printf("No workspaces match prefix %q and tags %v\n", prefix, tags)
+			return nil
+		}
+
+		console.Printf("Workspaces to delete:\n")
+		for _, name := range names {
+			console.Printf("  %s\n", name)
+		}
+		if !yes {
+			console.Printf("Delete %d workspaces? [y/N] ", len(names))
+			txt, _, _ := keyboard.GetSingleKey()
+			console.Println()
+			if txt != 'Y' && txt != 'y' {
+				return nil
+			}
+		}
+		return api.DeleteWorkspaces(names, safeDelete, deadline)
+	},
+}
+
+func init() {
+	deleteCmd.PersistentFlags().StringVarP(&prefix, "prefix", "p", "", "delete workspaces whose name starts with this prefix")
+	deleteCmd.PersistentFlags().StringSliceVar(&tags, "tag", nil, "only delete workspaces that have all of these tags, can be repeated")
+	deleteCmd.PersistentFlags().BoolVar(&safeDelete, "safe-delete", false, "only delete workspaces that no longer manage any resources")
+	deleteCmd.PersistentFlags().BoolVarP(&yes, "yes", "y", false, "delete without asking for confirmation")
+	deleteCmd.PersistentFlags().DurationVar(&deadline, "deadline", 0, "stop deleting workspaces once this time budget is nearly used up, e.g. 30m")
+}
@@ -0,0 +1,97 @@
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/mupuri/go-tfdr/internal/models"
+	"github.com/mupuri/go-tfdr/internal/statefile"
+	"github.com/spf13/cobra"
+)
+
+var dependenciesWorkspace string
+var dependenciesState string
+var dependenciesOrg string
+var dependenciesRenames map[string]string
+
+var dependenciesCmd = &cobra.Command{
+	Use:   "dependencies",
+	Short: "Checks the upstream workspaces a workspace reads remote state from",
+	Long: `Finds the terraform_remote_state data sources in the state of a workspace, or of a
+local state file, and checks that each upstream workspace exists and has the outputs the
+workspace reads, before the workspace is restored. Upstreams are looked up in --org, or the
+organization named by the data source, and --rename-prefix old=new maps upstream workspace
+names starting with old to names starting with new. Exits non-zero when any upstream is
+not ready.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if (dependenciesWorkspace == "") == (dependenciesState == "") {
+			return errors.New("exactly one of workspace and state is required")
+		}
+		return config.ValidateReadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var deps []api.RemoteStateDependency
+		if dependenciesState != "" {
+			data, err := statefile.Read(dependenciesState)
+			if err != nil {
+				return err
+			}
+			var state models.State
+			if err := json.Unmarshal(data, &state); err != nil {
+				return fmt.Errorf("Invalid state json. Err: %v", err)
+			}
+			deps = api.RemoteStateDependencies(&state)
+		} else {
+			var err error
+			deps, err = api.WorkspaceRemoteStateDependencies(dependenciesWorkspace)
+			if err != nil {
+				return err
+			}
+		}
+		if len(deps) == 0 {
+			console.Println("No remote state dependencies")
+			return nil
+		}
+
+		deps, err := api.CheckRemoteStateDependencies(deps, dependenciesOrg, dependenciesRenames)
+		if err != nil {
+			return err
+		}
+		notReady := 0
+		w := tabwriter.NewWriter(console.Out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "DATA SOURCE\tUPSTREAM\tOUTPUTS\tSTATUS\t")
+		for _, d := range deps {
+			status := console.Success("READY")
+			switch {
+			case !d.Exists:
+				status = console.Failure("NO WORKSPACE")
+			case len(d.MissingOutputs) > 0:
+				status = console.Failure("MISSING " + strings.Join(d.MissingOutputs, ", "))
+			}
+			if !d.Ready() {
+				notReady++
+			}
+			fmt.Fprintf(w, "%s\t%s/%s\t%d\t%s\n", d.Address, d.Organization, d.Workspace, len(d.Outputs), status)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if notReady > 0 {
+			return fmt.Errorf("%d of %d remote state dependencies are not ready", notReady, len(deps))
+		}
+		return nil
+	},
+}
+
+func init() {
+	dependenciesCmd.PersistentFlags().StringVarP(&dependenciesWorkspace, "workspace", "w", "", "workspace whose current state is checked")
+	dependenciesCmd.PersistentFlags().StringVar(&dependenciesState, "state", "", "local state file to check instead of a workspace")
+	dependenciesCmd.PersistentFlags().StringVar(&dependenciesOrg, "org", "", "organization to look up upstream workspaces in, defaults to the one named by each data source")
+	dependenciesCmd.PersistentFlags().StringToStringVar(&dependenciesRenames, "rename-prefix", nil, "map upstream workspace names starting with old to names starting with new, given as old=new, can be repeated")
+}
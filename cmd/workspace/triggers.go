@@ -0,0 +1,113 @@
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/atomicfile"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/spf13/cobra"
+)
+
+var triggersFile string
+var triggersPrefix string
+var renamePrefix string
+
+var triggersCmd = &cobra.Command{
+	Use:   "triggers",
+	Short: "Exports and recreates run triggers between workspaces",
+	Long:  `Exports and recreates run triggers between workspaces`,
+}
+
+var exportTriggersCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exports the run triggers between workspaces as JSON",
+	Long: `Writes every run trigger of the workspaces whose name starts with --prefix as a
+JSON list of source and workspace names, for recreating with 'workspace triggers recreate'.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		return config.ValidateReadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		triggers, err := api.ExportRunTriggers(api.WorkspaceFilter{Prefix: triggersPrefix})
+		if err != nil {
+			return err
+		}
+
+		if triggersFile == "" {
+			return writeTriggers(console.Out, triggers)
+		}
+		f, err := atomicfile.Create(triggersFile, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Abort()
+		if err := writeTriggers(f, triggers); err != nil {
+			return err
+		}
+		return f.Commit()
+	},
+}
+
+func writeTriggers(w io.Writer, triggers []api.RunTrigger) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(triggers)
+}
+
+var recreateTriggersCmd = &cobra.Command{
+	Use:   "recreate",
+	Short: "Recreates exported run triggers in the configured organization",
+	Long: `Recreates the run triggers written by 'workspace triggers export', typically with
+the DR organization's config. Triggers that already exist are skipped. With --rename-prefix
+old=new, workspace names starting with old are mapped to names starting with new.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(triggersFile) == 0 {
+			return errors.New("file is required")
+		}
+		if renamePrefix != "" && !strings.Contains(renamePrefix, "=") {
+			return fmt.Errorf("invalid rename-prefix %q, expected old=new", renamePrefix)
+		}
+		return config.ValidateConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := os.Open(triggersFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		var triggers []api.RunTrigger
+		if err := json.NewDecoder(f).Decode(&triggers); err != nil {
+			return fmt.Errorf("Unable to read run triggers from %s. Error: %v", triggersFile, err)
+		}
+
+		if renamePrefix != "" {
+			parts := strings.SplitN(renamePrefix, "=", 2)
+			rename := func(name string) string {
+				if strings.HasPrefix(name, parts[0]) {
+					return parts[1] + strings.TrimPrefix(name, parts[0])
+				}
+				return name
+			}
+			for i := range triggers {
+				triggers[i].Source = rename(triggers[i].Source)
+				triggers[i].Workspace = rename(triggers[i].Workspace)
+			}
+		}
+		return api.RecreateRunTriggers(triggers)
+	},
+}
+
+func init() {
+	exportTriggersCmd.PersistentFlags().StringVarP(&triggersPrefix, "prefix", "p", "", "only export run triggers into workspaces whose name starts with this prefix")
+	exportTriggersCmd.PersistentFlags().StringVarP(&triggersFile, "output", "o", "", "file to write to, defaults to stdout")
+	recreateTriggersCmd.PersistentFlags().StringVarP(&triggersFile, "file", "f", "", "file written by 'workspace triggers export'")
+	recreateTriggersCmd.PersistentFlags().StringVar(&renamePrefix, "rename-prefix", "", "map workspace names starting with old to names starting with new, given as old=new")
+	triggersCmd.AddCommand(exportTriggersCmd)
+	triggersCmd.AddCommand(recreateTriggersCmd)
+}
@@ -0,0 +1,85 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/spf13/cobra"
+)
+
+var verifyWorkspaces []string
+var verifyPrefix string
+var verifyTags []string
+var verifySample int
+var verifyTimeout time.Duration
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Queues plan-only runs to check restored workspaces converge",
+	Long: `Queues a plan-only run on each workspace, or on --sample of them picked at random,
+after a bulk restore and reports which plans have no changes. A workspace whose plan has
+changes doesn't match its restored state. Exits non-zero unless every plan has no changes.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(verifyWorkspaces) == 0 && verifyPrefix == "" {
+			return errors.New("workspace or prefix is required")
+		}
+		return config.ValidateConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names := verifyWorkspaces
+		if len(names) == 0 {
+			var err error
+			names, err = api.ListWorkspaces(api.WorkspaceFilter{Prefix: verifyPrefix, Tags: verifyTags})
+			if err != nil {
+				return err
+			}
+		}
+		if len(names) == 0 {
+			console.Printf("No workspaces match prefix %q and tags %v\n", verifyPrefix, verifyTags)
+			return nil
+		}
+
+		results, err := api.VerifyWorkspaces(names, verifySample, verifyTimeout)
+		if err != nil {
+			return err
+		}
+		converged := 0
+		w := tabwriter.NewWriter(console.Out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "WORKSPACE\tRUN\tRESULT\t")
+		for _, r := range results {
+			outcome := console.Failure(r.Outcome)
+			switch r.Outcome {
+			case api.VerifyNoChanges:
+				outcome = console.Success(r.Outcome)
+				converged++
+			case api.VerifyChanges:
+				outcome = console.Warning(r.Outcome)
+			}
+			if r.Err != nil {
+				outcome += " (" + r.Err.Error() + ")"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r.Workspace, r.RunID, outcome)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		console.Printf("%d of %d workspaces converged\n", converged, len(results))
+		if converged < len(results) {
+			return fmt.Errorf("%d of %d workspaces did not converge", len(results)-converged, len(results))
+		}
+		return nil
+	},
+}
+
+func init() {
+	verifyCmd.PersistentFlags().StringSliceVarP(&verifyWorkspaces, "workspace", "w", nil, "workspace to verify, can be repeated")
+	verifyCmd.PersistentFlags().StringVarP(&verifyPrefix, "prefix", "p", "", "verify workspaces whose name starts with this prefix")
+	verifyCmd.PersistentFlags().StringSliceVar(&verifyTags, "tag", nil, "only verify workspaces that have all of these tags, can be repeated")
+	verifyCmd.PersistentFlags().IntVar(&verifySample, "sample", 0, "only verify this many workspaces picked at random, 0 verifies all")
+	verifyCmd.PersistentFlags().DurationVar(&verifyTimeout, "timeout", 30*time.Minute, "how long to wait for the plans to finish")
+}
@@ -0,0 +1,19 @@
+package workspace
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// WorkspaceCmd &
+var WorkspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Manages TF cloud workspaces",
+	Long:  `Manages TF cloud workspaces`,
+}
+
+func init() {
+	WorkspaceCmd.AddCommand(deleteCmd)
+	WorkspaceCmd.AddCommand(dependenciesCmd)
+	WorkspaceCmd.AddCommand(triggersCmd)
+	WorkspaceCmd.AddCommand(verifyCmd)
+}
@@ -8,7 +8,10 @@ require (
 	github.com/jarcoal/httpmock v1.0.6
 	github.com/sirupsen/logrus v1.7.0
 	github.com/spf13/cobra v1.1.0
+	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.7.0
 	github.com/stretchr/testify v1.6.1
+	github.com/svanharmelen/jsonapi v0.0.0-20180618144545-0c0828c3f16d
+	go.uber.org/zap v1.16.0
 	gopkg.in/yaml.v2 v2.3.0
 )
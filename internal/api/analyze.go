@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/models"
+	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+)
+
+// WorkspaceStats describes the size of a workspace's current state and how
+// it has grown over the sampled state versions
+type WorkspaceStats struct {
+	Workspace string
+	Serial    int64
+	Size      int64
+	Resources int
+	// GrowthPerDay is the average change in state size, in bytes per day,
+	// between the oldest and newest sampled state versions
+	GrowthPerDay float64
+	// Err is why the workspace couldn't be analyzed
+	Err error
+}
+
+// stateVersionMetadata is the size and resource summary Terraform Cloud keeps
+// for a state version, which go-tfe does not know about
+type stateVersionMetadata struct {
+	Data struct {
+		Attributes struct {
+			Size               int64             `json:"size"`
+			ResourcesProcessed bool              `json:"resources-processed"`
+			Resources          []json.RawMessage `json:"resources"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// AnalyzeWorkspaces reports state statistics for the workspaces of an
+// organization that match filter, sampling the newest samples state versions
+// of each. A workspace that can't be analyzed is reported with Err set and
+// doesn't stop the others. A non-zero deadline stops analyzing once the time
+// budget is nearly used up, returning the stats gathered so far.
+func AnalyzeWorkspaces(orgName string, filter WorkspaceFilter, samples int, deadline time.Duration) ([]WorkspaceStats, error) {
+	if samples < 1 {
+		return nil, fmt.Errorf("At least one state version must be sampled")
+	}
+	c := config.GetConfig()
+
+	client, err := newTFEClient(c.ReadToken())
+	if err != nil {
+		return nil, fmt.Errorf("Cannot create tfe client. Err: %v", err)
+	}
+
+	workspaces, err := listWorkspaces(client, c.ReadToken(), orgName, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	op := startOperation("analyze", len(workspaces))
+	b := newBudget(deadline)
+	stats := make([]WorkspaceStats, 0, len(workspaces))
+	failed := 0
+	for i, w := range workspaces {
+		if !b.allows() {
+			return stats, op.finish(tfdrerrors.ErrDeadlineExceeded{Remaining: workspaceNames(workspaces[i:]), Failed: failed})
+		}
+		start := time.Now()
+		s, err := analyzeWorkspace(client, c.ReadToken(), orgName, w.Name, samples)
+		b.record(start)
+		op.workspaceDone(w.Name, err)
+		if err != nil {
+			failed++
+			logger.Errorf("Unable to analyze workspace %s. Error: %v", w.Name, err)
+			s = WorkspaceStats{Workspace: w.Name, Err: err}
+		}
+		stats = append(stats, s)
+	}
+	if failed > 0 {
+		return stats, op.finish(fmt.Errorf("Failed to analyze %d of %d workspaces", failed, len(workspaces)))
+	}
+	return stats, op.finish(nil)
+}
+
+func analyzeWorkspace(client *tfe.Client, token string, orgName string, workspaceName string, samples int) (WorkspaceStats, error) {
+	stats := WorkspaceStats{Workspace: workspaceName}
+
+	versions, err := listStateVersions(client, orgName, workspaceName)
+	if err != nil {
+		return stats, fmt.Errorf("Unable to list state versions of %s. Error: %v", workspaceName, err)
+	}
+	if len(versions) == 0 {
+		return stats, nil
+	}
+	if len(versions) > samples {
+		versions = versions[:samples]
+	}
+
+	logger.Debugf("Sampling %d state versions of %s", len(versions), workspaceName)
+	var oldestSize int64
+	for i, sv := range versions {
+		meta, err := readStateVersionMetadata(token, sv.ID)
+		if err != nil {
+			return stats, err
+		}
+		attrs := meta.Data.Attributes
+		size := attrs.Size
+		if i == 0 {
+			stats.Serial = sv.Serial
+			stats.Resources = len(attrs.Resources)
+		}
+		// Older TFE releases don't report sizes or resources, so only then
+		// the state itself is downloaded
+		if size == 0 || (i == 0 && !attrs.ResourcesProcessed) {
+			data, err := downloadState(client, token, sv.DownloadURL)
+			if err != nil {
+				return stats, fmt.Errorf("Unable to download state version %s. Error: %v", sv.ID, err)
+			}
+			size = int64(len(data))
+			if i == 0 {
+				var state models.State
+				if err := json.Unmarshal(data, &state); err != nil {
+					return stats, fmt.Errorf("Unable to read state version %s. Error: %v", sv.ID, err)
+				}
+				stats.Resources = managedResources(state.Resources)
+			}
+		}
+		if i == 0 {
+			stats.Size = size
+		}
+		oldestSize = size
+	}
+
+	newest, oldest := versions[0], versions[len(versions)-1]
+	if days := newest.CreatedAt.Sub(oldest.CreatedAt).Hours() / 24; days > 0 {
+		stats.GrowthPerDay = float64(stats.Size-oldestSize) / days
+	}
+	return stats, nil
+}
+
+// readStateVersionMetadata reads a state version's size and resources
+// without downloading the state
+func readStateVersionMetadata(token string, id string) (stateVersionMetadata, error) {
+	var meta stateVersionMetadata
+	resp, err := doAPIRequest("GET", "state-versions/"+id, token, nil)
+	if err != nil {
+		return meta, fmt.Errorf("Unable to read state version %s. Error: %v", id, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return meta, fmt.Errorf("Unexpected status reading state version %s: %s", id, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&meta); err != nil {
+		return meta, fmt.Errorf("Unable to read state version %s. Error: %v", id, err)
+	}
+	return meta, nil
+}
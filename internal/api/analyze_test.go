@@ -0,0 +1,120 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+	"github.com/stretchr/testify/suite"
+)
+
+const analyzeWorkspaceList = `{"data":[{"id":"ws-1","type":"workspaces","attributes":{"name":"test"}}],
+"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":1}}}`
+
+const analyzeStateVersionList = `{"data":[
+{"id":"sv-1","type":"state-versions","attributes":{"serial":1,"created-at":"2020-10-01T00:00:00Z","hosted-state-download-url":"https://state/1"}},
+{"id":"sv-3","type":"state-versions","attributes":{"serial":3,"created-at":"2020-10-11T00:00:00Z","hosted-state-download-url":"https://state/3"}},
+{"id":"sv-2","type":"state-versions","attributes":{"serial":2,"created-at":"2020-10-06T00:00:00Z","hosted-state-download-url":"https://state/2"}}
+],"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":3}}}`
+
+type AnalyzeSuite struct {
+	suite.Suite
+	newest string
+}
+
+func (s *AnalyzeSuite) SetupTest() {
+	os.Setenv("TF_TEAM_TOKEN", "test")
+	os.Setenv("TF_ORG_NAME", "team")
+	config.InitConfig("")
+	logging.InitLogger()
+	httpmock.ActivateNonDefault(httpClient)
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/ping", httpmock.NewStringResponder(204, ""))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/other/workspaces", httpmock.NewStringResponder(200, analyzeWorkspaceList))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/state-versions", httpmock.NewStringResponder(200, analyzeStateVersionList))
+
+	s.newest = `{"version":4,"serial":3,"resources":[{"mode":"managed","type":"a","name":"a"},{"mode":"data","type":"b","name":"b"}]}` + strings.Repeat(" ", 1000)
+	httpmock.RegisterResponder("GET", "https://state/3", httpmock.NewStringResponder(200, s.newest))
+	httpmock.RegisterResponder("GET", "https://state/2", httpmock.NewStringResponder(200, `{"version":4,"serial":2}`))
+	httpmock.RegisterResponder("GET", "https://state/1", httpmock.NewStringResponder(200, `{"version":4,"serial":1}`))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/state-versions/sv-3", stateVersionMetadataResponder(`"size":5000,"resources-processed":true,"resources":[{"type":"a","name":"a","count":2}]`))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/state-versions/sv-2", stateVersionMetadataResponder(`"size":4000,"resources-processed":true,"resources":[]`))
+}
+
+func stateVersionMetadataResponder(attributes string) httpmock.Responder {
+	return httpmock.NewStringResponder(200, `{"data":{"id":"sv","type":"state-versions","attributes":{`+attributes+`}}}`)
+}
+
+func (s *AnalyzeSuite) TearDownTest() {
+	httpmock.DeactivateAndReset()
+	os.Unsetenv("TF_TEAM_TOKEN")
+	os.Unsetenv("TF_ORG_NAME")
+}
+
+func (s *AnalyzeSuite) TestAnalyzeWorkspaces() {
+	stats, err := AnalyzeWorkspaces("other", WorkspaceFilter{}, 2, 0)
+	s.NoError(err)
+	s.Len(stats, 1)
+	s.Equal("test", stats[0].Workspace)
+	s.Equal(int64(3), stats[0].Serial)
+	s.Equal(int64(5000), stats[0].Size)
+	s.Equal(1, stats[0].Resources)
+	s.InDelta(float64(1000)/5, stats[0].GrowthPerDay, 0.001)
+	info := httpmock.GetCallCountInfo()
+	s.Zero(info["GET https://state/1"]+info["GET https://state/2"]+info["GET https://state/3"], "states should not be downloaded when their size is known")
+}
+
+func (s *AnalyzeSuite) TestAnalyzeWorkspacesWithoutMetadata() {
+	// Older TFE releases don't report sizes or resources
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/state-versions/sv-3", stateVersionMetadataResponder(`"serial":3`))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/state-versions/sv-2", stateVersionMetadataResponder(`"serial":2`))
+
+	stats, err := AnalyzeWorkspaces("other", WorkspaceFilter{}, 2, 0)
+	s.NoError(err)
+	s.Equal(int64(len(s.newest)), stats[0].Size)
+	s.Equal(1, stats[0].Resources, "data sources should not be counted")
+	s.InDelta(float64(len(s.newest)-len(`{"version":4,"serial":2}`))/5, stats[0].GrowthPerDay, 0.001)
+}
+
+func (s *AnalyzeSuite) TestAnalyzeWorkspacesStopsAtDeadline() {
+	stats, err := AnalyzeWorkspaces("other", WorkspaceFilter{}, 2, time.Nanosecond)
+	s.Equal(tfdrerrors.ErrDeadlineExceeded{Remaining: []string{"test"}}, err)
+	s.Empty(stats)
+	s.Equal(0, httpmock.GetCallCountInfo()["GET https://app.terraform.io/api/v2/state-versions"])
+}
+
+func (s *AnalyzeSuite) TestAnalyzeWorkspacesContinuesAfterFailure() {
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/other/workspaces", httpmock.NewStringResponder(200, `{"data":[
+{"id":"ws-0","type":"workspaces","attributes":{"name":"broken"}},
+{"id":"ws-1","type":"workspaces","attributes":{"name":"test"}}],
+"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":2}}}`))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/state-versions", func(req *http.Request) (*http.Response, error) {
+		if req.URL.Query().Get("filter[workspace][name]") == "broken" {
+			return httpmock.NewStringResponse(500, ""), nil
+		}
+		return httpmock.NewStringResponse(200, analyzeStateVersionList), nil
+	})
+
+	stats, err := AnalyzeWorkspaces("other", WorkspaceFilter{}, 2, 0)
+	s.EqualError(err, "Failed to analyze 1 of 2 workspaces")
+	s.Len(stats, 2)
+	s.Equal("broken", stats[0].Workspace)
+	s.Error(stats[0].Err)
+	s.Equal("test", stats[1].Workspace)
+	s.NoError(stats[1].Err)
+	s.Equal(int64(5000), stats[1].Size)
+}
+
+func (s *AnalyzeSuite) TestAnalyzeWorkspacesRequiresSample() {
+	_, err := AnalyzeWorkspaces("other", WorkspaceFilter{}, 0, 0)
+	s.Error(err)
+}
+
+func TestAnalyzeSuite(t *testing.T) {
+	suite.Run(t, new(AnalyzeSuite))
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/approval"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+)
+
+type workspaceTagsResponse struct {
+	Data struct {
+		Attributes struct {
+			TagNames []string `json:"tag-names"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// checkApprovals returns an error unless every destination with the dual
+// control tag is covered by one of the approval tokens. Tokens from the
+// person the run is on behalf of are rejected. It runs before anything is
+// written.
+func checkApprovals(c *config.Configuration, dests []string, tokens []string, createMissing bool) error {
+	dc := c.DualControl
+	if dc == nil {
+		return nil
+	}
+	tag := dc.ProtectedTag()
+	// Without tags no workspace could be recognized as critical
+	if !supports(c.ReadToken(), c.TerraformOrgName, CapabilityTags) {
+		return fmt.Errorf("dual_control is configured but this TFE release does not support workspace tags, so critical workspaces can't be recognized")
+	}
+
+	approvals := make([]approval.Approval, 0, len(tokens))
+	for _, t := range tokens {
+		a, err := approval.Verify(t, dc.Approvers)
+		if err != nil {
+			return err
+		}
+		if onBehalfOf != "" && strings.EqualFold(a.Approver, onBehalfOf) {
+			return fmt.Errorf("Approval token is from %q, who is running the restore. A second person has to approve it", a.Approver)
+		}
+		approvals = append(approvals, a)
+	}
+
+	now := time.Now()
+	for _, name := range dests {
+		tags, found, err := workspaceTags(c.ReadToken(), c.TerraformOrgName, name)
+		if err != nil {
+			return err
+		}
+		if !found {
+			if !createMissing {
+				continue
+			}
+			// The workspace will be created from the template
+			tags = c.WorkspaceTemplate.Tags
+		}
+		if !hasTag(tags, tag) {
+			continue
+		}
+
+		approved := false
+		for _, a := range approvals {
+			if a.Covers(c.TerraformOrgName, name, now) {
+				logger.Infof("Restore to workspace %s approved by %s", name, a.Approver)
+				approved = true
+				break
+			}
+		}
+		if !approved {
+			return tfdrerrors.ErrApprovalRequired{Workspace: name, Tag: tag}
+		}
+	}
+	return nil
+}
+
+// workspaceTags reads a workspace's tags, which go-tfe does not know about
+func workspaceTags(token string, orgName string, name string) ([]string, bool, error) {
+	resp, err := doAPIRequest("GET", fmt.Sprintf("organizations/%s/workspaces/%s", orgName, url.PathEscape(name)), token, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("Unexpected status reading workspace %s: %s", name, resp.Status)
+	}
+
+	var ws workspaceTagsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ws); err != nil {
+		return nil, false, err
+	}
+	return ws.Data.Attributes.TagNames, true, nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
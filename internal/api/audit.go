@@ -0,0 +1,325 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+)
+
+// Permission is a Terraform Cloud permission, named after the permissions
+// the API reports on workspaces and organizations
+type Permission string
+
+// Permissions tfdr audits
+const (
+	PermReadSettings       Permission = "workspace:can-read-settings"
+	PermQueueRun           Permission = "workspace:can-queue-run"
+	PermLock               Permission = "workspace:can-lock"
+	PermUpdateVariable     Permission = "workspace:can-update-variable"
+	PermQueueApply         Permission = "workspace:can-queue-apply"
+	PermQueueDestroy       Permission = "workspace:can-queue-destroy"
+	PermUpdateWorkspace    Permission = "workspace:can-update"
+	PermForceUnlock        Permission = "workspace:can-force-unlock"
+	PermDestroyWorkspace   Permission = "workspace:can-destroy"
+	PermCreateWorkspace    Permission = "organization:can-create-workspace"
+	PermCreateTeam         Permission = "organization:can-create-team"
+	PermUpdateOrganization Permission = "organization:can-update"
+	PermUpdateOAuth        Permission = "organization:can-update-oauth"
+	PermUpdateSentinel     Permission = "organization:can-update-sentinel"
+	PermDestroyOrg         Permission = "organization:can-destroy"
+)
+
+// workspaceAccess are Terraform Cloud's team access levels on workspaces,
+// from least to most, with the permissions each adds
+var workspaceAccess = []struct {
+	name        string
+	permissions []Permission
+}{
+	{"read", []Permission{PermReadSettings}},
+	{"plan", []Permission{PermQueueRun}},
+	{"write", []Permission{PermLock, PermUpdateVariable, PermQueueApply, PermQueueDestroy}},
+	{"admin", []Permission{PermUpdateWorkspace, PermForceUnlock, PermDestroyWorkspace}},
+}
+
+// operationNeeds are the permissions each tfdr command needs, split by the
+// token it uses
+type operationNeeds struct {
+	read  []Permission
+	write []Permission
+}
+
+var operations = map[string]operationNeeds{
+	"analyze":                     {read: []Permission{PermReadSettings}},
+	"import-local":                {read: []Permission{PermReadSettings}, write: []Permission{PermLock}},
+	"inventory":                   {read: []Permission{PermReadSettings}},
+	"state copy":                  {read: []Permission{PermReadSettings}, write: []Permission{PermLock}},
+	"state delete":                {read: []Permission{PermReadSettings}, write: []Permission{PermLock}},
+	"state prune":                 {read: []Permission{PermReadSettings}, write: []Permission{PermUpdateWorkspace}},
+	"state show":                  {read: []Permission{PermReadSettings}},
+	"state stats":                 {read: []Permission{PermReadSettings}},
+	"tui":                         {read: []Permission{PermReadSettings}, write: []Permission{PermQueueRun}},
+	"workspace delete":            {write: []Permission{PermDestroyWorkspace}},
+	"workspace dependencies":      {read: []Permission{PermReadSettings}},
+	"workspace triggers export":   {read: []Permission{PermReadSettings}},
+	"workspace triggers recreate": {write: []Permission{PermUpdateWorkspace}},
+	"workspace verify":            {write: []Permission{PermQueueRun}},
+}
+
+// copyFlagNeeds are the extra write permissions of state copy and
+// import-local flags
+var copyFlagNeeds = map[string][]Permission{
+	"--create-missing":     {PermCreateWorkspace, PermUpdateWorkspace},
+	"--suppress-runs":      {PermUpdateWorkspace},
+	"--copy-state-sharing": {PermUpdateWorkspace},
+	"--copy-notifications": {PermUpdateWorkspace},
+	"--align-tf-version":   {PermUpdateWorkspace},
+	"--variables-file":     {PermUpdateVariable},
+	"--execution-mode":     {PermUpdateWorkspace},
+}
+
+// AuditedOperations returns the commands AuditTokens knows the needs of
+func AuditedOperations() []string {
+	names := make([]string, 0, len(operations))
+	for name := range operations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TokenAudit compares what a configured token can do with what tfdr needs it
+// for
+type TokenAudit struct {
+	// Role is read, write, or team when one token is used for both
+	Role            string
+	Identity        string
+	ServiceAccount  bool
+	Workspaces      int
+	Granted         []Permission
+	Required        []Permission
+	Missing         []Permission
+	Unneeded        []Permission
+	Recommendations []string
+}
+
+// AuditTokens checks the permissions of the configured tokens on the
+// organization and the workspaces matching filter against what the given
+// commands need. Commands are tfdr command lines without the leading tfdr,
+// as in runbook steps; unknown commands are ignored.
+func AuditTokens(commands []string, filter WorkspaceFilter) ([]TokenAudit, error) {
+	c := config.GetConfig()
+
+	read, write := make(map[Permission]bool), make(map[Permission]bool)
+	for _, command := range commands {
+		needs, ok := commandNeeds(command)
+		if !ok {
+			logger.Debugf("No known permissions for %q, skipping it", command)
+			continue
+		}
+		for _, p := range needs.read {
+			read[p] = true
+		}
+		for _, p := range needs.write {
+			write[p] = true
+		}
+	}
+
+	if c.ReadToken() == c.WriteToken() {
+		for p := range read {
+			write[p] = true
+		}
+		a, err := auditToken(c.WriteToken(), "team", c.TerraformOrgName, filter, write)
+		if err != nil {
+			return nil, err
+		}
+		if len(read) > 0 && accessLevel(write) != "read" {
+			a.Recommendations = append(a.Recommendations, "Set tf_read_token to the token of a team with read access, so read-only commands can't modify workspaces")
+		}
+		return []TokenAudit{a}, nil
+	}
+
+	audits := make([]TokenAudit, 0, 2)
+	for _, t := range []struct {
+		role     string
+		token    string
+		required map[Permission]bool
+	}{{"read", c.ReadToken(), read}, {"write", c.WriteToken(), write}} {
+		a, err := auditToken(t.token, t.role, c.TerraformOrgName, filter, t.required)
+		if err != nil {
+			return nil, err
+		}
+		audits = append(audits, a)
+	}
+	return audits, nil
+}
+
+// commandNeeds returns the permissions of a tfdr command line
+func commandNeeds(command string) (operationNeeds, bool) {
+	args := strings.Fields(command)
+	for n := len(args); n > 0; n-- {
+		name := strings.Join(args[:n], " ")
+		needs, ok := operations[name]
+		if !ok {
+			continue
+		}
+		if name == "state copy" || name == "import-local" {
+			write := append([]Permission{}, needs.write...)
+			for _, arg := range args[n:] {
+				write = append(write, copyFlagNeeds[strings.SplitN(arg, "=", 2)[0]]...)
+			}
+			needs.write = write
+		}
+		return needs, true
+	}
+	return operationNeeds{}, false
+}
+
+func auditToken(token string, role string, orgName string, filter WorkspaceFilter, required map[Permission]bool) (TokenAudit, error) {
+	a := TokenAudit{Role: role}
+
+	client, err := newTFEClient(token)
+	if err != nil {
+		return a, fmt.Errorf("Cannot create tfe client. Err: %v", err)
+	}
+	user, err := client.Users.ReadCurrent(context.Background())
+	if err != nil {
+		if errors.Is(err, tfe.ErrUnauthorized) {
+			return a, tfdrerrors.ErrTokenRejected{}
+		}
+		return a, fmt.Errorf("Unable to read the %s token's account. Error: %v", role, err)
+	}
+	a.Identity = user.Username
+	// Team and organization tokens belong to service accounts
+	a.ServiceAccount = user.IsServiceAccount
+
+	granted, n, err := grantedPermissions(client, token, orgName, filter)
+	if err != nil {
+		return a, err
+	}
+	a.Workspaces = n
+
+	// Permissions of lower access levels than the required one come with it
+	// and aren't worth reporting
+	level := accessLevelIndex(required)
+	for p := range granted {
+		a.Granted = append(a.Granted, p)
+		if i, ok := permissionLevel(p); !required[p] && (!ok || i > level) {
+			a.Unneeded = append(a.Unneeded, p)
+		}
+	}
+	for p := range required {
+		a.Required = append(a.Required, p)
+		if !granted[p] {
+			a.Missing = append(a.Missing, p)
+		}
+	}
+	for _, ps := range [][]Permission{a.Granted, a.Required, a.Missing, a.Unneeded} {
+		sort.Slice(ps, func(i, j int) bool { return ps[i] < ps[j] })
+	}
+
+	if !a.ServiceAccount {
+		a.Recommendations = append(a.Recommendations, fmt.Sprintf("The %s token belongs to user %s, use a team token so access doesn't depend on one person", role, a.Identity))
+	}
+	if len(required) > 0 {
+		r := fmt.Sprintf("Give the %s token's team %s access to the workspaces", role, accessLevel(required))
+		if required[PermCreateWorkspace] {
+			r += " and the manage workspaces organization permission"
+		}
+		a.Recommendations = append(a.Recommendations, r)
+	}
+	return a, nil
+}
+
+// grantedPermissions returns the permissions a token has on the organization
+// and on every workspace matching filter, with the number of workspaces
+func grantedPermissions(client *tfe.Client, token string, orgName string, filter WorkspaceFilter) (map[Permission]bool, int, error) {
+	granted := make(map[Permission]bool)
+
+	org, err := client.Organizations.Read(context.Background(), orgName)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Unable to read organization %s. Error: %v", orgName, err)
+	}
+	if p := org.Permissions; p != nil {
+		for perm, ok := range map[Permission]bool{
+			PermCreateWorkspace:    p.CanCreateWorkspace,
+			PermCreateTeam:         p.CanCreateTeam,
+			PermUpdateOrganization: p.CanUpdate,
+			PermUpdateOAuth:        p.CanUpdateOAuth,
+			PermUpdateSentinel:     p.CanUpdateSentinel,
+			PermDestroyOrg:         p.CanDestroy,
+		} {
+			if ok {
+				granted[perm] = true
+			}
+		}
+	}
+
+	workspaces, err := listWorkspaces(client, token, orgName, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	// A workspace permission counts as granted when the token has it on
+	// every workspace
+	counts := make(map[Permission]int)
+	for _, w := range workspaces {
+		p := w.Permissions
+		if p == nil {
+			continue
+		}
+		for perm, ok := range map[Permission]bool{
+			PermReadSettings:     p.CanReadSettings,
+			PermQueueRun:         p.CanQueueRun,
+			PermLock:             p.CanLock,
+			PermUpdateVariable:   p.CanUpdateVariable,
+			PermQueueApply:       p.CanQueueApply,
+			PermQueueDestroy:     p.CanQueueDestroy,
+			PermUpdateWorkspace:  p.CanUpdate,
+			PermForceUnlock:      p.CanForceUnlock,
+			PermDestroyWorkspace: p.CanDestroy,
+		} {
+			if ok {
+				counts[perm]++
+			}
+		}
+	}
+	for perm, n := range counts {
+		if n == len(workspaces) {
+			granted[perm] = true
+		}
+	}
+	return granted, len(workspaces), nil
+}
+
+// accessLevel returns the lowest workspace access level with the permissions
+func accessLevel(permissions map[Permission]bool) string {
+	return workspaceAccess[accessLevelIndex(permissions)].name
+}
+
+func accessLevelIndex(permissions map[Permission]bool) int {
+	level := 0
+	for p := range permissions {
+		if i, ok := permissionLevel(p); ok && i > level {
+			level = i
+		}
+	}
+	return level
+}
+
+// permissionLevel returns the index in workspaceAccess of the access level
+// that adds a workspace permission
+func permissionLevel(p Permission) (int, bool) {
+	for i, a := range workspaceAccess {
+		for _, ap := range a.permissions {
+			if ap == p {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
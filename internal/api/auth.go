@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+)
+
+// TokenPageURL is where users create API tokens in Terraform Cloud
+const TokenPageURL = "https://app.terraform.io/app/settings/tokens"
+
+// ValidateToken checks a token against the API and returns the name of the
+// user or team it belongs to
+func ValidateToken(token string) (string, error) {
+	client, err := newTFEClient(token)
+	if err != nil {
+		return "", fmt.Errorf("Cannot create tfe client. Err: %v", err)
+	}
+
+	user, err := client.Users.ReadCurrent(context.Background())
+	if err != nil {
+		if errors.Is(err, tfe.ErrUnauthorized) {
+			return "", tfdrerrors.ErrTokenRejected{}
+		}
+		return "", fmt.Errorf("Unable to verify token. Error: %v", err)
+	}
+	return user.Username, nil
+}
+
+// RotateTeamToken generates a new token for a team. Terraform Cloud only keeps
+// one token per team, so the previous token is revoked as soon as this returns.
+func RotateTeamToken(teamID string) (string, error) {
+	c := config.GetConfig()
+
+	client, err := newTFEClient(c.WriteToken())
+	if err != nil {
+		return "", fmt.Errorf("Cannot create tfe client. Err: %v", err)
+	}
+
+	tt, err := client.TeamTokens.Generate(context.Background(), teamID)
+	if err != nil {
+		if errors.Is(err, tfe.ErrUnauthorized) {
+			return "", tfdrerrors.ErrTokenRejected{}
+		}
+		return "", fmt.Errorf("Unable to generate team token for %s. Error: %v", teamID, err)
+	}
+	return tt.Token, nil
+}
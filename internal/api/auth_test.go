@@ -0,0 +1,106 @@
+package api
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuthSuite struct {
+	suite.Suite
+}
+
+func (s *AuthSuite) SetupTest() {
+	os.Setenv("TF_TEAM_TOKEN", "test")
+	os.Setenv("TF_ORG_NAME", "team")
+	config.InitConfig("")
+	logging.InitLogger()
+	httpmock.ActivateNonDefault(httpClient)
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/ping", httpmock.NewStringResponder(204, ""))
+}
+
+func (s *AuthSuite) TearDownTest() {
+	httpmock.DeactivateAndReset()
+	os.Unsetenv("TF_TEAM_TOKEN")
+	os.Unsetenv("TF_ORG_NAME")
+}
+
+func (s *AuthSuite) TestValidateToken() {
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/account/details", httpmock.NewStringResponder(200,
+		`{"data":{"id":"user-1","type":"users","attributes":{"username":"api-team_1"}}}`))
+
+	name, err := ValidateToken("good")
+	s.NoError(err)
+	s.Equal("api-team_1", name)
+}
+
+func (s *AuthSuite) TestValidateTokenRejected() {
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/account/details", httpmock.NewStringResponder(401, ""))
+
+	_, err := ValidateToken("bad")
+	s.True(errors.Is(err, tfdrerrors.ErrTokenRejected{}))
+}
+
+func (s *AuthSuite) TestRotateTeamToken() {
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/teams/team-1/authentication-token", httpmock.NewStringResponder(201,
+		`{"data":{"id":"at-1","type":"authentication-tokens","attributes":{"token":"new-token"}}}`))
+
+	token, err := RotateTeamToken("team-1")
+	s.NoError(err)
+	s.Equal("new-token", token)
+}
+
+func registerAuditResponders(workspacePermissions string) {
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/account/details", httpmock.NewStringResponder(200,
+		`{"data":{"id":"user-1","type":"users","attributes":{"username":"api-team_1","is-service-account":true}}}`))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team", httpmock.NewStringResponder(200,
+		`{"data":{"id":"team","type":"organizations","attributes":{"name":"team","permissions":{"can-create-workspace":true,"can-update":true}}}}`))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces", httpmock.NewStringResponder(200, `{"data":[
+{"id":"ws-1","type":"workspaces","attributes":{"name":"dr-app","permissions":`+workspacePermissions+`}}
+],"meta":{"pagination":{"current-page":1,"total-pages":1}}}`))
+}
+
+func (s *AuthSuite) TestAuditTokens() {
+	registerAuditResponders(`{"can-read-settings":true,"can-queue-run":true,"can-lock":true,"can-update-variable":true,"can-update":true,"can-destroy":true}`)
+
+	audits, err := AuditTokens([]string{"state copy -o prod -n dr --variables-file vars.yaml", "inventory duplicates"}, WorkspaceFilter{})
+	s.NoError(err)
+	s.Len(audits, 1)
+	a := audits[0]
+	s.Equal("team", a.Role)
+	s.True(a.ServiceAccount)
+	s.Equal(1, a.Workspaces)
+	s.Equal([]Permission{PermLock, PermReadSettings, PermUpdateVariable}, a.Required)
+	s.Empty(a.Missing)
+	s.Equal([]Permission{PermCreateWorkspace, PermUpdateOrganization, PermDestroyWorkspace, PermUpdateWorkspace}, a.Unneeded)
+	s.Contains(a.Recommendations, "Give the team token's team write access to the workspaces")
+}
+
+func (s *AuthSuite) TestAuditTokensMissing() {
+	registerAuditResponders(`{"can-read-settings":true}`)
+
+	audits, err := AuditTokens([]string{"state copy --create-missing", "unknown command"}, WorkspaceFilter{})
+	s.NoError(err)
+	s.Equal([]Permission{PermLock, PermUpdateWorkspace}, audits[0].Missing)
+	s.Contains(audits[0].Recommendations, "Give the team token's team admin access to the workspaces and the manage workspaces organization permission")
+}
+
+func (s *AuthSuite) TestPullTFStateTokenRejected() {
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces/test", httpmock.NewStringResponder(401, ""))
+
+	_, err := pullTFState("test")
+	s.True(errors.Is(err, tfdrerrors.ErrTokenRejected{}))
+
+	err = CopyTFState("test", "test2", "./testdata/filterConfig.json", CopyOptions{})
+	s.True(errors.Is(err, tfdrerrors.ErrTokenRejected{}), "token errors should be visible through wrapping errors")
+}
+
+func TestAuthSuite(t *testing.T) {
+	suite.Run(t, new(AuthSuite))
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/logging"
+)
+
+// The breaker stops sending requests once the API fails breakerThreshold
+// times in a row, so tfdr doesn't add load to a TFE instance that is already
+// struggling. After the cooldown one request is let through to probe the API;
+// each failed probe doubles the cooldown up to breakerMaxCooldown.
+var (
+	breakerThreshold   = 5
+	breakerCooldown    = 30 * time.Second
+	breakerMaxCooldown = 5 * time.Minute
+)
+
+// breaker is an http.RoundTripper that pauses requests while the API is
+// failing and resumes on its own once a probe succeeds
+type breaker struct {
+	base http.RoundTripper
+	log  logging.Logger
+
+	mu        sync.Mutex
+	failures  int
+	cooldown  time.Duration
+	openUntil time.Time
+	probe     chan struct{}
+}
+
+func newBreaker(base http.RoundTripper, log logging.Logger) *breaker {
+	return &breaker{base: base, log: log}
+}
+
+func (b *breaker) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = replayable(req)
+	var paused time.Duration
+	for {
+		probing, err := b.wait(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		resp, err := b.base.RoundTrip(req)
+		if wait, ok := maintenanceWait(resp); ok && paused < maxMaintenancePause {
+			if retry, ok := rewind(req); ok {
+				resp.Body.Close()
+				b.pause(wait, probing)
+				paused += wait
+				req = retry
+				continue
+			}
+		}
+		b.record(failed(resp, err), probing)
+		return resp, err
+	}
+}
+
+// failed reports whether a request counts against the API's health. Rate
+// limiting is left to go-tfe's own retries.
+func failed(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// wait blocks while the breaker is open or another request is probing the
+// API. It reports whether the request is to probe the API.
+func (b *breaker) wait(ctx context.Context) (probe bool, err error) {
+	for {
+		b.mu.Lock()
+		if b.openUntil.IsZero() {
+			b.mu.Unlock()
+			return false, nil
+		}
+		var wake <-chan time.Time
+		var probed <-chan struct{}
+		switch {
+		case b.probe != nil:
+			probed = b.probe
+		case time.Now().Before(b.openUntil):
+			wake = time.After(time.Until(b.openUntil))
+		default:
+			b.probe = make(chan struct{})
+			b.mu.Unlock()
+			b.log.Infof("Probing the TFE API")
+			return true, nil
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-wake:
+		case <-probed:
+		}
+	}
+}
+
+// record updates the breaker with the outcome of a request. While the
+// breaker is open only the probe counts: requests that were in flight when
+// it opened say nothing about whether the API has recovered since.
+func (b *breaker) record(failure bool, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.openUntil.IsZero() && !probe {
+		return
+	}
+	if probe && b.probe != nil {
+		close(b.probe)
+		b.probe = nil
+	}
+
+	if !failure {
+		if !b.openUntil.IsZero() {
+			b.log.Infof("TFE API is responding again, resuming")
+		}
+		b.failures = 0
+		b.cooldown = 0
+		b.openUntil = time.Time{}
+		return
+	}
+
+	b.failures++
+	if b.failures < breakerThreshold {
+		return
+	}
+	switch {
+	case b.cooldown == 0:
+		b.cooldown = breakerCooldown
+	case b.cooldown < breakerMaxCooldown:
+		b.cooldown *= 2
+		if b.cooldown > breakerMaxCooldown {
+			b.cooldown = breakerMaxCooldown
+		}
+	}
+	b.openUntil = time.Now().Add(b.cooldown)
+	b.log.Warnf("TFE API failed %d requests in a row, pausing until %s", b.failures, b.openUntil.Format(time.Kitchen))
+	// Handlers may call the API, which needs the lock
+	failures, cooldown := b.failures, b.cooldown
+	b.mu.Unlock()
+	emit(Event{Type: RetryScheduled, Attempt: failures, Wait: cooldown})
+	b.mu.Lock()
+}
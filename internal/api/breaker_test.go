@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	defer func(threshold int, cooldown, max time.Duration) {
+		breakerThreshold, breakerCooldown, breakerMaxCooldown = threshold, cooldown, max
+	}(breakerThreshold, breakerCooldown, breakerMaxCooldown)
+	breakerThreshold = 3
+	breakerCooldown = 20 * time.Millisecond
+	breakerMaxCooldown = 30 * time.Millisecond
+
+	calls := 0
+	status := http.StatusServiceUnavailable
+	b := newBreaker(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: status, Body: http.NoBody}, nil
+	}), logging.Discard())
+	get := func(ctx context.Context) error {
+		req, _ := http.NewRequestWithContext(ctx, "GET", "https://app.terraform.io/api/v2/ping", nil)
+		_, err := b.RoundTrip(req)
+		return err
+	}
+
+	for i := 0; i < breakerThreshold; i++ {
+		assert.NoError(t, get(context.Background()))
+	}
+	assert.Equal(t, 3, calls)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	assert.True(t, errors.Is(get(ctx), context.DeadlineExceeded), "requests should wait while the breaker is open")
+	assert.Equal(t, 3, calls, "no request should reach the API while the breaker is open")
+
+	start := time.Now()
+	assert.NoError(t, get(context.Background()))
+	assert.Equal(t, 4, calls, "a probe should be let through after the cooldown")
+	assert.True(t, time.Since(start) >= 10*time.Millisecond)
+
+	status = http.StatusOK
+	start = time.Now()
+	assert.NoError(t, get(context.Background()))
+	assert.True(t, time.Since(start) >= 20*time.Millisecond, "a failed probe should double the cooldown")
+	assert.NoError(t, get(context.Background()))
+	assert.Equal(t, 6, calls, "requests should flow again once a probe succeeds")
+}
+
+func TestBreakerWaitsForProbe(t *testing.T) {
+	defer func(threshold int, cooldown, max time.Duration) {
+		breakerThreshold, breakerCooldown, breakerMaxCooldown = threshold, cooldown, max
+	}(breakerThreshold, breakerCooldown, breakerMaxCooldown)
+	breakerThreshold = 1
+	breakerCooldown = 10 * time.Millisecond
+	breakerMaxCooldown = 10 * time.Millisecond
+
+	started := make(chan string, 3)
+	release := map[string]chan int{"/slow": make(chan int), "/probe": make(chan int)}
+	b := newBreaker(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		started <- req.URL.Path
+		status := http.StatusServiceUnavailable
+		if c, ok := release[req.URL.Path]; ok {
+			status = <-c
+		} else if req.URL.Path == "/ok" {
+			status = http.StatusOK
+		}
+		return &http.Response{StatusCode: status, Body: http.NoBody}, nil
+	}), logging.Discard())
+	get := func(path string) {
+		req, _ := http.NewRequest("GET", "https://app.terraform.io"+path, nil)
+		_, _ = b.RoundTrip(req)
+	}
+
+	go get("/slow")
+	assert.Equal(t, "/slow", <-started)
+	get("/fail")
+	assert.Equal(t, "/fail", <-started)
+	time.Sleep(15 * time.Millisecond)
+	go get("/probe")
+	assert.Equal(t, "/probe", <-started)
+	done := make(chan struct{})
+	go func() {
+		get("/ok")
+		close(done)
+	}()
+
+	// The request from before the breaker opened finishes first
+	release["/slow"] <- http.StatusOK
+	select {
+	case path := <-started:
+		t.Errorf("%s reached the API while the probe was running", path)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release["/probe"] <- http.StatusOK
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("requests should flow again once the probe succeeds")
+	}
+}
+
+func TestBreakerIgnoresRateLimits(t *testing.T) {
+	calls := 0
+	b := newBreaker(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusTooManyRequests, Body: http.NoBody}, nil
+	}), logging.Discard())
+	for i := 0; i < breakerThreshold*2; i++ {
+		req, _ := http.NewRequest("GET", "https://app.terraform.io/api/v2/ping", nil)
+		_, err := b.RoundTrip(req)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, breakerThreshold*2, calls, "go-tfe retries rate limited requests itself")
+}
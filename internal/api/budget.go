@@ -0,0 +1,32 @@
+package api
+
+import "time"
+
+// budget tracks a time budget for bulk operations. A new operation is only
+// started when the time left exceeds the longest operation seen so far, so
+// the run stops before the deadline rather than overrunning it.
+type budget struct {
+	deadline time.Time
+	longest  time.Duration
+}
+
+func newBudget(d time.Duration) *budget {
+	b := &budget{}
+	if d > 0 {
+		b.deadline = time.Now().Add(d)
+	}
+	return b
+}
+
+func (b *budget) allows() bool {
+	if b.deadline.IsZero() {
+		return true
+	}
+	return time.Until(b.deadline) > b.longest
+}
+
+func (b *budget) record(start time.Time) {
+	if elapsed := time.Since(start); elapsed > b.longest {
+		b.longest = elapsed
+	}
+}
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudgetWithoutDeadline(t *testing.T) {
+	b := newBudget(0)
+	b.longest = time.Hour
+	assert.True(t, b.allows())
+}
+
+func TestBudgetStopsBeforeDeadline(t *testing.T) {
+	b := newBudget(time.Minute)
+	assert.True(t, b.allows())
+
+	b.longest = 2 * time.Minute
+	assert.False(t, b.allows())
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/mupuri/go-tfdr/internal/config"
+)
+
+// Capability is an optional API feature that older TFE releases lack
+type Capability string
+
+// Capabilities tfdr uses when available
+const (
+	CapabilityTags         Capability = "workspace tags"
+	CapabilityProjects     Capability = "projects"
+	CapabilityVariableSets Capability = "variable sets"
+)
+
+// capabilityProbes are organization endpoints that only exist on releases
+// with the capability. Releases without it answer 404.
+var capabilityProbes = map[Capability]string{
+	CapabilityTags:         "organizations/%s/tags?page%%5Bsize%%5D=1",
+	CapabilityProjects:     "organizations/%s/projects?page%%5Bsize%%5D=1",
+	CapabilityVariableSets: "organizations/%s/varsets?page%%5Bsize%%5D=1",
+}
+
+// Probe results are kept for the rest of the process. Inconclusive probes
+// aren't kept, so they are tried again.
+var (
+	capabilitiesMu sync.Mutex
+	capabilities   = make(map[Capability]bool)
+)
+
+// Capabilities reports which optional features the API supports
+func Capabilities() map[Capability]bool {
+	c := config.GetConfig()
+	supported := make(map[Capability]bool, len(capabilityProbes))
+	for capability := range capabilityProbes {
+		supported[capability] = supports(c.ReadToken(), c.TerraformOrgName, capability)
+	}
+	return supported
+}
+
+// CapabilityNames returns the sorted names of the capabilities with the
+// given support
+func CapabilityNames(capabilities map[Capability]bool, supported bool) []string {
+	names := make([]string, 0)
+	for c, s := range capabilities {
+		if s == supported {
+			names = append(names, string(c))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// supports reports whether the API has the capability. When the probe is
+// inconclusive, e.g. the token can't read the endpoint, the capability is
+// assumed to be there so the feature fails on its own with a real error.
+func supports(token string, orgName string, capability Capability) bool {
+	capabilitiesMu.Lock()
+	supported, known := capabilities[capability]
+	capabilitiesMu.Unlock()
+	if known {
+		return supported
+	}
+
+	resp, err := doAPIRequest("GET", fmt.Sprintf(capabilityProbes[capability], orgName), token, nil)
+	if err != nil {
+		logger.Debugf("Unable to check for %s support, assuming it is available. Error: %v", capability, err)
+		return true
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		supported = true
+	case http.StatusNotFound:
+		supported = false
+	default:
+		logger.Debugf("Unable to check for %s support, assuming it is available. Status: %s", capability, resp.Status)
+		return true
+	}
+
+	capabilitiesMu.Lock()
+	capabilities[capability] = supported
+	capabilitiesMu.Unlock()
+	if !supported {
+		logger.Warnf("This TFE release does not support %s, features that need them are turned off", capability)
+	}
+	return supported
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/stretchr/testify/suite"
+)
+
+type CapabilitiesSuite struct {
+	suite.Suite
+}
+
+func (s *CapabilitiesSuite) SetupTest() {
+	os.Setenv("TF_TEAM_TOKEN", "test")
+	os.Setenv("TF_ORG_NAME", "team")
+	config.InitConfig("")
+	logging.InitLogger()
+	resetCapabilities()
+	httpmock.ActivateNonDefault(httpClient)
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/ping", httpmock.NewStringResponder(204, ""))
+}
+
+func (s *CapabilitiesSuite) TearDownTest() {
+	resetCapabilities()
+	httpmock.DeactivateAndReset()
+	os.Unsetenv("TF_TEAM_TOKEN")
+	os.Unsetenv("TF_ORG_NAME")
+}
+
+func resetCapabilities() {
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+	capabilities = make(map[Capability]bool)
+}
+
+func (s *CapabilitiesSuite) TestCapabilities() {
+	httpmock.RegisterResponder("GET", `=~^https://app.terraform.io/api/v2/organizations/team/tags`, httpmock.NewStringResponder(200, `{"data":[]}`))
+	httpmock.RegisterResponder("GET", `=~^https://app.terraform.io/api/v2/organizations/team/projects`, httpmock.NewStringResponder(404, ""))
+	httpmock.RegisterResponder("GET", `=~^https://app.terraform.io/api/v2/organizations/team/varsets`, httpmock.NewStringResponder(403, ""))
+
+	s.Equal(map[Capability]bool{
+		CapabilityTags:         true,
+		CapabilityProjects:     false,
+		CapabilityVariableSets: true,
+	}, Capabilities(), "inconclusive probes should assume support")
+
+	Capabilities()
+	calls := httpmock.GetCallCountInfo()
+	s.Equal(1, calls[`GET =~^https://app.terraform.io/api/v2/organizations/team/projects`], "definite answers should be kept")
+	s.Equal(2, calls[`GET =~^https://app.terraform.io/api/v2/organizations/team/varsets`], "inconclusive answers should be probed again")
+}
+
+func (s *CapabilitiesSuite) TestListWorkspacesByTagWithoutTagSupport() {
+	httpmock.RegisterResponder("GET", `=~^https://app.terraform.io/api/v2/organizations/team/tags`, httpmock.NewStringResponder(404, ""))
+	httpmock.RegisterResponder("GET", `=~^https://app.terraform.io/api/v2/organizations/team/workspaces`, httpmock.NewStringResponder(200, workspaceList))
+
+	_, err := ListWorkspaces(WorkspaceFilter{Prefix: "drtest-", Tags: []string{"dr"}})
+	s.EqualError(err, "This TFE release does not support workspace tags, workspaces can't be selected by tag")
+	s.Zero(httpmock.GetCallCountInfo()[`GET =~^https://app.terraform.io/api/v2/organizations/team/workspaces`], "workspaces should not be listed without the tag filter")
+}
+
+func (s *CapabilitiesSuite) TestCreateWorkspaceWithoutProjects() {
+	config.Override(func(c *config.Configuration) {
+		c.WorkspaceTemplate = config.WorkspaceTemplate{Tags: []string{"dr"}, ProjectID: "prj-123"}
+	})
+	httpmock.RegisterResponder("GET", `=~^https://app.terraform.io/api/v2/organizations/team/tags`, httpmock.NewStringResponder(200, `{"data":[]}`))
+	httpmock.RegisterResponder("GET", `=~^https://app.terraform.io/api/v2/organizations/team/projects`, httpmock.NewStringResponder(404, ""))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces/new", httpmock.NewStringResponder(404, ""))
+	var req workspaceCreateRequest
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/organizations/team/workspaces", func(r *http.Request) (*http.Response, error) {
+		s.NoError(json.NewDecoder(r.Body).Decode(&req))
+		return httpmock.NewStringResponse(201, `{"data":{"id":"new","type":"workspaces"}}`), nil
+	})
+
+	s.NoError(ensureWorkspace("new", "0.13.4"))
+	s.Equal([]interface{}{"dr"}, req.Data.Attributes["tag-names"])
+	s.Nil(req.Data.Relationships, "the project should be left out")
+}
+
+func TestCapabilitiesSuite(t *testing.T) {
+	suite.Run(t, new(CapabilitiesSuite))
+}
@@ -2,25 +2,163 @@ package api
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/mupuri/go-tfdr/internal/config"
 	"github.com/mupuri/go-tfdr/internal/filter"
 	"github.com/mupuri/go-tfdr/internal/models"
 	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+	"github.com/mupuri/go-tfdr/internal/variables"
 )
 
+// CopyOptions holds the optional behaviour of CopyTFState
+type CopyOptions struct {
+	// CheckCredentials warns when the destination workspace has no credentials
+	// for the providers in the copied resources
+	CheckCredentials bool
+	// AlignTerraformVersion updates the destination workspace's terraform
+	// version when it is too old to read the copied state
+	AlignTerraformVersion bool
+	// CreateMissing creates the destination workspace from the configured
+	// workspace template when it does not exist
+	CreateMissing bool
+	// SuppressRuns turns off auto-apply and VCS-triggered runs on the
+	// destination workspace for the duration of the copy
+	SuppressRuns bool
+	// Redaction replaces the profile's attributes with placeholders in the
+	// copied state
+	Redaction *config.RedactionProfile
+	// CopyStateSharing gives the destination workspace the remote state
+	// sharing settings of the source workspace
+	CopyStateSharing bool
+	// CopyNotifications creates the source workspace's notification
+	// configurations on the destination workspace
+	CopyNotifications bool
+	// NotificationURLs maps notification URL prefixes to their replacement,
+	// e.g. a webhook receiver's primary address to its DR address
+	NotificationURLs map[string]string
+	// Variables are written to the destination workspace, with their values
+	// resolved from their sources once before anything is copied
+	Variables []variables.Variable
+	// Approvals are tokens from `tfdr approve` for destinations that need a
+	// second person's approval
+	Approvals []string
+	// ExecutionMode switches the destination workspace to remote, local or
+	// agent execution, e.g. when the primary region's agents are down.
+	// AgentPoolID is the pool of the agent mode.
+	ExecutionMode string
+	AgentPoolID   string
+	// CleanDeposed leaves deposed instances out of the copied state and
+	// Untaint clears the tainted status of copied instances, so the first
+	// apply doesn't destroy or replace them
+	CleanDeposed bool
+	Untaint      bool
+	// TagStatus tags the destination workspace with the date of the restore
+	// and its source once the state is written
+	TagStatus bool
+	// Deadline stops copying to further destinations once this time budget
+	// is nearly used up. Zero means no deadline.
+	Deadline time.Duration
+}
+
 // CopyTFState &
-func CopyTFState(origWorkspaceName string, newWorkspaceName string, filterConfigFileName string) error {
+func CopyTFState(origWorkspaceName string, newWorkspaceName string, filterConfigFileName string, opts CopyOptions) error {
+	return CopyTFStateToMany(origWorkspaceName, []string{newWorkspaceName}, filterConfigFileName, opts)
+}
+
+// CopyTFStateToMany copies the filtered state of one workspace to each of the
+// new workspaces, downloading and filtering the source only once. A failed
+// destination doesn't stop the others.
+func CopyTFStateToMany(origWorkspaceName string, newWorkspaceNames []string, filterConfigFileName string, opts CopyOptions) error {
+	op := startOperation("copy", len(newWorkspaceNames))
+	b := newBudget(opts.Deadline)
+	if err := ValidateExecutionMode(opts.ExecutionMode, opts.AgentPoolID); err != nil {
+		return op.finish(err)
+	}
+	if err := checkApprovals(config.GetConfig(), newWorkspaceNames, opts.Approvals, opts.CreateMissing); err != nil {
+		return op.finish(err)
+	}
+	if len(opts.Variables) > 0 {
+		vars, err := variables.ResolveAll(opts.Variables)
+		if err != nil {
+			return op.finish(err)
+		}
+		opts.Variables = vars
+	}
+
 	oldState, err := pullTFState(origWorkspaceName)
 	if err != nil {
-		return tfdrerrors.ErrReadState{Err: err}
+		return op.finish(tfdrerrors.ErrReadState{Err: err})
 	}
 	if oldState == nil {
-		return tfdrerrors.ErrSourceIsEmpty{}
+		return op.finish(tfdrerrors.ErrSourceIsEmpty{})
 	}
 
 	newResources, err := filter.StateFilter(oldState.Resources, filter.CopyResourceFilterFunc, filterConfigFileName)
 	if err != nil {
-		return fmt.Errorf("Unable to filter resources from state. Error: %v", err)
+		return op.finish(fmt.Errorf("Unable to filter resources from state. Error: %v", err))
+	}
+	overrides, err := filter.ReadVariables(filterConfigFileName)
+	if err != nil {
+		return op.finish(err)
+	}
+	if opts.Variables, err = variables.WithOverrides(opts.Variables, overrides); err != nil {
+		return op.finish(err)
+	}
+	if opts.CleanDeposed {
+		if n := filter.DropDeposed(newResources); n > 0 {
+			logger.Infof("Left %d deposed instances out of the copied state", n)
+		}
+	}
+	if opts.Untaint {
+		if n := filter.Untaint(newResources); n > 0 {
+			logger.Infof("Untainted %d instances in the copied state", n)
+		}
+	}
+	if opts.Redaction != nil {
+		n := filter.Redact(newResources, opts.Redaction.Attributes, opts.Redaction.Placeholder)
+		logger.Infof("Redacted %d values from the copied state", n)
+	}
+
+	if len(newWorkspaceNames) == 1 {
+		err := copyToWorkspace(origWorkspaceName, oldState, newResources, newWorkspaceNames[0], opts)
+		op.workspaceDone(newWorkspaceNames[0], err)
+		return op.finish(err)
+	}
+
+	failed := 0
+	for i, name := range newWorkspaceNames {
+		if !b.allows() {
+			return op.finish(tfdrerrors.ErrDeadlineExceeded{Remaining: newWorkspaceNames[i:], Failed: failed})
+		}
+		start := time.Now()
+		err := copyToWorkspace(origWorkspaceName, oldState, newResources, name, opts)
+		b.record(start)
+		op.workspaceDone(name, err)
+		if err != nil {
+			failed++
+			logger.Errorf("Unable to copy state to workspace %s. Error: %v", name, err)
+			continue
+		}
+		logger.Infof("Copied state from %s to %s", origWorkspaceName, name)
+	}
+	if failed > 0 {
+		return op.finish(fmt.Errorf("Failed to copy state to %d of %d workspaces", failed, len(newWorkspaceNames)))
+	}
+	return op.finish(nil)
+}
+
+func copyToWorkspace(origWorkspaceName string, oldState *models.State, newResources []models.Resource, newWorkspaceName string, opts CopyOptions) error {
+	unlock, err := lockLocal(newWorkspaceName, "state copy")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if opts.CreateMissing {
+		if err := ensureWorkspace(newWorkspaceName, oldState.TerraformVersion); err != nil {
+			return err
+		}
 	}
 
 	newState, err := pullTFState(newWorkspaceName)
@@ -31,6 +169,58 @@ func CopyTFState(origWorkspaceName string, newWorkspaceName string, filterConfig
 		return tfdrerrors.ErrDestinationNotEmpty{}
 	}
 
+	// Hold the destination lock for the rest of the copy, so a run or a
+	// person can't write state in between
+	lock, err := acquireWorkspaceLock(newWorkspaceName, "tfdr: restoring state from "+origWorkspaceName)
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+
+	// Check the destination before writing anything to it, so a copy that
+	// fails its checks doesn't leave it half configured
+	if err := checkTerraformVersion(newWorkspaceName, oldState.TerraformVersion, opts.AlignTerraformVersion); err != nil {
+		return err
+	}
+
+	if opts.CheckCredentials {
+		if _, err := checkProviderCredentials(newWorkspaceName, newResources, envKeys(opts.Variables)); err != nil {
+			return err
+		}
+	}
+
+	if opts.SuppressRuns {
+		if err := lock.suppressRuns(); err != nil {
+			return err
+		}
+	}
+
+	if opts.CopyStateSharing {
+		c := config.GetConfig()
+		if err := copyStateSharing(lock.client, lock.token, c.TerraformOrgName, origWorkspaceName, lock.workspace); err != nil {
+			return fmt.Errorf("Unable to copy remote state sharing from %s. Error: %v", origWorkspaceName, err)
+		}
+	}
+
+	if opts.CopyNotifications {
+		c := config.GetConfig()
+		if err := copyNotifications(lock.client, c.TerraformOrgName, origWorkspaceName, lock.workspace, opts.NotificationURLs); err != nil {
+			return fmt.Errorf("Unable to copy notifications from %s. Error: %v", origWorkspaceName, err)
+		}
+	}
+
+	if len(opts.Variables) > 0 {
+		if err := setVariables(lock.client, lock.workspace, opts.Variables); err != nil {
+			return fmt.Errorf("Unable to set variables on %s. Error: %v", newWorkspaceName, err)
+		}
+	}
+
+	if opts.ExecutionMode != "" {
+		if err := lock.setExecutionMode(opts.ExecutionMode, opts.AgentPoolID); err != nil {
+			return fmt.Errorf("Unable to set the execution mode of %s. Error: %v", newWorkspaceName, err)
+		}
+	}
+
 	newState = &models.State{
 		TerraformVersion: oldState.TerraformVersion,
 		Version:          oldState.Version,
@@ -38,10 +228,17 @@ func CopyTFState(origWorkspaceName string, newWorkspaceName string, filterConfig
 		Serial:           1,
 	}
 
-	err = createTFStateVersion(newState, newWorkspaceName)
+	err = lock.uploadState(newState, noStateSerial)
 	if err != nil {
 		return tfdrerrors.ErrUnableToCreateStateVersion{Err: err}
 	}
 
+	// The state is already restored, so failing to tag doesn't fail the copy
+	if opts.TagStatus {
+		if err := lock.tagStatus(origWorkspaceName, time.Now()); err != nil {
+			logger.Warnf("Unable to tag workspace %s with its restore status. Error: %v", newWorkspaceName, err)
+		}
+	}
+
 	return nil
 }
@@ -1,19 +1,24 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/go-tfe"
 	"github.com/jarcoal/httpmock"
+	"github.com/mupuri/go-tfdr/internal/approval"
 	"github.com/mupuri/go-tfdr/internal/config"
 	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/mupuri/go-tfdr/internal/models"
 	"github.com/mupuri/go-tfdr/internal/testutils"
 	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+	"github.com/mupuri/go-tfdr/internal/variables"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -175,7 +180,7 @@ func (s *CopySuite) TestCopyTFState() {
 		err = testutils.SetupWksMockHTTPResponses(c.newwks)
 		s.NoError(err, c.errMessage)
 
-		err = CopyTFState(c.origwks.Name, c.newwks.Name, c.filterFile)
+		err = CopyTFState(c.origwks.Name, c.newwks.Name, c.filterFile, CopyOptions{})
 
 		if c.shouldErr {
 			s.Error(err, c.errMessage)
@@ -189,6 +194,478 @@ func (s *CopySuite) TestCopyTFState() {
 	}
 }
 
+func (s *CopySuite) TestCopyTFStateSuppressRuns() {
+	httpmock.ActivateNonDefault(httpClient)
+	defer httpmock.DeactivateAndReset()
+	s.setupCopy()
+
+	calls := make([]string, 0)
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test2/state-versions", func(req *http.Request) (*http.Response, error) {
+		calls = append(calls, "state")
+		return testutils.NewJSONResponse("test2", "state-versions", "")
+	})
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces/test2",
+		httpmock.NewStringResponder(200, `{"data":{"id":"test2","type":"workspaces","attributes":{"name":"test2","auto-apply":true}}}`))
+	httpmock.RegisterResponder("PATCH", "https://app.terraform.io/api/v2/workspaces/test2", func(req *http.Request) (*http.Response, error) {
+		var body workspaceUpdateRequest
+		s.NoError(json.NewDecoder(req.Body).Decode(&body))
+		calls = append(calls, fmt.Sprintf("auto-apply=%v", body.Data.Attributes["auto-apply"]))
+		return testutils.NewJSONResponse("test2", "workspaces", "")
+	})
+	// A VCS push queues run-new while the state is being written
+	httpmock.RegisterResponder("GET", `=~^https://app.terraform.io/api/v2/workspaces/test2/runs`, func(req *http.Request) (*http.Response, error) {
+		runs := `{"id":"run-old","type":"runs","attributes":{"status":"pending"}},{"id":"run-done","type":"runs","attributes":{"status":"applied"}}`
+		for _, c := range calls {
+			if c == "state" {
+				runs = `{"id":"run-new","type":"runs","attributes":{"status":"pending"}},` + runs
+			}
+		}
+		return httpmock.NewStringResponse(200, `{"data":[`+runs+`]}`), nil
+	})
+	httpmock.RegisterResponder("POST", `=~^https://app.terraform.io/api/v2/runs/([^/]+)/actions/discard`, func(req *http.Request) (*http.Response, error) {
+		calls = append(calls, "discard "+httpmock.MustGetSubmatch(req, 1))
+		return httpmock.NewStringResponse(202, ""), nil
+	})
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test2/actions/unlock", func(req *http.Request) (*http.Response, error) {
+		calls = append(calls, "unlock")
+		return testutils.NewJSONResponse("test2", "workspaces", "")
+	})
+
+	err := CopyTFState("test1", "test2", "./testdata/filterConfig.json", CopyOptions{SuppressRuns: true})
+	s.NoError(err)
+	s.Equal([]string{
+		"auto-apply=false",
+		"state",
+		"discard run-new",
+		"unlock",
+		"auto-apply=true",
+	}, calls, "runs queued during the copy should be discarded while the workspace is locked, before auto-apply is restored")
+}
+
+func (s *CopySuite) TestCopyTFStateSetsExecutionMode() {
+	httpmock.ActivateNonDefault(httpClient)
+	defer httpmock.DeactivateAndReset()
+	s.setupCopy()
+
+	var attrs map[string]interface{}
+	httpmock.RegisterResponder("PATCH", "https://app.terraform.io/api/v2/workspaces/test2", func(req *http.Request) (*http.Response, error) {
+		var body workspaceUpdateRequest
+		s.NoError(json.NewDecoder(req.Body).Decode(&body))
+		attrs = body.Data.Attributes
+		return testutils.NewJSONResponse("test2", "workspaces", "")
+	})
+
+	err := CopyTFState("test1", "test2", "./testdata/filterConfig.json", CopyOptions{ExecutionMode: ExecutionAgent, AgentPoolID: "apool-dr"})
+	s.NoError(err)
+	s.Equal(map[string]interface{}{"execution-mode": "agent", "agent-pool-id": "apool-dr"}, attrs)
+
+	err = CopyTFState("test1", "test2", "./testdata/filterConfig.json", CopyOptions{ExecutionMode: ExecutionAgent})
+	s.EqualError(err, "the agent execution mode needs an agent pool")
+}
+
+func (s *CopySuite) TestCopyTFStateChecksBeforeSettingExecutionMode() {
+	httpmock.ActivateNonDefault(httpClient)
+	defer httpmock.DeactivateAndReset()
+	s.setupDowngrade()
+	httpmock.RegisterResponder("PATCH", "https://app.terraform.io/api/v2/workspaces/test2", testutils.NewResponder("test2", "workspaces", ""))
+
+	err := CopyTFState("test1", "test2", "./testdata/filterConfig.json", CopyOptions{ExecutionMode: ExecutionAgent, AgentPoolID: "apool-dr"})
+	s.Error(err)
+	s.Zero(httpmock.GetCallCountInfo()["PATCH https://app.terraform.io/api/v2/workspaces/test2"], "the execution mode should not change when a check fails")
+}
+
+func (s *CopySuite) TestCopyTFStateCleansInstances() {
+	source := testutils.NewState()
+	for r := range source.Resources {
+		if len(source.Resources[r].Instances) == 0 {
+			continue
+		}
+		tainted := source.Resources[r].Instances[0]
+		tainted.Status = "tainted"
+		deposed := source.Resources[r].Instances[0]
+		deposed.Deposed = "00000001"
+		source.Resources[r].Instances = []models.Instance{tainted, deposed}
+	}
+
+	for _, opts := range []CopyOptions{{}, {CleanDeposed: true, Untaint: true}} {
+		httpmock.ActivateNonDefault(httpClient)
+		s.setupCopy()
+		sourceJSON, err := json.Marshal(source)
+		s.NoError(err)
+		httpmock.RegisterResponder("GET", "https://state", httpmock.NewStringResponder(200, string(sourceJSON)))
+		var uploaded models.State
+		httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test2/state-versions", func(req *http.Request) (*http.Response, error) {
+			var body struct {
+				Data struct {
+					Attributes struct {
+						State []byte `json:"state"`
+					} `json:"attributes"`
+				} `json:"data"`
+			}
+			s.NoError(json.NewDecoder(req.Body).Decode(&body))
+			s.NoError(json.Unmarshal(body.Data.Attributes.State, &uploaded))
+			return testutils.NewJSONResponse("test2", "state-versions", "")
+		})
+
+		s.NoError(CopyTFState("test1", "test2", "./testdata/filterConfig.json", opts))
+		httpmock.DeactivateAndReset()
+
+		withInstances, tainted, deposed := 0, 0, 0
+		for _, r := range uploaded.Resources {
+			if len(r.Instances) > 0 {
+				withInstances++
+			}
+			for _, i := range r.Instances {
+				if i.Status == "tainted" {
+					tainted++
+				}
+				if i.Deposed != "" {
+					deposed++
+				}
+			}
+		}
+		if opts.CleanDeposed {
+			s.Equal(0, tainted, "tainted instances should be untainted")
+			s.Equal(0, deposed, "deposed instances should be left out")
+		} else {
+			s.NotZero(withInstances)
+			s.Equal(withInstances, tainted, "tainted instances should be copied as they are")
+			s.Equal(withInstances, deposed, "deposed instances should be copied as they are")
+		}
+	}
+}
+
+func (s *CopySuite) TestCopyTFStateToMany() {
+	httpmock.ActivateNonDefault(httpClient)
+	defer httpmock.DeactivateAndReset()
+	s.setupCopy()
+
+	written := make([]string, 0)
+	for _, name := range []string{"dr-east", "dr-west"} {
+		name := name
+		s.NoError(testutils.SetupWksMockHTTPResponses(&testutils.TfeTestWks{
+			Name:         name,
+			Exists:       true,
+			CsvResponder: httpmock.NewStringResponder(404, ""),
+			SvPostResponder: func(req *http.Request) (*http.Response, error) {
+				written = append(written, name)
+				return testutils.NewJSONResponse(name, "state-versions", "")
+			},
+		}))
+	}
+	s.NoError(testutils.SetupWksMockHTTPResponses(&testutils.TfeTestWks{
+		Name:         "dr-full",
+		Exists:       true,
+		CsvResponder: testutils.NewResponder("dr-full", "state-versions", "https://state"),
+	}))
+
+	events := make([]string, 0)
+	SetEventHandler(func(e Event) {
+		events = append(events, fmt.Sprintf("%s %s %d %v", e.Type, e.Workspace, e.Total, e.Err != nil))
+	})
+	defer SetEventHandler(nil)
+
+	err := CopyTFStateToMany("test1", []string{"dr-east", "dr-full", "dr-west"}, "./testdata/filterConfig.json", CopyOptions{})
+	s.EqualError(err, "Failed to copy state to 1 of 3 workspaces")
+	s.Equal([]string{
+		"operation_started  3 false",
+		"workspace_completed dr-east 0 false",
+		"workspace_completed dr-full 0 true",
+		"workspace_completed dr-west 0 false",
+		"operation_finished  0 true",
+	}, events)
+	s.Equal([]string{"dr-east", "dr-west"}, written, "a failed destination should not stop the others")
+	s.Equal(2, httpmock.GetCallCountInfo()["GET https://state"], "source state should be downloaded once, plus once to check dr-full")
+}
+
+func (s *CopySuite) TestCopyTFStateToManyStopsAtDeadline() {
+	httpmock.ActivateNonDefault(httpClient)
+	defer httpmock.DeactivateAndReset()
+	s.setupCopy()
+
+	err := CopyTFStateToMany("test1", []string{"dr-east", "dr-west"}, "./testdata/filterConfig.json", CopyOptions{Deadline: time.Nanosecond})
+	s.Equal(tfdrerrors.ErrDeadlineExceeded{Remaining: []string{"dr-east", "dr-west"}}, err)
+}
+
+func (s *CopySuite) TestCopyTFStateCopiesStateSharing() {
+	httpmock.ActivateNonDefault(httpClient)
+	defer httpmock.DeactivateAndReset()
+	s.setupCopy()
+
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/workspaces/test1",
+		httpmock.NewStringResponder(200, `{"data":{"id":"test1","type":"workspaces","attributes":{"global-remote-state":false}}}`))
+	httpmock.RegisterResponder("GET", `=~^https://app.terraform.io/api/v2/workspaces/test1/relationships/remote-state-consumers`,
+		httpmock.NewStringResponder(200, `{"data":[{"id":"ws-app","type":"workspaces"},{"id":"ws-dns","type":"workspaces"}],"meta":{"pagination":{"current-page":1,"total-pages":1}}}`))
+	var consumers consumerRequest
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test2/relationships/remote-state-consumers", func(req *http.Request) (*http.Response, error) {
+		s.NoError(json.NewDecoder(req.Body).Decode(&consumers))
+		return httpmock.NewStringResponse(204, ""), nil
+	})
+
+	err := CopyTFState("test1", "test2", "./testdata/filterConfig.json", CopyOptions{CopyStateSharing: true})
+	s.NoError(err)
+	s.Equal([]relationshipData{{Type: "workspaces", ID: "ws-app"}, {Type: "workspaces", ID: "ws-dns"}}, consumers.Data)
+}
+
+func (s *CopySuite) TestCopyTFStateChecksBeforeCopyingStateSharing() {
+	httpmock.ActivateNonDefault(httpClient)
+	defer httpmock.DeactivateAndReset()
+	s.setupDowngrade()
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/workspaces/test1",
+		httpmock.NewStringResponder(200, `{"data":{"id":"test1","type":"workspaces","attributes":{"global-remote-state":true}}}`))
+	httpmock.RegisterResponder("PATCH", "https://app.terraform.io/api/v2/workspaces/test2", testutils.NewResponder("test2", "workspaces", ""))
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test2/relationships/remote-state-consumers", httpmock.NewStringResponder(204, ""))
+
+	err := CopyTFState("test1", "test2", "./testdata/filterConfig.json", CopyOptions{CopyStateSharing: true})
+	s.Error(err)
+	info := httpmock.GetCallCountInfo()
+	s.Zero(info["PATCH https://app.terraform.io/api/v2/workspaces/test2"]+info["POST https://app.terraform.io/api/v2/workspaces/test2/relationships/remote-state-consumers"],
+		"state sharing should not be granted when a check fails")
+}
+
+func (s *CopySuite) TestCopyTFStateCopiesNotifications() {
+	httpmock.ActivateNonDefault(httpClient)
+	defer httpmock.DeactivateAndReset()
+	s.setupCopy()
+
+	httpmock.RegisterResponder("GET", `=~^https://app.terraform.io/api/v2/workspaces/test1/notification-configurations`,
+		httpmock.NewStringResponder(200, `{"data":[
+{"id":"nc-1","type":"notification-configurations","attributes":{"name":"deploys","destination-type":"slack","enabled":true,"url":"https://hooks.primary.example.com/T1","triggers":["run:errored"]}},
+{"id":"nc-2","type":"notification-configurations","attributes":{"name":"audit","destination-type":"generic","enabled":true,"url":"https://audit.example.com/hook"}}
+],"meta":{"pagination":{"current-page":1,"total-pages":1}}}`))
+	httpmock.RegisterResponder("GET", `=~^https://app.terraform.io/api/v2/workspaces/test2/notification-configurations`,
+		httpmock.NewStringResponder(200, `{"data":[
+{"id":"nc-3","type":"notification-configurations","attributes":{"name":"audit","destination-type":"generic","enabled":true,"url":"https://audit.example.com/hook"}}
+],"meta":{"pagination":{"current-page":1,"total-pages":1}}}`))
+	var created []map[string]interface{}
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test2/notification-configurations", func(req *http.Request) (*http.Response, error) {
+		var body struct {
+			Data struct {
+				Attributes map[string]interface{} `json:"attributes"`
+			} `json:"data"`
+		}
+		s.NoError(json.NewDecoder(req.Body).Decode(&body))
+		created = append(created, body.Data.Attributes)
+		return httpmock.NewStringResponse(201, `{"data":{"id":"nc-4","type":"notification-configurations"}}`), nil
+	})
+
+	err := CopyTFState("test1", "test2", "./testdata/filterConfig.json", CopyOptions{
+		CopyNotifications: true,
+		NotificationURLs:  map[string]string{"https://hooks.primary.example.com": "https://hooks.dr.example.com"},
+	})
+	s.NoError(err)
+	s.Len(created, 1, "notifications the destination already has should be kept")
+	s.Equal("deploys", created[0]["name"])
+	s.Equal("https://hooks.dr.example.com/T1", created[0]["url"])
+}
+
+func (s *CopySuite) TestCopyTFStateChecksBeforeCopyingNotifications() {
+	httpmock.ActivateNonDefault(httpClient)
+	defer httpmock.DeactivateAndReset()
+	s.setupDowngrade()
+	httpmock.RegisterResponder("GET", `=~^https://app.terraform.io/api/v2/workspaces/test1/notification-configurations`,
+		httpmock.NewStringResponder(200, `{"data":[
+{"id":"nc-1","type":"notification-configurations","attributes":{"name":"deploys","destination-type":"slack","enabled":true,"url":"https://hooks.primary.example.com/T1"}}
+],"meta":{"pagination":{"current-page":1,"total-pages":1}}}`))
+	httpmock.RegisterResponder("GET", `=~^https://app.terraform.io/api/v2/workspaces/test2/notification-configurations`,
+		httpmock.NewStringResponder(200, `{"data":[],"meta":{"pagination":{"current-page":1,"total-pages":1}}}`))
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test2/notification-configurations",
+		httpmock.NewStringResponder(201, `{"data":{"id":"nc-2","type":"notification-configurations"}}`))
+
+	err := CopyTFState("test1", "test2", "./testdata/filterConfig.json", CopyOptions{CopyNotifications: true})
+	s.Error(err)
+	s.Zero(httpmock.GetCallCountInfo()["POST https://app.terraform.io/api/v2/workspaces/test2/notification-configurations"],
+		"no notifications should be created when a check fails")
+}
+
+func (s *CopySuite) TestCopyTFStateSetsVariables() {
+	httpmock.ActivateNonDefault(httpClient)
+	defer httpmock.DeactivateAndReset()
+	s.setupCopy()
+	os.Setenv("TFDR_TEST_DB_PASSWORD", "hunter2")
+	defer os.Unsetenv("TFDR_TEST_DB_PASSWORD")
+	httpmock.RegisterResponder("GET", `=~^https://app.terraform.io/api/v2/workspaces/test2/vars`,
+		httpmock.NewStringResponder(200, `{"data":[
+{"id":"var-1","type":"vars","attributes":{"key":"region","category":"terraform","value":"us-east-1"}}
+],"meta":{"pagination":{"current-page":1,"total-pages":1}}}`))
+	values := make(map[string]string)
+	record := func(status int) httpmock.Responder {
+		return func(req *http.Request) (*http.Response, error) {
+			var body struct {
+				Data struct {
+					Attributes struct {
+						Value string `json:"value"`
+					} `json:"attributes"`
+				} `json:"data"`
+			}
+			s.NoError(json.NewDecoder(req.Body).Decode(&body))
+			values[req.Method] = body.Data.Attributes.Value
+			return httpmock.NewStringResponse(status, `{"data":{"id":"var-2","type":"vars"}}`), nil
+		}
+	}
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test2/vars", record(201))
+	httpmock.RegisterResponder("PATCH", "https://app.terraform.io/api/v2/workspaces/test2/vars/var-1", record(200))
+
+	err := CopyTFState("test1", "test2", "./testdata/filterConfig.json", CopyOptions{Variables: []variables.Variable{
+		{Key: "region", Category: variables.CategoryTerraform, Value: "us-west-2"},
+		{Key: "db_password", Category: variables.CategoryTerraform, Sensitive: true, Env: "TFDR_TEST_DB_PASSWORD"},
+	}})
+	s.NoError(err)
+	s.Equal(map[string]string{"PATCH": "us-west-2", "POST": "hunter2"}, values)
+}
+
+func (s *CopySuite) TestCopyTFStateChecksBeforeSettingVariables() {
+	httpmock.ActivateNonDefault(httpClient)
+	defer httpmock.DeactivateAndReset()
+	s.setupDowngrade()
+	httpmock.RegisterResponder("GET", `=~^https://app.terraform.io/api/v2/workspaces/test2/vars`,
+		httpmock.NewStringResponder(200, `{"data":[],"meta":{"pagination":{"current-page":1,"total-pages":1}}}`))
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test2/vars",
+		httpmock.NewStringResponder(201, `{"data":{"id":"var-1","type":"vars"}}`))
+
+	err := CopyTFState("test1", "test2", "./testdata/filterConfig.json", CopyOptions{Variables: []variables.Variable{
+		{Key: "region", Category: variables.CategoryTerraform, Value: "us-west-2"},
+	}})
+	s.True(errors.Is(err, tfdrerrors.ErrTerraformVersionDowngrade{WorkspaceVersion: "0.12.29", StateVersion: testutils.DefaultTerraformVersion}), "%v", err)
+	s.Zero(httpmock.GetCallCountInfo()["POST https://app.terraform.io/api/v2/workspaces/test2/vars"], "no variables should be written when a check fails")
+}
+
+func (s *CopySuite) TestCopyTFStateSetsFilterVariables() {
+	httpmock.ActivateNonDefault(httpClient)
+	defer httpmock.DeactivateAndReset()
+	s.setupCopy()
+
+	httpmock.RegisterResponder("GET", `=~^https://app.terraform.io/api/v2/workspaces/test2/vars`,
+		httpmock.NewStringResponder(200, `{"data":[],"meta":{"pagination":{"current-page":1,"total-pages":1}}}`))
+	values := make(map[string]string)
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test2/vars", func(req *http.Request) (*http.Response, error) {
+		var body struct {
+			Data struct {
+				Attributes struct {
+					Key   string `json:"key"`
+					Value string `json:"value"`
+				} `json:"attributes"`
+			} `json:"data"`
+		}
+		s.NoError(json.NewDecoder(req.Body).Decode(&body))
+		values[body.Data.Attributes.Key] = body.Data.Attributes.Value
+		return httpmock.NewStringResponse(201, `{"data":{"id":"var-2","type":"vars"}}`), nil
+	})
+
+	err := CopyTFState("test1", "test2", "./testdata/filterConfigVariables.json", CopyOptions{})
+	s.NoError(err)
+	s.Equal(map[string]string{"region": "us-west-2", "dr_mode": "true"}, values)
+}
+
+func (s *CopySuite) TestCopyTFStateResolvesVariablesBeforeWriting() {
+	httpmock.ActivateNonDefault(httpClient)
+	defer httpmock.DeactivateAndReset()
+
+	err := CopyTFState("test1", "test2", "./testdata/filterConfig.json", CopyOptions{Variables: []variables.Variable{
+		{Key: "db_password", Category: variables.CategoryTerraform, Env: "TFDR_TEST_UNSET"},
+	}})
+	s.EqualError(err, "Variable db_password: environment variable TFDR_TEST_UNSET is not set")
+	s.Zero(httpmock.GetTotalCallCount())
+}
+
+func (s *CopySuite) TestCopyTFStateRequiresApprovalForCriticalWorkspaces() {
+	httpmock.ActivateNonDefault(httpClient)
+	defer httpmock.DeactivateAndReset()
+	s.setupCopy()
+	public, private, err := approval.GenerateKey()
+	s.NoError(err)
+	config.Override(func(c *config.Configuration) {
+		c.DualControl = &config.DualControl{Approvers: map[string]string{"alice": public}}
+	})
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces/test2",
+		httpmock.NewStringResponder(200, `{"data":{"id":"test2","type":"workspaces","attributes":{"name":"test2","tag-names":["tier:critical"]}}}`))
+	approve := func(workspace string) string {
+		token, err := approval.Sign(private, approval.Approval{Approver: "alice", Organization: "team", Workspaces: []string{workspace}, ExpiresAt: time.Now().Add(time.Hour)})
+		s.NoError(err)
+		return token
+	}
+
+	err = CopyTFState("test1", "test2", "./testdata/filterConfig.json", CopyOptions{})
+	s.Equal(tfdrerrors.ErrApprovalRequired{Workspace: "test2", Tag: "tier:critical"}, err)
+
+	err = CopyTFState("test1", "test2", "./testdata/filterConfig.json", CopyOptions{Approvals: []string{approve("test3")}})
+	s.Equal(tfdrerrors.ErrApprovalRequired{Workspace: "test2", Tag: "tier:critical"}, err)
+	s.Zero(httpmock.GetCallCountInfo()["POST https://app.terraform.io/api/v2/workspaces/test2/state-versions"], "nothing should be written without approval")
+
+	s.NoError(SetOnBehalfOf("Alice"))
+	err = CopyTFState("test1", "test2", "./testdata/filterConfig.json", CopyOptions{Approvals: []string{approve("test2")}})
+	s.NoError(SetOnBehalfOf(""))
+	s.EqualError(err, `Approval token is from "alice", who is running the restore. A second person has to approve it`)
+	s.Zero(httpmock.GetCallCountInfo()["POST https://app.terraform.io/api/v2/workspaces/test2/state-versions"], "the operator should not approve their own restore")
+
+	err = CopyTFState("test1", "test2", "./testdata/filterConfig.json", CopyOptions{Approvals: []string{approve("test2")}})
+	s.NoError(err)
+	s.Equal(1, httpmock.GetCallCountInfo()["POST https://app.terraform.io/api/v2/workspaces/test2/state-versions"])
+}
+
+func (s *CopySuite) TestCopyTFStateTagsStatus() {
+	httpmock.ActivateNonDefault(httpClient)
+	defer httpmock.DeactivateAndReset()
+	s.setupCopy()
+	s.NoError(testutils.SetupWksMockHTTPResponses(&testutils.TfeTestWks{
+		Name:         "Prod.App",
+		Exists:       true,
+		CsvResponder: testutils.NewResponder("test", "state-versions", "https://state"),
+	}))
+	tagsURL := "https://app.terraform.io/api/v2/workspaces/test2/relationships/tags"
+	httpmock.RegisterResponder("GET", tagsURL, httpmock.NewStringResponder(200, `{"data":[
+		{"id":"tag-1","type":"tags","attributes":{"name":"tier:critical"}},
+		{"id":"tag-2","type":"tags","attributes":{"name":"tfdr:restored-2020-01-01"}},
+		{"id":"tag-3","type":"tags","attributes":{"name":"tfdr:source:old-app"}}]}`))
+	var removed, added workspaceTagsRelationship
+	httpmock.RegisterResponder("DELETE", tagsURL, func(req *http.Request) (*http.Response, error) {
+		s.NoError(json.NewDecoder(req.Body).Decode(&removed))
+		return httpmock.NewStringResponse(204, ""), nil
+	})
+	httpmock.RegisterResponder("POST", tagsURL, func(req *http.Request) (*http.Response, error) {
+		s.NoError(json.NewDecoder(req.Body).Decode(&added))
+		return httpmock.NewStringResponse(204, ""), nil
+	})
+
+	err := CopyTFState("Prod.App", "test2", "./testdata/filterConfig.json", CopyOptions{TagStatus: true})
+	s.NoError(err)
+	s.Equal([]workspaceTag{{Type: "tags", ID: "tag-2"}, {Type: "tags", ID: "tag-3"}}, removed.Data, "tags of the earlier restore should be replaced")
+	s.Equal([]workspaceTag{
+		{Type: "tags", Attributes: &workspaceTagAttributes{Name: "tfdr:restored-" + time.Now().UTC().Format("2006-01-02")}},
+		{Type: "tags", Attributes: &workspaceTagAttributes{Name: "tfdr:source:prod-app"}},
+	}, added.Data)
+
+	httpmock.RegisterResponder("POST", tagsURL, httpmock.NewStringResponder(422, ""))
+	err = CopyTFState("Prod.App", "test2", "./testdata/filterConfig.json", CopyOptions{TagStatus: true})
+	s.NoError(err, "the copy should not fail when the tags can't be set")
+}
+
+// setupCopy mocks a copy from test1 to test2, which has no state yet. Tests
+// register their own responders afterwards, replacing these where needed.
+func (s *CopySuite) setupCopy() {
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/ping", httpmock.NewStringResponder(204, ""))
+	s.NoError(testutils.SetupWksMockHTTPResponses(&testutils.TfeTestWks{
+		Name:         "test1",
+		Exists:       true,
+		CurrentState: testutils.NewState(),
+		CsvResponder: testutils.NewResponder("test", "state-versions", "https://state"),
+	}))
+	s.NoError(testutils.SetupWksMockHTTPResponses(&testutils.TfeTestWks{
+		Name:            "test2",
+		Exists:          true,
+		CsvResponder:    httpmock.NewStringResponder(404, ""),
+		SvPostResponder: testutils.NewResponder("test2", "state-versions", ""),
+	}))
+}
+
+// setupDowngrade mocks a copy from test1 to test2, whose terraform version
+// is too old to read the copied state
+func (s *CopySuite) setupDowngrade() {
+	s.setupCopy()
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces/test2",
+		httpmock.NewStringResponder(200, `{"data":{"id":"test2","type":"workspaces","attributes":{"name":"test2","terraform-version":"0.12.29"}}}`))
+}
+
 func TestCopySuite(t *testing.T) {
 	suite.Run(t, new(CopySuite))
 }
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/models"
+)
+
+// providerCredentials lists, per provider type, the alternative sets of
+// environment variables that let a run authenticate. One complete set is
+// enough.
+var providerCredentials = map[string][][]string{
+	"aws": {
+		{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"},
+		{"TFC_AWS_PROVIDER_AUTH", "TFC_AWS_RUN_ROLE_ARN"},
+	},
+	"azurerm": {
+		{"ARM_CLIENT_ID", "ARM_CLIENT_SECRET", "ARM_TENANT_ID", "ARM_SUBSCRIPTION_ID"},
+		{"TFC_AZURE_PROVIDER_AUTH", "TFC_AZURE_RUN_CLIENT_ID", "ARM_TENANT_ID", "ARM_SUBSCRIPTION_ID"},
+	},
+	"google": {
+		{"GOOGLE_CREDENTIALS"},
+		{"GOOGLE_APPLICATION_CREDENTIALS"},
+		{"TFC_GCP_PROVIDER_AUTH", "TFC_GCP_RUN_SERVICE_ACCOUNT_EMAIL"},
+	},
+}
+
+var providerTypeRegexp = regexp.MustCompile(`provider(?:\["(?:[^"]*/)?([^"/]+)"\]|\.([A-Za-z0-9_-]+))`)
+
+// providerTypes returns the provider types used by the given resources
+func providerTypes(resources []models.Resource) []string {
+	seen := make(map[string]bool)
+	for _, r := range resources {
+		m := providerTypeRegexp.FindStringSubmatch(r.Provider)
+		if m == nil {
+			continue
+		}
+		if m[1] != "" {
+			seen[m[1]] = true
+		} else {
+			seen[m[2]] = true
+		}
+	}
+	types := make([]string, 0, len(seen))
+	for t := range seen {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// missingCredentials returns a warning for each provider in resources that
+// has no complete set of credentials among the environment variables in env
+func missingCredentials(resources []models.Resource, env map[string]bool) []string {
+	warnings := make([]string, 0)
+	for _, provider := range providerTypes(resources) {
+		sets, ok := providerCredentials[provider]
+		if !ok {
+			continue
+		}
+		satisfied := false
+		options := make([]string, 0, len(sets))
+		for _, set := range sets {
+			complete := true
+			for _, key := range set {
+				if !env[key] {
+					complete = false
+				}
+			}
+			satisfied = satisfied || complete
+			options = append(options, strings.Join(set, "+"))
+		}
+		if !satisfied {
+			warnings = append(warnings, fmt.Sprintf("provider %s has no credentials configured, expected one of: %s", provider, strings.Join(options, ", ")))
+		}
+	}
+	return warnings
+}
+
+// CheckProviderCredentials warns about providers in resources that the
+// workspace has no environment variable credentials for. It only warns,
+// since credentials can also come from agents or provider blocks.
+func CheckProviderCredentials(workspaceName string, resources []models.Resource) ([]string, error) {
+	return checkProviderCredentials(workspaceName, resources, nil)
+}
+
+// checkProviderCredentials is CheckProviderCredentials counting env as
+// already set, for credentials a copy is about to write
+func checkProviderCredentials(workspaceName string, resources []models.Resource, env []string) ([]string, error) {
+	c := config.GetConfig()
+
+	client, err := newTFEClient(c.ReadToken())
+	if err != nil {
+		return nil, fmt.Errorf("Cannot create tfe client. Err: %v", err)
+	}
+
+	workspace, err := client.Workspaces.Read(context.Background(), c.TerraformOrgName, workspaceName)
+	if err != nil {
+		return nil, workspaceError(err)
+	}
+
+	set := make(map[string]bool)
+	for _, k := range env {
+		set[k] = true
+	}
+	err = eachPage(func(page tfe.ListOptions) (*tfe.Pagination, error) {
+		vl, err := client.Variables.List(context.Background(), workspace.ID, tfe.VariableListOptions{ListOptions: page})
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vl.Items {
+			if v.Category == tfe.CategoryEnv {
+				set[v.Key] = true
+			}
+		}
+		return vl.Pagination, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list workspace variables. Error: %v", err)
+	}
+
+	if supports(c.ReadToken(), c.TerraformOrgName, CapabilityVariableSets) {
+		varsetKeys, err := varsetEnvKeys(c.ReadToken(), workspace.ID)
+		if err != nil {
+			logger.Debugf("Unable to read variable sets, checking workspace variables only. Error: %v", err)
+		}
+		for _, k := range varsetKeys {
+			set[k] = true
+		}
+	}
+
+	warnings := missingCredentials(resources, set)
+	for _, w := range warnings {
+		logger.Warnf("Workspace %s: %s", workspaceName, w)
+	}
+	return warnings, nil
+}
+
+// varsetEnvKeys returns the environment variable keys from variable sets
+// applied to a workspace. go-tfe does not wrap variable sets yet.
+func varsetEnvKeys(token string, workspaceID string) ([]string, error) {
+	resp, err := doAPIRequest("GET", fmt.Sprintf("workspaces/%s/varsets?include=vars&page%%5Bsize%%5D=100", workspaceID), token, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected status listing variable sets: %s", resp.Status)
+	}
+
+	var body struct {
+		Included []struct {
+			Type       string `json:"type"`
+			Attributes struct {
+				Key      string `json:"key"`
+				Category string `json:"category"`
+			} `json:"attributes"`
+		} `json:"included"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0)
+	for _, v := range body.Included {
+		if v.Type == "vars" && v.Attributes.Category == string(tfe.CategoryEnv) {
+			keys = append(keys, v.Attributes.Key)
+		}
+	}
+	return keys, nil
+}
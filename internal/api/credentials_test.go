@@ -0,0 +1,88 @@
+package api
+
+import (
+	"os"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/mupuri/go-tfdr/internal/models"
+	"github.com/mupuri/go-tfdr/internal/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+type CredentialsSuite struct {
+	suite.Suite
+}
+
+func (s *CredentialsSuite) SetupTest() {
+	os.Setenv("TF_TEAM_TOKEN", "test")
+	os.Setenv("TF_ORG_NAME", "team")
+	config.InitConfig("")
+	logging.InitLogger()
+	httpmock.ActivateNonDefault(httpClient)
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/ping", httpmock.NewStringResponder(204, ""))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces/test", testutils.NewResponder("test", "workspaces", ""))
+}
+
+func (s *CredentialsSuite) TearDownTest() {
+	httpmock.DeactivateAndReset()
+	os.Unsetenv("TF_TEAM_TOKEN")
+	os.Unsetenv("TF_ORG_NAME")
+}
+
+func resourcesWithProviders(providers ...string) []models.Resource {
+	resources := make([]models.Resource, 0)
+	for _, p := range providers {
+		resources = append(resources, models.Resource{Mode: "managed", Type: "t", Name: "n", Provider: p})
+	}
+	return resources
+}
+
+func (s *CredentialsSuite) TestProviderTypes() {
+	s.Equal([]string{"aws", "azurerm", "google", "random"}, providerTypes(resourcesWithProviders(
+		`provider["registry.terraform.io/hashicorp/aws"]`,
+		`provider["registry.terraform.io/hashicorp/aws"].west`,
+		`provider.azurerm`,
+		`module.app.provider.google`,
+		`provider["registry.terraform.io/hashicorp/random"]`,
+	)))
+}
+
+func (s *CredentialsSuite) TestMissingCredentials() {
+	resources := resourcesWithProviders(`provider["registry.terraform.io/hashicorp/aws"]`, `provider.google`, `provider.random`)
+
+	warnings := missingCredentials(resources, map[string]bool{"AWS_ACCESS_KEY_ID": true})
+	s.Equal(2, len(warnings))
+	s.Contains(warnings[0], "provider aws")
+	s.Contains(warnings[1], "provider google")
+
+	warnings = missingCredentials(resources, map[string]bool{"AWS_ACCESS_KEY_ID": true, "AWS_SECRET_ACCESS_KEY": true, "GOOGLE_CREDENTIALS": true})
+	s.Empty(warnings)
+}
+
+func (s *CredentialsSuite) TestCheckProviderCredentials() {
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/workspaces/test/vars", httpmock.NewStringResponder(200,
+		`{"data":[{"id":"var-1","type":"vars","attributes":{"key":"AWS_ACCESS_KEY_ID","category":"env"}},{"id":"var-2","type":"vars","attributes":{"key":"AWS_SECRET_ACCESS_KEY","category":"terraform"}}]}`))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/workspaces/test/varsets", httpmock.NewStringResponder(200,
+		`{"data":[{"id":"varset-1","type":"varsets"}],"included":[{"id":"var-3","type":"vars","attributes":{"key":"GOOGLE_CREDENTIALS","category":"env"}}]}`))
+
+	warnings, err := CheckProviderCredentials("test", resourcesWithProviders(`provider.aws`, `provider.google`))
+	s.NoError(err)
+	s.Equal(1, len(warnings), "terraform variables should not count as aws credentials")
+	s.Contains(warnings[0], "provider aws")
+}
+
+func (s *CredentialsSuite) TestCheckProviderCredentialsNoVarsets() {
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/workspaces/test/vars", httpmock.NewStringResponder(200, `{"data":[]}`))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/workspaces/test/varsets", httpmock.NewStringResponder(404, ""))
+
+	warnings, err := CheckProviderCredentials("test", resourcesWithProviders(`provider.azurerm`))
+	s.NoError(err)
+	s.Equal(1, len(warnings))
+}
+
+func TestCredentialsSuite(t *testing.T) {
+	suite.Run(t, new(CredentialsSuite))
+}
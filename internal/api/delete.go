@@ -9,6 +9,12 @@ import (
 
 // DeleteTFStateResources &
 func DeleteTFStateResources(workspaceName string, filterConfigFileName string) error {
+	unlock, err := lockLocal(workspaceName, "state delete")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	state, err := pullTFState(workspaceName)
 	if err != nil {
 		return tfdrerrors.ErrReadState{Err: err}
@@ -21,9 +27,10 @@ func DeleteTFStateResources(workspaceName string, filterConfigFileName string) e
 	if err != nil {
 		return tfdrerrors.ErrUnableToFilter{Err: err}
 	}
+	baseSerial := state.Serial
 	state.Serial++
 
-	err = createTFStateVersion(state, workspaceName)
+	err = createTFStateVersion(state, workspaceName, baseSerial)
 	if err != nil {
 		return fmt.Errorf("Unable to create new state version. Error: %v", err)
 	}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/models"
+)
+
+// RemoteStateDependency is an upstream workspace whose outputs a workspace
+// reads through a terraform_remote_state data source
+type RemoteStateDependency struct {
+	// Address of the data source, e.g. data.terraform_remote_state.network
+	Address      string
+	Organization string
+	Workspace    string
+	// Outputs are the upstream outputs the data source read when the
+	// consuming workspace was last applied
+	Outputs []string
+	// Exists and MissingOutputs are set by CheckRemoteStateDependencies
+	Exists         bool
+	MissingOutputs []string
+}
+
+// Ready reports whether the upstream workspace exists and has every output
+// the consuming workspace reads
+func (d RemoteStateDependency) Ready() bool {
+	return d.Exists && len(d.MissingOutputs) == 0
+}
+
+// WorkspaceRemoteStateDependencies returns the remote state dependencies in
+// the current state of a workspace of the configured organization
+func WorkspaceRemoteStateDependencies(workspaceName string) ([]RemoteStateDependency, error) {
+	state, err := pullTFState(workspaceName)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, fmt.Errorf("Workspace %s has no state", workspaceName)
+	}
+	return RemoteStateDependencies(state), nil
+}
+
+// RemoteStateDependencies returns the remote and cloud backend
+// terraform_remote_state data sources in a state
+func RemoteStateDependencies(state *models.State) []RemoteStateDependency {
+	deps := make([]RemoteStateDependency, 0)
+	for _, r := range state.Resources {
+		if r.Mode != "data" || r.Type != "terraform_remote_state" {
+			continue
+		}
+		for _, i := range r.Instances {
+			if backend, _ := i.Attributes["backend"].(string); backend != "remote" && backend != "cloud" {
+				continue
+			}
+			cfg, _ := dynamicValue(i.Attributes["config"]).(map[string]interface{})
+			d := RemoteStateDependency{Address: dataSourceAddress(r, i)}
+			d.Organization, _ = cfg["organization"].(string)
+			workspaces, _ := cfg["workspaces"].(map[string]interface{})
+			if name, ok := workspaces["name"].(string); ok {
+				d.Workspace = name
+			} else if prefix, ok := workspaces["prefix"].(string); ok {
+				// Prefixed workspaces are selected with the workspace argument
+				workspace, _ := i.Attributes["workspace"].(string)
+				if workspace == "" {
+					workspace = "default"
+				}
+				d.Workspace = prefix + workspace
+			}
+			if d.Workspace == "" {
+				logger.Warnf("Unable to find the upstream workspace of %s", d.Address)
+				continue
+			}
+			outputs, _ := dynamicValue(i.Attributes["outputs"]).(map[string]interface{})
+			for name := range outputs {
+				d.Outputs = append(d.Outputs, name)
+			}
+			sort.Strings(d.Outputs)
+			deps = append(deps, d)
+		}
+	}
+	return deps
+}
+
+// CheckRemoteStateDependencies checks that the upstream workspaces exist and
+// have the outputs their consumers read. Upstreams are looked up in orgName,
+// or the organization named by the data source when it is empty, with
+// workspace names starting with a key of renames given that prefix's value.
+func CheckRemoteStateDependencies(deps []RemoteStateDependency, orgName string, renames map[string]string) ([]RemoteStateDependency, error) {
+	c := config.GetConfig()
+
+	client, err := newTFEClient(c.ReadToken())
+	if err != nil {
+		return nil, fmt.Errorf("Cannot create tfe client. Err: %v", err)
+	}
+
+	op := startOperation("dependency check", len(deps))
+	checked := make([]RemoteStateDependency, 0, len(deps))
+	for _, d := range deps {
+		if orgName != "" {
+			d.Organization = orgName
+		}
+		if d.Organization == "" {
+			d.Organization = c.TerraformOrgName
+		}
+		d.Workspace = replacePrefix(d.Workspace, renames)
+
+		err := checkRemoteStateDependency(client, c.ReadToken(), &d)
+		op.workspaceDone(d.Workspace, err)
+		if err != nil {
+			return nil, op.finish(fmt.Errorf("Unable to check upstream workspace %s. Error: %v", d.Workspace, err))
+		}
+		checked = append(checked, d)
+	}
+	return checked, op.finish(nil)
+}
+
+func checkRemoteStateDependency(client *tfe.Client, token string, d *RemoteStateDependency) error {
+	workspace, err := client.Workspaces.Read(context.Background(), d.Organization, d.Workspace)
+	if err != nil {
+		if err.Error() == tfe.ErrResourceNotFound.Error() {
+			d.MissingOutputs = d.Outputs
+			return nil
+		}
+		return err
+	}
+	d.Exists = true
+
+	state, err := pullWorkspaceState(client, token, workspace)
+	if err != nil {
+		return err
+	}
+	var outputs map[string]interface{}
+	if state != nil {
+		outputs, _ = state.Outputs.(map[string]interface{})
+	}
+	for _, name := range d.Outputs {
+		if _, ok := outputs[name]; !ok {
+			d.MissingOutputs = append(d.MissingOutputs, name)
+		}
+	}
+	return nil
+}
+
+// dynamicValue unwraps attributes of dynamic type, which state stores as an
+// object with the value and its type
+func dynamicValue(v interface{}) interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		if value, ok := m["value"]; ok {
+			if _, ok := m["type"]; ok && len(m) == 2 {
+				return value
+			}
+		}
+	}
+	return v
+}
+
+func dataSourceAddress(r models.Resource, i models.Instance) string {
+	address := "data." + r.Type + "." + r.Name
+	if r.Module != "" {
+		address = r.Module + "." + address
+	}
+	switch key := i.IndexKey.(type) {
+	case string:
+		address += fmt.Sprintf("[%q]", key)
+	case float64:
+		address += fmt.Sprintf("[%d]", int(key))
+	}
+	return address
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"os"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/mupuri/go-tfdr/internal/models"
+	"github.com/mupuri/go-tfdr/internal/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+type DependenciesSuite struct {
+	suite.Suite
+}
+
+func (s *DependenciesSuite) SetupTest() {
+	os.Setenv("TF_TEAM_TOKEN", "test")
+	os.Setenv("TF_ORG_NAME", "team")
+	config.InitConfig("")
+	logging.InitLogger()
+	httpmock.ActivateNonDefault(httpClient)
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/ping", httpmock.NewStringResponder(204, ""))
+}
+
+func (s *DependenciesSuite) TearDownTest() {
+	httpmock.DeactivateAndReset()
+	os.Unsetenv("TF_TEAM_TOKEN")
+	os.Unsetenv("TF_ORG_NAME")
+}
+
+func remoteStateResource(name string, attributes map[string]interface{}) models.Resource {
+	return models.Resource{Mode: "data", Type: "terraform_remote_state", Name: name, Instances: []models.Instance{{Attributes: attributes}}}
+}
+
+func consumerState() *models.State {
+	return &models.State{Resources: []models.Resource{
+		{Mode: "managed", Type: "aws_instance", Name: "app"},
+		remoteStateResource("network", map[string]interface{}{
+			"backend": "remote",
+			"config": map[string]interface{}{
+				"value": map[string]interface{}{"organization": "prod", "workspaces": map[string]interface{}{"name": "prod-network"}},
+				"type":  []interface{}{"object", map[string]interface{}{}},
+			},
+			"outputs": map[string]interface{}{
+				"value": map[string]interface{}{"vpc_id": "vpc-1", "subnet_ids": []interface{}{"subnet-1"}},
+				"type":  []interface{}{"object", map[string]interface{}{}},
+			},
+		}),
+		remoteStateResource("dns", map[string]interface{}{
+			"backend":   "remote",
+			"workspace": "dns",
+			"config":    map[string]interface{}{"organization": "prod", "workspaces": map[string]interface{}{"prefix": "prod-"}},
+			"outputs":   map[string]interface{}{"zone_id": "Z1"},
+		}),
+		remoteStateResource("legacy", map[string]interface{}{"backend": "s3"}),
+	}}
+}
+
+func (s *DependenciesSuite) TestRemoteStateDependencies() {
+	deps := RemoteStateDependencies(consumerState())
+	s.Equal([]RemoteStateDependency{
+		{Address: "data.terraform_remote_state.network", Organization: "prod", Workspace: "prod-network", Outputs: []string{"subnet_ids", "vpc_id"}},
+		{Address: "data.terraform_remote_state.dns", Organization: "prod", Workspace: "prod-dns", Outputs: []string{"zone_id"}},
+	}, deps)
+}
+
+func (s *DependenciesSuite) TestCheckRemoteStateDependencies() {
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/dr/workspaces/dr-network",
+		httpmock.NewStringResponder(200, `{"data":{"id":"ws-net","type":"workspaces","attributes":{"name":"dr-network"}}}`))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/workspaces/ws-net/current-state-version",
+		testutils.NewResponder("sv-1", "state-versions", "https://state"))
+	httpmock.RegisterResponder("GET", "https://state",
+		httpmock.NewStringResponder(200, `{"version":4,"outputs":{"vpc_id":{"value":"vpc-2","type":"string"}},"resources":[]}`))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/dr/workspaces/dr-dns", httpmock.NewStringResponder(404, ""))
+
+	deps, err := CheckRemoteStateDependencies(RemoteStateDependencies(consumerState()), "dr", map[string]string{"prod-": "dr-"})
+	s.NoError(err)
+	s.Len(deps, 2)
+
+	s.Equal("dr/dr-network", deps[0].Organization+"/"+deps[0].Workspace)
+	s.True(deps[0].Exists)
+	s.Equal([]string{"subnet_ids"}, deps[0].MissingOutputs)
+	s.False(deps[0].Ready())
+
+	s.Equal("dr-dns", deps[1].Workspace)
+	s.False(deps[1].Exists)
+	s.False(deps[1].Ready())
+}
+
+func TestDependenciesSuite(t *testing.T) {
+	suite.Run(t, new(DependenciesSuite))
+}
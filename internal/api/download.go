@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-tfe"
+)
+
+// States at or above chunkedDownloadThreshold bytes are downloaded with
+// parallel ranged requests. Each chunk is retried on its own, so a dropped
+// connection only costs the chunk in flight instead of the whole state.
+var (
+	chunkedDownloadThreshold int64 = 64 << 20
+	downloadChunkSize        int64 = 16 << 20
+	downloadConcurrency            = 4
+	downloadChunkRetries           = 3
+	downloadRetryWait              = time.Second
+)
+
+type chunk struct {
+	start int64
+	end   int64
+}
+
+func downloadState(client *tfe.Client, token string, url string) ([]byte, error) {
+	size, ranged, err := probeStateDownload(token, url)
+	if err != nil || !ranged || size < chunkedDownloadThreshold {
+		if err != nil {
+			logger.Debugf("Unable to probe state download, falling back to single request. Error: %v", err)
+		}
+		return client.StateVersions.Download(context.Background(), url)
+	}
+
+	logger.Debugf("Downloading %d byte state in %d byte chunks", size, downloadChunkSize)
+	return downloadChunks(token, url, size)
+}
+
+func probeStateDownload(token string, url string) (int64, bool, error) {
+	req, err := http.NewRequest("HEAD", url, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, false, fmt.Errorf("Unexpected status probing state download: %s", resp.Status)
+	}
+
+	size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("Invalid Content-Length. Error: %v", err)
+	}
+	ranged := strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes")
+
+	return size, ranged, nil
+}
+
+func downloadChunks(token string, url string, size int64) ([]byte, error) {
+	buf := make([]byte, size)
+	chunks := make(chan chunk)
+	errs := make(chan error, downloadConcurrency)
+
+	var wg sync.WaitGroup
+	for i := 0; i < downloadConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range chunks {
+				if err := downloadChunkWithRetry(token, url, c, buf[c.start:c.end+1]); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	var err error
+	for start := int64(0); start < size && err == nil; start += downloadChunkSize {
+		end := start + downloadChunkSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		select {
+		case chunks <- chunk{start: start, end: end}:
+		case err = <-errs:
+		}
+	}
+	close(chunks)
+	wg.Wait()
+
+	if err == nil {
+		select {
+		case err = <-errs:
+		default:
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func downloadChunkWithRetry(token string, url string, c chunk, dst []byte) error {
+	var err error
+	for attempt := 0; attempt <= downloadChunkRetries; attempt++ {
+		if attempt > 0 {
+			logger.Debugf("Retrying state chunk %d-%d (attempt %d). Error: %v", c.start, c.end, attempt, err)
+			time.Sleep(downloadRetryWait * time.Duration(attempt))
+		}
+		if err = downloadChunk(token, url, c, dst); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("Unable to download state bytes %d-%d. Error: %v", c.start, c.end, err)
+}
+
+func downloadChunk(token string, url string, c chunk, dst []byte) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", c.start, c.end))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("Unexpected status for ranged request: %s", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if int64(len(body)) != c.end-c.start+1 {
+		return fmt.Errorf("Short chunk: got %d bytes, expected %d", len(body), c.end-c.start+1)
+	}
+	copy(dst, body)
+	return nil
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/mupuri/go-tfdr/internal/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+type DownloadSuite struct {
+	suite.Suite
+	threshold int64
+	chunkSize int64
+	retryWait time.Duration
+}
+
+func (s *DownloadSuite) SetupTest() {
+	os.Setenv("TF_TEAM_TOKEN", "test")
+	os.Setenv("TF_ORG_NAME", "team")
+	config.InitConfig("")
+	logging.InitLogger()
+	s.threshold, s.chunkSize, s.retryWait = chunkedDownloadThreshold, downloadChunkSize, downloadRetryWait
+	chunkedDownloadThreshold, downloadChunkSize, downloadRetryWait = 16, 10, time.Millisecond
+	httpmock.ActivateNonDefault(httpClient)
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/ping", httpmock.NewStringResponder(204, ""))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces/test", testutils.NewResponder("test", "workspaces", ""))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/workspaces/test/current-state-version", testutils.NewResponder("test", "state-versions", "https://state"))
+}
+
+func (s *DownloadSuite) TearDownTest() {
+	chunkedDownloadThreshold, downloadChunkSize, downloadRetryWait = s.threshold, s.chunkSize, s.retryWait
+	httpmock.DeactivateAndReset()
+	os.Unsetenv("TF_TEAM_TOKEN")
+	os.Unsetenv("TF_ORG_NAME")
+}
+
+func rangedResponder(body string, failures map[string]int) httpmock.Responder {
+	var mu sync.Mutex
+	return func(req *http.Request) (*http.Response, error) {
+		rng := req.Header.Get("Range")
+		if rng == "" {
+			return httpmock.NewStringResponse(200, body), nil
+		}
+		mu.Lock()
+		if failures[rng] > 0 {
+			failures[rng]--
+			mu.Unlock()
+			return nil, errors.New("connection reset")
+		}
+		mu.Unlock()
+
+		var start, end int
+		if _, err := fmt.Sscanf(rng, "bytes=%d-%d", &start, &end); err != nil {
+			return httpmock.NewStringResponse(416, ""), nil
+		}
+		return httpmock.NewStringResponse(206, body[start:end+1]), nil
+	}
+}
+
+func headResponder(size int, ranges string) httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(200, "")
+		resp.Header.Set("Content-Length", fmt.Sprint(size))
+		resp.Header.Set("Accept-Ranges", ranges)
+		return resp, nil
+	}
+}
+
+func (s *DownloadSuite) TestPullTFStateChunked() {
+	body := `{"version":4,"terraform_version":"0.13.4","serial":7,"lineage":"chunked","resources":[]}`
+	httpmock.RegisterResponder("HEAD", "https://state", headResponder(len(body), "bytes"))
+	httpmock.RegisterResponder("GET", "https://state", rangedResponder(body, map[string]int{"bytes=10-19": 2}))
+
+	st, err := pullTFState("test")
+	s.NoError(err)
+	s.NotNil(st)
+	s.Equal("chunked", st.Lineage)
+	s.Equal(int64(7), st.Serial)
+}
+
+func (s *DownloadSuite) TestPullTFStateChunkedGivesUp() {
+	body := `{"version":4,"terraform_version":"0.13.4","serial":7,"lineage":"chunked","resources":[]}`
+	httpmock.RegisterResponder("HEAD", "https://state", headResponder(len(body), "bytes"))
+	httpmock.RegisterResponder("GET", "https://state", rangedResponder(body, map[string]int{"bytes=20-29": downloadChunkRetries + 1}))
+
+	st, err := pullTFState("test")
+	s.Error(err)
+	s.True(strings.Contains(err.Error(), "Unable to download state bytes 20-29"))
+	s.Nil(st)
+}
+
+func (s *DownloadSuite) TestPullTFStateNoRangeSupport() {
+	body := `{"version":4,"terraform_version":"0.13.4","serial":7,"lineage":"single","resources":[]}`
+	httpmock.RegisterResponder("HEAD", "https://state", headResponder(len(body), "none"))
+	httpmock.RegisterResponder("GET", "https://state", rangedResponder(body, nil))
+
+	st, err := pullTFState("test")
+	s.NoError(err)
+	s.Equal("single", st.Lineage)
+}
+
+func TestDownloadSuite(t *testing.T) {
+	suite.Run(t, new(DownloadSuite))
+}
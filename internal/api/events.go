@@ -0,0 +1,220 @@
+package api
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/logging"
+)
+
+// EventType identifies a progress event
+type EventType string
+
+// Progress events reported to the handler set with SetEventHandler
+const (
+	// OperationStarted is sent when a multi-workspace operation begins.
+	// Total is the number of workspaces, or 0 when it isn't known yet.
+	OperationStarted EventType = "operation_started"
+	// WorkspaceCompleted is sent when an operation is done with a
+	// workspace. Err is set when the workspace failed.
+	WorkspaceCompleted EventType = "workspace_completed"
+	// RetryScheduled is sent when a request failed and will be retried,
+	// or the API is paused, after Wait
+	RetryScheduled EventType = "retry_scheduled"
+	// OperationFinished is sent when an operation ends, with Err set when
+	// it failed
+	OperationFinished EventType = "operation_finished"
+)
+
+// Event reports the progress of a long running operation
+type Event struct {
+	Type      EventType
+	Time      time.Time
+	Operation string
+	// OperationID is a UUID shared by the events and log lines of one
+	// operation
+	OperationID string
+	Workspace   string
+	Total       int
+	Attempt     int
+	Wait        time.Duration
+	Err         error
+	// OnBehalfOf is who the operation is attributed to, see SetOnBehalfOf
+	OnBehalfOf string
+	// APICalls are the requests the operation made, sent with
+	// OperationFinished
+	APICalls []APICalls
+}
+
+var (
+	eventMu      sync.Mutex
+	eventHandler func(Event)
+	// added are the handlers from AddEventHandler, keyed by a registration
+	// number so they can be removed again
+	added     map[int]func(Event)
+	addedNext int
+)
+
+// SetEventHandler sets a function that receives progress events, or removes
+// it when h is nil. Handlers are called from whichever goroutine produced
+// the event, possibly from several at once, so h must be safe for
+// concurrent use and should return quickly.
+func SetEventHandler(h func(Event)) {
+	eventMu.Lock()
+	defer eventMu.Unlock()
+	eventHandler = h
+}
+
+// AddEventHandler adds a function that receives progress events after the
+// handler set with SetEventHandler, if any. Calling the returned function
+// removes it.
+func AddEventHandler(h func(Event)) (remove func()) {
+	eventMu.Lock()
+	defer eventMu.Unlock()
+	if added == nil {
+		added = make(map[int]func(Event))
+	}
+	id := addedNext
+	addedNext++
+	added[id] = h
+	return func() {
+		eventMu.Lock()
+		defer eventMu.Unlock()
+		delete(added, id)
+	}
+}
+
+// handlers returns the event handlers in the order they get events
+func handlers() []func(Event) {
+	eventMu.Lock()
+	defer eventMu.Unlock()
+	hs := make([]func(Event), 0, len(added)+1)
+	if eventHandler != nil {
+		hs = append(hs, eventHandler)
+	}
+	ids := make([]int, 0, len(added))
+	for id := range added {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		hs = append(hs, added[id])
+	}
+	return hs
+}
+
+// emit delivers an event without holding eventMu, so a slow handler such as
+// the email notifier doesn't hold up other goroutines' events
+func emit(e Event) {
+	hs := handlers()
+	if len(hs) == 0 {
+		return
+	}
+	e.Time = time.Now()
+	if e.OperationID == "" {
+		e.OperationID = currentOperationID()
+	}
+	e.OnBehalfOf = onBehalfOf
+	for _, h := range hs {
+		h(e)
+	}
+}
+
+// operation reports the progress of one multi-workspace operation. While it
+// runs, every api log line carries its ID in the operation_id field.
+// Operations run one at a time per process, as they do in the CLI.
+type operation struct {
+	name string
+	id   string
+	// usage is the API usage when the operation started
+	usage map[usageKey]int
+}
+
+var (
+	operationMu sync.Mutex
+	currentOp   *operation
+)
+
+func startOperation(name string, total int) *operation {
+	o := &operation{name: name, id: newUUID(), usage: usageSnapshot()}
+	operationMu.Lock()
+	currentOp = o
+	operationMu.Unlock()
+	logger.Debugf("Starting %s", name)
+	emit(Event{Type: OperationStarted, Operation: name, OperationID: o.id, Total: total})
+	return o
+}
+
+func (o *operation) workspaceDone(workspace string, err error) {
+	emit(Event{Type: WorkspaceCompleted, Operation: o.name, OperationID: o.id, Workspace: workspace, Err: err})
+}
+
+// finish reports the end of the operation and returns err
+func (o *operation) finish(err error) error {
+	usageMu.Lock()
+	calls := usageSince(o.usage)
+	usageMu.Unlock()
+	logger.Debugf("%s made %d API requests:\n%s", o.name, TotalCalls(calls), FormatUsage(calls))
+	emit(Event{Type: OperationFinished, Operation: o.name, OperationID: o.id, Err: err, APICalls: calls})
+	operationMu.Lock()
+	if currentOp == o {
+		currentOp = nil
+	}
+	operationMu.Unlock()
+	return err
+}
+
+// currentOperationID returns the ID of the running operation, if any
+func currentOperationID() string {
+	operationMu.Lock()
+	defer operationMu.Unlock()
+	if currentOp == nil {
+		return ""
+	}
+	return currentOp.id
+}
+
+// operationLogger adds the running operation's ID, and who it runs on behalf
+// of, to every entry
+type operationLogger struct {
+	logging.Logger
+}
+
+func (l operationLogger) current() logging.Logger {
+	fields := logging.Fields{}
+	if id := currentOperationID(); id != "" {
+		fields["operation_id"] = id
+	}
+	if onBehalfOf != "" {
+		fields["on_behalf_of"] = onBehalfOf
+	}
+	if len(fields) == 0 {
+		return l.Logger
+	}
+	return l.Logger.WithFields(fields)
+}
+
+func (l operationLogger) Tracef(format string, args ...interface{}) {
+	l.current().Tracef(format, args...)
+}
+
+func (l operationLogger) Debugf(format string, args ...interface{}) {
+	l.current().Debugf(format, args...)
+}
+
+func (l operationLogger) Infof(format string, args ...interface{}) {
+	l.current().Infof(format, args...)
+}
+
+func (l operationLogger) Warnf(format string, args ...interface{}) {
+	l.current().Warnf(format, args...)
+}
+
+func (l operationLogger) Errorf(format string, args ...interface{}) {
+	l.current().Errorf(format, args...)
+}
+
+func (l operationLogger) WithFields(fields logging.Fields) logging.Logger {
+	return operationLogger{l.Logger.WithFields(fields)}
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventsFromConcurrentGoroutines(t *testing.T) {
+	defer SetEventHandler(nil)
+	var mu sync.Mutex
+	count := 0
+	SetEventHandler(func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		count++
+		assert.False(t, e.Time.IsZero())
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			op := startOperation("test", 1)
+			op.workspaceDone("test", nil)
+			op.finish(nil)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 30, count, "every event should be delivered")
+}
+
+func TestAddEventHandler(t *testing.T) {
+	defer SetEventHandler(nil)
+	got := make([]string, 0)
+	SetEventHandler(func(e Event) { got = append(got, "set") })
+	removeFirst := AddEventHandler(func(e Event) { got = append(got, "first") })
+	removeSecond := AddEventHandler(func(e Event) { got = append(got, "second") })
+	defer removeSecond()
+
+	emit(Event{Type: RetryScheduled})
+	assert.Equal(t, []string{"set", "first", "second"}, got)
+
+	removeFirst()
+	got = got[:0]
+	emit(Event{Type: RetryScheduled})
+	assert.Equal(t, []string{"set", "second"}, got, "removed handlers should get no more events")
+}
+
+func TestEmitDoesNotBlockOnSlowHandlers(t *testing.T) {
+	defer SetEventHandler(nil)
+	release := make(chan struct{})
+	SetEventHandler(func(e Event) {
+		if e.Workspace == "slow" {
+			<-release
+		}
+	})
+	go emit(Event{Type: WorkspaceCompleted, Workspace: "slow"})
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		emit(Event{Type: WorkspaceCompleted, Workspace: "fast"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("an event should not wait for another goroutine's slow handler")
+	}
+	close(release)
+}
+
+func TestRetryScheduledEvent(t *testing.T) {
+	defer SetEventHandler(nil)
+	defer func(d time.Duration) { pageRetryWait = d }(pageRetryWait)
+	pageRetryWait = time.Millisecond
+
+	var events []Event
+	SetEventHandler(func(e Event) { events = append(events, e) })
+
+	attempts := 0
+	err := withPageRetry(func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New(http.StatusText(http.StatusBadGateway))
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, RetryScheduled, events[1].Type)
+		assert.Equal(t, 2, events[1].Attempt)
+		assert.Equal(t, 2*time.Millisecond, events[1].Wait)
+	}
+}
+
+func TestOperationID(t *testing.T) {
+	defer SetEventHandler(nil)
+	defer SetLogger(logger)
+	var logs bytes.Buffer
+	l := logrus.New()
+	l.SetLevel(logrus.DebugLevel)
+	l.SetOutput(&logs)
+	SetLogger(logging.FromLogrus(l))
+
+	var events []Event
+	SetEventHandler(func(e Event) { events = append(events, e) })
+
+	op := startOperation("test", 1)
+	req, _ := http.NewRequest("GET", "http://127.0.0.1:0/ping", nil)
+	_, err := httpClient.Do(req)
+	assert.Error(t, err)
+	op.workspaceDone("test", nil)
+	op.finish(nil)
+	assert.Contains(t, logs.String(), "API request failed")
+	assert.Contains(t, logs.String(), "operation_id="+events[0].OperationID)
+
+	logs.Reset()
+	logger.Infof("after")
+	assert.NotContains(t, logs.String(), "operation_id")
+
+	startOperation("test", 0).finish(nil)
+	assert.Len(t, events, 5)
+	assert.NotEmpty(t, events[0].OperationID)
+	assert.Equal(t, events[0].OperationID, events[1].OperationID)
+	assert.Equal(t, events[0].OperationID, events[2].OperationID)
+	assert.NotEqual(t, events[0].OperationID, events[3].OperationID)
+}
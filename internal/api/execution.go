@@ -0,0 +1,60 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Execution modes of a workspace
+const (
+	ExecutionRemote = "remote"
+	ExecutionLocal  = "local"
+	ExecutionAgent  = "agent"
+)
+
+// ValidateExecutionMode checks an execution mode and agent pool can be set
+// together
+func ValidateExecutionMode(mode string, agentPoolID string) error {
+	switch mode {
+	case "":
+		if agentPoolID != "" {
+			return fmt.Errorf("an agent pool needs the %s execution mode", ExecutionAgent)
+		}
+	case ExecutionRemote, ExecutionLocal:
+		if agentPoolID != "" {
+			return fmt.Errorf("an agent pool can't be used with the %s execution mode", mode)
+		}
+	case ExecutionAgent:
+		if agentPoolID == "" {
+			return fmt.Errorf("the %s execution mode needs an agent pool", ExecutionAgent)
+		}
+	default:
+		return fmt.Errorf("unknown execution mode %q, expected %s, %s or %s", mode, ExecutionRemote, ExecutionLocal, ExecutionAgent)
+	}
+	return nil
+}
+
+// setExecutionMode switches the locked workspace to an execution mode, e.g.
+// from the primary region's agents to remote execution during a failover.
+// go-tfe does not know about execution modes.
+func (l *workspaceLock) setExecutionMode(mode string, agentPoolID string) error {
+	attrs := map[string]interface{}{"execution-mode": mode}
+	if mode == ExecutionAgent {
+		attrs["agent-pool-id"] = agentPoolID
+	}
+	body := workspaceUpdateRequest{Data: workspaceUpdateData{Type: "workspaces", Attributes: attrs}}
+	resp, err := doAPIRequest("PATCH", "workspaces/"+l.workspace.ID, l.token, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected status updating workspace: %s", resp.Status)
+	}
+	if mode == ExecutionAgent {
+		logger.Infof("Set workspace %s to run on agent pool %s", l.workspace.Name, agentPoolID)
+	} else {
+		logger.Infof("Set workspace %s to %s execution", l.workspace.Name, mode)
+	}
+	return nil
+}
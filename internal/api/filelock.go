@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+)
+
+// localLock is the content of a workspace lock file. Lock files keep tfdr
+// processes on one machine, such as a daemon and an operator, from working on
+// the same workspace at once. The TFE workspace lock only covers writing
+// state, so two processes could otherwise both read a workspace and act on
+// it.
+type localLock struct {
+	PID         int       `json:"pid"`
+	Host        string    `json:"host"`
+	Operation   string    `json:"operation"`
+	OperationID string    `json:"operation_id,omitempty"`
+	Started     time.Time `json:"started"`
+}
+
+// errLockHeld is returned by openLockFile when another process holds the lock
+var errLockHeld = errors.New("lock file is held by another process")
+
+// lockLocal takes the lock file of a workspace of the configured
+// organization and returns a function that removes it. The lock is held on
+// the open file, see openLockFile, so the lock file of a process on this
+// host that has exited is free to take over.
+func lockLocal(workspaceName string, operation string) (func(), error) {
+	c := config.GetConfig()
+	dir := filepath.Join(c.LockDirectory(), c.TerraformOrgName)
+	// Group writable so operators and a daemon sharing a group can use the
+	// same lock directory
+	if err := os.MkdirAll(dir, 0770); err != nil {
+		return nil, fmt.Errorf("Unable to create lock directory %s. Error: %v", dir, err)
+	}
+	path := filepath.Join(dir, workspaceName+".lock")
+
+	host, _ := os.Hostname()
+	data, err := json.Marshal(localLock{
+		PID:         os.Getpid(),
+		Host:        host,
+		Operation:   operation,
+		OperationID: currentOperationID(),
+		Started:     time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := openLockFile(path)
+	if err == errLockHeld {
+		held, _ := readLocalLock(path)
+		return nil, tfdrerrors.ErrWorkspaceBusy{Workspace: workspaceName, Operation: held.Operation, PID: held.PID, Host: held.Host, LockFile: path}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create lock file %s. Error: %v", path, err)
+	}
+	// Nobody holds the lock, but a lock file written on another host sharing
+	// the lock directory is kept: its process can't be checked from here
+	if held, err := readLocalLock(path); err == nil {
+		if held.Host != host {
+			f.Close()
+			return nil, tfdrerrors.ErrWorkspaceBusy{Workspace: workspaceName, Operation: held.Operation, PID: held.PID, Host: held.Host, LockFile: path}
+		}
+		logger.Warnf("Taking over lock file of exited tfdr process %d on workspace %s", held.PID, workspaceName)
+	}
+
+	err = f.Truncate(0)
+	if err == nil {
+		_, err = f.WriteAt(data, 0)
+	}
+	if err != nil {
+		releaseLockFile(f, path)
+		return nil, fmt.Errorf("Unable to write lock file %s. Error: %v", path, err)
+	}
+	logger.Debugf("Created lock file %s", path)
+	return func() {
+		if err := releaseLockFile(f, path); err != nil && !os.IsNotExist(err) {
+			logger.Warnf("Unable to remove lock file %s. Error: %v", path, err)
+		}
+	}, nil
+}
+
+func readLocalLock(path string) (localLock, error) {
+	var l localLock
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return l, err
+	}
+	err = json.Unmarshal(data, &l)
+	return l, err
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+	"github.com/stretchr/testify/suite"
+)
+
+type FileLockSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (s *FileLockSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "tfdr-locks")
+	s.NoError(err)
+	s.dir = dir
+	os.Setenv("TF_TEAM_TOKEN", "test")
+	os.Setenv("TF_ORG_NAME", "team")
+	os.Setenv("TF_LOCK_DIR", dir)
+	config.InitConfig("")
+	logging.InitLogger()
+}
+
+func (s *FileLockSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+	os.Unsetenv("TF_TEAM_TOKEN")
+	os.Unsetenv("TF_ORG_NAME")
+	os.Unsetenv("TF_LOCK_DIR")
+}
+
+func (s *FileLockSuite) TestLockIsExclusive() {
+	unlock, err := lockLocal("prod-app", "state copy")
+	s.NoError(err)
+	s.FileExists(filepath.Join(s.dir, "team", "prod-app.lock"))
+
+	_, err = lockLocal("prod-app", "state delete")
+	var busy tfdrerrors.ErrWorkspaceBusy
+	s.True(errors.As(err, &busy))
+	s.Equal("state copy", busy.Operation)
+	s.Equal(os.Getpid(), busy.PID)
+
+	other, err := lockLocal("prod-network", "state delete")
+	s.NoError(err)
+	other()
+
+	unlock()
+	unlock, err = lockLocal("prod-app", "state delete")
+	s.NoError(err)
+	unlock()
+}
+
+func (s *FileLockSuite) TestTakesOverLockOfExitedProcess() {
+	host, _ := os.Hostname()
+	data, _ := json.Marshal(localLock{PID: 1 << 30, Host: host, Operation: "state copy"})
+	s.NoError(os.MkdirAll(filepath.Join(s.dir, "team"), 0770))
+	s.NoError(ioutil.WriteFile(filepath.Join(s.dir, "team", "prod-app.lock"), data, 0660))
+
+	unlock, err := lockLocal("prod-app", "state copy")
+	s.NoError(err)
+	unlock()
+}
+
+func (s *FileLockSuite) TestOneProcessTakesOverLockOfExitedProcess() {
+	host, _ := os.Hostname()
+	data, _ := json.Marshal(localLock{PID: 1 << 30, Host: host, Operation: "state copy"})
+	s.NoError(os.MkdirAll(filepath.Join(s.dir, "team"), 0770))
+	s.NoError(ioutil.WriteFile(filepath.Join(s.dir, "team", "prod-app.lock"), data, 0660))
+
+	// Each lockLocal call opens the file on its own, like separate processes
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	unlocks := make([]func(), 0)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if unlock, err := lockLocal("prod-app", "state copy"); err == nil {
+				mu.Lock()
+				unlocks = append(unlocks, unlock)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	s.Len(unlocks, 1)
+	for _, unlock := range unlocks {
+		unlock()
+	}
+	s.NoFileExists(filepath.Join(s.dir, "team", "prod-app.lock"))
+}
+
+func (s *FileLockSuite) TestKeepsLockOfOtherHost() {
+	data, _ := json.Marshal(localLock{PID: 1 << 30, Host: "elsewhere", Operation: "state copy"})
+	s.NoError(os.MkdirAll(filepath.Join(s.dir, "team"), 0770))
+	s.NoError(ioutil.WriteFile(filepath.Join(s.dir, "team", "prod-app.lock"), data, 0660))
+
+	err := DeleteTFStateResources("prod-app", "./testdata/filterConfig.json")
+	s.True(errors.As(err, &tfdrerrors.ErrWorkspaceBusy{}))
+}
+
+func TestFileLockSuite(t *testing.T) {
+	suite.Run(t, new(FileLockSuite))
+}
@@ -0,0 +1,50 @@
+//go:build !windows
+// +build !windows
+
+package api
+
+import (
+	"os"
+	"syscall"
+)
+
+// openLockFile opens the lock file at path, creating it if needed, and
+// flocks it. The kernel releases the lock when the process exits, so no
+// process has to remove a lock file to take it over.
+func openLockFile(path string) (*os.File, error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0660)
+		if err != nil {
+			return nil, err
+		}
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			f.Close()
+			if err == syscall.EWOULDBLOCK {
+				return nil, errLockHeld
+			}
+			return nil, err
+		}
+		// The previous holder removes the file before it lets go of the
+		// lock, so the file locked here may no longer be the one at path
+		opened, err := f.Stat()
+		current, statErr := os.Stat(path)
+		if err == nil && statErr == nil && os.SameFile(opened, current) {
+			return f, nil
+		}
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		if statErr != nil && !os.IsNotExist(statErr) {
+			return nil, statErr
+		}
+	}
+}
+
+// releaseLockFile removes the lock file while still holding the lock, then
+// releases it
+func releaseLockFile(f *os.File, path string) error {
+	err := os.Remove(path)
+	f.Close()
+	return err
+}
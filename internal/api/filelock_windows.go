@@ -0,0 +1,39 @@
+package api
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+const errorSharingViolation syscall.Errno = 32
+
+// openLockFile opens the lock file at path, creating it if needed, without
+// sharing write access. Windows closes the handle when the process exits,
+// so no process has to remove a lock file to take it over.
+func openLockFile(path string) (*os.File, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	h, err := syscall.CreateFile(name, syscall.GENERIC_READ|syscall.GENERIC_WRITE, syscall.FILE_SHARE_READ, nil, syscall.OPEN_ALWAYS, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if err == errorSharingViolation {
+		return nil, errLockHeld
+	}
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(h), path), nil
+}
+
+// releaseLockFile closes the lock file, then removes it unless another
+// process has opened it in the meantime
+func releaseLockFile(f *os.File, path string) error {
+	f.Close()
+	err := os.Remove(path)
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) && pathErr.Err == errorSharingViolation {
+		return nil
+	}
+	return err
+}
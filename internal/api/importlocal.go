@@ -0,0 +1,84 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/models"
+	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+)
+
+// LocalState is a state file from a terraform CLI local backend and the
+// workspace to push it to
+type LocalState struct {
+	File      string
+	Workspace string
+	State     *models.State
+}
+
+// PushLocalStates uploads local state files to their workspaces, keeping
+// their serial and lineage so the terraform CLI sees the same state history.
+// Workspaces must have no state yet. A failed workspace doesn't stop the
+// others.
+func PushLocalStates(states []LocalState, createMissing bool, approvals []string) error {
+	op := startOperation("import-local", len(states))
+	names := make([]string, 0, len(states))
+	for _, s := range states {
+		names = append(names, s.Workspace)
+	}
+	if err := checkApprovals(config.GetConfig(), names, approvals, createMissing); err != nil {
+		return op.finish(err)
+	}
+
+	failed := 0
+	for _, s := range states {
+		err := pushLocalState(s, createMissing)
+		op.workspaceDone(s.Workspace, err)
+		if err != nil {
+			failed++
+			logger.Errorf("Unable to push %s to workspace %s. Error: %v", s.File, s.Workspace, err)
+			continue
+		}
+		logger.Infof("Pushed %s to workspace %s", s.File, s.Workspace)
+	}
+	if failed > 0 {
+		return op.finish(fmt.Errorf("Failed to push %d of %d local states", failed, len(states)))
+	}
+	return op.finish(nil)
+}
+
+func pushLocalState(s LocalState, createMissing bool) error {
+	unlock, err := lockLocal(s.Workspace, "import-local")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if createMissing {
+		if err := ensureWorkspace(s.Workspace, s.State.TerraformVersion); err != nil {
+			return err
+		}
+	}
+
+	current, err := pullTFState(s.Workspace)
+	if err != nil {
+		return tfdrerrors.ErrReadState{Err: err}
+	}
+	if current != nil {
+		return tfdrerrors.ErrDestinationNotEmpty{}
+	}
+
+	lock, err := acquireWorkspaceLock(s.Workspace, "tfdr: importing local state from "+s.File)
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+
+	if err := checkTerraformVersion(s.Workspace, s.State.TerraformVersion, false); err != nil {
+		return err
+	}
+	if err := lock.uploadState(s.State, noStateSerial); err != nil {
+		return tfdrerrors.ErrUnableToCreateStateVersion{Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/mupuri/go-tfdr/internal/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+type ImportLocalSuite struct {
+	suite.Suite
+}
+
+func (s *ImportLocalSuite) SetupTest() {
+	os.Setenv("TF_TEAM_TOKEN", "test")
+	os.Setenv("TF_ORG_NAME", "team")
+	config.InitConfig("")
+	logging.InitLogger()
+}
+
+func (s *ImportLocalSuite) TearDownTest() {
+	os.Unsetenv("TF_TEAM_TOKEN")
+	os.Unsetenv("TF_ORG_NAME")
+}
+
+func (s *ImportLocalSuite) TestPushLocalStates() {
+	httpmock.ActivateNonDefault(httpClient)
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/ping", httpmock.NewStringResponder(204, ""))
+
+	var uploaded tfeStateVersionCreate
+	s.NoError(testutils.SetupWksMockHTTPResponses(&testutils.TfeTestWks{
+		Name:         "legacy-network",
+		Exists:       true,
+		CsvResponder: httpmock.NewStringResponder(404, ""),
+		SvPostResponder: func(req *http.Request) (*http.Response, error) {
+			s.NoError(json.NewDecoder(req.Body).Decode(&uploaded))
+			return testutils.NewJSONResponse("legacy-network", "state-versions", "")
+		},
+	}))
+	s.NoError(testutils.SetupWksMockHTTPResponses(&testutils.TfeTestWks{
+		Name:         "legacy-app",
+		Exists:       true,
+		CsvResponder: testutils.NewResponder("legacy-app", "state-versions", "https://state"),
+	}))
+
+	network := testutils.NewState()
+	network.Serial = 42
+	err := PushLocalStates([]LocalState{
+		{File: "network/terraform.tfstate", Workspace: "legacy-network", State: network},
+		{File: "app/terraform.tfstate", Workspace: "legacy-app", State: testutils.NewState()},
+	}, false, nil)
+	s.EqualError(err, "Failed to push 1 of 2 local states")
+	s.Equal(int64(42), uploaded.Data.Attributes.Serial, "the local serial should be kept")
+	s.Equal(testutils.DefaultLineage, uploaded.Data.Attributes.Lineage, "the local lineage should be kept")
+}
+
+// tfeStateVersionCreate is the body go-tfe sends to create a state version
+type tfeStateVersionCreate struct {
+	Data struct {
+		Attributes struct {
+			Serial  int64  `json:"serial"`
+			Lineage string `json:"lineage"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+func TestImportLocalSuite(t *testing.T) {
+	suite.Run(t, new(ImportLocalSuite))
+}
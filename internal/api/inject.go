@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInjectedFailure is returned for requests failed by failure injection
+var ErrInjectedFailure = errors.New("injected failure")
+
+const injectedErrorBody = `{"errors":[{"status":"503","title":"injected failure"}]}`
+
+// injector fails a fraction of requests on purpose, so automation around
+// tfdr can be tested against an API that fails part way through
+var injector = newFaultInjector(pool)
+
+// SetFailureInjection makes a random fraction of API requests fail, half
+// with a 503 response and half with a network error, without reaching the
+// API. Zero turns failure injection off.
+func SetFailureInjection(rate float64) error {
+	if rate < 0 || rate > 1 {
+		return fmt.Errorf("Failure injection rate must be between 0 and 1, got %v", rate)
+	}
+	injector.mu.Lock()
+	defer injector.mu.Unlock()
+	injector.rate = rate
+	if rate > 0 {
+		logger.Warnf("Failing %.0f%% of API requests on purpose", rate*100)
+	}
+	return nil
+}
+
+// faultInjector is an http.RoundTripper that fails requests at the set rate
+// and passes the others to base
+type faultInjector struct {
+	base http.RoundTripper
+
+	mu   sync.Mutex
+	rate float64
+	rand *rand.Rand
+}
+
+func newFaultInjector(base http.RoundTripper) *faultInjector {
+	return &faultInjector{base: base, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+func (f *faultInjector) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	fail := f.rate > 0 && f.rand.Float64() < f.rate
+	asError := f.rand.Intn(2) == 0
+	f.mu.Unlock()
+	if !fail {
+		return f.base.RoundTrip(req)
+	}
+
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	logger.Debugf("Injecting a failure into %s %s", req.Method, sanitizeURL(req.URL))
+	if asError {
+		return nil, ErrInjectedFailure
+	}
+	return &http.Response{
+		Status:     "503 Service Unavailable",
+		StatusCode: http.StatusServiceUnavailable,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": []string{"application/vnd.api+json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(injectedErrorBody)),
+		Request:    req,
+	}, nil
+}
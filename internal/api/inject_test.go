@@ -0,0 +1,48 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFaultInjector(t *testing.T) {
+	calls := 0
+	f := newFaultInjector(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}))
+	get := func() (*http.Response, error) {
+		req, _ := http.NewRequest("GET", "https://app.terraform.io/api/v2/ping", nil)
+		return f.RoundTrip(req)
+	}
+
+	resp, err := get()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 1, calls, "requests should pass through when injection is off")
+
+	f.rate = 1
+	statuses := make(map[int]bool)
+	errs := 0
+	for i := 0; i < 50; i++ {
+		resp, err := get()
+		if err != nil {
+			assert.True(t, errors.Is(err, ErrInjectedFailure))
+			errs++
+			continue
+		}
+		statuses[resp.StatusCode] = true
+	}
+	assert.Equal(t, 1, calls, "failed requests should not reach the API")
+	assert.True(t, errs > 0, "some failures should be network errors")
+	assert.Equal(t, map[int]bool{http.StatusServiceUnavailable: true}, statuses)
+}
+
+func TestSetFailureInjectionRejectsInvalidRates(t *testing.T) {
+	assert.Error(t, SetFailureInjection(1.5))
+	assert.Error(t, SetFailureInjection(-0.1))
+	assert.NoError(t, SetFailureInjection(0))
+}
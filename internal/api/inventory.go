@@ -0,0 +1,55 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/inventory"
+	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+)
+
+// CollectInventory returns every resource instance in the current state of
+// every workspace in the organization. A workspace whose state can't be read
+// is recorded as an item with Error set, and the rest are still collected. A
+// non-zero deadline stops reading states once the time budget is nearly used
+// up, returning the items collected so far.
+func CollectInventory(deadline time.Duration) ([]inventory.Item, error) {
+	c := config.GetConfig()
+
+	client, err := newTFEClient(c.ReadToken())
+	if err != nil {
+		return nil, fmt.Errorf("Cannot create tfe client. Err: %v", err)
+	}
+
+	workspaces, err := listWorkspaces(client, c.ReadToken(), c.TerraformOrgName, WorkspaceFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	op := startOperation("inventory", len(workspaces))
+	b := newBudget(deadline)
+	items := make([]inventory.Item, 0)
+	failed := 0
+	for i, w := range workspaces {
+		if !b.allows() {
+			return items, op.finish(tfdrerrors.ErrDeadlineExceeded{Remaining: workspaceNames(workspaces[i:]), Failed: failed})
+		}
+		logger.Debugf("Reading state of workspace %s", w.Name)
+		start := time.Now()
+		state, err := pullWorkspaceState(client, c.ReadToken(), w)
+		b.record(start)
+		op.workspaceDone(w.Name, err)
+		if err != nil {
+			failed++
+			logger.Errorf("Unable to read state of workspace %s. Error: %v", w.Name, err)
+			items = append(items, inventory.Item{Workspace: w.Name, Error: err.Error()})
+			continue
+		}
+		items = append(items, inventory.FromState(w.Name, state)...)
+	}
+	if failed > 0 {
+		return items, op.finish(fmt.Errorf("Failed to read the state of %d of %d workspaces", failed, len(workspaces)))
+	}
+	return items, op.finish(nil)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/mupuri/go-tfdr/internal/testutils"
+	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+	"github.com/stretchr/testify/suite"
+)
+
+const inventoryWorkspaceList = `{"data":[
+{"id":"test","type":"workspaces","attributes":{"name":"test"}},
+{"id":"empty","type":"workspaces","attributes":{"name":"empty"}}
+],"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":2}}}`
+
+type InventorySuite struct {
+	suite.Suite
+}
+
+func (s *InventorySuite) SetupTest() {
+	os.Setenv("TF_TEAM_TOKEN", "test")
+	os.Setenv("TF_ORG_NAME", "team")
+	config.InitConfig("")
+	logging.InitLogger()
+	httpmock.ActivateNonDefault(httpClient)
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/ping", httpmock.NewStringResponder(204, ""))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces", httpmock.NewStringResponder(200, inventoryWorkspaceList))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/workspaces/empty/current-state-version", httpmock.NewStringResponder(404, ""))
+}
+
+func (s *InventorySuite) TearDownTest() {
+	httpmock.DeactivateAndReset()
+	os.Unsetenv("TF_TEAM_TOKEN")
+	os.Unsetenv("TF_ORG_NAME")
+}
+
+func (s *InventorySuite) TestCollectInventory() {
+	wks := testutils.TfeTestWks{
+		Name:         "test",
+		Exists:       true,
+		CsvResponder: testutils.NewResponder("test", "state-versions", "https://state"),
+		CurrentState: testutils.NewState(),
+	}
+	s.NoError(testutils.SetupWksMockHTTPResponses(&wks))
+
+	items, err := CollectInventory(0)
+	s.NoError(err)
+	instances := 0
+	for _, r := range wks.CurrentState.Resources {
+		instances += len(r.Instances)
+	}
+	s.Len(items, instances)
+	for _, i := range items {
+		s.Equal("test", i.Workspace)
+	}
+}
+
+func (s *InventorySuite) TestCollectInventoryStateError() {
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/workspaces/test/current-state-version", httpmock.NewStringResponder(500, ""))
+
+	items, err := CollectInventory(0)
+	s.EqualError(err, "Failed to read the state of 1 of 2 workspaces")
+	s.Len(items, 1)
+	s.Equal("test", items[0].Workspace)
+	s.NotEmpty(items[0].Error)
+}
+
+func (s *InventorySuite) TestCollectInventoryStopsAtDeadline() {
+	items, err := CollectInventory(time.Nanosecond)
+	s.Equal(tfdrerrors.ErrDeadlineExceeded{Remaining: []string{"test", "empty"}}, err)
+	s.Empty(items)
+}
+
+func TestInventorySuite(t *testing.T) {
+	suite.Run(t, new(InventorySuite))
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/models"
+	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+)
+
+// lockCheckInterval is how often a held workspace lock is verified while a
+// long operation runs
+var lockCheckInterval = 30 * time.Second
+
+// workspaceLock is a lock tfdr holds on a workspace for the length of an
+// operation. It is checked in the background, and verify fails once the lock
+// has been force-unlocked or taken over by someone else, so the operation can
+// stop before writing state.
+type workspaceLock struct {
+	client    *tfe.Client
+	token     string
+	workspace *tfe.Workspace
+	holder    string
+	// suppressed is set while runs are suppressed on the workspace
+	suppressed *runSuppression
+
+	stop chan struct{}
+	done chan struct{}
+	mu   sync.Mutex
+	lost error
+}
+
+type workspaceLockResponse struct {
+	Data struct {
+		Attributes struct {
+			Locked bool `json:"locked"`
+		} `json:"attributes"`
+		Relationships struct {
+			LockedBy struct {
+				Data *struct {
+					ID   string `json:"id"`
+					Type string `json:"type"`
+				} `json:"data"`
+			} `json:"locked-by"`
+		} `json:"relationships"`
+	} `json:"data"`
+}
+
+// acquireWorkspaceLock locks the named workspace for writing
+func acquireWorkspaceLock(workspaceName string, reason string) (*workspaceLock, error) {
+	c := config.GetConfig()
+
+	client, err := newTFEClient(c.WriteToken())
+	if err != nil {
+		return nil, fmt.Errorf("Cannot create tfe client. Err: %v", err)
+	}
+
+	workspace, err := client.Workspaces.Read(context.Background(), c.TerraformOrgName, workspaceName)
+	if err != nil {
+		return nil, workspaceError(err)
+	}
+
+	return lockWorkspace(client, c.WriteToken(), workspace, reason)
+}
+
+func lockWorkspace(client *tfe.Client, token string, workspace *tfe.Workspace, reason string) (*workspaceLock, error) {
+	if onBehalfOf != "" {
+		reason += " on behalf of " + onBehalfOf
+	}
+	if _, err := client.Workspaces.Lock(context.Background(), workspace.ID, tfe.WorkspaceLockOptions{Reason: &reason}); err != nil {
+		if errors.Is(err, tfe.ErrWorkspaceLocked) {
+			return nil, tfdrerrors.ErrWorkspaceLocked{Workspace: workspace.Name}
+		}
+		return nil, fmt.Errorf("Unable to lock workspace %s. Error: %v", workspace.Name, err)
+	}
+
+	l := &workspaceLock{
+		client:    client,
+		token:     token,
+		workspace: workspace,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	// Older TFE releases don't report who holds a lock, in which case only
+	// force-unlocks can be detected
+	if _, holder, err := lockState(token, workspace.ID); err == nil {
+		l.holder = holder
+	}
+	logger.Debugf("Locked workspace %s", workspace.Name)
+
+	go l.watch()
+	return l, nil
+}
+
+// lockState returns whether a workspace is locked and the id of the lock holder
+func lockState(token string, workspaceID string) (bool, string, error) {
+	resp, err := doAPIRequest("GET", "workspaces/"+workspaceID, token, nil)
+	if err != nil {
+		return false, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, "", fmt.Errorf("Unexpected status reading workspace: %s", resp.Status)
+	}
+
+	var ws workspaceLockResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ws); err != nil {
+		return false, "", err
+	}
+	holder := ""
+	if by := ws.Data.Relationships.LockedBy.Data; by != nil {
+		holder = by.Type + "/" + by.ID
+	}
+	return ws.Data.Attributes.Locked, holder, nil
+}
+
+// check returns an error when the lock is no longer held by tfdr
+func (l *workspaceLock) check() error {
+	locked, holder, err := lockState(l.token, l.workspace.ID)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		return tfdrerrors.ErrWorkspaceLockLost{Workspace: l.workspace.Name, Reason: "workspace was unlocked"}
+	}
+	if l.holder != "" && holder != l.holder {
+		return tfdrerrors.ErrWorkspaceLockLost{Workspace: l.workspace.Name, Reason: "locked by " + holder}
+	}
+	return nil
+}
+
+func (l *workspaceLock) watch() {
+	defer close(l.done)
+	ticker := time.NewTicker(lockCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			err := l.check()
+			var lost tfdrerrors.ErrWorkspaceLockLost
+			if errors.As(err, &lost) {
+				logger.Errorf("%v", err)
+				l.mu.Lock()
+				l.lost = err
+				l.mu.Unlock()
+				return
+			}
+			if err != nil {
+				logger.Debugf("Unable to check lock on workspace %s. Error: %v", l.workspace.Name, err)
+			}
+		}
+	}
+}
+
+// verify confirms the lock is still held. Call it right before writing.
+func (l *workspaceLock) verify() error {
+	l.mu.Lock()
+	lost := l.lost
+	l.mu.Unlock()
+	if lost != nil {
+		return lost
+	}
+	return l.check()
+}
+
+// uploadState writes a new state version, provided the lock is still held and
+// nobody wrote state since baseSerial was read
+func (l *workspaceLock) uploadState(state *models.State, baseSerial int64) error {
+	if err := l.verify(); err != nil {
+		return err
+	}
+	if err := checkStateBase(l.client, l.workspace, baseSerial); err != nil {
+		return err
+	}
+	return uploadStateVersion(l.client, l.workspace.ID, state)
+}
+
+// release stops checking the lock and unlocks the workspace, unless the lock
+// was lost and now belongs to someone else. Runs queued while runs were
+// suppressed are discarded before unlocking, and auto-apply is restored
+// after.
+func (l *workspaceLock) release() {
+	close(l.stop)
+	<-l.done
+	if l.suppressed != nil {
+		defer l.restoreAutoApply()
+	}
+	if l.lost != nil {
+		return
+	}
+	if l.suppressed != nil {
+		l.discardQueuedRuns()
+	}
+	if _, err := l.client.Workspaces.Unlock(context.Background(), l.workspace.ID); err != nil {
+		logger.Warnf("Unable to unlock workspace %s. Error: %v", l.workspace.Name, err)
+		return
+	}
+	logger.Debugf("Unlocked workspace %s", l.workspace.Name)
+}
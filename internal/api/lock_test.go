@@ -0,0 +1,115 @@
+package api
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/mupuri/go-tfdr/internal/testutils"
+	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+	"github.com/stretchr/testify/suite"
+)
+
+type LockSuite struct {
+	suite.Suite
+}
+
+func (s *LockSuite) SetupTest() {
+	os.Setenv("TF_TEAM_TOKEN", "test")
+	os.Setenv("TF_ORG_NAME", "team")
+	config.InitConfig("")
+	logging.InitLogger()
+	httpmock.ActivateNonDefault(httpClient)
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/ping", httpmock.NewStringResponder(204, ""))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces/test", testutils.NewResponder("test", "workspaces", ""))
+	testutils.RegisterLockResponders("test")
+}
+
+func (s *LockSuite) TearDownTest() {
+	httpmock.DeactivateAndReset()
+	os.Unsetenv("TF_TEAM_TOKEN")
+	os.Unsetenv("TF_ORG_NAME")
+}
+
+func (s *LockSuite) TestLockedBySomeoneElse() {
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test/actions/lock", httpmock.NewStringResponder(409, ""))
+
+	_, err := acquireWorkspaceLock("test", "test")
+	var locked tfdrerrors.ErrWorkspaceLocked
+	s.True(errors.As(err, &locked), "a workspace locked by someone else should not be written")
+}
+
+func (s *LockSuite) TestLockReasonOnBehalfOf() {
+	defer SetOnBehalfOf("")
+	s.NoError(SetOnBehalfOf("jane@example.com"))
+	var body string
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test/actions/lock", func(req *http.Request) (*http.Response, error) {
+		data, _ := ioutil.ReadAll(req.Body)
+		body = string(data)
+		return testutils.NewJSONResponse("test", "workspaces", "")
+	})
+
+	l, err := acquireWorkspaceLock("test", "tfdr: restoring state")
+	s.NoError(err)
+	l.release()
+	s.Contains(body, "tfdr: restoring state on behalf of jane@example.com")
+}
+
+func (s *LockSuite) TestUploadAbortsWhenLockLost() {
+	cases := []struct {
+		name     string
+		locked   bool
+		lockedBy string
+	}{
+		{"force unlocked", false, "user-test"},
+		{"stolen", true, "user-other"},
+	}
+
+	for _, c := range cases {
+		l, err := acquireWorkspaceLock("test", "test")
+		s.NoError(err, c.name)
+
+		httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/workspaces/test", testutils.NewLockStateResponder("test", c.locked, c.lockedBy))
+		posted := false
+		httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test/state-versions", func(req *http.Request) (*http.Response, error) {
+			posted = true
+			return testutils.NewJSONResponse("test", "state-versions", "")
+		})
+
+		err = l.uploadState(testutils.NewState(), noStateSerial)
+		var lost tfdrerrors.ErrWorkspaceLockLost
+		s.True(errors.As(err, &lost), c.name)
+		s.False(posted, "%s: state should not be written without the lock", c.name)
+		l.release()
+
+		testutils.RegisterLockResponders("test")
+	}
+}
+
+func (s *LockSuite) TestWatchNoticesLostLock() {
+	defer func(d time.Duration) { lockCheckInterval = d }(lockCheckInterval)
+	lockCheckInterval = time.Millisecond
+
+	l, err := acquireWorkspaceLock("test", "test")
+	s.NoError(err)
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/workspaces/test", testutils.NewLockStateResponder("test", true, "user-other"))
+	<-l.done
+
+	unlocked := false
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test/actions/unlock", func(req *http.Request) (*http.Response, error) {
+		unlocked = true
+		return testutils.NewJSONResponse("test", "workspaces", "")
+	})
+	l.release()
+	s.False(unlocked, "a lock taken over by someone else should not be released")
+}
+
+func TestLockSuite(t *testing.T) {
+	suite.Run(t, new(LockSuite))
+}
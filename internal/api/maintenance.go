@@ -0,0 +1,101 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrMaintenance is reported with RetryScheduled events while Terraform Cloud
+// is down for maintenance
+var ErrMaintenance = errors.New("Terraform Cloud is in a maintenance window")
+
+// Requests answered with a maintenance page are retried after Retry-After,
+// or maintenanceRetryWait without one, for up to maxMaintenancePause in
+// total. Maintenance doesn't count towards the breaker's failures.
+var (
+	maintenanceRetryWait = time.Minute
+	maxMaintenancePause  = 2 * time.Hour
+)
+
+// maintenanceWait reports whether resp is a maintenance response and how
+// long to wait before trying again
+func maintenanceWait(resp *http.Response) (time.Duration, bool) {
+	if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	retryAfter := resp.Header.Get("Retry-After")
+
+	// Keep the body readable for the caller if this is an ordinary 503
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), resp.Body))
+	if retryAfter == "" && !strings.Contains(strings.ToLower(string(body)), "maintenance") {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(retryAfter); err == nil && time.Until(t) > 0 {
+		return time.Until(t), true
+	}
+	return maintenanceRetryWait, true
+}
+
+// replayable buffers a request body that can't be read again, so the
+// request can be retried after maintenance. Request bodies are small JSON
+// documents or state that is already held in memory.
+func replayable(req *http.Request) *http.Request {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return req
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	req = req.Clone(req.Context())
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), err
+	}
+	req.Body, _ = req.GetBody()
+	return req
+}
+
+// rewind returns a copy of req that can be sent again, if its body can be
+// read again
+func rewind(req *http.Request) (*http.Request, bool) {
+	retry := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return retry, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	retry.Body = body
+	return retry, true
+}
+
+// pause holds back requests for wait without counting a failure. A paused
+// probe gives up probing, so the first request after the pause probes again.
+func (b *breaker) pause(wait time.Duration, probe bool) {
+	b.mu.Lock()
+	if probe && b.probe != nil {
+		close(b.probe)
+		b.probe = nil
+	}
+	until := time.Now().Add(wait)
+	if until.After(b.openUntil) {
+		b.openUntil = until
+	}
+	b.mu.Unlock()
+
+	b.log.Warnf("%v, pausing requests until %s", ErrMaintenance, until.Format(time.Kitchen))
+	emit(Event{Type: RetryScheduled, Wait: wait, Err: ErrMaintenance})
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreakerWaitsOutMaintenance(t *testing.T) {
+	defer func(wait time.Duration, threshold int) {
+		maintenanceRetryWait, breakerThreshold = wait, threshold
+	}(maintenanceRetryWait, breakerThreshold)
+	maintenanceRetryWait = 5 * time.Millisecond
+	breakerThreshold = 1
+	defer SetEventHandler(nil)
+	var events []Event
+	SetEventHandler(func(e Event) { events = append(events, e) })
+
+	calls := 0
+	b := newBreaker(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.Equal(t, `{"data":{}}`, string(body), "the request body should be sent again")
+		if calls < 3 {
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader("<h1>Scheduled Maintenance</h1>"))}, nil
+		}
+		return &http.Response{StatusCode: http.StatusCreated, Body: http.NoBody}, nil
+	}), logging.Discard())
+
+	req, _ := http.NewRequest("POST", "https://app.terraform.io/api/v2/workspaces/ws-1/state-versions", ioutil.NopCloser(strings.NewReader(`{"data":{}}`)))
+	resp, err := b.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 0, b.failures, "maintenance should not count as failures")
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, ErrMaintenance, events[0].Err)
+	}
+}
+
+func TestMaintenanceWait(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader("upstream connect error"))}
+	_, ok := maintenanceWait(resp)
+	assert.False(t, ok, "a plain 503 is a failure, not maintenance")
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, "upstream connect error", string(body), "body should still be readable")
+
+	resp = &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": []string{"120"}}, Body: http.NoBody}
+	wait, ok := maintenanceWait(resp)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, wait)
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-tfe"
+)
+
+// copyNotifications creates the source workspace's notification
+// configurations on the destination, so run alerts keep flowing after
+// failover. URLs starting with a key of urlMap get that prefix replaced by
+// its value. Configurations the destination already has by name are kept.
+func copyNotifications(client *tfe.Client, orgName string, sourceName string, dest *tfe.Workspace, urlMap map[string]string) error {
+	source, err := client.Workspaces.Read(context.Background(), orgName, sourceName)
+	if err != nil {
+		return workspaceError(err)
+	}
+
+	configs, err := listNotifications(client, source.ID)
+	if err != nil {
+		return err
+	}
+	if len(configs) == 0 {
+		return nil
+	}
+	existing, err := listNotifications(client, dest.ID)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]bool)
+	for _, n := range existing {
+		names[n.Name] = true
+	}
+
+	for _, n := range configs {
+		if names[n.Name] {
+			logger.Debugf("Workspace %s already has notification %s", dest.Name, n.Name)
+			continue
+		}
+		destinationType := n.DestinationType
+		options := tfe.NotificationConfigurationCreateOptions{
+			DestinationType: &destinationType,
+			Enabled:         tfe.Bool(n.Enabled),
+			Name:            tfe.String(n.Name),
+			Triggers:        n.Triggers,
+			EmailAddresses:  n.EmailAddresses,
+			EmailUsers:      n.EmailUsers,
+		}
+		if n.URL != "" {
+			options.URL = tfe.String(replacePrefix(n.URL, urlMap))
+		}
+		if _, err := client.NotificationConfigurations.Create(context.Background(), dest.ID, options); err != nil {
+			return err
+		}
+		logger.Infof("Copied notification %s to workspace %s", n.Name, dest.Name)
+		// The API never returns webhook tokens, so they can't be copied
+		if n.DestinationType == tfe.NotificationDestinationTypeGeneric {
+			logger.Warnf("Notification %s on workspace %s was created without a token, set it again if the receiver verifies signatures", n.Name, dest.Name)
+		}
+	}
+	return nil
+}
+
+func listNotifications(client *tfe.Client, workspaceID string) ([]*tfe.NotificationConfiguration, error) {
+	configs := make([]*tfe.NotificationConfiguration, 0)
+	err := eachPage(func(page tfe.ListOptions) (*tfe.Pagination, error) {
+		nl, err := client.NotificationConfigurations.List(context.Background(), workspaceID, tfe.NotificationConfigurationListOptions{ListOptions: page})
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, nl.Items...)
+		return nl.Pagination, nil
+	})
+	return configs, err
+}
+
+// replacePrefix replaces the longest prefix of s found in replacements
+func replacePrefix(s string, replacements map[string]string) string {
+	prefixes := make([]string, 0, len(replacements))
+	for p := range replacements {
+		prefixes = append(prefixes, p)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return replacements[p] + strings.TrimPrefix(s, p)
+		}
+	}
+	return s
+}
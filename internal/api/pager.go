@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hashicorp/go-tfe"
+)
+
+// Every list endpoint is read through eachPage. go-tfe already waits out rate
+// limiting (429), eachPage additionally retries pages that fail with server
+// or network errors so one bad page doesn't abort a walk of the organization.
+var (
+	listPageSize    = 100
+	pageRetries     = 3
+	pageRetryWait   = time.Second
+	errStopPaging   = errors.New("stop paging")
+	unretriedErrors = []error{tfe.ErrUnauthorized, tfe.ErrResourceNotFound, context.Canceled, context.DeadlineExceeded}
+)
+
+// eachPage calls list with the options for every page in turn, starting at
+// the first page, until list reports there are no more pages. list returns
+// the pagination of the page it read; it can return errStopPaging to end
+// the walk early without an error.
+func eachPage(list func(options tfe.ListOptions) (*tfe.Pagination, error)) error {
+	options := tfe.ListOptions{PageNumber: 1, PageSize: listPageSize}
+	for {
+		var pagination *tfe.Pagination
+		err := withPageRetry(func() error {
+			var err error
+			pagination, err = list(options)
+			return err
+		})
+		if err == errStopPaging {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if pagination == nil || pagination.NextPage == 0 {
+			return nil
+		}
+		options.PageNumber = pagination.NextPage
+	}
+}
+
+// ErrStopPaging ends EachPage early without an error
+var ErrStopPaging = errStopPaging
+
+// EachPage is eachPage for pkg/client, which exposes it to embedders
+func EachPage(list func(options tfe.ListOptions) (*tfe.Pagination, error)) error {
+	return eachPage(list)
+}
+
+func withPageRetry(f func() error) error {
+	var err error
+	for attempt := 0; attempt <= pageRetries; attempt++ {
+		if attempt > 0 {
+			logger.Debugf("Retrying page (attempt %d). Error: %v", attempt, err)
+			wait := pageRetryWait * time.Duration(attempt)
+			emit(Event{Type: RetryScheduled, Attempt: attempt, Wait: wait, Err: err})
+			time.Sleep(wait)
+		}
+		if err = f(); err == nil || !retryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func retryable(err error) bool {
+	if err == errStopPaging {
+		return false
+	}
+	for _, e := range unretriedErrors {
+		if errors.Is(err, e) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/stretchr/testify/assert"
+)
+
+func fastPageRetry() func() {
+	wait := pageRetryWait
+	pageRetryWait = time.Millisecond
+	return func() { pageRetryWait = wait }
+}
+
+func TestEachPage(t *testing.T) {
+	pages := make([]int, 0)
+	err := eachPage(func(options tfe.ListOptions) (*tfe.Pagination, error) {
+		assert.Equal(t, listPageSize, options.PageSize)
+		pages = append(pages, options.PageNumber)
+		next := options.PageNumber + 1
+		if next > 3 {
+			next = 0
+		}
+		return &tfe.Pagination{CurrentPage: options.PageNumber, NextPage: next, TotalPages: 3}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, pages)
+}
+
+func TestEachPageRetriesFailedPage(t *testing.T) {
+	defer fastPageRetry()()
+
+	calls := 0
+	err := eachPage(func(options tfe.ListOptions) (*tfe.Pagination, error) {
+		calls++
+		if calls < 3 {
+			return nil, errors.New("502 bad gateway")
+		}
+		return &tfe.Pagination{}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestEachPageGivesUp(t *testing.T) {
+	defer fastPageRetry()()
+
+	calls := 0
+	err := eachPage(func(options tfe.ListOptions) (*tfe.Pagination, error) {
+		calls++
+		return nil, errors.New("502 bad gateway")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, pageRetries+1, calls)
+}
+
+func TestEachPageDoesNotRetryUnauthorized(t *testing.T) {
+	calls := 0
+	err := eachPage(func(options tfe.ListOptions) (*tfe.Pagination, error) {
+		calls++
+		return nil, tfe.ErrUnauthorized
+	})
+	assert.True(t, errors.Is(err, tfe.ErrUnauthorized))
+	assert.Equal(t, 1, calls)
+}
+
+func TestEachPageStop(t *testing.T) {
+	calls := 0
+	err := eachPage(func(options tfe.ListOptions) (*tfe.Pagination, error) {
+		calls++
+		return nil, errStopPaging
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/config"
+)
+
+// APIURL returns the base URL API requests are sent to
+func APIURL() string {
+	return apiBaseURL
+}
+
+// Ping calls the API ping endpoint and returns the server's clock, taken from
+// the response Date header
+func Ping() (time.Time, error) {
+	resp, err := doAPIRequest("GET", "ping", config.GetConfig().ReadToken(), nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return time.Time{}, fmt.Errorf("Unexpected status from ping: %s", resp.Status)
+	}
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid Date header from ping. Error: %v", err)
+	}
+	return date, nil
+}
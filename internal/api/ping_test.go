@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPing(t *testing.T) {
+	os.Setenv("TF_TEAM_TOKEN", "test")
+	defer os.Unsetenv("TF_TEAM_TOKEN")
+	config.InitConfig("")
+	httpmock.ActivateNonDefault(httpClient)
+	defer httpmock.DeactivateAndReset()
+
+	serverTime := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/ping", func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(204, "")
+		resp.Header.Set("Date", serverTime.Format(http.TimeFormat))
+		return resp, nil
+	})
+
+	date, err := Ping()
+	assert.NoError(t, err)
+	assert.True(t, serverTime.Equal(date))
+
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/ping", httpmock.NewStringResponder(503, ""))
+	_, err = Ping()
+	assert.Error(t, err)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/config"
+)
+
+// Connection pool defaults. net/http keeps 2 idle connections per host,
+// fewer than the parallel chunk downloads of a single state, so bulk
+// operations kept opening connections and reusing ones the server had
+// already closed.
+const (
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 16
+	defaultIdleConnTimeout     = 90 * time.Second
+)
+
+// pool is the transport every API request is sent over, shared by all
+// goroutines
+var pool = newPool(config.HTTPTransport{})
+
+func newPool(s config.HTTPTransport) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConns = defaultMaxIdleConns
+	t.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	t.IdleConnTimeout = defaultIdleConnTimeout
+	if s.MaxIdleConns > 0 {
+		t.MaxIdleConns = s.MaxIdleConns
+	}
+	if s.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = s.MaxIdleConnsPerHost
+	}
+	t.MaxConnsPerHost = s.MaxConnsPerHost
+	if s.DisableHTTP2 {
+		// A non-nil empty map turns HTTP/2 off
+		t.ForceAttemptHTTP2 = false
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return t
+}
+
+// ConfigureTransport applies the connection pool settings to the API
+// client. Like SetLogger, it must be called before any API calls are made.
+func ConfigureTransport(s config.HTTPTransport) error {
+	if s.MaxIdleConns < 0 || s.MaxIdleConnsPerHost < 0 || s.MaxConnsPerHost < 0 {
+		return fmt.Errorf("Invalid http settings, connection limits can't be negative")
+	}
+	old := pool
+	pool = newPool(s)
+	injector.base = pool
+	old.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPool(t *testing.T) {
+	p := newPool(config.HTTPTransport{})
+	assert.Equal(t, defaultMaxIdleConns, p.MaxIdleConns)
+	assert.Equal(t, defaultMaxIdleConnsPerHost, p.MaxIdleConnsPerHost)
+	assert.True(t, p.MaxIdleConnsPerHost >= downloadConcurrency, "parallel downloads should keep their connections")
+	assert.True(t, p.ForceAttemptHTTP2)
+
+	p = newPool(config.HTTPTransport{MaxIdleConns: 10, MaxIdleConnsPerHost: 5, MaxConnsPerHost: 8, DisableHTTP2: true})
+	assert.Equal(t, 10, p.MaxIdleConns)
+	assert.Equal(t, 5, p.MaxIdleConnsPerHost)
+	assert.Equal(t, 8, p.MaxConnsPerHost)
+	assert.False(t, p.ForceAttemptHTTP2)
+	assert.NotNil(t, p.TLSNextProto)
+	assert.Empty(t, p.TLSNextProto)
+}
+
+func TestConfigureTransport(t *testing.T) {
+	defer func(p *http.Transport) {
+		pool = p
+		injector.base = p
+	}(pool)
+
+	assert.Error(t, ConfigureTransport(config.HTTPTransport{MaxConnsPerHost: -1}))
+	assert.NoError(t, ConfigureTransport(config.HTTPTransport{MaxIdleConnsPerHost: 32}))
+	assert.Equal(t, 32, pool.MaxIdleConnsPerHost)
+	assert.Equal(t, pool, injector.base, "requests should be sent over the new pool")
+}
+
+func TestPoolReusesConnections(t *testing.T) {
+	var mu sync.Mutex
+	conns := 0
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	p := newPool(config.HTTPTransport{})
+	defer p.CloseIdleConnections()
+	client := &http.Client{Transport: p}
+	for round := 0; round < 3; round++ {
+		var wg sync.WaitGroup
+		for i := 0; i < downloadConcurrency*2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := client.Get(server.URL)
+				if assert.NoError(t, err) {
+					resp.Body.Close()
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.True(t, conns <= downloadConcurrency*2, "idle connections should be reused, opened %d", conns)
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+)
+
+// PruneStateVersions deletes all but the newest keep state versions of a workspace
+func PruneStateVersions(workspaceName string, keep int, dryRun bool) error {
+	if keep < 1 {
+		return fmt.Errorf("At least one state version must be kept")
+	}
+	if !dryRun {
+		unlock, err := lockLocal(workspaceName, "state prune")
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	c := config.GetConfig()
+
+	client, err := newTFEClient(c.ReadToken())
+	if err != nil {
+		return fmt.Errorf("Cannot create tfe client. Err: %v", err)
+	}
+
+	versions, err := listStateVersions(client, c.TerraformOrgName, workspaceName)
+	if err != nil {
+		return tfdrerrors.ErrUnableToListStateVersions{Err: err}
+	}
+	if len(versions) <= keep {
+		logger.Infof("Workspace %s has %d state versions, nothing to prune", workspaceName, len(versions))
+		return nil
+	}
+
+	for _, sv := range versions[keep:] {
+		if dryRun {
+			logger.Infof("Would delete state version %s (serial %d)", sv.ID, sv.Serial)
+			continue
+		}
+		if err := deleteStateVersion(c.WriteToken(), sv.ID); err != nil {
+			return err
+		}
+		logger.Infof("Deleted state version %s (serial %d)", sv.ID, sv.Serial)
+	}
+	return nil
+}
+
+// listStateVersions returns every state version of a workspace, newest first
+func listStateVersions(client *tfe.Client, orgName string, workspaceName string) ([]*tfe.StateVersion, error) {
+	versions := make([]*tfe.StateVersion, 0)
+	options := tfe.StateVersionListOptions{
+		Organization: &orgName,
+		Workspace:    &workspaceName,
+	}
+	err := eachPage(func(page tfe.ListOptions) (*tfe.Pagination, error) {
+		options.ListOptions = page
+		svl, err := client.StateVersions.List(context.Background(), options)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, svl.Items...)
+		return svl.Pagination, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].Serial > versions[j].Serial
+	})
+	return versions, nil
+}
+
+func deleteStateVersion(token string, id string) error {
+	resp, err := doAPIRequest("DELETE", "state-versions/"+id, token, nil)
+	if err != nil {
+		return fmt.Errorf("Unable to delete state version %s. Error: %v", id, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return tfdrerrors.ErrStateVersionDeleteUnsupported{}
+	default:
+		return fmt.Errorf("Unable to delete state version %s. Status: %s", id, resp.Status)
+	}
+}
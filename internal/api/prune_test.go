@@ -0,0 +1,75 @@
+package api
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/mupuri/go-tfdr/internal/testutils"
+	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+	"github.com/stretchr/testify/suite"
+)
+
+type PruneSuite struct {
+	suite.Suite
+}
+
+func (s *PruneSuite) SetupTest() {
+	os.Setenv("TF_TEAM_TOKEN", "test")
+	os.Setenv("TF_ORG_NAME", "team")
+	config.InitConfig("")
+	logging.InitLogger()
+	httpmock.ActivateNonDefault(httpClient)
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/ping", httpmock.NewStringResponder(204, ""))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/state-versions", testutils.NewStateVersionListResponder([]int64{3, 5, 1, 4, 2}))
+}
+
+func (s *PruneSuite) TearDownTest() {
+	httpmock.DeactivateAndReset()
+	os.Unsetenv("TF_TEAM_TOKEN")
+	os.Unsetenv("TF_ORG_NAME")
+}
+
+func (s *PruneSuite) TestPruneStateVersions() {
+	for _, id := range []string{"sv-1", "sv-2"} {
+		httpmock.RegisterResponder("DELETE", "https://app.terraform.io/api/v2/state-versions/"+id, httpmock.NewStringResponder(204, ""))
+	}
+
+	err := PruneStateVersions("test", 3, false)
+	s.NoError(err)
+
+	info := httpmock.GetCallCountInfo()
+	s.Equal(1, info["DELETE https://app.terraform.io/api/v2/state-versions/sv-1"])
+	s.Equal(1, info["DELETE https://app.terraform.io/api/v2/state-versions/sv-2"])
+}
+
+func (s *PruneSuite) TestPruneStateVersionsDryRun() {
+	err := PruneStateVersions("test", 1, true)
+	s.NoError(err)
+	s.Equal(0, httpmock.GetCallCountInfo()["DELETE https://app.terraform.io/api/v2/state-versions/sv-1"])
+}
+
+func (s *PruneSuite) TestPruneStateVersionsNothingToPrune() {
+	err := PruneStateVersions("test", 50, false)
+	s.NoError(err)
+}
+
+func (s *PruneSuite) TestPruneStateVersionsUnsupported() {
+	httpmock.RegisterResponder("DELETE", "https://app.terraform.io/api/v2/state-versions/sv-1", httpmock.NewStringResponder(404, ""))
+
+	err := PruneStateVersions("test", 4, false)
+	s.Error(err)
+	s.True(errors.Is(err, tfdrerrors.ErrStateVersionDeleteUnsupported{}))
+}
+
+func (s *PruneSuite) TestPruneStateVersionsInvalidKeep() {
+	err := PruneStateVersions("test", 0, false)
+	s.Error(err)
+}
+
+func TestPruneSuite(t *testing.T) {
+	suite.Run(t, new(PruneSuite))
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/go-tfe"
+)
+
+type workspaceSharingResponse struct {
+	Data struct {
+		Attributes struct {
+			GlobalRemoteState bool `json:"global-remote-state"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+type consumerList struct {
+	Data []relationshipData `json:"data"`
+	Meta struct {
+		Pagination *tfe.Pagination `json:"pagination"`
+	} `json:"meta"`
+}
+
+type consumerRequest struct {
+	Data []relationshipData `json:"data"`
+}
+
+// copyStateSharing gives the destination workspace the remote state sharing
+// settings of the source, so workspaces that read the source's outputs with
+// terraform_remote_state can read the restored workspace too. go-tfe does
+// not know about remote state sharing, so the API is called directly.
+func copyStateSharing(client *tfe.Client, token string, orgName string, sourceName string, dest *tfe.Workspace) error {
+	source, err := client.Workspaces.Read(context.Background(), orgName, sourceName)
+	if err != nil {
+		return workspaceError(err)
+	}
+
+	resp, err := doAPIRequest("GET", "workspaces/"+source.ID, token, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected status reading workspace %s: %s", sourceName, resp.Status)
+	}
+	var ws workspaceSharingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ws); err != nil {
+		return err
+	}
+
+	if ws.Data.Attributes.GlobalRemoteState {
+		body := workspaceUpdateRequest{Data: workspaceUpdateData{
+			Type:       "workspaces",
+			Attributes: map[string]interface{}{"global-remote-state": true},
+		}}
+		if err := sendSharingRequest("PATCH", "workspaces/"+dest.ID, token, body); err != nil {
+			return err
+		}
+		logger.Infof("Shared state of workspace %s with the whole organization, like %s", dest.Name, sourceName)
+		return nil
+	}
+
+	consumers, err := listStateConsumers(token, source.ID)
+	if err != nil {
+		return err
+	}
+	if len(consumers) == 0 {
+		return nil
+	}
+	if err := sendSharingRequest("POST", fmt.Sprintf("workspaces/%s/relationships/remote-state-consumers", dest.ID), token, consumerRequest{Data: consumers}); err != nil {
+		return err
+	}
+	logger.Infof("Shared state of workspace %s with the %d workspaces %s shares with", dest.Name, len(consumers), sourceName)
+	return nil
+}
+
+func listStateConsumers(token string, workspaceID string) ([]relationshipData, error) {
+	consumers := make([]relationshipData, 0)
+	err := eachPage(func(page tfe.ListOptions) (*tfe.Pagination, error) {
+		resp, err := doAPIRequest("GET", fmt.Sprintf("workspaces/%s/relationships/remote-state-consumers?page%%5Bnumber%%5D=%d&page%%5Bsize%%5D=%d", workspaceID, page.PageNumber, page.PageSize), token, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("Unexpected status listing remote state consumers: %s", resp.Status)
+		}
+		var list consumerList
+		if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+			return nil, err
+		}
+		for _, c := range list.Data {
+			consumers = append(consumers, relationshipData{Type: "workspaces", ID: c.ID})
+		}
+		return list.Meta.Pagination, nil
+	})
+	return consumers, err
+}
+
+func sendSharingRequest(method string, path string, token string, body interface{}) error {
+	resp, err := doAPIRequest(method, path, token, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("Unable to update remote state sharing. Status: %s", resp.Status)
+	}
+	return nil
+}
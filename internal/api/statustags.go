@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/config"
+)
+
+// Prefixes of the tags that record a workspace's last restore. Tag names
+// can't contain '=', so the source is separated by a colon.
+const (
+	restoredTagPrefix = "tfdr:restored-"
+	sourceTagPrefix   = "tfdr:source:"
+)
+
+type workspaceTagsRelationship struct {
+	Data []workspaceTag `json:"data"`
+}
+
+type workspaceTag struct {
+	Type       string                  `json:"type"`
+	ID         string                  `json:"id,omitempty"`
+	Attributes *workspaceTagAttributes `json:"attributes,omitempty"`
+}
+
+type workspaceTagAttributes struct {
+	Name string `json:"name"`
+}
+
+// statusTags returns the tags recording a restore from source on day
+func statusTags(source string, day time.Time) []string {
+	return []string{
+		restoredTagPrefix + day.UTC().Format("2006-01-02"),
+		sourceTagPrefix + tagName(source),
+	}
+}
+
+// tagName turns a workspace name into a valid tag name, which is lower case
+// letters, digits, hyphens, underscores and colons
+func tagName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', '0' <= r && r <= '9', r == '-', r == '_', r == ':':
+			return r
+		case 'A' <= r && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '-'
+		}
+	}, s)
+}
+
+// tagStatus tags the locked workspace with the date of the restore and its
+// source, replacing the tags of an earlier restore, so the provenance of the
+// state shows in the Terraform Cloud UI. go-tfe does not know about tags.
+func (l *workspaceLock) tagStatus(source string, now time.Time) error {
+	c := config.GetConfig()
+	if !supports(c.ReadToken(), c.TerraformOrgName, CapabilityTags) {
+		logger.Warnf("Not tagging workspace %s with its restore status", l.workspace.Name)
+		return nil
+	}
+	path := "workspaces/" + l.workspace.ID + "/relationships/tags"
+	tags := statusTags(source, now)
+
+	resp, err := doAPIRequest("GET", path, l.token, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected status reading workspace tags: %s", resp.Status)
+	}
+	var current workspaceTagsRelationship
+	if err := json.NewDecoder(resp.Body).Decode(&current); err != nil {
+		return err
+	}
+	stale := workspaceTagsRelationship{Data: []workspaceTag{}}
+	for _, t := range current.Data {
+		if t.Attributes == nil || hasTag(tags, t.Attributes.Name) {
+			continue
+		}
+		if strings.HasPrefix(t.Attributes.Name, restoredTagPrefix) || strings.HasPrefix(t.Attributes.Name, sourceTagPrefix) {
+			stale.Data = append(stale.Data, workspaceTag{Type: "tags", ID: t.ID})
+		}
+	}
+	if len(stale.Data) > 0 {
+		if err := l.updateTags("DELETE", path, stale); err != nil {
+			return err
+		}
+	}
+
+	add := workspaceTagsRelationship{Data: make([]workspaceTag, 0, len(tags))}
+	for _, name := range tags {
+		add.Data = append(add.Data, workspaceTag{Type: "tags", Attributes: &workspaceTagAttributes{Name: name}})
+	}
+	if err := l.updateTags("POST", path, add); err != nil {
+		return err
+	}
+	logger.Infof("Tagged workspace %s with %s", l.workspace.Name, strings.Join(tags, ", "))
+	return nil
+}
+
+func (l *workspaceLock) updateTags(method string, path string, body workspaceTagsRelationship) error {
+	resp, err := doAPIRequest(method, path, l.token, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected status updating workspace tags: %s", resp.Status)
+	}
+	return nil
+}
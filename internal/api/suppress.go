@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/go-tfe"
+)
+
+type workspaceUpdateRequest struct {
+	Data workspaceUpdateData `json:"data"`
+}
+
+type workspaceUpdateData struct {
+	Type       string                 `json:"type"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// runSuppression records what suppressRuns changed on a locked workspace
+type runSuppression struct {
+	autoApply bool
+	// before are the runs that were already queued, which are left alone
+	before map[string]bool
+}
+
+// suppressRuns keeps runs from starting on the locked workspace until the
+// lock is released. Runs queued while it is locked, e.g. by VCS pushes,
+// wait for the lock; release discards them before unlocking and turns
+// auto-apply back on only after that.
+func (l *workspaceLock) suppressRuns() error {
+	before, err := l.pendingRuns()
+	if err != nil {
+		return fmt.Errorf("Unable to list runs on workspace %s. Error: %v", l.workspace.Name, err)
+	}
+	if err := l.setAutoApply(false); err != nil {
+		return fmt.Errorf("Unable to suppress runs on workspace %s. Error: %v", l.workspace.Name, err)
+	}
+	l.suppressed = &runSuppression{autoApply: l.workspace.AutoApply, before: before}
+	logger.Infof("Suppressed auto-apply and queued runs on workspace %s", l.workspace.Name)
+	return nil
+}
+
+// pendingRuns returns the ids of the runs waiting to start. Runs are listed
+// newest first, and waiting runs are never more than a page.
+func (l *workspaceLock) pendingRuns() (map[string]bool, error) {
+	runs, err := l.client.Runs.List(context.Background(), l.workspace.ID, tfe.RunListOptions{
+		ListOptions: tfe.ListOptions{PageSize: listPageSize},
+	})
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[string]bool)
+	for _, r := range runs.Items {
+		if r.Status == tfe.RunPending {
+			pending[r.ID] = true
+		}
+	}
+	return pending, nil
+}
+
+// discardQueuedRuns discards the runs queued since runs were suppressed.
+// Call it while the workspace is still locked.
+func (l *workspaceLock) discardQueuedRuns() {
+	pending, err := l.pendingRuns()
+	if err != nil {
+		logger.Errorf("Unable to list runs on workspace %s, discard the runs queued during the copy by hand. Error: %v", l.workspace.Name, err)
+		return
+	}
+	comment := "tfdr: queued while state was being restored"
+	for id := range pending {
+		if l.suppressed.before[id] {
+			continue
+		}
+		if err := l.client.Runs.Discard(context.Background(), id, tfe.RunDiscardOptions{Comment: &comment}); err != nil {
+			logger.Errorf("Unable to discard run %s on workspace %s, discard it by hand. Error: %v", id, l.workspace.Name, err)
+			continue
+		}
+		logger.Infof("Discarded run %s queued on workspace %s during the copy", id, l.workspace.Name)
+	}
+}
+
+// restoreAutoApply puts back the auto-apply setting runs were suppressed from
+func (l *workspaceLock) restoreAutoApply() {
+	if err := l.setAutoApply(l.suppressed.autoApply); err != nil {
+		logger.Errorf("Unable to restore run settings on workspace %s, set them back by hand: auto-apply=%v. Error: %v",
+			l.workspace.Name, l.suppressed.autoApply, err)
+		return
+	}
+	logger.Infof("Restored run settings on workspace %s", l.workspace.Name)
+}
+
+func (l *workspaceLock) setAutoApply(autoApply bool) error {
+	_, err := l.client.Workspaces.UpdateByID(context.Background(), l.workspace.ID, tfe.WorkspaceUpdateOptions{AutoApply: tfe.Bool(autoApply)})
+	return err
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+)
+
+type tfVersion struct {
+	parts      [3]int
+	prerelease string
+}
+
+func parseTFVersion(v string) (tfVersion, bool) {
+	var version tfVersion
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		version.prerelease = v[i+1:]
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return version, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return version, false
+		}
+		version.parts[i] = n
+	}
+	return version, true
+}
+
+// compare returns -1, 0 or 1 when v is older, equal or newer than o
+func (v tfVersion) compare(o tfVersion) int {
+	for i := range v.parts {
+		if v.parts[i] != o.parts[i] {
+			if v.parts[i] < o.parts[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case v.prerelease == o.prerelease:
+		return 0
+	case v.prerelease == "":
+		return 1
+	case o.prerelease == "":
+		return -1
+	case v.prerelease < o.prerelease:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// canReadState reports whether terraform at workspaceVersion can read state
+// written by stateVersion. From 1.0 on every 1.x release reads every other
+// 1.x state; before that terraform refuses state written by a newer release.
+func canReadState(workspaceVersion tfVersion, stateVersion tfVersion) bool {
+	if workspaceVersion.parts[0] >= 1 && stateVersion.parts[0] == workspaceVersion.parts[0] {
+		return true
+	}
+	return workspaceVersion.compare(stateVersion) >= 0
+}
+
+// checkTerraformVersion makes sure the destination workspace runs a terraform
+// version that can read the copied state, updating the workspace to the
+// state's version when align is set
+func checkTerraformVersion(workspaceName string, stateVersion string, align bool) error {
+	sv, ok := parseTFVersion(stateVersion)
+	if !ok {
+		logger.Warnf("Unable to parse state terraform version %q, skipping version check", stateVersion)
+		return nil
+	}
+	c := config.GetConfig()
+
+	client, err := newTFEClient(c.ReadToken())
+	if err != nil {
+		return fmt.Errorf("Cannot create tfe client. Err: %v", err)
+	}
+
+	workspace, err := client.Workspaces.Read(context.Background(), c.TerraformOrgName, workspaceName)
+	if err != nil {
+		return workspaceError(err)
+	}
+	if workspace.TerraformVersion == "" {
+		logger.Debugf("Workspace %s has no terraform version set, skipping version check", workspaceName)
+		return nil
+	}
+	wv, ok := parseTFVersion(workspace.TerraformVersion)
+	if !ok {
+		logger.Warnf("Unable to parse workspace %s terraform version %q, skipping version check", workspaceName, workspace.TerraformVersion)
+		return nil
+	}
+
+	if canReadState(wv, sv) {
+		if wv.compare(sv) < 0 {
+			logger.Warnf("Workspace %s runs terraform %s, older than the state's %s", workspaceName, workspace.TerraformVersion, stateVersion)
+		}
+		return nil
+	}
+	if !align {
+		return tfdrerrors.ErrTerraformVersionDowngrade{WorkspaceVersion: workspace.TerraformVersion, StateVersion: stateVersion}
+	}
+
+	writeClient, err := newTFEClient(c.WriteToken())
+	if err != nil {
+		return fmt.Errorf("Cannot create tfe client. Err: %v", err)
+	}
+	_, err = writeClient.Workspaces.UpdateByID(context.Background(), workspace.ID, tfe.WorkspaceUpdateOptions{
+		TerraformVersion: tfe.String(stateVersion),
+	})
+	if err != nil {
+		return fmt.Errorf("Unable to update workspace %s terraform version. Error: %v", workspaceName, err)
+	}
+	logger.Infof("Updated workspace %s terraform version from %s to %s", workspaceName, workspace.TerraformVersion, stateVersion)
+	return nil
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+	"github.com/stretchr/testify/suite"
+)
+
+type TFVersionSuite struct {
+	suite.Suite
+}
+
+func (s *TFVersionSuite) SetupTest() {
+	os.Setenv("TF_TEAM_TOKEN", "test")
+	os.Setenv("TF_ORG_NAME", "team")
+	config.InitConfig("")
+	logging.InitLogger()
+	httpmock.ActivateNonDefault(httpClient)
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/ping", httpmock.NewStringResponder(204, ""))
+}
+
+func (s *TFVersionSuite) TearDownTest() {
+	httpmock.DeactivateAndReset()
+	os.Unsetenv("TF_TEAM_TOKEN")
+	os.Unsetenv("TF_ORG_NAME")
+}
+
+func workspaceWithVersion(version string) httpmock.Responder {
+	return httpmock.NewStringResponder(200, `{"data":{"id":"ws-1","type":"workspaces","attributes":{"name":"test","terraform-version":"`+version+`"}}}`)
+}
+
+func (s *TFVersionSuite) TestCanReadState() {
+	cases := []struct {
+		workspace string
+		state     string
+		ok        bool
+	}{
+		{"0.13.4", "0.13.4", true},
+		{"0.13.5", "0.13.4", true},
+		{"0.12.29", "0.13.4", false},
+		{"0.13.4", "0.13.5", false},
+		{"0.15.0-beta1", "0.15.0", false},
+		{"1.0.0", "1.3.7", true},
+		{"1.3.7", "0.14.11", true},
+		{"0.14.11", "1.0.0", false},
+	}
+	for _, c := range cases {
+		wv, ok := parseTFVersion(c.workspace)
+		s.True(ok, c.workspace)
+		sv, ok := parseTFVersion(c.state)
+		s.True(ok, c.state)
+		s.Equal(c.ok, canReadState(wv, sv), "workspace %s, state %s", c.workspace, c.state)
+	}
+
+	_, ok := parseTFVersion("latest")
+	s.False(ok)
+}
+
+func (s *TFVersionSuite) TestCheckTerraformVersionDowngrade() {
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces/test", workspaceWithVersion("0.12.29"))
+
+	err := checkTerraformVersion("test", "0.13.4", false)
+	s.True(errors.Is(err, tfdrerrors.ErrTerraformVersionDowngrade{WorkspaceVersion: "0.12.29", StateVersion: "0.13.4"}))
+}
+
+func (s *TFVersionSuite) TestCheckTerraformVersionAlign() {
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces/test", workspaceWithVersion("0.12.29"))
+	httpmock.RegisterResponder("PATCH", "https://app.terraform.io/api/v2/workspaces/ws-1", func(req *http.Request) (*http.Response, error) {
+		var body struct {
+			Data struct {
+				Attributes map[string]interface{} `json:"attributes"`
+			} `json:"data"`
+		}
+		s.NoError(json.NewDecoder(req.Body).Decode(&body))
+		s.Equal("0.13.4", body.Data.Attributes["terraform-version"])
+		return workspaceWithVersion("0.13.4")(req)
+	})
+
+	s.NoError(checkTerraformVersion("test", "0.13.4", true))
+	s.Equal(1, httpmock.GetCallCountInfo()["PATCH https://app.terraform.io/api/v2/workspaces/ws-1"])
+}
+
+func (s *TFVersionSuite) TestCheckTerraformVersionCompatible() {
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces/test", workspaceWithVersion("1.5.0"))
+
+	s.NoError(checkTerraformVersion("test", "1.6.2", false))
+	s.NoError(checkTerraformVersion("test", "unknown", false))
+}
+
+func TestTFVersionSuite(t *testing.T) {
+	suite.Run(t, new(TFVersionSuite))
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/mupuri/go-tfdr/internal/logging"
+)
+
+// userAgent is sent with every API request
+var userAgent = "tfdr/devbuild"
+
+// onBehalfOf is the person the operations of this process are attributed to
+// when they run with a shared automation token. It is sent with every API
+// request in onBehalfOfHeader.
+var onBehalfOf string
+
+const onBehalfOfHeader = "X-TFDR-On-Behalf-Of"
+
+// traceFile receives full request and response bodies when tracing is enabled
+var (
+	traceFile io.Writer
+	traceMu   sync.Mutex
+)
+
+var rateLimitHeaders = []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"}
+
+// logger receives all log output of the api package
+var logger logging.Logger = operationLogger{logging.Default()}
+
+// SetLogger replaces the logger used by the api package, including the
+// transport and breaker of the shared HTTP client. The operations are package
+// functions without a constructor to pass it to, so it must be called before
+// any API calls are made.
+func SetLogger(l logging.Logger) {
+	if _, ok := l.(operationLogger); !ok {
+		l = operationLogger{l}
+	}
+	logger = l
+	if t, ok := httpClient.Transport.(*transport); ok {
+		t.log = l
+		if b, ok := t.base.(*breaker); ok {
+			b.log = l
+		}
+	}
+}
+
+// SetVersion sets the tfdr version reported in the User-Agent header
+func SetVersion(version string) {
+	userAgent = "tfdr/" + version
+}
+
+// SetOnBehalfOf attributes the operations of this process to identity, e.g.
+// the operator who started a CI job. It is added to api log lines, events and
+// workspace lock reasons, and sent to Terraform Cloud in the
+// X-TFDR-On-Behalf-Of header for proxies and TFE installations that log it.
+func SetOnBehalfOf(identity string) error {
+	for _, r := range identity {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("Invalid on behalf of identity %q, it can't contain control characters", identity)
+		}
+	}
+	onBehalfOf = identity
+	return nil
+}
+
+// EnableHTTPTrace appends the bodies of every API request and response to
+// fileName. Bodies include state contents, so the file must be handled like a
+// state file. Token attributes are redacted.
+func EnableHTTPTrace(fileName string) error {
+	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("Unable to open http trace file. Error: %v", err)
+	}
+	traceFile = f
+	return nil
+}
+
+// transport tags every request with the tfdr User-Agent and a unique
+// X-Request-ID so failed calls can be matched up with TFE server logs
+type transport struct {
+	base http.RoundTripper
+	log  logging.Logger
+}
+
+func newTransport(base http.RoundTripper, log logging.Logger) *transport {
+	return &transport{base: base, log: log}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	requestID := newUUID()
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("X-Request-ID", requestID)
+	if onBehalfOf != "" {
+		req.Header.Set(onBehalfOfHeader, onBehalfOf)
+	}
+
+	log := t.log.WithFields(logging.Fields{"request_id": requestID, "method": req.Method, "url": sanitizeURL(req.URL)})
+	tracing := log.TraceEnabled()
+
+	var reqBody []byte
+	if tracing && traceFile != nil && req.Body != nil {
+		reqBody, _ = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	duration := time.Since(start)
+	if err != nil {
+		recordAPICall(req.Method, req.URL, 0)
+		log.WithFields(logging.Fields{"duration": duration}).Debugf("API request failed. Error: %v", err)
+		return nil, err
+	}
+	recordAPICall(req.Method, req.URL, resp.StatusCode)
+	fields := logging.Fields{"status": resp.StatusCode}
+
+	if !tracing {
+		log.WithFields(fields).Debugf("API request")
+		return resp, nil
+	}
+
+	fields["duration"] = duration
+	for _, h := range rateLimitHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			fields[strings.ToLower(h)] = v
+		}
+	}
+	log.WithFields(fields).Tracef("API request")
+
+	if traceFile != nil {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+		writeTrace(requestID, req, reqBody, resp, respBody)
+	}
+	return resp, nil
+}
+
+func writeTrace(requestID string, req *http.Request, reqBody []byte, resp *http.Response, respBody []byte) {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	fmt.Fprintf(traceFile, "=== %s %s %s %s\n", time.Now().UTC().Format(time.RFC3339), requestID, req.Method, sanitizeURL(req.URL))
+	if len(reqBody) > 0 {
+		fmt.Fprintf(traceFile, "--- request body\n%s\n", redactTokens(reqBody))
+	}
+	fmt.Fprintf(traceFile, "--- response %d\n%s\n", resp.StatusCode, redactTokens(respBody))
+}
+
+// redactTokens hides the values of token attributes in a JSON body, such as
+// the new token in the response of a team token rotation. Other bodies are
+// returned as they are.
+func redactTokens(body []byte) []byte {
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil || !redactTokenValues(v) {
+		return body
+	}
+	redacted, err := json.Marshal(v)
+	if err != nil {
+		return []byte("REDACTED")
+	}
+	return redacted
+}
+
+// redactTokenValues replaces the values of token keys in v, reporting
+// whether it found any
+func redactTokenValues(v interface{}) bool {
+	found := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if strings.EqualFold(k, "token") {
+				v[k] = "REDACTED"
+				found = true
+				continue
+			}
+			found = redactTokenValues(child) || found
+		}
+	case []interface{}:
+		for _, child := range v {
+			found = redactTokenValues(child) || found
+		}
+	}
+	return found
+}
+
+// sanitizeURL hides query values and signed object paths outside the API,
+// which can grant access to state without a token
+func sanitizeURL(u *url.URL) string {
+	if strings.HasPrefix(u.String(), apiBaseURL) {
+		return u.String()
+	}
+	s := *u
+	if i := strings.Index(s.Path, "/object/"); i >= 0 {
+		s.Path = s.Path[:i] + "/object/REDACTED"
+		s.RawPath = ""
+	}
+	if s.RawQuery != "" {
+		q := s.Query()
+		for k := range q {
+			q.Set(k, "REDACTED")
+		}
+		s.RawQuery = q.Encode()
+	}
+	return s.String()
+}
+
+// newUUID returns a random RFC 4122 version 4 UUID
+func newUUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTransportHeaders(t *testing.T) {
+	defer SetVersion("devbuild")
+	SetVersion("1.2.3")
+
+	ids := make(map[string]bool)
+	tr := newTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "tfdr/1.2.3", req.Header.Get("User-Agent"))
+		id := req.Header.Get("X-Request-ID")
+		assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), id)
+		ids[id] = true
+		return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+	}), logging.Discard())
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "https://app.terraform.io/api/v2/ping", nil)
+		req.Header.Set("User-Agent", "go-tfe")
+		_, err := tr.RoundTrip(req)
+		assert.NoError(t, err)
+		assert.Equal(t, "go-tfe", req.Header.Get("User-Agent"), "caller's request should not be modified")
+	}
+	assert.Equal(t, 3, len(ids), "every request should get its own id")
+}
+
+func TestTransportTrace(t *testing.T) {
+	defer func() { traceFile = nil }()
+	var logs, bodies bytes.Buffer
+	l := logrus.New()
+	l.SetLevel(logrus.TraceLevel)
+	l.SetOutput(&logs)
+	traceFile = &bodies
+
+	tr := newTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.Equal(t, `{"state":"abc"}`, string(body), "transport should pass the request body on")
+		resp := &http.Response{StatusCode: 201, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(`{"data":{}}`))}
+		resp.Header.Set("X-RateLimit-Remaining", "29")
+		return resp, nil
+	}), logging.FromLogrus(l))
+
+	req, _ := http.NewRequest("POST", "https://archivist.terraform.io/v1/object/secret-blob?sig=secret", strings.NewReader(`{"state":"abc"}`))
+	req.Header.Set("Authorization", "Bearer super-secret-token")
+	resp, err := tr.RoundTrip(req)
+	assert.NoError(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, `{"data":{}}`, string(body), "caller should still be able to read the response body")
+
+	assert.Contains(t, logs.String(), "x-ratelimit-remaining=29")
+	assert.Contains(t, logs.String(), "status=201")
+	assert.Contains(t, bodies.String(), `{"state":"abc"}`)
+	for _, out := range []string{logs.String(), bodies.String()} {
+		assert.NotContains(t, out, "super-secret-token")
+		assert.NotContains(t, out, "secret-blob")
+		assert.NotContains(t, out, "sig=secret")
+	}
+}
+
+func TestTransportTraceRedactsTokens(t *testing.T) {
+	defer func() { traceFile = nil }()
+	var bodies bytes.Buffer
+	l := logrus.New()
+	l.SetLevel(logrus.TraceLevel)
+	l.SetOutput(ioutil.Discard)
+	traceFile = &bodies
+
+	// The response of a team token rotation
+	tr := newTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"data":{"id":"at-1","type":"authentication-tokens","attributes":{"created-at":"2024-06-01T00:00:00Z","token":"new-secret-token"}}}`
+		return &http.Response{StatusCode: 201, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+	}), logging.FromLogrus(l))
+
+	req, _ := http.NewRequest("POST", "https://app.terraform.io/api/v2/teams/team-1/authentication-token", nil)
+	resp, err := tr.RoundTrip(req)
+	assert.NoError(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Contains(t, string(body), "new-secret-token", "the caller should get the token")
+
+	assert.NotContains(t, bodies.String(), "new-secret-token")
+	assert.Contains(t, bodies.String(), `"token":"REDACTED"`)
+	assert.Contains(t, bodies.String(), `"created-at":"2024-06-01T00:00:00Z"`)
+}
+
+func TestRedactTokens(t *testing.T) {
+	assert.Equal(t, `{"serial":12345678901234567890}`, string(redactTokens([]byte(`{"serial":12345678901234567890}`))), "bodies without tokens should be kept as they are")
+	assert.Equal(t, "not json token", string(redactTokens([]byte("not json token"))))
+	assert.Equal(t, `[{"Token":"REDACTED"}]`, string(redactTokens([]byte(`[{"Token":"x"}]`))))
+}
+
+func TestSetLogger(t *testing.T) {
+	defer SetLogger(logger)
+	var logs bytes.Buffer
+	l := logrus.New()
+	l.SetLevel(logrus.DebugLevel)
+	l.SetOutput(&logs)
+	SetLogger(logging.FromLogrus(l))
+
+	req, _ := http.NewRequest("GET", "http://127.0.0.1:0/ping", nil)
+	_, err := httpClient.Do(req)
+	assert.Error(t, err)
+	assert.Contains(t, logs.String(), "API request failed")
+	assert.Equal(t, logger, httpClient.Transport.(*transport).base.(*breaker).log)
+}
+
+func TestOnBehalfOf(t *testing.T) {
+	defer SetOnBehalfOf("")
+	assert.Error(t, SetOnBehalfOf("jane\r\nX-Injected: 1"))
+	assert.NoError(t, SetOnBehalfOf("jane@example.com"))
+
+	var logs bytes.Buffer
+	l := logrus.New()
+	l.SetLevel(logrus.DebugLevel)
+	l.SetOutput(&logs)
+	tr := newTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "jane@example.com", req.Header.Get("X-TFDR-On-Behalf-Of"))
+		return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+	}), operationLogger{logging.FromLogrus(l)})
+
+	req, _ := http.NewRequest("GET", "https://app.terraform.io/api/v2/ping", nil)
+	_, err := tr.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Contains(t, logs.String(), "on_behalf_of=jane@example.com")
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/mupuri/go-tfdr/internal/config"
+)
+
+// RunTrigger is an edge in the run trigger graph: a successful apply in
+// Source queues a run in Workspace
+type RunTrigger struct {
+	Source    string `json:"source"`
+	Workspace string `json:"workspace"`
+}
+
+// ExportRunTriggers returns the run triggers of the workspaces that match
+// the filter, so the pipeline between them can be recreated after failover
+func ExportRunTriggers(filter WorkspaceFilter) ([]RunTrigger, error) {
+	c := config.GetConfig()
+
+	client, err := newTFEClient(c.ReadToken())
+	if err != nil {
+		return nil, fmt.Errorf("Cannot create tfe client. Err: %v", err)
+	}
+
+	workspaces, err := listWorkspaces(client, c.ReadToken(), c.TerraformOrgName, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	op := startOperation("run trigger export", len(workspaces))
+	triggers := make([]RunTrigger, 0)
+	for _, w := range workspaces {
+		inbound, err := inboundRunTriggers(client, w.ID)
+		op.workspaceDone(w.Name, err)
+		if err != nil {
+			return nil, op.finish(fmt.Errorf("Unable to list run triggers of workspace %s. Error: %v", w.Name, err))
+		}
+		for _, t := range inbound {
+			triggers = append(triggers, RunTrigger{Source: t.SourceableName, Workspace: w.Name})
+		}
+	}
+	return triggers, op.finish(nil)
+}
+
+// RecreateRunTriggers creates the given run triggers in the configured
+// organization. Triggers that already exist are left alone, so it is safe
+// to run again after a partial failure.
+func RecreateRunTriggers(triggers []RunTrigger) error {
+	c := config.GetConfig()
+
+	client, err := newTFEClient(c.WriteToken())
+	if err != nil {
+		return fmt.Errorf("Cannot create tfe client. Err: %v", err)
+	}
+
+	op := startOperation("run trigger recreate", len(triggers))
+	workspaces := make(map[string]*tfe.Workspace)
+	read := func(name string) (*tfe.Workspace, error) {
+		if w, ok := workspaces[name]; ok {
+			return w, nil
+		}
+		w, err := client.Workspaces.Read(context.Background(), c.TerraformOrgName, name)
+		if err != nil {
+			return nil, workspaceError(err)
+		}
+		workspaces[name] = w
+		return w, nil
+	}
+	existing := make(map[string]map[string]bool)
+
+	failed := 0
+	for _, t := range triggers {
+		created, err := recreateRunTrigger(client, t, read, existing)
+		op.workspaceDone(t.Workspace, err)
+		switch {
+		case err != nil:
+			failed++
+			logger.Errorf("Unable to create run trigger from %s to %s. Error: %v", t.Source, t.Workspace, err)
+		case !created:
+			logger.Debugf("Run trigger from %s to %s already exists", t.Source, t.Workspace)
+		default:
+			logger.Infof("Created run trigger from %s to %s", t.Source, t.Workspace)
+		}
+	}
+	if failed > 0 {
+		return op.finish(fmt.Errorf("Failed to create %d of %d run triggers", failed, len(triggers)))
+	}
+	return op.finish(nil)
+}
+
+func recreateRunTrigger(client *tfe.Client, t RunTrigger, read func(string) (*tfe.Workspace, error), existing map[string]map[string]bool) (bool, error) {
+	source, err := read(t.Source)
+	if err != nil {
+		return false, err
+	}
+	dest, err := read(t.Workspace)
+	if err != nil {
+		return false, err
+	}
+
+	sources, ok := existing[dest.ID]
+	if !ok {
+		inbound, err := inboundRunTriggers(client, dest.ID)
+		if err != nil {
+			return false, err
+		}
+		sources = make(map[string]bool)
+		for _, rt := range inbound {
+			sources[rt.SourceableName] = true
+		}
+		existing[dest.ID] = sources
+	}
+	if sources[source.Name] {
+		return false, nil
+	}
+
+	_, err = client.RunTriggers.Create(context.Background(), dest.ID, tfe.RunTriggerCreateOptions{Sourceable: source})
+	if err != nil {
+		return false, err
+	}
+	sources[source.Name] = true
+	return true, nil
+}
+
+// inboundRunTriggers lists the run triggers that queue runs in a workspace
+func inboundRunTriggers(client *tfe.Client, workspaceID string) ([]*tfe.RunTrigger, error) {
+	triggers := make([]*tfe.RunTrigger, 0)
+	err := eachPage(func(page tfe.ListOptions) (*tfe.Pagination, error) {
+		rl, err := client.RunTriggers.List(context.Background(), workspaceID, tfe.RunTriggerListOptions{
+			ListOptions:    page,
+			RunTriggerType: tfe.String("inbound"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, rl.Items...)
+		return rl.Pagination, nil
+	})
+	return triggers, err
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/stretchr/testify/suite"
+)
+
+type TriggersSuite struct {
+	suite.Suite
+}
+
+func (s *TriggersSuite) SetupTest() {
+	os.Setenv("TF_TEAM_TOKEN", "test")
+	os.Setenv("TF_ORG_NAME", "team")
+	config.InitConfig("")
+	logging.InitLogger()
+	httpmock.ActivateNonDefault(httpClient)
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/ping", httpmock.NewStringResponder(204, ""))
+}
+
+func (s *TriggersSuite) TearDownTest() {
+	httpmock.DeactivateAndReset()
+	os.Unsetenv("TF_TEAM_TOKEN")
+	os.Unsetenv("TF_ORG_NAME")
+}
+
+func registerWorkspace(name string) {
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces/"+name,
+		httpmock.NewStringResponder(200, `{"data":{"id":"`+name+`","type":"workspaces","attributes":{"name":"`+name+`"}}}`))
+}
+
+func (s *TriggersSuite) TestExportRunTriggers() {
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces", httpmock.NewStringResponder(200, `{"data":[
+{"id":"ws-net","type":"workspaces","attributes":{"name":"prod-network"}},
+{"id":"ws-app","type":"workspaces","attributes":{"name":"prod-app"}}
+],"meta":{"pagination":{"current-page":1,"total-pages":1}}}`))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/workspaces/ws-net/run-triggers",
+		httpmock.NewStringResponder(200, `{"data":[],"meta":{"pagination":{"current-page":1,"total-pages":1}}}`))
+	httpmock.RegisterResponderWithQuery("GET", "https://app.terraform.io/api/v2/workspaces/ws-app/run-triggers", map[string]string{
+		"filter[run-trigger][type]": "inbound",
+		"page[number]":              "1",
+		"page[size]":                "100",
+	}, httpmock.NewStringResponder(200, `{"data":[
+{"id":"rt-1","type":"run-triggers","attributes":{"sourceable-name":"prod-network","workspace-name":"prod-app"}}
+],"meta":{"pagination":{"current-page":1,"total-pages":1}}}`))
+
+	triggers, err := ExportRunTriggers(WorkspaceFilter{Prefix: "prod-"})
+	s.NoError(err)
+	s.Equal([]RunTrigger{{Source: "prod-network", Workspace: "prod-app"}}, triggers)
+}
+
+func (s *TriggersSuite) TestRecreateRunTriggersSkipsExisting() {
+	for _, name := range []string{"dr-network", "dr-dns", "dr-app"} {
+		registerWorkspace(name)
+	}
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/workspaces/dr-app/run-triggers", httpmock.NewStringResponder(200, `{"data":[
+{"id":"rt-1","type":"run-triggers","attributes":{"sourceable-name":"dr-dns","workspace-name":"dr-app"}}
+],"meta":{"pagination":{"current-page":1,"total-pages":1}}}`))
+	var sources []string
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/dr-app/run-triggers", func(req *http.Request) (*http.Response, error) {
+		var body struct {
+			Data struct {
+				Relationships struct {
+					Sourceable struct {
+						Data struct {
+							ID string `json:"id"`
+						} `json:"data"`
+					} `json:"sourceable"`
+				} `json:"relationships"`
+			} `json:"data"`
+		}
+		s.NoError(json.NewDecoder(req.Body).Decode(&body))
+		sources = append(sources, body.Data.Relationships.Sourceable.Data.ID)
+		return httpmock.NewStringResponse(201, `{"data":{"id":"rt-2","type":"run-triggers"}}`), nil
+	})
+
+	err := RecreateRunTriggers([]RunTrigger{
+		{Source: "dr-network", Workspace: "dr-app"},
+		{Source: "dr-dns", Workspace: "dr-app"},
+	})
+	s.NoError(err)
+	s.Equal([]string{"dr-network"}, sources)
+}
+
+func (s *TriggersSuite) TestRecreateRunTriggersReportsMissingWorkspace() {
+	registerWorkspace("dr-app")
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces/dr-network", httpmock.NewStringResponder(404, ""))
+
+	err := RecreateRunTriggers([]RunTrigger{{Source: "dr-network", Workspace: "dr-app"}})
+	s.EqualError(err, "Failed to create 1 of 1 run triggers")
+}
+
+func TestTriggersSuite(t *testing.T) {
+	suite.Run(t, new(TriggersSuite))
+}
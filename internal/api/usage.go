@@ -0,0 +1,126 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// APICalls is how many requests were made to an endpoint and got a status.
+// Status is 0 for requests that got no response.
+type APICalls struct {
+	Method   string `json:"method"`
+	Endpoint string `json:"endpoint"`
+	Status   int    `json:"status"`
+	Count    int    `json:"count"`
+}
+
+type usageKey struct {
+	method   string
+	endpoint string
+	status   int
+}
+
+var (
+	usageMu sync.Mutex
+	usage   = make(map[usageKey]int)
+)
+
+// idPattern matches Terraform Cloud resource IDs such as ws-4mQo6zYBBzG1b2aF
+var idPattern = regexp.MustCompile(`^[a-z]+-[A-Za-z0-9]{12,}$`)
+
+func recordAPICall(method string, u *url.URL, status int) {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	usage[usageKey{method, endpointPattern(u), status}]++
+}
+
+// Usage returns the API requests made by this process so far, busiest
+// endpoint first
+func Usage() []APICalls {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	return usageSince(nil)
+}
+
+// usageSince returns the requests made since the snapshot. The caller holds
+// usageMu.
+func usageSince(snapshot map[usageKey]int) []APICalls {
+	calls := make([]APICalls, 0, len(usage))
+	for k, n := range usage {
+		if n -= snapshot[k]; n > 0 {
+			calls = append(calls, APICalls{Method: k.method, Endpoint: k.endpoint, Status: k.status, Count: n})
+		}
+	}
+	sort.Slice(calls, func(i, j int) bool {
+		a, b := calls[i], calls[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Endpoint != b.Endpoint {
+			return a.Endpoint < b.Endpoint
+		}
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		return a.Status < b.Status
+	})
+	return calls
+}
+
+func usageSnapshot() map[usageKey]int {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	snapshot := make(map[usageKey]int, len(usage))
+	for k, n := range usage {
+		snapshot[k] = n
+	}
+	return snapshot
+}
+
+// TotalCalls returns the number of requests in calls
+func TotalCalls(calls []APICalls) int {
+	total := 0
+	for _, c := range calls {
+		total += c.Count
+	}
+	return total
+}
+
+// FormatUsage describes calls on one line per endpoint and status
+func FormatUsage(calls []APICalls) string {
+	lines := make([]string, 0, len(calls))
+	for _, c := range calls {
+		status := "no response"
+		if c.Status != 0 {
+			status = fmt.Sprint(c.Status)
+		}
+		lines = append(lines, fmt.Sprintf("%6d  %s %s (%s)", c.Count, c.Method, c.Endpoint, status))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// endpointPattern names the endpoint of a request without the IDs and names
+// in its path, e.g. organizations/:org/workspaces/:name. Requests outside the
+// API, such as state downloads, are named after their host.
+func endpointPattern(u *url.URL) string {
+	base, err := url.Parse(apiBaseURL)
+	if err != nil || u.Host != base.Host || !strings.HasPrefix(u.Path, base.Path) {
+		return u.Host
+	}
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(u.Path, base.Path), "/"), "/")
+	for i, s := range segments {
+		switch {
+		case i > 0 && segments[i-1] == "organizations":
+			segments[i] = ":org"
+		case i > 2 && segments[i-3] == "organizations" && segments[i-1] == "workspaces":
+			segments[i] = ":name"
+		case idPattern.MatchString(s):
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
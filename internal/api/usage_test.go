@@ -0,0 +1,60 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointPattern(t *testing.T) {
+	tests := map[string]string{
+		"https://app.terraform.io/api/v2/ping":                                                  "ping",
+		"https://app.terraform.io/api/v2/organizations/team/workspaces":                         "organizations/:org/workspaces",
+		"https://app.terraform.io/api/v2/organizations/team/workspaces/prod-east?include=x":     "organizations/:org/workspaces/:name",
+		"https://app.terraform.io/api/v2/workspaces/ws-4mQo6zYBBzG1b2aF/current-state-version":  "workspaces/:id/current-state-version",
+		"https://app.terraform.io/api/v2/workspaces/ws-4mQo6zYBBzG1b2aF/actions/lock":           "workspaces/:id/actions/lock",
+		"https://archivist.terraform.io/v1/object/dmF1bHQ6djE6c3RhdGU":                          "archivist.terraform.io",
+		"https://app.terraform.io/api/v2/state-versions/sv-kq8KBKqVBjAq9xUf/relationships/file": "state-versions/:id/relationships/file",
+	}
+	for raw, want := range tests {
+		u, err := url.Parse(raw)
+		assert.NoError(t, err)
+		assert.Equal(t, want, endpointPattern(u), raw)
+	}
+}
+
+func TestUsageByOperation(t *testing.T) {
+	defer SetEventHandler(nil)
+	var finished Event
+	SetEventHandler(func(e Event) {
+		if e.Type == OperationFinished {
+			finished = e
+		}
+	})
+
+	tr := newTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == "POST" {
+			return nil, errors.New("connection reset")
+		}
+		return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+	}), logging.Discard())
+
+	op := startOperation("test", 0)
+	for _, method := range []string{"GET", "GET", "POST"} {
+		req, _ := http.NewRequest(method, "https://app.terraform.io/api/v2/organizations/team/workspaces", nil)
+		tr.RoundTrip(req)
+	}
+	op.finish(nil)
+
+	assert.Equal(t, []APICalls{
+		{Method: "GET", Endpoint: "organizations/:org/workspaces", Status: 200, Count: 2},
+		{Method: "POST", Endpoint: "organizations/:org/workspaces", Status: 0, Count: 1},
+	}, finished.APICalls)
+	assert.Equal(t, 3, TotalCalls(finished.APICalls))
+	assert.Contains(t, Usage(), APICalls{Method: "POST", Endpoint: "organizations/:org/workspaces", Status: 0, Count: 1}, "process usage should include the operation's requests")
+	assert.Equal(t, "     2  GET organizations/:org/workspaces (200)\n     1  POST organizations/:org/workspaces (no response)", FormatUsage(finished.APICalls))
+}
@@ -1,11 +1,14 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"crypto/md5"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/hashicorp/go-tfe"
@@ -14,28 +17,93 @@ import (
 	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
 )
 
-var httpClient = &http.Client{}
+var httpClient = &http.Client{Transport: newTransport(newBreaker(injector, logger), logger)}
 
-func createTFStateVersion(state *models.State, workspaceName string) error {
-	c := config.GetConfig()
+// apiBaseURL is the root every raw (non go-tfe) API request is resolved against
+var apiBaseURL = tfe.DefaultAddress + tfe.DefaultBasePath
 
-	tfeConfig := &tfe.Config{
+func newTFEClient(token string) (*tfe.Client, error) {
+	return tfe.NewClient(&tfe.Config{
 		HTTPClient: httpClient,
-		Token:      c.TerraformTeamToken,
+		Token:      token,
+	})
+}
+
+// NewTFEClient returns a go-tfe client for the TFE instance at address, or
+// Terraform Cloud when it is empty, that sends its requests through the same
+// transport as the rest of tfdr. It backs pkg/client.
+func NewTFEClient(address string, token string) (*tfe.Client, error) {
+	return tfe.NewClient(&tfe.Config{
+		Address:    address,
+		HTTPClient: httpClient,
+		Token:      token,
+	})
+}
+
+// workspaceError wraps a failed workspace lookup, calling out rejected tokens
+// separately since they need different remediation than a missing workspace
+func workspaceError(err error) error {
+	if errors.Is(err, tfe.ErrUnauthorized) {
+		return tfdrerrors.ErrTokenRejected{}
 	}
+	return tfdrerrors.ErrGetWorkspace{Err: err}
+}
 
-	client, err := tfe.NewClient(tfeConfig)
+// doAPIRequest sends a request for an endpoint or attribute go-tfe does not
+// wrap. A non-nil body is sent as JSON.
+func doAPIRequest(method string, path string, token string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, apiBaseURL+path, reader)
 	if err != nil {
-		return fmt.Errorf("Cannot create tfe client. Err: %v", err)
+		return nil, err
 	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.api+json")
+	req.Header.Set("Content-Type", "application/vnd.api+json")
 
-	workspace, err := client.Workspaces.Read(context.Background(), c.TerraformOrgName, workspaceName)
+	return httpClient.Do(req)
+}
+
+// noStateSerial is the base serial of a workspace without state
+const noStateSerial int64 = -1
+
+// createTFStateVersion uploads state, provided the workspace's current state
+// still has the base serial the new state was derived from
+func createTFStateVersion(state *models.State, workspaceName string, baseSerial int64) error {
+	l, err := acquireWorkspaceLock(workspaceName, "tfdr: writing state")
 	if err != nil {
-		return tfdrerrors.ErrGetWorkspace{Err: err}
+		return err
 	}
+	defer l.release()
+
+	return l.uploadState(state, baseSerial)
+}
 
-	client.Workspaces.Lock(context.Background(), workspace.ID, tfe.WorkspaceLockOptions{})
+// checkStateBase returns ErrStateConflict when another writer advanced the
+// workspace's state past baseSerial
+func checkStateBase(client *tfe.Client, workspace *tfe.Workspace, baseSerial int64) error {
+	current := noStateSerial
+	sv, err := client.StateVersions.Current(context.Background(), workspace.ID)
+	switch {
+	case err == nil:
+		current = sv.Serial
+	case err.Error() != tfe.ErrResourceNotFound.Error():
+		return tfdrerrors.ErrUnableToGetStateVersion{Err: err}
+	}
+	if current != baseSerial {
+		return tfdrerrors.ErrStateConflict{Workspace: workspace.Name, Expected: baseSerial, Actual: current}
+	}
+	return nil
+}
 
+func uploadStateVersion(client *tfe.Client, workspaceID string, state *models.State) error {
 	stateBytes, err := json.Marshal(state)
 	if err != nil {
 		return fmt.Errorf("Unable to unmarshal state object. Error: %v", err)
@@ -47,7 +115,7 @@ func createTFStateVersion(state *models.State, workspaceName string) error {
 
 	base64State := base64.StdEncoding.EncodeToString(stateBytes)
 
-	_, err = client.StateVersions.Create(context.Background(), workspace.ID, tfe.StateVersionCreateOptions{
+	_, err = client.StateVersions.Create(context.Background(), workspaceID, tfe.StateVersionCreateOptions{
 		MD5:     &versionMd5,
 		Serial:  &serial,
 		State:   &base64State,
@@ -56,28 +124,62 @@ func createTFStateVersion(state *models.State, workspaceName string) error {
 	if err != nil {
 		return fmt.Errorf("Unable to create new state version. Err: %v", err)
 	}
-	client.Workspaces.Unlock(context.Background(), workspace.ID)
 	return nil
 }
 
 func pullTFState(workspaceName string) (*models.State, error) {
 	c := config.GetConfig()
 
-	tfeConfig := &tfe.Config{
-		HTTPClient: httpClient,
-		Token:      c.TerraformTeamToken,
+	client, err := newTFEClient(c.ReadToken())
+	if err != nil {
+		return nil, fmt.Errorf("Cannot create tfe client. Err: %v", err)
+	}
+
+	workspace, err := client.Workspaces.Read(context.Background(), c.TerraformOrgName, workspaceName)
+	if err != nil {
+		return nil, workspaceError(err)
 	}
 
-	client, err := tfe.NewClient(tfeConfig)
+	return pullWorkspaceState(client, c.ReadToken(), workspace)
+}
+
+// CurrentStateData downloads the current state of a workspace as it was
+// uploaded, returning nil when the workspace has no state yet
+func CurrentStateData(workspaceName string) ([]byte, error) {
+	c := config.GetConfig()
+
+	client, err := newTFEClient(c.ReadToken())
 	if err != nil {
 		return nil, fmt.Errorf("Cannot create tfe client. Err: %v", err)
 	}
 
 	workspace, err := client.Workspaces.Read(context.Background(), c.TerraformOrgName, workspaceName)
 	if err != nil {
-		return nil, tfdrerrors.ErrGetWorkspace{Err: err}
+		return nil, workspaceError(err)
+	}
+
+	return pullWorkspaceStateData(client, c.ReadToken(), workspace)
+}
+
+// pullWorkspaceState downloads the current state of a workspace, returning
+// nil when the workspace has no state yet
+func pullWorkspaceState(client *tfe.Client, token string, workspace *tfe.Workspace) (*models.State, error) {
+	s, err := pullWorkspaceStateData(client, token, workspace)
+	if err != nil || s == nil {
+		return nil, err
+	}
+
+	var state models.State
+
+	err = json.Unmarshal(s, &state)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot unmarshal downloaded state json. Err: : %v", err)
 	}
 
+	return &state, nil
+}
+
+func pullWorkspaceStateData(client *tfe.Client, token string, workspace *tfe.Workspace) ([]byte, error) {
 	sv, err := client.StateVersions.Current(context.Background(), workspace.ID)
 	if err != nil {
 		if err.Error() == tfe.ErrResourceNotFound.Error() {
@@ -86,17 +188,19 @@ func pullTFState(workspaceName string) (*models.State, error) {
 		return nil, tfdrerrors.ErrUnableToGetStateVersion{Err: err}
 	}
 
-	s, err := client.StateVersions.Download(context.Background(), sv.DownloadURL)
+	s, err := downloadState(client, token, sv.DownloadURL)
 	if err != nil {
 		return nil, tfdrerrors.ErrUnableToDownloadState{Err: err}
 	}
+	return s, nil
+}
 
-	var state models.State
-
-	err = json.Unmarshal(s, &state)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot unmarshal downloaded state json. Err: : %v", err)
+func managedResources(resources []models.Resource) int {
+	n := 0
+	for _, r := range resources {
+		if r.Mode == "managed" {
+			n++
+		}
 	}
-
-	return &state, nil
+	return n
 }
@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/json"
 	"errors"
+	"net/http"
 	"os"
 	"strings"
 	"testing"
@@ -30,6 +31,7 @@ func (s *UtilSuite) SetupTest() {
 	httpmock.ActivateNonDefault(httpClient)
 	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/ping", httpmock.NewStringResponder(204, ""))
 	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces/test", testutils.NewResponder("test", "workspaces", ""))
+	testutils.RegisterLockResponders("test")
 }
 
 func (s *UtilSuite) TearDownTest() {
@@ -41,17 +43,36 @@ func (s *UtilSuite) TearDownTest() {
 func (s *UtilSuite) TestCreateTFStateVersion() {
 	state := testutils.NewState()
 	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test/state-versions", testutils.NewResponder("test", "state-versions", "https://state"))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/workspaces/test/current-state-version", httpmock.NewStringResponder(404, ""))
 
-	err := createTFStateVersion(state, "test")
+	err := createTFStateVersion(state, "test", noStateSerial)
 	s.NoError(err)
 }
 
+func (s *UtilSuite) TestCreateTFStateVersionConflict() {
+	posted := false
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test/state-versions", func(req *http.Request) (*http.Response, error) {
+		posted = true
+		return testutils.NewJSONResponse("test", "state-versions", "")
+	})
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/workspaces/test/current-state-version", testutils.NewResponder("test", "state-versions", "https://state"))
+
+	state := testutils.NewState()
+	state.Serial = 3
+	err := createTFStateVersion(state, "test", 2)
+	s.True(errors.Is(err, tfdrerrors.ErrStateConflict{Workspace: "", Expected: 2, Actual: testutils.DefaultSerial}), "unexpected error: %v", err)
+	s.False(posted, "state should not be written over another writer's changes")
+
+	err = createTFStateVersion(state, "test", noStateSerial)
+	s.Contains(err.Error(), "expected no state, found serial 1")
+}
+
 func (s *UtilSuite) TestCreateTFStateVersionNoWorkspace() {
 	state := testutils.NewState()
 	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test/state-versions", testutils.NewResponder("test", "state-versions", "https://state"))
 	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces/not-found", httpmock.NewStringResponder(404, ""))
 
-	err := createTFStateVersion(state, "not-found")
+	err := createTFStateVersion(state, "not-found", noStateSerial)
 	s.Error(err)
 	s.True(errors.Is(err, tfdrerrors.ErrGetWorkspace{
 		Err: tfe.ErrResourceNotFound,
@@ -96,6 +117,32 @@ func (s *UtilSuite) TestPullTFStateErrDownloadState() {
 	s.Nil(st)
 }
 
+func (s *UtilSuite) TestSplitTokens() {
+	os.Setenv("TF_READ_TOKEN", "read")
+	os.Setenv("TF_WRITE_TOKEN", "write")
+	defer os.Unsetenv("TF_READ_TOKEN")
+	defer os.Unsetenv("TF_WRITE_TOKEN")
+	config.InitConfig("")
+
+	authorized := func(token string, responder httpmock.Responder) httpmock.Responder {
+		return func(req *http.Request) (*http.Response, error) {
+			s.Equal("Bearer "+token, req.Header.Get("Authorization"), req.URL.String())
+			return responder(req)
+		}
+	}
+	currentState, err := json.Marshal(testutils.NewState())
+	s.NoError(err)
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/workspaces/test/current-state-version", authorized("read", testutils.NewResponder("test", "state-versions", "https://state")))
+	httpmock.RegisterResponder("GET", "https://state", authorized("read", httpmock.NewStringResponder(200, string(currentState))))
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test/state-versions", authorized("write", testutils.NewResponder("test", "state-versions", "https://state")))
+
+	st, err := pullTFState("test")
+	s.NoError(err)
+	// The conflict check right before the upload is part of the write
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/workspaces/test/current-state-version", authorized("write", testutils.NewResponder("test", "state-versions", "https://state")))
+	s.NoError(createTFStateVersion(st, "test", st.Serial))
+}
+
 func TestUtilSuite(t *testing.T) {
 	suite.Run(t, new(UtilSuite))
 }
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/mupuri/go-tfdr/internal/variables"
+)
+
+// setVariables writes resolved variables to a workspace, updating variables
+// with the same key and category and creating the others
+func setVariables(client *tfe.Client, workspace *tfe.Workspace, vars []variables.Variable) error {
+	existing := make(map[string]*tfe.Variable)
+	err := eachPage(func(page tfe.ListOptions) (*tfe.Pagination, error) {
+		vl, err := client.Variables.List(context.Background(), workspace.ID, tfe.VariableListOptions{ListOptions: page})
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vl.Items {
+			existing[string(v.Category)+"/"+v.Key] = v
+		}
+		return vl.Pagination, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, v := range vars {
+		var sensitive *bool
+		if v.Sensitive {
+			sensitive = tfe.Bool(true)
+		}
+		if current, ok := existing[v.Category+"/"+v.Key]; ok {
+			_, err = client.Variables.Update(context.Background(), workspace.ID, current.ID, tfe.VariableUpdateOptions{
+				Value:     tfe.String(v.Value),
+				HCL:       tfe.Bool(v.HCL),
+				Sensitive: sensitive,
+			})
+		} else {
+			category := tfe.CategoryType(v.Category)
+			_, err = client.Variables.Create(context.Background(), workspace.ID, tfe.VariableCreateOptions{
+				Key:       tfe.String(v.Key),
+				Value:     tfe.String(v.Value),
+				Category:  &category,
+				HCL:       tfe.Bool(v.HCL),
+				Sensitive: sensitive,
+			})
+		}
+		if err != nil {
+			return err
+		}
+		logger.Debugf("Set %s variable %s on workspace %s", v.Category, v.Key, workspace.Name)
+	}
+	logger.Infof("Set %d variables on workspace %s", len(vars), workspace.Name)
+	return nil
+}
+
+// envKeys returns the keys of the environment variables among vars
+func envKeys(vars []variables.Variable) []string {
+	keys := make([]string, 0)
+	for _, v := range vars {
+		if v.Category == string(tfe.CategoryEnv) {
+			keys = append(keys, v.Key)
+		}
+	}
+	return keys
+}
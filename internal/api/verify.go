@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/mupuri/go-tfdr/internal/config"
+)
+
+// Outcomes of a verification run
+const (
+	VerifyNoChanges = "no changes"
+	VerifyChanges   = "changes present"
+	VerifyErrored   = "errored"
+	VerifyTimedOut  = "timed out"
+)
+
+// runPollInterval is how often queued verification runs are checked
+var runPollInterval = 10 * time.Second
+
+// VerifyResult is the outcome of a plan-only run on a restored workspace.
+// A workspace converges when its plan has no changes.
+type VerifyResult struct {
+	Workspace string
+	RunID     string
+	Outcome   string
+	Err       error
+}
+
+type runCreateRequest struct {
+	Data runCreateData `json:"data"`
+}
+
+type runCreateData struct {
+	Type          string                 `json:"type"`
+	Attributes    map[string]interface{} `json:"attributes"`
+	Relationships map[string]interface{} `json:"relationships"`
+}
+
+type runCreateResponse struct {
+	Data struct {
+		ID         string `json:"id"`
+		Attributes struct {
+			PlanOnly bool `json:"plan-only"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// VerifyWorkspaces queues plan-only runs on the workspaces, or on sample of
+// them picked at random when sample is between 0 and the number of
+// workspaces, and waits up to timeout for the plans to finish
+func VerifyWorkspaces(names []string, sample int, timeout time.Duration) ([]VerifyResult, error) {
+	c := config.GetConfig()
+
+	client, err := newTFEClient(c.WriteToken())
+	if err != nil {
+		return nil, fmt.Errorf("Cannot create tfe client. Err: %v", err)
+	}
+
+	if sample > 0 && sample < len(names) {
+		r := rand.New(rand.NewSource(time.Now().UnixNano()))
+		picked := make([]string, 0, sample)
+		for _, i := range r.Perm(len(names))[:sample] {
+			picked = append(picked, names[i])
+		}
+		names = picked
+	}
+
+	op := startOperation("verify", len(names))
+	results := make([]VerifyResult, len(names))
+	pending := 0
+	for i, name := range names {
+		results[i] = VerifyResult{Workspace: name}
+		runID, err := queuePlanOnlyRun(client, c.WriteToken(), c.TerraformOrgName, name)
+		if err != nil {
+			results[i].Outcome = VerifyErrored
+			results[i].Err = err
+			op.workspaceDone(name, err)
+			continue
+		}
+		logger.Infof("Queued plan-only run %s on workspace %s", runID, name)
+		results[i].RunID = runID
+		pending++
+	}
+
+	deadline := time.Now().Add(timeout)
+	for pending > 0 {
+		for i := range results {
+			r := &results[i]
+			if r.RunID == "" || r.Outcome != "" {
+				continue
+			}
+			run, err := client.Runs.Read(context.Background(), r.RunID)
+			if err != nil {
+				logger.Debugf("Unable to read run %s. Error: %v", r.RunID, err)
+				continue
+			}
+			if r.Outcome = runOutcome(run); r.Outcome == "" {
+				continue
+			}
+			if r.Outcome == VerifyErrored {
+				r.Err = fmt.Errorf("run %s ended %s", run.ID, run.Status)
+			}
+			op.workspaceDone(r.Workspace, r.Err)
+			pending--
+		}
+		if pending == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			for i := range results {
+				if r := &results[i]; r.RunID != "" && r.Outcome == "" {
+					r.Outcome = VerifyTimedOut
+					r.Err = fmt.Errorf("run %s did not finish within %s", r.RunID, timeout)
+					op.workspaceDone(r.Workspace, r.Err)
+				}
+			}
+			break
+		}
+		time.Sleep(runPollInterval)
+	}
+	return results, op.finish(nil)
+}
+
+// runOutcome returns the outcome of a finished plan-only run, or an empty
+// string while it runs
+func runOutcome(run *tfe.Run) string {
+	switch run.Status {
+	case tfe.RunPlannedAndFinished, tfe.RunPolicySoftFailed:
+		if run.HasChanges {
+			return VerifyChanges
+		}
+		return VerifyNoChanges
+	case tfe.RunErrored, tfe.RunCanceled, tfe.RunDiscarded, "force_canceled":
+		return VerifyErrored
+	}
+	return ""
+}
+
+// queuePlanOnlyRun creates a plan-only run by hand, go-tfe can't set
+// plan-only. Releases that don't know the attribute queue a normal run, which
+// is canceled straight away so nothing is applied.
+func queuePlanOnlyRun(client *tfe.Client, token string, orgName string, workspaceName string) (string, error) {
+	workspace, err := client.Workspaces.Read(context.Background(), orgName, workspaceName)
+	if err != nil {
+		return "", workspaceError(err)
+	}
+
+	resp, err := doAPIRequest("POST", "runs", token, runCreateRequest{Data: runCreateData{
+		Type: "runs",
+		Attributes: map[string]interface{}{
+			"plan-only": true,
+			"message":   "tfdr: verifying restored state",
+		},
+		Relationships: map[string]interface{}{
+			"workspace": map[string]interface{}{
+				"data": map[string]string{"type": "workspaces", "id": workspace.ID},
+			},
+		},
+	}})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("Unexpected status creating run: %s", resp.Status)
+	}
+
+	var created runCreateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", err
+	}
+	if !created.Data.Attributes.PlanOnly {
+		if err := client.Runs.Cancel(context.Background(), created.Data.ID, tfe.RunCancelOptions{}); err != nil {
+			logger.Errorf("Unable to cancel run %s on workspace %s, cancel it by hand before it is applied. Error: %v", created.Data.ID, workspaceName, err)
+		}
+		return "", fmt.Errorf("Terraform Enterprise does not support plan-only runs")
+	}
+	return created.Data.ID, nil
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/stretchr/testify/suite"
+)
+
+type VerifySuite struct {
+	suite.Suite
+	pollInterval time.Duration
+}
+
+func (s *VerifySuite) SetupTest() {
+	os.Setenv("TF_TEAM_TOKEN", "test")
+	os.Setenv("TF_ORG_NAME", "team")
+	config.InitConfig("")
+	logging.InitLogger()
+	httpmock.ActivateNonDefault(httpClient)
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/ping", httpmock.NewStringResponder(204, ""))
+	s.pollInterval = runPollInterval
+	runPollInterval = time.Millisecond
+}
+
+func (s *VerifySuite) TearDownTest() {
+	runPollInterval = s.pollInterval
+	httpmock.DeactivateAndReset()
+	os.Unsetenv("TF_TEAM_TOKEN")
+	os.Unsetenv("TF_ORG_NAME")
+}
+
+// registerRuns answers run creation with run-<workspace id>, reporting
+// plan-only as given
+func registerRuns(s *VerifySuite, planOnly bool) {
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/runs", func(req *http.Request) (*http.Response, error) {
+		var body struct {
+			Data struct {
+				Attributes    map[string]interface{} `json:"attributes"`
+				Relationships struct {
+					Workspace struct {
+						Data struct {
+							ID string `json:"id"`
+						} `json:"data"`
+					} `json:"workspace"`
+				} `json:"relationships"`
+			} `json:"data"`
+		}
+		s.NoError(json.NewDecoder(req.Body).Decode(&body))
+		s.Equal(true, body.Data.Attributes["plan-only"])
+		id := "run-" + body.Data.Relationships.Workspace.Data.ID
+		resp, _ := json.Marshal(map[string]interface{}{"data": map[string]interface{}{
+			"id": id, "type": "runs", "attributes": map[string]interface{}{"plan-only": planOnly},
+		}})
+		return httpmock.NewStringResponse(201, string(resp)), nil
+	})
+}
+
+func runResponse(id string, status string, hasChanges bool) string {
+	resp, _ := json.Marshal(map[string]interface{}{"data": map[string]interface{}{
+		"id": id, "type": "runs", "attributes": map[string]interface{}{"status": status, "has-changes": hasChanges},
+	}})
+	return string(resp)
+}
+
+func (s *VerifySuite) TestVerifyWorkspaces() {
+	registerWorkspace("dr-network")
+	registerWorkspace("dr-app")
+	registerRuns(s, true)
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/runs/run-dr-network",
+		httpmock.NewStringResponder(200, runResponse("run-dr-network", "planned_and_finished", false)))
+	polls := 0
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/runs/run-dr-app", func(req *http.Request) (*http.Response, error) {
+		polls++
+		if polls < 3 {
+			return httpmock.NewStringResponse(200, runResponse("run-dr-app", "planning", false)), nil
+		}
+		return httpmock.NewStringResponse(200, runResponse("run-dr-app", "planned_and_finished", true)), nil
+	})
+
+	results, err := VerifyWorkspaces([]string{"dr-network", "dr-app"}, 0, time.Minute)
+	s.NoError(err)
+	s.Equal([]VerifyResult{
+		{Workspace: "dr-network", RunID: "run-dr-network", Outcome: VerifyNoChanges},
+		{Workspace: "dr-app", RunID: "run-dr-app", Outcome: VerifyChanges},
+	}, results)
+}
+
+func (s *VerifySuite) TestVerifyWorkspacesTimesOut() {
+	registerWorkspace("dr-app")
+	registerRuns(s, true)
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/runs/run-dr-app",
+		httpmock.NewStringResponder(200, runResponse("run-dr-app", "plan_queued", false)))
+
+	results, err := VerifyWorkspaces([]string{"dr-app"}, 0, 5*time.Millisecond)
+	s.NoError(err)
+	s.Equal(VerifyTimedOut, results[0].Outcome)
+}
+
+func (s *VerifySuite) TestCancelsRunWithoutPlanOnly() {
+	registerWorkspace("dr-app")
+	registerRuns(s, false)
+	canceled := false
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/runs/run-dr-app/actions/cancel", func(req *http.Request) (*http.Response, error) {
+		canceled = true
+		return httpmock.NewStringResponse(202, ""), nil
+	})
+
+	results, err := VerifyWorkspaces([]string{"dr-app", "dr-app"}, 1, time.Minute)
+	s.NoError(err)
+	s.Len(results, 1)
+	s.Equal(VerifyErrored, results[0].Outcome)
+	s.EqualError(results[0].Err, "Terraform Enterprise does not support plan-only runs")
+	s.True(canceled)
+}
+
+func TestVerifySuite(t *testing.T) {
+	suite.Run(t, new(VerifySuite))
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/mupuri/go-tfdr/internal/config"
+)
+
+type workspaceCreateRequest struct {
+	Data workspaceCreateData `json:"data"`
+}
+
+type workspaceCreateData struct {
+	Type          string                           `json:"type"`
+	Attributes    map[string]interface{}           `json:"attributes"`
+	Relationships map[string]workspaceRelationship `json:"relationships,omitempty"`
+}
+
+type workspaceRelationship struct {
+	Data relationshipData `json:"data"`
+}
+
+type relationshipData struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// newWorkspaceCreateRequest builds the create payload by hand since go-tfe
+// does not know about execution modes, tags or projects
+func newWorkspaceCreateRequest(name string, tmpl config.WorkspaceTemplate, terraformVersion string) workspaceCreateRequest {
+	attrs := map[string]interface{}{
+		"name":       name,
+		"auto-apply": tmpl.AutoApply,
+	}
+	if tmpl.TerraformVersion != "" {
+		terraformVersion = tmpl.TerraformVersion
+	}
+	if terraformVersion != "" {
+		attrs["terraform-version"] = terraformVersion
+	}
+	if tmpl.ExecutionMode != "" {
+		attrs["execution-mode"] = tmpl.ExecutionMode
+	}
+	if tmpl.AgentPoolID != "" {
+		attrs["agent-pool-id"] = tmpl.AgentPoolID
+	}
+	if len(tmpl.Tags) > 0 {
+		attrs["tag-names"] = tmpl.Tags
+	}
+	if tmpl.WorkingDirectory != "" {
+		attrs["working-directory"] = tmpl.WorkingDirectory
+	}
+	if tmpl.VCSRepo != nil {
+		repo := map[string]interface{}{
+			"identifier":     tmpl.VCSRepo.Identifier,
+			"oauth-token-id": tmpl.VCSRepo.OAuthTokenID,
+		}
+		if tmpl.VCSRepo.Branch != "" {
+			repo["branch"] = tmpl.VCSRepo.Branch
+		}
+		attrs["vcs-repo"] = repo
+	}
+
+	req := workspaceCreateRequest{
+		Data: workspaceCreateData{Type: "workspaces", Attributes: attrs},
+	}
+	if tmpl.ProjectID != "" {
+		req.Data.Relationships = map[string]workspaceRelationship{
+			"project": {Data: relationshipData{Type: "projects", ID: tmpl.ProjectID}},
+		}
+	}
+	return req
+}
+
+// ensureWorkspace creates the workspace from the configured template when it
+// does not exist yet
+func ensureWorkspace(workspaceName string, terraformVersion string) error {
+	c := config.GetConfig()
+
+	client, err := newTFEClient(c.ReadToken())
+	if err != nil {
+		return fmt.Errorf("Cannot create tfe client. Err: %v", err)
+	}
+
+	_, err = client.Workspaces.Read(context.Background(), c.TerraformOrgName, workspaceName)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, tfe.ErrResourceNotFound) {
+		return workspaceError(err)
+	}
+
+	tmpl := c.WorkspaceTemplate
+	if len(tmpl.Tags) > 0 && !supports(c.ReadToken(), c.TerraformOrgName, CapabilityTags) {
+		logger.Warnf("Creating workspace %s without the template's tags", workspaceName)
+		tmpl.Tags = nil
+	}
+	if tmpl.ProjectID != "" && !supports(c.ReadToken(), c.TerraformOrgName, CapabilityProjects) {
+		logger.Warnf("Creating workspace %s outside the template's project", workspaceName)
+		tmpl.ProjectID = ""
+	}
+	body := newWorkspaceCreateRequest(workspaceName, tmpl, terraformVersion)
+	resp, err := doAPIRequest("POST", fmt.Sprintf("organizations/%s/workspaces", c.TerraformOrgName), c.WriteToken(), body)
+	if err != nil {
+		return fmt.Errorf("Unable to create workspace %s. Error: %v", workspaceName, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Unable to create workspace %s. Status: %s %s", workspaceName, resp.Status, msg)
+	}
+
+	logger.Infof("Created workspace %s", workspaceName)
+	return nil
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/mupuri/go-tfdr/internal/testutils"
+	"github.com/stretchr/testify/suite"
+)
+
+type WorkspaceSuite struct {
+	suite.Suite
+}
+
+func (s *WorkspaceSuite) SetupTest() {
+	os.Setenv("TF_TEAM_TOKEN", "test")
+	os.Setenv("TF_ORG_NAME", "team")
+	config.InitConfig("")
+	logging.InitLogger()
+	httpmock.ActivateNonDefault(httpClient)
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/ping", httpmock.NewStringResponder(204, ""))
+}
+
+func (s *WorkspaceSuite) TearDownTest() {
+	httpmock.DeactivateAndReset()
+	os.Unsetenv("TF_TEAM_TOKEN")
+	os.Unsetenv("TF_ORG_NAME")
+}
+
+func (s *WorkspaceSuite) TestNewWorkspaceCreateRequest() {
+	req := newWorkspaceCreateRequest("dr-app", config.WorkspaceTemplate{
+		ExecutionMode: "agent",
+		AgentPoolID:   "apool-1",
+		Tags:          []string{"dr"},
+		ProjectID:     "prj-1",
+		VCSRepo:       &config.VCSRepo{Identifier: "org/app", OAuthTokenID: "ot-1"},
+	}, "0.13.4")
+
+	s.Equal("dr-app", req.Data.Attributes["name"])
+	s.Equal("0.13.4", req.Data.Attributes["terraform-version"], "state terraform version should be the default")
+	s.Equal("agent", req.Data.Attributes["execution-mode"])
+	s.Equal("apool-1", req.Data.Attributes["agent-pool-id"])
+	s.Equal([]string{"dr"}, req.Data.Attributes["tag-names"])
+	s.Equal("prj-1", req.Data.Relationships["project"].Data.ID)
+	s.Equal("org/app", req.Data.Attributes["vcs-repo"].(map[string]interface{})["identifier"])
+
+	req = newWorkspaceCreateRequest("dr-app", config.WorkspaceTemplate{TerraformVersion: "0.14.0"}, "0.13.4")
+	s.Equal("0.14.0", req.Data.Attributes["terraform-version"], "template terraform version should win")
+	s.Nil(req.Data.Relationships)
+}
+
+func (s *WorkspaceSuite) TestEnsureWorkspaceCreates() {
+	config.GetConfig().WorkspaceTemplate = config.WorkspaceTemplate{AutoApply: true}
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces/dr-app", httpmock.NewStringResponder(404, ""))
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/organizations/team/workspaces", func(req *http.Request) (*http.Response, error) {
+		var body workspaceCreateRequest
+		s.NoError(json.NewDecoder(req.Body).Decode(&body))
+		s.Equal("dr-app", body.Data.Attributes["name"])
+		s.Equal(true, body.Data.Attributes["auto-apply"])
+		return httpmock.NewStringResponse(201, `{"data":{"id":"ws-2","type":"workspaces"}}`), nil
+	})
+
+	s.NoError(ensureWorkspace("dr-app", "0.13.4"))
+	s.Equal(1, httpmock.GetCallCountInfo()["POST https://app.terraform.io/api/v2/organizations/team/workspaces"])
+}
+
+func (s *WorkspaceSuite) TestEnsureWorkspaceExists() {
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces/test", testutils.NewResponder("test", "workspaces", ""))
+
+	s.NoError(ensureWorkspace("test", "0.13.4"))
+	s.Equal(0, httpmock.GetCallCountInfo()["POST https://app.terraform.io/api/v2/organizations/team/workspaces"])
+}
+
+func (s *WorkspaceSuite) TestEnsureWorkspaceCreateFails() {
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces/dr-app", httpmock.NewStringResponder(404, ""))
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/organizations/team/workspaces", httpmock.NewStringResponder(422, `{"errors":[{"detail":"Name has already been taken"}]}`))
+
+	err := ensureWorkspace("dr-app", "0.13.4")
+	s.Error(err)
+	s.Contains(err.Error(), "Name has already been taken")
+}
+
+func TestWorkspaceSuite(t *testing.T) {
+	suite.Run(t, new(WorkspaceSuite))
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+	"github.com/svanharmelen/jsonapi"
+)
+
+// WorkspaceFilter selects workspaces by name prefix and tags. Both are
+// applied by the API, so large organizations aren't listed in full.
+type WorkspaceFilter struct {
+	Prefix string
+	// Tags the workspaces must all have
+	Tags []string
+}
+
+// ListWorkspaces returns the names of the organization's workspaces that
+// match the filter
+func ListWorkspaces(filter WorkspaceFilter) ([]string, error) {
+	c := config.GetConfig()
+
+	client, err := newTFEClient(c.ReadToken())
+	if err != nil {
+		return nil, fmt.Errorf("Cannot create tfe client. Err: %v", err)
+	}
+
+	workspaces, err := listWorkspaces(client, c.ReadToken(), c.TerraformOrgName, filter)
+	if err != nil {
+		return nil, err
+	}
+	return workspaceNames(workspaces), nil
+}
+
+func workspaceNames(workspaces []*tfe.Workspace) []string {
+	names := make([]string, 0, len(workspaces))
+	for _, w := range workspaces {
+		names = append(names, w.Name)
+	}
+	return names
+}
+
+func listWorkspaces(client *tfe.Client, token string, orgName string, filter WorkspaceFilter) ([]*tfe.Workspace, error) {
+	// Releases without tags ignore search[tags] and would list every
+	// workspace, which is dangerous for workspace delete
+	if len(filter.Tags) > 0 && !supports(token, orgName, CapabilityTags) {
+		return nil, fmt.Errorf("This TFE release does not support workspace tags, workspaces can't be selected by tag")
+	}
+	workspaces := make([]*tfe.Workspace, 0)
+	options := tfe.WorkspaceListOptions{}
+	if filter.Prefix != "" {
+		options.Search = &filter.Prefix
+	}
+	err := eachPage(func(page tfe.ListOptions) (*tfe.Pagination, error) {
+		var wl *tfe.WorkspaceList
+		var err error
+		if len(filter.Tags) > 0 {
+			wl, err = listTaggedWorkspaces(token, orgName, filter, page)
+		} else {
+			options.ListOptions = page
+			wl, err = client.Workspaces.List(context.Background(), orgName, options)
+		}
+		if err != nil {
+			return nil, err
+		}
+		// search[name] matches anywhere in the name
+		for _, w := range wl.Items {
+			if strings.HasPrefix(w.Name, filter.Prefix) {
+				workspaces = append(workspaces, w)
+			}
+		}
+		return wl.Pagination, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list workspaces. Error: %v", err)
+	}
+	return workspaces, nil
+}
+
+// listTaggedWorkspaces reads one page of workspaces with search[tags], which
+// go-tfe does not support yet
+func listTaggedWorkspaces(token string, orgName string, filter WorkspaceFilter, page tfe.ListOptions) (*tfe.WorkspaceList, error) {
+	query := url.Values{}
+	query.Set("search[tags]", strings.Join(filter.Tags, ","))
+	if filter.Prefix != "" {
+		query.Set("search[name]", filter.Prefix)
+	}
+	query.Set("page[number]", strconv.Itoa(page.PageNumber))
+	query.Set("page[size]", strconv.Itoa(page.PageSize))
+
+	resp, err := doAPIRequest("GET", fmt.Sprintf("organizations/%s/workspaces?%s", orgName, query.Encode()), token, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected status listing workspaces: %s", resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := jsonapi.UnmarshalManyPayload(bytes.NewReader(body), reflect.TypeOf(&tfe.Workspace{}))
+	if err != nil {
+		return nil, err
+	}
+	var meta struct {
+		Meta struct {
+			Pagination *tfe.Pagination `json:"pagination"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(body, &meta); err != nil {
+		return nil, err
+	}
+
+	wl := &tfe.WorkspaceList{Pagination: meta.Meta.Pagination}
+	for _, item := range items {
+		wl.Items = append(wl.Items, item.(*tfe.Workspace))
+	}
+	return wl, nil
+}
+
+// DeleteWorkspaces deletes the named workspaces. With safeDelete, workspaces
+// that still manage resources are skipped instead of deleted. A non-zero
+// deadline stops deleting once the time budget is nearly used up.
+func DeleteWorkspaces(names []string, safeDelete bool, deadline time.Duration) error {
+	c := config.GetConfig()
+
+	client, err := newTFEClient(c.WriteToken())
+	if err != nil {
+		return fmt.Errorf("Cannot create tfe client. Err: %v", err)
+	}
+
+	op := startOperation("workspace delete", len(names))
+	b := newBudget(deadline)
+	failed := 0
+	for i, name := range names {
+		if !b.allows() {
+			return op.finish(tfdrerrors.ErrDeadlineExceeded{Remaining: names[i:], Failed: failed})
+		}
+		start := time.Now()
+		deleted, err := deleteWorkspace(client, c, name, safeDelete)
+		b.record(start)
+		op.workspaceDone(name, err)
+		switch {
+		case err != nil:
+			failed++
+			logger.Errorf("Unable to delete workspace %s. Error: %v", name, err)
+		case !deleted:
+			logger.Warnf("Skipped workspace %s, it still manages resources", name)
+		default:
+			logger.Infof("Deleted workspace %s", name)
+		}
+	}
+	if failed > 0 {
+		return op.finish(fmt.Errorf("Failed to delete %d of %d workspaces", failed, len(names)))
+	}
+	return op.finish(nil)
+}
+
+func deleteWorkspace(client *tfe.Client, c *config.Configuration, name string, safeDelete bool) (bool, error) {
+	unlock, err := lockLocal(name, "workspace delete")
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	if !safeDelete {
+		return true, client.Workspaces.Delete(context.Background(), c.TerraformOrgName, name)
+	}
+
+	workspace, err := client.Workspaces.Read(context.Background(), c.TerraformOrgName, name)
+	if err != nil {
+		return false, workspaceError(err)
+	}
+
+	resp, err := doAPIRequest("POST", fmt.Sprintf("workspaces/%s/actions/safe-delete", workspace.ID), c.WriteToken(), nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+		return true, nil
+	case http.StatusConflict:
+		return false, nil
+	case http.StatusNotFound:
+		// Older TFE releases have no safe-delete action, so check the
+		// current state ourselves
+		state, err := pullTFState(name)
+		if err != nil {
+			return false, err
+		}
+		if state != nil && managedResources(state.Resources) > 0 {
+			return false, nil
+		}
+		return true, client.Workspaces.DeleteByID(context.Background(), workspace.ID)
+	default:
+		return false, fmt.Errorf("Unexpected status from safe-delete: %s", resp.Status)
+	}
+}
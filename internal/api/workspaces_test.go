@@ -0,0 +1,124 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/mupuri/go-tfdr/internal/testutils"
+	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
+	"github.com/stretchr/testify/suite"
+)
+
+const workspaceList = `{"data":[
+{"id":"ws-1","type":"workspaces","attributes":{"name":"drtest-a"}},
+{"id":"ws-2","type":"workspaces","attributes":{"name":"prod-drtest-b"}},
+{"id":"ws-3","type":"workspaces","attributes":{"name":"drtest-c"}}
+],"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":3}}}`
+
+type WorkspacesSuite struct {
+	suite.Suite
+}
+
+func (s *WorkspacesSuite) SetupTest() {
+	os.Setenv("TF_TEAM_TOKEN", "test")
+	os.Setenv("TF_ORG_NAME", "team")
+	config.InitConfig("")
+	logging.InitLogger()
+	httpmock.ActivateNonDefault(httpClient)
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/ping", httpmock.NewStringResponder(204, ""))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces", httpmock.NewStringResponder(200, workspaceList))
+	httpmock.RegisterResponder("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces/test", testutils.NewResponder("test", "workspaces", ""))
+}
+
+func (s *WorkspacesSuite) TearDownTest() {
+	httpmock.DeactivateAndReset()
+	os.Unsetenv("TF_TEAM_TOKEN")
+	os.Unsetenv("TF_ORG_NAME")
+}
+
+func (s *WorkspacesSuite) TestListWorkspacesMatchesPrefix() {
+	names, err := ListWorkspaces(WorkspaceFilter{Prefix: "drtest-"})
+	s.NoError(err)
+	s.Equal([]string{"drtest-a", "drtest-c"}, names)
+}
+
+func (s *WorkspacesSuite) TestListWorkspacesByTag() {
+	httpmock.RegisterResponderWithQuery("GET", "https://app.terraform.io/api/v2/organizations/team/workspaces", map[string]string{
+		"search[tags]": "dr,rehearsal",
+		"search[name]": "drtest-",
+		"page[number]": "1",
+		"page[size]":   "100",
+	}, httpmock.NewStringResponder(200, `{"data":[
+{"id":"ws-1","type":"workspaces","attributes":{"name":"drtest-a","tag-names":["dr","rehearsal"]}}
+],"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":1}}}`))
+
+	names, err := ListWorkspaces(WorkspaceFilter{Prefix: "drtest-", Tags: []string{"dr", "rehearsal"}})
+	s.NoError(err)
+	s.Equal([]string{"drtest-a"}, names, "tags should be filtered by the API")
+}
+
+func (s *WorkspacesSuite) TestDeleteWorkspaces() {
+	httpmock.RegisterResponder("DELETE", "https://app.terraform.io/api/v2/organizations/team/workspaces/test", httpmock.NewStringResponder(204, ""))
+
+	err := DeleteWorkspaces([]string{"test"}, false, 0)
+	s.NoError(err)
+	s.Equal(1, httpmock.GetCallCountInfo()["DELETE https://app.terraform.io/api/v2/organizations/team/workspaces/test"])
+}
+
+func (s *WorkspacesSuite) TestSafeDeleteSkipsWorkspaceWithResources() {
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test/actions/safe-delete", httpmock.NewStringResponder(409, ""))
+
+	err := DeleteWorkspaces([]string{"test"}, true, 0)
+	s.NoError(err)
+	s.Equal(1, httpmock.GetCallCountInfo()["POST https://app.terraform.io/api/v2/workspaces/test/actions/safe-delete"])
+}
+
+func (s *WorkspacesSuite) TestSafeDeleteFallsBackToStateCheck() {
+	wks := testutils.TfeTestWks{
+		Name:         "test",
+		Exists:       true,
+		CsvResponder: testutils.NewResponder("test", "state-versions", "https://state"),
+		CurrentState: testutils.NewState(),
+	}
+	s.NoError(testutils.SetupWksMockHTTPResponses(&wks))
+	httpmock.RegisterResponder("POST", "https://app.terraform.io/api/v2/workspaces/test/actions/safe-delete", httpmock.NewStringResponder(404, ""))
+
+	err := DeleteWorkspaces([]string{"test"}, true, 0)
+	s.NoError(err)
+	s.Equal(0, httpmock.GetCallCountInfo()["DELETE https://app.terraform.io/api/v2/workspaces/test"])
+}
+
+func (s *WorkspacesSuite) TestDeleteWorkspacesReportsFailures() {
+	httpmock.RegisterResponder("DELETE", "https://app.terraform.io/api/v2/organizations/team/workspaces/test", httpmock.NewStringResponder(500, ""))
+
+	err := DeleteWorkspaces([]string{"test"}, false, 0)
+	s.Error(err)
+}
+
+func (s *WorkspacesSuite) TestDeleteWorkspacesStopsAtDeadline() {
+	httpmock.RegisterResponder("DELETE", "https://app.terraform.io/api/v2/organizations/team/workspaces/test", httpmock.NewStringResponder(204, ""))
+
+	err := DeleteWorkspaces([]string{"test", "other"}, false, time.Nanosecond)
+	s.Equal(tfdrerrors.ErrDeadlineExceeded{Remaining: []string{"test", "other"}}, err)
+	s.Equal(0, httpmock.GetCallCountInfo()["DELETE https://app.terraform.io/api/v2/organizations/team/workspaces/test"])
+}
+
+func (s *WorkspacesSuite) TestDeleteWorkspacesReportsFailuresAtDeadline() {
+	httpmock.RegisterResponder("DELETE", "https://app.terraform.io/api/v2/organizations/team/workspaces/test", func(req *http.Request) (*http.Response, error) {
+		time.Sleep(100 * time.Millisecond)
+		return httpmock.NewStringResponse(404, ""), nil
+	})
+
+	err := DeleteWorkspaces([]string{"test", "other"}, false, 50*time.Millisecond)
+	s.Equal(tfdrerrors.ErrDeadlineExceeded{Remaining: []string{"other"}, Failed: 1}, err)
+	s.EqualError(err, "Deadline reached before 1 workspaces were processed: other. 1 workspaces failed before the deadline")
+}
+
+func TestWorkspacesSuite(t *testing.T) {
+	suite.Run(t, new(WorkspacesSuite))
+}
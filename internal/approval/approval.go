@@ -0,0 +1,100 @@
+// Package approval signs and verifies the approval tokens a second person
+// gives for restores to critical workspaces. Approvers sign with their own
+// ed25519 private key and tfdr checks the signature against the public keys
+// listed in the config. The api package rejects tokens from the person the
+// run is on behalf of. Whoever can edit the config can add their own key or
+// remove dual_control, so this only holds when the config is a signed remote
+// config the person running the restore can't change.
+package approval
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Approval allows restores to the listed workspaces of an organization until
+// it expires
+type Approval struct {
+	Approver     string    `json:"approver"`
+	Organization string    `json:"organization"`
+	Workspaces   []string  `json:"workspaces"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Covers reports whether the approval allows a restore to the workspace at
+// the given time
+func (a Approval) Covers(org string, workspace string, now time.Time) bool {
+	if a.Organization != org || !now.Before(a.ExpiresAt) {
+		return false
+	}
+	for _, w := range a.Workspaces {
+		if w == workspace {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateKey returns a new base64 encoded key pair
+func GenerateKey() (public string, private string, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(pub), base64.StdEncoding.EncodeToString(priv), nil
+}
+
+// Sign returns a token for the approval signed with the approver's base64
+// encoded private key
+func Sign(privateKey string, a Approval) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(privateKey))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return "", fmt.Errorf("Invalid approval private key")
+	}
+	payload, err := json.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	sig := ed25519.Sign(ed25519.PrivateKey(key), payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// Verify checks the token was signed by the approver it names, using the
+// base64 encoded public keys of approvers by name, and returns its approval
+func Verify(token string, approvers map[string]string) (Approval, error) {
+	var a Approval
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 2 {
+		return a, fmt.Errorf("Malformed approval token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return a, fmt.Errorf("Malformed approval token")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return a, fmt.Errorf("Malformed approval token")
+	}
+	if err := json.Unmarshal(payload, &a); err != nil {
+		return a, fmt.Errorf("Malformed approval token")
+	}
+
+	// Config keys are read case insensitively
+	encoded, ok := approvers[strings.ToLower(a.Approver)]
+	if !ok {
+		return a, fmt.Errorf("Approval token is from %q, who is not a configured approver", a.Approver)
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return a, fmt.Errorf("Invalid public key for approver %q", a.Approver)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), payload, sig) {
+		return a, fmt.Errorf("Approval token from %q has an invalid signature", a.Approver)
+	}
+	return a, nil
+}
@@ -0,0 +1,42 @@
+package approval
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	public, private, err := GenerateKey()
+	assert.NoError(t, err)
+	otherPublic, _, err := GenerateKey()
+	assert.NoError(t, err)
+
+	expires := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	token, err := Sign(private, Approval{Approver: "Alice", Organization: "team", Workspaces: []string{"prod-db"}, ExpiresAt: expires})
+	assert.NoError(t, err)
+
+	a, err := Verify(token, map[string]string{"alice": public})
+	assert.NoError(t, err, "approver names should match config keys case insensitively")
+	assert.True(t, a.Covers("team", "prod-db", expires.Add(-time.Minute)))
+	assert.False(t, a.Covers("team", "prod-db", expires), "expired approvals should not cover restores")
+	assert.False(t, a.Covers("team", "prod-app", expires.Add(-time.Minute)))
+	assert.False(t, a.Covers("other", "prod-db", expires.Add(-time.Minute)))
+
+	_, err = Verify(token, map[string]string{"alice": otherPublic})
+	assert.EqualError(t, err, `Approval token from "Alice" has an invalid signature`)
+
+	_, err = Verify(token, map[string]string{"bob": public})
+	assert.EqualError(t, err, `Approval token is from "Alice", who is not a configured approver`)
+
+	parts := strings.Split(token, ".")
+	forged, err := Sign(private, Approval{Approver: "Alice", Organization: "team", Workspaces: []string{"prod-db", "prod-app"}, ExpiresAt: expires})
+	assert.NoError(t, err)
+	_, err = Verify(strings.Split(forged, ".")[0]+"."+parts[1], map[string]string{"alice": public})
+	assert.Error(t, err, "a changed payload should not verify with the original signature")
+
+	_, err = Verify("not-a-token", map[string]string{"alice": public})
+	assert.EqualError(t, err, "Malformed approval token")
+}
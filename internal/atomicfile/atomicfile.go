@@ -0,0 +1,88 @@
+// Package atomicfile writes files through a temporary file in the same
+// directory that is synced and renamed over the destination, so a crash or
+// a failed write leaves either the old file or the complete new one, never a
+// half written config or state file.
+package atomicfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// File is a file being written. Its contents replace the destination when
+// Commit is called.
+type File struct {
+	*os.File
+	name      string
+	perm      os.FileMode
+	committed bool
+}
+
+// Create starts writing name, which gets permissions perm when committed
+func Create(name string, perm os.FileMode) (*File, error) {
+	f, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".tmp-")
+	if err != nil {
+		return nil, err
+	}
+	return &File{File: f, name: name, perm: perm}, nil
+}
+
+// Commit syncs the written contents to disk and renames them over the
+// destination
+func (f *File) Commit() error {
+	if f.committed {
+		return nil
+	}
+	err := f.Chmod(f.perm)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), f.name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	f.committed = true
+	syncDir(filepath.Dir(f.name))
+	return nil
+}
+
+// Abort removes the temporary file unless Commit succeeded. It is meant to be
+// deferred right after Create.
+func (f *File) Abort() {
+	if f.committed {
+		return
+	}
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// WriteFile writes data to name atomically, like ioutil.WriteFile
+func WriteFile(name string, data []byte, perm os.FileMode) error {
+	f, err := Create(name, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// syncDir makes a rename in dir durable. Not every platform can sync a
+// directory, so errors are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,64 @@
+package atomicfile
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomicfile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "config.yaml")
+	assert.NoError(t, ioutil.WriteFile(name, []byte("old"), 0644))
+
+	assert.NoError(t, WriteFile(name, []byte("new"), 0600))
+	data, err := ioutil.ReadFile(name)
+	assert.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+	info, err := os.Stat(name)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	assertOnlyFile(t, dir)
+}
+
+func TestAbortKeepsOldFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomicfile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "config.yaml")
+	assert.NoError(t, ioutil.WriteFile(name, []byte("old"), 0644))
+
+	write := func() error {
+		f, err := Create(name, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Abort()
+		if _, err := f.WriteString("half"); err != nil {
+			return err
+		}
+		return errors.New("interrupted")
+	}
+	assert.Error(t, write())
+
+	data, err := ioutil.ReadFile(name)
+	assert.NoError(t, err)
+	assert.Equal(t, "old", string(data), "a failed write should leave the old file")
+	assertOnlyFile(t, dir)
+}
+
+func TestWriteFileMissingDirectory(t *testing.T) {
+	assert.Error(t, WriteFile(filepath.Join(os.TempDir(), "tfdr-no-such-dir", "config.yaml"), nil, 0600))
+}
+
+func assertOnlyFile(t *testing.T, dir string) {
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1, "temporary files should be removed")
+}
@@ -6,14 +6,31 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 
 	"github.com/mupuri/go-tfdr/internal/config/file"
+	"github.com/mupuri/go-tfdr/internal/config/remote"
 	vpr "github.com/spf13/viper"
 	"gopkg.in/yaml.v2"
 )
 
-var configuration *Configuration
+// The active configuration is replaced as a whole on reload and never
+// modified in place, so it can be read from any goroutine without locking.
+// mu serializes loads because viper is not safe for concurrent use.
+var (
+	current   atomic.Value
+	mu        sync.Mutex
+	overrides []func(*Configuration)
+	// remoteSource is the s3:// or https:// location the config file was
+	// fetched from, if any
+	remoteSource string
+)
 
 // ErrTFTeamTokenRequired &
 var (
@@ -24,19 +41,243 @@ var (
 
 // Configuration &
 type Configuration struct {
+	// Version is the config file layout, see CurrentVersion. It is read from
+	// the file only, never from the environment.
+	Version            int    `mapstructure:"-" yaml:"version"`
 	TerraformTeamToken string `mapstructure:"tf_team_token" yaml:"tf_team_token"`
 	TerraformOrgName   string `mapstructure:"tf_org_name" yaml:"tf_org_name"`
 	LogLevel           string `mapstructure:"tf_state_copy_log_level" yaml:"tf_state_copy_log_level"`
+	// Optional least-privilege tokens. When unset the team token is used.
+	TerraformReadToken  string `mapstructure:"tf_read_token" yaml:"tf_read_token,omitempty"`
+	TerraformWriteToken string `mapstructure:"tf_write_token" yaml:"tf_write_token,omitempty"`
+	// Team token rotation settings. tf_token_created_at is maintained by
+	// `tfdr login` and `tfdr auth rotate`.
+	TerraformTeamID string `mapstructure:"tf_team_id" yaml:"tf_team_id,omitempty"`
+	TokenCreatedAt  string `mapstructure:"tf_token_created_at" yaml:"tf_token_created_at,omitempty"`
+	TokenMaxAge     string `mapstructure:"tf_token_max_age" yaml:"tf_token_max_age,omitempty"`
+	// Directory of the lock files that keep tfdr processes on this machine
+	// from working on the same workspace at once
+	LockDir string `mapstructure:"tf_lock_dir" yaml:"tf_lock_dir,omitempty"`
+	// Person operations are attributed to when tfdr runs with a shared
+	// automation token
+	OnBehalfOf string `mapstructure:"on_behalf_of" yaml:"on_behalf_of,omitempty"`
+	// Settings for workspaces created by `state copy --create-missing`
+	WorkspaceTemplate WorkspaceTemplate `mapstructure:"workspace_template" yaml:"workspace_template,omitempty"`
+	// Named sets of attributes `state copy --redact` replaces with placeholders
+	RedactionProfiles map[string]RedactionProfile `mapstructure:"redaction_profiles" yaml:"redaction_profiles,omitempty"`
+	// Where to report the outcome of long running operations
+	Notifications Notifications `mapstructure:"notifications" yaml:"notifications,omitempty"`
+	// Restores to workspaces with the dual control tag need a second
+	// person's approval
+	DualControl *DualControl `mapstructure:"dual_control" yaml:"dual_control,omitempty"`
+	// Named sequences of tfdr commands run by `tfdr runbook run`
+	Runbooks map[string]Runbook `mapstructure:"runbooks" yaml:"runbooks,omitempty"`
+	// Settings of `tfdr serve api`
+	APIServer APIServer `mapstructure:"api_server" yaml:"api_server,omitempty"`
+	// Connection pool of the HTTP client every API request is sent with
+	HTTP HTTPTransport `mapstructure:"http" yaml:"http,omitempty"`
+}
+
+// HTTPTransport tunes the connection pool of the API client. Zero values
+// keep tfdr's defaults; MaxConnsPerHost 0 means no limit.
+type HTTPTransport struct {
+	MaxIdleConns        int  `mapstructure:"max_idle_conns" yaml:"max_idle_conns,omitempty"`
+	MaxIdleConnsPerHost int  `mapstructure:"max_idle_conns_per_host" yaml:"max_idle_conns_per_host,omitempty"`
+	MaxConnsPerHost     int  `mapstructure:"max_conns_per_host" yaml:"max_conns_per_host,omitempty"`
+	DisableHTTP2        bool `mapstructure:"disable_http2" yaml:"disable_http2,omitempty"`
+}
+
+// APIServer configures the HTTP API. Clients authenticate with one of the
+// bearer tokens, which are given by client name so requests can be logged
+// with who made them.
+type APIServer struct {
+	Listen string            `mapstructure:"listen" yaml:"listen,omitempty"`
+	Tokens map[string]string `mapstructure:"tokens" yaml:"tokens"`
+	// Webhooks are the secrets incident tooling signs restore requests
+	// with, by name
+	Webhooks map[string]string `mapstructure:"webhooks" yaml:"webhooks,omitempty"`
+	Slack    *Slack            `mapstructure:"slack" yaml:"slack,omitempty"`
+}
+
+// Slack configures the /tfdr slash command. Viewers, given by Slack user
+// ID, can see the status of operations.
+type Slack struct {
+	SigningSecret string   `mapstructure:"signing_secret" yaml:"signing_secret,omitempty"`
+	Viewers       []string `mapstructure:"viewers" yaml:"viewers,omitempty"`
+}
+
+// Runbook is a sequence of tfdr commands, each given as its arguments
+// without the leading tfdr, e.g. "state copy -o prod -n dr -f filters.json".
+// Arguments are separated by whitespace and can't be quoted.
+type Runbook struct {
+	Description string   `mapstructure:"description" yaml:"description,omitempty"`
+	Steps       []string `mapstructure:"steps" yaml:"steps"`
+}
+
+// GetRunbook returns the named runbook
+func (c *Configuration) GetRunbook(name string) (Runbook, error) {
+	r, ok := c.Runbooks[strings.ToLower(name)]
+	if !ok {
+		return r, fmt.Errorf("No runbook named %q in runbooks", name)
+	}
+	if len(r.Steps) == 0 {
+		return r, fmt.Errorf("Runbook %q has no steps", name)
+	}
+	for i, step := range r.Steps {
+		args := strings.Fields(step)
+		if len(args) == 0 {
+			return r, fmt.Errorf("Step %d of runbook %q is empty", i+1, name)
+		}
+		if args[0] == "runbook" {
+			return r, fmt.Errorf("Step %d of runbook %q runs another runbook, which is not supported", i+1, name)
+		}
+	}
+	return r, nil
+}
+
+// LockDirectory returns the directory of workspace lock files
+func (c *Configuration) LockDirectory() string {
+	if c.LockDir == "" {
+		return filepath.Join(os.TempDir(), "tfdr-locks")
+	}
+	return c.LockDir
+}
+
+// DualControl lists who can approve restores to tagged workspaces, by name,
+// with their base64 encoded ed25519 public keys from `tfdr approve keygen`
+type DualControl struct {
+	Tag       string            `mapstructure:"tag" yaml:"tag,omitempty"`
+	Approvers map[string]string `mapstructure:"approvers" yaml:"approvers"`
+}
+
+// ProtectedTag returns the tag of workspaces that need approval
+func (d *DualControl) ProtectedTag() string {
+	if d.Tag == "" {
+		return "tier:critical"
+	}
+	return d.Tag
+}
+
+// Notifications &
+type Notifications struct {
+	Email *EmailNotification `mapstructure:"email" yaml:"email,omitempty"`
+}
+
+// EmailNotification configures outcome emails sent over SMTP. TLS is
+// starttls (the default), tls for implicit TLS, or none. Subject and Body
+// are text/template templates; see the notify package for their data.
+type EmailNotification struct {
+	Host     string   `mapstructure:"host" yaml:"host"`
+	Port     int      `mapstructure:"port" yaml:"port,omitempty"`
+	Username string   `mapstructure:"username" yaml:"username,omitempty"`
+	Password string   `mapstructure:"password" yaml:"password,omitempty"`
+	From     string   `mapstructure:"from" yaml:"from"`
+	To       []string `mapstructure:"to" yaml:"to"`
+	TLS      string   `mapstructure:"tls" yaml:"tls,omitempty"`
+	Subject  string   `mapstructure:"subject" yaml:"subject,omitempty"`
+	Body     string   `mapstructure:"body" yaml:"body,omitempty"`
+}
+
+// RedactionProfile lists attributes to hide when copying state to a lower
+// trust workspace. Attributes are given as <type>.<attribute>[.<nested>...],
+// with * matching any resource type.
+type RedactionProfile struct {
+	Placeholder string   `mapstructure:"placeholder" yaml:"placeholder,omitempty"`
+	Attributes  []string `mapstructure:"attributes" yaml:"attributes"`
+}
+
+// GetRedactionProfile returns the named redaction profile
+func (c *Configuration) GetRedactionProfile(name string) (RedactionProfile, error) {
+	p, ok := c.RedactionProfiles[strings.ToLower(name)]
+	if !ok {
+		return p, fmt.Errorf("No redaction profile named %q in redaction_profiles", name)
+	}
+	if p.Placeholder == "" {
+		p.Placeholder = "REDACTED"
+	}
+	return p, nil
+}
+
+// WorkspaceTemplate &
+type WorkspaceTemplate struct {
+	// TerraformVersion defaults to the version that wrote the copied state
+	TerraformVersion string   `mapstructure:"terraform_version" yaml:"terraform_version,omitempty"`
+	ExecutionMode    string   `mapstructure:"execution_mode" yaml:"execution_mode,omitempty"`
+	AgentPoolID      string   `mapstructure:"agent_pool_id" yaml:"agent_pool_id,omitempty"`
+	AutoApply        bool     `mapstructure:"auto_apply" yaml:"auto_apply,omitempty"`
+	Tags             []string `mapstructure:"tags" yaml:"tags,omitempty"`
+	ProjectID        string   `mapstructure:"project_id" yaml:"project_id,omitempty"`
+	WorkingDirectory string   `mapstructure:"working_directory" yaml:"working_directory,omitempty"`
+	VCSRepo          *VCSRepo `mapstructure:"vcs_repo" yaml:"vcs_repo,omitempty"`
+}
+
+// VCSRepo &
+type VCSRepo struct {
+	Identifier   string `mapstructure:"identifier" yaml:"identifier"`
+	Branch       string `mapstructure:"branch" yaml:"branch,omitempty"`
+	OAuthTokenID string `mapstructure:"oauth_token_id" yaml:"oauth_token_id"`
+}
+
+// ReadToken returns the token used for API calls that only read
+func (c *Configuration) ReadToken() string {
+	if len(c.TerraformReadToken) > 0 {
+		return c.TerraformReadToken
+	}
+	return c.TerraformTeamToken
 }
 
-// GetConfig &
+// WriteToken returns the token used for API calls that modify workspaces
+func (c *Configuration) WriteToken() string {
+	if len(c.TerraformWriteToken) > 0 {
+		return c.TerraformWriteToken
+	}
+	return c.TerraformTeamToken
+}
+
+// GetConfig returns the active configuration. It is shared and must not be
+// modified; use Override to change settings.
 func GetConfig() *Configuration {
-	return configuration
+	c, _ := current.Load().(*Configuration)
+	if c == nil {
+		return New()
+	}
+	return c
+}
+
+func store(c *Configuration) {
+	current.Store(c)
+}
+
+// Override changes a setting of the active configuration, for example from a
+// command line flag. Overrides are applied again whenever the configuration
+// is reloaded.
+func Override(f func(*Configuration)) {
+	mu.Lock()
+	defer mu.Unlock()
+	overrides = append(overrides, f)
+	c := *GetConfig()
+	f(&c)
+	store(&c)
 }
 
-// ValidateConfig &
+// ValidateConfig checks the configuration has everything needed to read and
+// write workspace state
 func ValidateConfig() error {
-	if len(configuration.TerraformTeamToken) == 0 {
+	configuration := GetConfig()
+	if len(configuration.ReadToken()) == 0 || len(configuration.WriteToken()) == 0 {
+		return ErrTFTeamTokenRequired
+	}
+	if len(configuration.TerraformOrgName) == 0 {
+		return ErrTFOrgNameRequired
+	}
+	return nil
+}
+
+// ValidateReadConfig checks the configuration has everything needed for read
+// only operations
+func ValidateReadConfig() error {
+	configuration := GetConfig()
+	if len(configuration.ReadToken()) == 0 {
 		return ErrTFTeamTokenRequired
 	}
 	if len(configuration.TerraformOrgName) == 0 {
@@ -53,9 +294,26 @@ func New() *Configuration {
 	return &c
 }
 
-// InitConfig &
-func InitConfig(cfgFile string) {
-	configuration = New()
+// InitConfig loads the configuration from cfgFile, or from
+// $HOME/.tfdr/config.yaml when empty, and environment variables. cfgFile can
+// be an s3:// or https:// URL, see the remote package; its signature is
+// checked when TF_CONFIG_PUBLIC_KEY is set.
+func InitConfig(cfgFile string) *Configuration {
+	mu.Lock()
+	defer mu.Unlock()
+	overrides = nil
+	remoteSource = ""
+	if remote.IsRemote(cfgFile) {
+		c, err := remote.Fetch(cfgFile, os.Getenv("TF_CONFIG_PUBLIC_KEY"))
+		if err != nil {
+			log.Fatalf("ERROR: Error reading config: %v", err)
+		}
+		if c.Err != nil {
+			log.Printf("WARNING: Using the cached copy of %s, unable to refresh it. Error: %v", cfgFile, c.Err)
+		}
+		remoteSource = cfgFile
+		cfgFile = c.Path
+	}
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
 	} else {
@@ -67,12 +325,89 @@ func InitConfig(cfgFile string) {
 	_ = viper.BindEnv("TF_TEAM_TOKEN")
 	_ = viper.BindEnv("TF_ORG_NAME")
 	_ = viper.BindEnv("TF_STATE_COPY_LOG_LEVEL")
+	_ = viper.BindEnv("TF_READ_TOKEN")
+	_ = viper.BindEnv("TF_WRITE_TOKEN")
+	_ = viper.BindEnv("TF_TEAM_ID")
+	_ = viper.BindEnv("TF_TOKEN_MAX_AGE")
+	_ = viper.BindEnv("TF_LOCK_DIR")
+	_ = viper.BindEnv("on_behalf_of", "TF_ON_BEHALF_OF")
+	_ = viper.BindEnv("notifications.email.password", "TF_SMTP_PASSWORD")
+	_ = viper.BindEnv("api_server.slack.signing_secret", "TF_SLACK_SIGNING_SECRET")
 	viper.AutomaticEnv()
 	_ = viper.ReadInConfig()
+	if err := migrateLoaded(); err != nil {
+		log.Fatalf("ERROR: Error reading config: %v", err)
+	}
 
-	if err := viper.Unmarshal(&configuration); err != nil {
+	c, err := load()
+	if err != nil {
 		log.Fatalf("ERROR: Error reading config: %v", err)
 	}
+	return c
+}
+
+// Reload reads the config file again and makes the result the active
+// configuration. The previous configuration stays active on error.
+func Reload() (*Configuration, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if remoteSource != "" {
+		c, err := remote.Fetch(remoteSource, os.Getenv("TF_CONFIG_PUBLIC_KEY"))
+		if err != nil {
+			return nil, err
+		}
+		if c.Err != nil {
+			return nil, c.Err
+		}
+	}
+	if err := viper.ReadInConfig(); err != nil {
+		if _, ok := err.(vpr.ConfigFileNotFoundError); !ok {
+			return nil, err
+		}
+	}
+	if err := migrateLoaded(); err != nil {
+		return nil, err
+	}
+	return load()
+}
+
+// load unmarshals viper's settings into a new configuration and publishes
+// it. Callers must hold mu.
+func load() (*Configuration, error) {
+	c := New()
+	if err := viper.Unmarshal(c); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(viper.ConfigFileUsed()); err == nil {
+		c.Version = CurrentVersion
+	}
+	for _, f := range overrides {
+		f(c)
+	}
+	store(c)
+	return c, nil
+}
+
+// ReloadOnHangup reloads the configuration on SIGHUP and calls onChange with
+// the result, until stop is called
+func ReloadOnHangup(onChange func(*Configuration, error)) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-signals:
+				onChange(Reload())
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
 }
 
 // GenerateConfig &
@@ -91,8 +426,26 @@ func promptConfig(r io.Reader) Configuration {
 	tfOrgName, _ := reader.ReadString('\n')
 
 	configuration := Configuration{
+		Version:            CurrentVersion,
 		TerraformTeamToken: strings.TrimSpace(tfToken),
 		TerraformOrgName:   strings.TrimSpace(tfOrgName),
 	}
 	return configuration
 }
+
+// errRemote is returned when asked to change a config file fetched from a
+// remote location, which is only a cached copy
+func errRemote() error {
+	if remoteSource == "" {
+		return nil
+	}
+	return fmt.Errorf("The config is read from %s, change it there", remoteSource)
+}
+
+// FileUsed returns the config file that was read, if any. For remote config
+// it is the cached copy.
+func FileUsed() string {
+	mu.Lock()
+	defer mu.Unlock()
+	return viper.ConfigFileUsed()
+}
@@ -10,6 +10,7 @@ import (
 	"path"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	vpr "github.com/spf13/viper"
@@ -24,7 +25,11 @@ func (s *TestSuite) SetupTest() {
 	os.Unsetenv("TF_TEAM_TOKEN")
 	os.Unsetenv("TF_ORG_NAME")
 	os.Unsetenv("TF_STATE_COPY_LOG_LEVEL")
+	os.Unsetenv("TF_READ_TOKEN")
+	os.Unsetenv("TF_WRITE_TOKEN")
 	viper = vpr.New()
+	overrides = nil
+	store(New())
 }
 
 func TestRunSuite(t *testing.T) {
@@ -45,8 +50,7 @@ func (s *TestSuite) TestValidateConfig() {
 	}
 
 	for _, c := range cases {
-		configuration.TerraformTeamToken, configuration.TerraformOrgName =
-			c.tftoken, c.tforgname
+		store(&Configuration{TerraformTeamToken: c.tftoken, TerraformOrgName: c.tforgname})
 		err := ValidateConfig()
 		if c.errorType != nil {
 			s.True(errors.Is(err, c.errorType), c.message)
@@ -56,6 +60,30 @@ func (s *TestSuite) TestValidateConfig() {
 	}
 }
 
+func (s *TestSuite) TestValidateConfigSplitTokens() {
+	store(&Configuration{TerraformOrgName: "test", TerraformReadToken: "read"})
+	s.NoError(ValidateReadConfig(), "read token should be enough for read only operations")
+	s.True(errors.Is(ValidateConfig(), ErrTFTeamTokenRequired), "write operations should require a write token")
+
+	Override(func(c *Configuration) { c.TerraformWriteToken = "write" })
+	s.NoError(ValidateConfig(), "read and write tokens should replace the team token")
+}
+
+func (s *TestSuite) TestTokenFallback() {
+	os.Setenv("TF_TEAM_TOKEN", "team_token")
+	os.Setenv("TF_ORG_NAME", "org_name")
+	InitConfig("./no-file")
+	s.Equal("team_token", GetConfig().ReadToken(), "read token should fall back to the team token")
+	s.Equal("team_token", GetConfig().WriteToken(), "write token should fall back to the team token")
+
+	viper = vpr.New()
+	os.Setenv("TF_READ_TOKEN", "read_token")
+	os.Setenv("TF_WRITE_TOKEN", "write_token")
+	InitConfig("./no-file")
+	s.Equal("read_token", GetConfig().ReadToken(), "read token should be 'read_token'")
+	s.Equal("write_token", GetConfig().WriteToken(), "write token should be 'write_token'")
+}
+
 func createTestFile(filepath, tftoken, tforgname, loglevel string) error {
 	content := `tf_team_token: "%s"
 tf_org_name: "%s"
@@ -89,9 +117,97 @@ func (s *TestSuite) TestInitConfigFile() {
 	s.NoError(err, "should not error creating config file")
 	InitConfig(cfgFile)
 
-	s.Equal("init_tf_team_token", configuration.TerraformTeamToken, "tf token should be 'init_tf_team_token'")
-	s.Equal("init_org_name", configuration.TerraformOrgName, "tf org name should be 'init_org_name'")
-	s.Equal("debug", configuration.LogLevel, "log level should be 'debug'")
+	s.Equal("init_tf_team_token", GetConfig().TerraformTeamToken, "tf token should be 'init_tf_team_token'")
+	s.Equal("init_org_name", GetConfig().TerraformOrgName, "tf org name should be 'init_org_name'")
+	s.Equal("debug", GetConfig().LogLevel, "log level should be 'debug'")
+}
+
+func (s *TestSuite) TestInitConfigWorkspaceTemplate() {
+	cfgFile := "./template-test.yml"
+	content := `tf_team_token: "token"
+tf_org_name: "org"
+workspace_template:
+  execution_mode: agent
+  auto_apply: true
+  tags: ["dr", "restored"]
+  vcs_repo:
+    identifier: org/app
+    oauth_token_id: ot-123
+`
+	err := ioutil.WriteFile(cfgFile, []byte(content), 0644)
+	defer os.RemoveAll(cfgFile)
+	s.NoError(err, "should not error creating config file")
+	InitConfig(cfgFile)
+
+	tmpl := GetConfig().WorkspaceTemplate
+	s.Equal("agent", tmpl.ExecutionMode)
+	s.True(tmpl.AutoApply)
+	s.Equal([]string{"dr", "restored"}, tmpl.Tags)
+	s.Equal("org/app", tmpl.VCSRepo.Identifier)
+	s.Equal("ot-123", tmpl.VCSRepo.OAuthTokenID)
+}
+
+func (s *TestSuite) TestRedactionProfile() {
+	cfgFile := "./redaction-test.yml"
+	content := `tf_team_token: "token"
+tf_org_name: "org"
+redaction_profiles:
+  Staging:
+    attributes: ["aws_db_instance.password", "*.private_key_pem"]
+  rehearsal:
+    placeholder: "xxx"
+    attributes: ["*.password"]
+`
+	err := ioutil.WriteFile(cfgFile, []byte(content), 0644)
+	defer os.RemoveAll(cfgFile)
+	s.NoError(err, "should not error creating config file")
+	InitConfig(cfgFile)
+
+	p, err := GetConfig().GetRedactionProfile("staging")
+	s.NoError(err)
+	s.Equal("REDACTED", p.Placeholder, "placeholder should default to REDACTED")
+	s.Equal([]string{"aws_db_instance.password", "*.private_key_pem"}, p.Attributes)
+
+	p, err = GetConfig().GetRedactionProfile("rehearsal")
+	s.NoError(err)
+	s.Equal("xxx", p.Placeholder)
+
+	_, err = GetConfig().GetRedactionProfile("prod")
+	s.Error(err)
+}
+
+func (s *TestSuite) TestRunbook() {
+	cfgFile := "./runbook-test.yml"
+	content := `tf_team_token: "token"
+tf_org_name: "org"
+runbooks:
+  Quarterly-Drill:
+    description: copy, check and clean up
+    steps:
+      - state copy -o prod -n drill-prod -f filters.json --create-missing
+      - inventory duplicates --refresh
+      - workspace delete -p drill- -y
+  empty:
+    steps: []
+  nested:
+    steps: ["runbook run empty"]
+`
+	err := ioutil.WriteFile(cfgFile, []byte(content), 0644)
+	defer os.RemoveAll(cfgFile)
+	s.NoError(err, "should not error creating config file")
+	InitConfig(cfgFile)
+
+	r, err := GetConfig().GetRunbook("quarterly-drill")
+	s.NoError(err)
+	s.Equal("copy, check and clean up", r.Description)
+	s.Len(r.Steps, 3)
+
+	_, err = GetConfig().GetRunbook("empty")
+	s.EqualError(err, `Runbook "empty" has no steps`)
+	_, err = GetConfig().GetRunbook("nested")
+	s.EqualError(err, `Step 1 of runbook "nested" runs another runbook, which is not supported`)
+	_, err = GetConfig().GetRunbook("missing")
+	s.Error(err)
 }
 
 func (s *TestSuite) TestInitConfigEnv() {
@@ -104,9 +220,9 @@ func (s *TestSuite) TestInitConfigEnv() {
 
 	InitConfig(cfgFile)
 
-	s.Equal("team_token", configuration.TerraformTeamToken, "tf token should be 'team_token'")
-	s.Equal("org_name", configuration.TerraformOrgName, "tf org name should be 'org_name'")
-	s.Equal("debug", configuration.LogLevel, "log level should be 'debug'")
+	s.Equal("team_token", GetConfig().TerraformTeamToken, "tf token should be 'team_token'")
+	s.Equal("org_name", GetConfig().TerraformOrgName, "tf org name should be 'org_name'")
+	s.Equal("debug", GetConfig().LogLevel, "log level should be 'debug'")
 }
 
 func (s *TestSuite) TestInitConfigFileOverrides() {
@@ -120,9 +236,90 @@ func (s *TestSuite) TestInitConfigFileOverrides() {
 	s.NoError(err, "should not error creating config file")
 	InitConfig(cfgFile)
 
-	s.Equal("env_team_token", configuration.TerraformTeamToken, "tf token should be 'env_team_token'")
-	s.Equal("env_org_name", configuration.TerraformOrgName, "tf org name should be 'env_org_name'")
-	s.Equal("env_debug", configuration.LogLevel, "log level should be 'env_debug'")
+	s.Equal("env_team_token", GetConfig().TerraformTeamToken, "tf token should be 'env_team_token'")
+	s.Equal("env_org_name", GetConfig().TerraformOrgName, "tf org name should be 'env_org_name'")
+	s.Equal("env_debug", GetConfig().LogLevel, "log level should be 'env_debug'")
+}
+
+func (s *TestSuite) TestOverrideSurvivesReload() {
+	cfgFile := "./reload-test.yml"
+	err := createTestFile(cfgFile, "team_token", "org_name", "info")
+	defer os.RemoveAll(cfgFile)
+	s.NoError(err, "should not error creating config file")
+	loaded := InitConfig(cfgFile)
+
+	Override(func(c *Configuration) { c.LogLevel = "debug" })
+	s.Equal("debug", GetConfig().LogLevel, "override should apply to the active config")
+	s.Equal("info", loaded.LogLevel, "configs already handed out should not change")
+
+	s.NoError(createTestFile(cfgFile, "new_team_token", "org_name", "info"))
+	c, err := Reload()
+	s.NoError(err)
+	s.Equal(c, GetConfig(), "reloaded config should become the active config")
+	s.Equal("new_team_token", c.TerraformTeamToken, "reload should read the changed file")
+	s.Equal("debug", c.LogLevel, "overrides should be applied again after reload")
+}
+
+func (s *TestSuite) TestReloadKeepsConfigOnError() {
+	cfgFile := "./reload-error-test.yml"
+	err := createTestFile(cfgFile, "team_token", "org_name", "info")
+	defer os.RemoveAll(cfgFile)
+	s.NoError(err, "should not error creating config file")
+	InitConfig(cfgFile)
+
+	s.NoError(ioutil.WriteFile(cfgFile, []byte("tf_team_token: [unterminated"), 0644))
+	_, err = Reload()
+	s.Error(err)
+	s.Equal("team_token", GetConfig().TerraformTeamToken, "previous config should stay active")
+}
+
+func (s *TestSuite) TestSaveToken() {
+	cfgFile := "./save-token-test.yml"
+	err := createTestFile(cfgFile, "old_team_token", "org_name", "debug")
+	defer os.RemoveAll(cfgFile)
+	s.NoError(err, "should not error creating config file")
+	InitConfig(cfgFile)
+
+	s.NoError(SaveToken("new_team_token"))
+	s.Equal("new_team_token", GetConfig().TerraformTeamToken, "saved token should be applied to the loaded config")
+
+	viper = vpr.New()
+	InitConfig(cfgFile)
+	s.Equal("new_team_token", GetConfig().TerraformTeamToken, "tf token should be 'new_team_token'")
+	s.Equal("org_name", GetConfig().TerraformOrgName, "other settings should be kept")
+	s.Equal("debug", GetConfig().LogLevel, "other settings should be kept")
+	s.NotEmpty(GetConfig().TokenCreatedAt, "token creation time should be recorded")
+}
+
+func (s *TestSuite) TestReplaceToken() {
+	cfgFile := "./replace-token-test.yml"
+	defer os.RemoveAll(cfgFile)
+	s.NoError(ioutil.WriteFile(cfgFile, []byte("tf_team_token: team_token\ntf_org_name: org_name\ntf_read_token: read_token\ntf_write_token: write_token\n"), 0600))
+	InitConfig(cfgFile)
+
+	s.NoError(ReplaceToken(GetConfig().WriteToken(), "new_write_token"))
+	s.Equal("new_write_token", GetConfig().WriteToken(), "the rotated token should be applied to the loaded config")
+
+	viper = vpr.New()
+	InitConfig(cfgFile)
+	s.Equal("new_write_token", GetConfig().TerraformWriteToken, "the key the rotated token came from should be updated")
+	s.Equal("team_token", GetConfig().TerraformTeamToken)
+	s.Equal("read_token", GetConfig().TerraformReadToken)
+}
+
+func (s *TestSuite) TestTokenAgeWarning() {
+	now := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	store(&Configuration{TokenCreatedAt: "2020-09-01T00:00:00Z"})
+	s.Empty(TokenAgeWarning(now), "no warning without a max age")
+
+	Override(func(c *Configuration) { c.TokenMaxAge = "1000h" })
+	s.Empty(TokenAgeWarning(now), "token younger than max age should not warn")
+
+	Override(func(c *Configuration) { c.TokenMaxAge = "240h" })
+	s.Contains(TokenAgeWarning(now), "older than tf_token_max_age", "token older than max age should warn")
+
+	Override(func(c *Configuration) { c.TokenMaxAge = "ten days" })
+	s.Contains(TokenAgeWarning(now), "Invalid tf_token_max_age")
 }
 
 func (s *TestSuite) TestCreate() {
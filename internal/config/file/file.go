@@ -2,18 +2,32 @@ package file
 
 import (
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"path/filepath"
 
 	"github.com/eiannone/keyboard"
+	"github.com/mupuri/go-tfdr/internal/atomicfile"
 )
 
-func Create(contents string) {
+// Path returns the default config file location
+func Path() string {
 	homeDir, _ := os.UserHomeDir()
-	configDir := filepath.Join(homeDir, ".tfdr")
-	fileName := "config.yaml"
+	return filepath.Join(homeDir, ".tfdr", "config.yaml")
+}
+
+// Write replaces the contents of a config file without prompting, creating
+// it readable only by the current user
+func Write(cfgFile string, contents []byte) error {
+	if err := os.MkdirAll(filepath.Dir(cfgFile), 0755); err != nil {
+		return fmt.Errorf("Unable to create config directory %s. Error: %v", filepath.Dir(cfgFile), err)
+	}
+	return atomicfile.WriteFile(cfgFile, contents, 0600)
+}
+
+func Create(contents string) {
+	configDir := filepath.Dir(Path())
+	fileName := filepath.Base(Path())
 	if _, err := os.Stat(configDir); err != nil {
 		if os.IsNotExist(err) {
 			if err := os.MkdirAll(configDir, 0755); err != nil {
@@ -36,17 +50,9 @@ func Create(contents string) {
 }
 
 func saveConfig(cfgFile string, contents string) {
-	file, err := os.Create(cfgFile)
-	if err != nil {
-		log.Fatalf("Error: failed while creating config file. Error: %s", err.Error())
-	}
-	defer file.Close()
-
-	_, err = io.WriteString(file, contents)
-	if err != nil {
-		log.Fatal("Error: failed while attempting to write config yaml")
+	if err := atomicfile.WriteFile(cfgFile, []byte(contents), 0600); err != nil {
+		log.Fatalf("Error: failed while attempting to write config yaml. Error: %s", err.Error())
 	}
-	_ = file.Sync()
 
 	fmt.Println("\nSuccessfully configured terraform disaster recovery cli. Use `tfdr config get` to view your configuration.")
 }
@@ -19,3 +19,14 @@ func TestCreate(t *testing.T) {
 	Create("hello, world")
 	assert.FileExists(t, path.Join(dir, ".tfdr/config.yaml"))
 }
+
+func TestWrite(t *testing.T) {
+	dir := "./test-write"
+	defer os.RemoveAll(dir)
+	cfgFile := path.Join(dir, "nested", "config.yaml")
+
+	assert.NoError(t, Write(cfgFile, []byte("tf_team_token: abc\n")))
+	info, err := os.Stat(cfgFile)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
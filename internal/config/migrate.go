@@ -0,0 +1,144 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mupuri/go-tfdr/internal/atomicfile"
+	"gopkg.in/yaml.v2"
+)
+
+// CurrentVersion is the config file layout written by this release. Files
+// without a version field predate versioning and are version 0.
+const CurrentVersion = 1
+
+// migrations upgrade a config file from the version they are keyed by to the
+// next one
+var migrations = map[int]func(yaml.MapSlice) yaml.MapSlice{
+	// version 1 only adds the version field
+	0: func(settings yaml.MapSlice) yaml.MapSlice {
+		return settings
+	},
+}
+
+// Migrate upgrades the contents of a config file to CurrentVersion. It
+// reports whether anything changed, and fails for files written by a newer
+// release.
+func Migrate(contents []byte) ([]byte, bool, error) {
+	settings := yaml.MapSlice{}
+	if err := yaml.Unmarshal(contents, &settings); err != nil {
+		return nil, false, err
+	}
+
+	version, err := fileVersion(settings)
+	if err != nil {
+		return nil, false, err
+	}
+	if version > CurrentVersion {
+		return nil, false, fmt.Errorf("config file version %d is newer than this tfdr release supports (%d). Upgrade tfdr", version, CurrentVersion)
+	}
+	if version == CurrentVersion {
+		return contents, false, nil
+	}
+
+	for ; version < CurrentVersion; version++ {
+		settings = migrations[version](settings)
+	}
+	settings = setVersion(settings, CurrentVersion)
+
+	out, err := yaml.Marshal(settings)
+	if err != nil {
+		return nil, false, err
+	}
+	return out, true, nil
+}
+
+func fileVersion(settings yaml.MapSlice) (int, error) {
+	for _, item := range settings {
+		if item.Key != "version" {
+			continue
+		}
+		version, ok := item.Value.(int)
+		if !ok || version < 0 {
+			return 0, fmt.Errorf("invalid config file version %v", item.Value)
+		}
+		return version, nil
+	}
+	return 0, nil
+}
+
+// setVersion sets the version field, keeping it first in the file
+func setVersion(settings yaml.MapSlice, version int) yaml.MapSlice {
+	out := yaml.MapSlice{{Key: "version", Value: version}}
+	for _, item := range settings {
+		if item.Key != "version" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// isYAML reports whether a config file is YAML. Only YAML files are
+// versioned, files in the other formats viper reads are used as they are.
+func isYAML(cfgFile string) bool {
+	ext := strings.ToLower(filepath.Ext(cfgFile))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// migrateLoaded upgrades the config file viper read, in memory. Callers must
+// hold mu.
+func migrateLoaded() error {
+	cfgFile := viper.ConfigFileUsed()
+	if cfgFile == "" || !isYAML(cfgFile) {
+		return nil
+	}
+	contents, err := ioutil.ReadFile(cfgFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	migrated, changed, err := Migrate(contents)
+	if err != nil {
+		return fmt.Errorf("%s: %v", cfgFile, err)
+	}
+	if changed {
+		return viper.ReadConfig(bytes.NewReader(migrated))
+	}
+	return nil
+}
+
+// MigrateFile rewrites the config file in use in the current layout. It
+// returns the file name and whether it needed migrating.
+func MigrateFile() (string, bool, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if err := errRemote(); err != nil {
+		return "", false, err
+	}
+	cfgFile := viper.ConfigFileUsed()
+	if cfgFile == "" {
+		return "", false, fmt.Errorf("no config file found")
+	}
+	if !isYAML(cfgFile) {
+		return cfgFile, false, fmt.Errorf("only YAML config files are versioned, %s is left as it is", cfgFile)
+	}
+	contents, err := ioutil.ReadFile(cfgFile)
+	if err != nil {
+		return cfgFile, false, err
+	}
+	migrated, changed, err := Migrate(contents)
+	if err != nil || !changed {
+		return cfgFile, false, err
+	}
+	info, err := os.Stat(cfgFile)
+	if err != nil {
+		return cfgFile, false, err
+	}
+	return cfgFile, true, atomicfile.WriteFile(cfgFile, migrated, info.Mode().Perm())
+}
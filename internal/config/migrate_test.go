@@ -0,0 +1,81 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+func (s *TestSuite) TestMigrateLegacyFile() {
+	out, changed, err := Migrate([]byte("tf_team_token: token\ntf_org_name: org\n"))
+	s.NoError(err)
+	s.True(changed, "unversioned files should be migrated")
+	s.Equal("version: 1\ntf_team_token: token\ntf_org_name: org\n", string(out))
+}
+
+func (s *TestSuite) TestMigrateCurrentFile() {
+	contents := []byte("version: 1\ntf_team_token: token\n")
+	out, changed, err := Migrate(contents)
+	s.NoError(err)
+	s.False(changed)
+	s.Equal(contents, out)
+}
+
+func (s *TestSuite) TestMigrateFutureVersion() {
+	_, _, err := Migrate([]byte("version: 99\n"))
+	s.Error(err)
+	s.Contains(err.Error(), "newer than this tfdr release supports")
+
+	_, _, err = Migrate([]byte("version: two\n"))
+	s.Error(err)
+}
+
+func (s *TestSuite) TestInitConfigMigratesInMemory() {
+	cfgFile := "./migrate-test.yml"
+	err := createTestFile(cfgFile, "team_token", "org_name", "debug")
+	defer os.RemoveAll(cfgFile)
+	s.NoError(err, "should not error creating config file")
+
+	InitConfig(cfgFile)
+	s.Equal(CurrentVersion, GetConfig().Version)
+	s.Equal("team_token", GetConfig().TerraformTeamToken)
+
+	contents, _ := ioutil.ReadFile(cfgFile)
+	s.NotContains(string(contents), "version", "reading should not rewrite the file")
+
+	name, changed, err := MigrateFile()
+	s.NoError(err)
+	s.True(changed)
+	s.Equal(cfgFile, name)
+	contents, _ = ioutil.ReadFile(cfgFile)
+	s.Contains(string(contents), "version: 1")
+}
+
+func (s *TestSuite) TestReloadRejectsFutureVersion() {
+	cfgFile := "./future-test.yml"
+	err := createTestFile(cfgFile, "team_token", "org_name", "debug")
+	defer os.RemoveAll(cfgFile)
+	s.NoError(err, "should not error creating config file")
+	InitConfig(cfgFile)
+
+	s.NoError(ioutil.WriteFile(cfgFile, []byte("version: 2\ntf_team_token: new\n"), 0644))
+	_, err = Reload()
+	s.Error(err)
+	s.Equal("team_token", GetConfig().TerraformTeamToken)
+}
+
+func (s *TestSuite) TestInitConfigReadsUnversionedJSON() {
+	cfgFile := "./migrate-test.json"
+	contents := `{"tf_team_token":"x","tf_org_name":"o"}`
+	s.NoError(ioutil.WriteFile(cfgFile, []byte(contents), 0600))
+	defer os.RemoveAll(cfgFile)
+
+	InitConfig(cfgFile)
+	s.Equal("x", GetConfig().TerraformTeamToken)
+	s.Equal("o", GetConfig().TerraformOrgName)
+
+	_, changed, err := MigrateFile()
+	s.Error(err)
+	s.False(changed)
+	written, _ := ioutil.ReadFile(cfgFile)
+	s.Equal(contents, string(written), "JSON files should not be rewritten")
+}
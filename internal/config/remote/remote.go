@@ -0,0 +1,216 @@
+// Package remote fetches centrally managed config files from S3 or an HTTPS
+// server. Every fetch is cached with its ETag, so an unchanged file isn't
+// downloaded again and the last good copy is used when the source can't be
+// reached, e.g. during the outage being recovered from. Files can be signed
+// with an ed25519 key from `tfdr approve keygen`, in which case the
+// signature is read from the same location with a .sig suffix.
+package remote
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/atomicfile"
+	"github.com/mupuri/go-tfdr/internal/config/file"
+)
+
+// SignatureSuffix is appended to the location of a config file to get the
+// location of its signature
+const SignatureSuffix = ".sig"
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// IsRemote reports whether a config location is an s3:// or https:// URL
+func IsRemote(location string) bool {
+	return strings.HasPrefix(location, "s3://") || strings.HasPrefix(location, "https://")
+}
+
+// CacheDir returns the directory remote config files are cached in
+func CacheDir() string {
+	return filepath.Join(filepath.Dir(file.Path()), "remote-config")
+}
+
+// Copy is the local copy of a remote config file
+type Copy struct {
+	Path string
+	// Err is why the copy couldn't be refreshed, when the previously cached
+	// copy is used instead
+	Err error
+}
+
+// Fetch downloads the config file at location into the cache unless the
+// cached copy is current. When publicKey is set, the file must be signed
+// with the matching private key. A cached copy is only used if its
+// signature still verifies.
+func Fetch(location string, publicKey string) (Copy, error) {
+	if err := os.MkdirAll(CacheDir(), 0700); err != nil {
+		return Copy{}, fmt.Errorf("Unable to create config cache directory %s. Error: %v", CacheDir(), err)
+	}
+	c := Copy{Path: cachePath(location)}
+
+	err := refresh(location, c.Path, publicKey)
+	if err == nil {
+		return c, nil
+	}
+	if _, ok := err.(errInvalidSignature); ok {
+		return Copy{}, err
+	}
+	if _, statErr := os.Stat(c.Path); statErr != nil {
+		return Copy{}, fmt.Errorf("Unable to fetch config %s. Error: %v", location, err)
+	}
+	if publicKey != "" {
+		if verifyErr := verifyCached(c.Path, publicKey); verifyErr != nil {
+			return Copy{}, verifyErr
+		}
+	}
+	c.Err = err
+	return c, nil
+}
+
+// cachePath names the cached copy after a hash of the location, keeping the
+// extension so the config format is recognized
+func cachePath(location string) string {
+	sum := sha256.Sum256([]byte(location))
+	ext := path.Ext(location)
+	if u, err := url.Parse(location); err == nil {
+		ext = path.Ext(u.Path)
+	}
+	if ext == "" {
+		ext = ".yaml"
+	}
+	return filepath.Join(CacheDir(), hex.EncodeToString(sum[:8])+ext)
+}
+
+// refresh downloads the file into cached when it changed since it was
+// cached, along with its signature
+func refresh(location string, cached string, publicKey string) error {
+	etag := ""
+	if _, err := os.Stat(cached); err == nil {
+		if data, err := ioutil.ReadFile(cached + ".etag"); err == nil {
+			etag = strings.TrimSpace(string(data))
+		}
+	}
+
+	data, newETag, err := get(location, etag)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		// Not modified, but the cached copy may predate the key or have been
+		// edited since
+		if publicKey != "" {
+			return verifyCached(cached, publicKey)
+		}
+		return nil
+	}
+
+	var sig []byte
+	if publicKey != "" {
+		sig, _, err = get(location+SignatureSuffix, "")
+		if err != nil {
+			return fmt.Errorf("Unable to fetch config signature. Error: %v", err)
+		}
+		if err := Verify(publicKey, data, string(sig)); err != nil {
+			return err
+		}
+		if err := atomicfile.WriteFile(cached+SignatureSuffix, sig, 0600); err != nil {
+			return err
+		}
+	}
+	if err := atomicfile.WriteFile(cached, data, 0600); err != nil {
+		return err
+	}
+	if newETag == "" {
+		os.Remove(cached + ".etag")
+		return nil
+	}
+	return atomicfile.WriteFile(cached+".etag", []byte(newETag), 0600)
+}
+
+// get reads location, returning nil data when it matches etag
+func get(location string, etag string) ([]byte, string, error) {
+	var req *http.Request
+	var err error
+	if strings.HasPrefix(location, "s3://") {
+		req, err = newS3Request(location, etag, time.Now())
+	} else {
+		req, err = http.NewRequest("GET", location, nil)
+		if err == nil && etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotModified && etag != "":
+		return nil, etag, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, "", fmt.Errorf("Unexpected status reading %s: %s", location, resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, resp.Header.Get("ETag"), nil
+}
+
+func verifyCached(cached string, publicKey string) error {
+	data, err := ioutil.ReadFile(cached)
+	if err != nil {
+		return err
+	}
+	sig, err := ioutil.ReadFile(cached + SignatureSuffix)
+	if err != nil {
+		return errInvalidSignature{fmt.Sprintf("cached config %s has no signature", cached)}
+	}
+	return Verify(publicKey, data, string(sig))
+}
+
+type errInvalidSignature struct {
+	reason string
+}
+
+func (e errInvalidSignature) Error() string {
+	return "Invalid config signature: " + e.reason
+}
+
+// Sign returns the signature of a config file, made with a base64 encoded
+// private key from `tfdr approve keygen`
+func Sign(privateKey string, data []byte) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(privateKey))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return "", fmt.Errorf("Invalid config signing private key")
+	}
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(ed25519.PrivateKey(key), data)), nil
+}
+
+// Verify checks sig is a signature of data by the base64 encoded publicKey
+func Verify(publicKey string, data []byte, sig string) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return errInvalidSignature{"the public key is not a base64 encoded ed25519 key"}
+	}
+	s, err := base64.StdEncoding.DecodeString(strings.TrimSpace(sig))
+	if err != nil || !ed25519.Verify(ed25519.PublicKey(key), data, s) {
+		return errInvalidSignature{"the config file was not signed with the configured key"}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/eiannone/keyboard"
+	"github.com/mupuri/go-tfdr/internal/config/file"
+	"gopkg.in/yaml.v2"
+)
+
+// ErrInputCancelled is returned when the user aborts a prompt with Ctrl-C
+var ErrInputCancelled = errors.New("input cancelled")
+
+// PromptSecret reads a line from the terminal without echoing it
+func PromptSecret(prompt string) (string, error) {
+	fmt.Print(prompt)
+	if err := keyboard.Open(); err != nil {
+		return "", fmt.Errorf("Unable to read from terminal. Error: %v", err)
+	}
+	defer keyboard.Close()
+
+	secret := make([]rune, 0)
+	for {
+		ch, key, err := keyboard.GetKey()
+		if err != nil {
+			return "", err
+		}
+		switch key {
+		case keyboard.KeyEnter:
+			fmt.Println()
+			return string(secret), nil
+		case keyboard.KeyCtrlC, keyboard.KeyEsc:
+			fmt.Println()
+			return "", ErrInputCancelled
+		case keyboard.KeyBackspace, keyboard.KeyBackspace2:
+			if len(secret) > 0 {
+				secret = secret[:len(secret)-1]
+			}
+		default:
+			if ch != 0 {
+				secret = append(secret, ch)
+			}
+		}
+	}
+}
+
+// TokenAgeWarning returns a warning when the stored token is older than the
+// configured tf_token_max_age, or an empty string when no rotation is due
+func TokenAgeWarning(now time.Time) string {
+	configuration := GetConfig()
+	if configuration.TokenMaxAge == "" || configuration.TokenCreatedAt == "" {
+		return ""
+	}
+	maxAge, err := time.ParseDuration(configuration.TokenMaxAge)
+	if err != nil {
+		return fmt.Sprintf("Invalid tf_token_max_age %q. Error: %v", configuration.TokenMaxAge, err)
+	}
+	createdAt, err := time.Parse(time.RFC3339, configuration.TokenCreatedAt)
+	if err != nil {
+		return fmt.Sprintf("Invalid tf_token_created_at %q. Error: %v", configuration.TokenCreatedAt, err)
+	}
+	if age := now.Sub(createdAt); age > maxAge {
+		return fmt.Sprintf("Terraform token is %s old, older than tf_token_max_age (%s). Run `tfdr auth rotate` to replace it", age.Truncate(time.Hour), maxAge)
+	}
+	return ""
+}
+
+// SaveToken stores the team token and its creation time in the config file in
+// use, leaving every other setting in the file untouched
+func SaveToken(token string) error {
+	return saveToken([]string{"tf_team_token"}, token)
+}
+
+// ReplaceToken stores a rotated token in place of old, which has been
+// revoked, in each of tf_team_token, tf_read_token and tf_write_token that
+// holds it
+func ReplaceToken(old string, token string) error {
+	c := GetConfig()
+	keys := make([]string, 0)
+	for key, value := range map[string]string{
+		"tf_team_token":  c.TerraformTeamToken,
+		"tf_read_token":  c.TerraformReadToken,
+		"tf_write_token": c.TerraformWriteToken,
+	} {
+		if value != "" && value == old {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		keys = append(keys, "tf_team_token")
+	}
+	sort.Strings(keys)
+	return saveToken(keys, token)
+}
+
+func saveToken(keys []string, token string) error {
+	// Hold mu for the whole read-modify-write, viper isn't safe for
+	// concurrent use and a reload in between would lose the new token
+	mu.Lock()
+	defer mu.Unlock()
+	if err := errRemote(); err != nil {
+		return err
+	}
+	createdAt := time.Now().UTC().Format(time.RFC3339)
+	cfgFile := viper.ConfigFileUsed()
+	if cfgFile == "" {
+		cfgFile = file.Path()
+	}
+
+	settings := yaml.MapSlice{}
+	contents, err := ioutil.ReadFile(cfgFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Unable to read config file %s. Error: %v", cfgFile, err)
+	}
+	if contents, _, err = Migrate(contents); err != nil {
+		return fmt.Errorf("Unable to migrate config file %s. Error: %v", cfgFile, err)
+	}
+	if err := yaml.Unmarshal(contents, &settings); err != nil {
+		return fmt.Errorf("Unable to parse config file %s. Error: %v", cfgFile, err)
+	}
+
+	for _, key := range keys {
+		settings = setValue(settings, key, token)
+	}
+	settings = setValue(settings, "tf_token_created_at", createdAt)
+
+	out, err := yaml.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	if err := file.Write(cfgFile, out); err != nil {
+		return fmt.Errorf("Unable to write config file %s. Error: %v", cfgFile, err)
+	}
+	c := *GetConfig()
+	for _, key := range keys {
+		switch key {
+		case "tf_team_token":
+			c.TerraformTeamToken = token
+		case "tf_read_token":
+			c.TerraformReadToken = token
+		case "tf_write_token":
+			c.TerraformWriteToken = token
+		}
+	}
+	c.TokenCreatedAt = createdAt
+	store(&c)
+	return nil
+}
+
+func setValue(settings yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i := range settings {
+		if settings[i].Key == key {
+			settings[i].Value = value
+			return settings
+		}
+	}
+	return append(settings, yaml.MapItem{Key: key, Value: value})
+}
@@ -0,0 +1,95 @@
+// Package console writes command output for people to read, coloring
+// statuses when the output is a terminal.
+package console
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Color modes accepted by --color
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+const (
+	red    = "31"
+	green  = "32"
+	yellow = "33"
+	bold   = "1"
+)
+
+// Out receives all command output
+var Out io.Writer = os.Stdout
+
+var colorMode = ColorAuto
+
+// SetColorMode sets whether output is colored: always, never, or auto to
+// color only terminals and only when NO_COLOR is not set
+func SetColorMode(mode string) error {
+	switch mode {
+	case ColorAuto, ColorAlways, ColorNever:
+		colorMode = mode
+		return nil
+	}
+	return fmt.Errorf("Invalid color mode %q, expected auto, always or never", mode)
+}
+
+// ColorsFor reports whether output written to w should be colored
+func ColorsFor(w io.Writer) bool {
+	switch colorMode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Printf writes formatted output
+func Printf(format string, a ...interface{}) {
+	fmt.Fprintf(Out, format, a...)
+}
+
+// Println writes a line of output
+func Println(a ...interface{}) {
+	fmt.Fprintln(Out, a...)
+}
+
+// Success colors s green
+func Success(s string) string {
+	return colorize(green, s)
+}
+
+// Warning colors s yellow
+func Warning(s string) string {
+	return colorize(yellow, s)
+}
+
+// Failure colors s red
+func Failure(s string) string {
+	return colorize(red, s)
+}
+
+// Bold makes s bold
+func Bold(s string) string {
+	return colorize(bold, s)
+}
+
+func colorize(code string, s string) string {
+	if s == "" || !ColorsFor(Out) {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
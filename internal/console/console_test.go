@@ -0,0 +1,32 @@
+package console
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColorMode(t *testing.T) {
+	defer SetColorMode(ColorAuto)
+	var buf bytes.Buffer
+	Out = &buf
+	defer func() { Out = os.Stdout }()
+
+	assert.Equal(t, "FAIL", Failure("FAIL"), "auto should not color output that isn't a terminal")
+
+	assert.NoError(t, SetColorMode(ColorAlways))
+	assert.Equal(t, "\x1b[31mFAIL\x1b[0m", Failure("FAIL"))
+	os.Setenv("NO_COLOR", "1")
+	defer os.Unsetenv("NO_COLOR")
+	assert.Equal(t, "\x1b[32mOK\x1b[0m", Success("OK"), "--color=always should win over NO_COLOR")
+
+	assert.NoError(t, SetColorMode(ColorAuto))
+	assert.False(t, ColorsFor(os.Stdout), "NO_COLOR should turn colors off")
+
+	assert.NoError(t, SetColorMode(ColorNever))
+	assert.Equal(t, "WARN", Warning("WARN"))
+
+	assert.Error(t, SetColorMode("sometimes"))
+}
@@ -0,0 +1,202 @@
+package doctor
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/config/file"
+)
+
+// Status is the outcome of a check
+type Status int
+
+// Check outcomes, from best to worst
+const (
+	OK Status = iota
+	Warn
+	Fail
+)
+
+func (s Status) String() string {
+	switch s {
+	case OK:
+		return "OK"
+	case Warn:
+		return "WARN"
+	default:
+		return "FAIL"
+	}
+}
+
+// Result is the outcome of one diagnostic check, with a hint on how to fix
+// anything that is not OK
+type Result struct {
+	Name    string
+	Status  Status
+	Message string
+	Hint    string
+}
+
+// Thresholds for warnings
+var (
+	maxClockSkew         = 5 * time.Minute
+	minFreeSpace  uint64 = 1 << 30
+	apiLookupHost        = net.LookupHost
+)
+
+// Run runs every check in order. Checks that need the API are skipped once
+// the API is found to be unreachable.
+func Run() []Result {
+	results := make([]Result, 0)
+	apiURL, _ := url.Parse(api.APIURL())
+
+	results = append(results, checkProxy(apiURL))
+	dns := checkDNS(apiURL.Hostname())
+	results = append(results, dns)
+	if dns.Status != Fail {
+		serverTime, err := api.Ping()
+		results = append(results, checkConnectivity(apiURL.String(), err))
+		if err == nil {
+			results = append(results, checkClockSkew(serverTime, time.Now()))
+			results = append(results, checkTokens()...)
+			results = append(results, checkCapabilities(api.Capabilities()))
+		}
+	}
+	results = append(results, checkConfigPermissions(config.FileUsed()))
+	results = append(results, checkDiskSpace(filepath.Dir(file.Path())))
+	return results
+}
+
+func checkProxy(apiURL *url.URL) Result {
+	r := Result{Name: "proxy", Status: OK}
+	proxy, err := http.ProxyFromEnvironment(&http.Request{URL: apiURL})
+	switch {
+	case err != nil:
+		r.Status = Fail
+		r.Message = fmt.Sprintf("invalid proxy setting: %v", err)
+		r.Hint = "Fix the HTTPS_PROXY environment variable"
+	case proxy == nil:
+		r.Message = "no proxy"
+	default:
+		r.Message = fmt.Sprintf("using proxy %s", proxy.Host)
+		r.Hint = "If API calls fail, check the proxy allows " + apiURL.Hostname() + " or add it to NO_PROXY"
+	}
+	return r
+}
+
+func checkDNS(host string) Result {
+	addrs, err := apiLookupHost(host)
+	if err != nil {
+		return Result{Name: "dns", Status: Fail, Message: err.Error(), Hint: "Check the DNS resolver can resolve " + host}
+	}
+	return Result{Name: "dns", Status: OK, Message: fmt.Sprintf("%s resolves to %v", host, addrs)}
+}
+
+func checkConnectivity(apiURL string, err error) Result {
+	if err != nil {
+		return Result{Name: "api", Status: Fail, Message: err.Error(), Hint: "Check network access and firewall rules to " + apiURL}
+	}
+	return Result{Name: "api", Status: OK, Message: apiURL + " is reachable"}
+}
+
+func checkClockSkew(serverTime time.Time, now time.Time) Result {
+	skew := now.Sub(serverTime)
+	if skew < 0 {
+		skew = -skew
+	}
+	r := Result{Name: "clock", Status: OK, Message: fmt.Sprintf("local clock is within %s of the API", skew.Truncate(time.Second))}
+	if skew > maxClockSkew {
+		r.Status = Warn
+		r.Message = fmt.Sprintf("local clock is %s off the API", skew.Truncate(time.Second))
+		r.Hint = "Enable NTP; token age checks and timestamps in reports use the local clock"
+	}
+	return r
+}
+
+func checkTokens() []Result {
+	c := config.GetConfig()
+	tokens := []struct{ name, token string }{{"read token", c.ReadToken()}}
+	if c.WriteToken() != c.ReadToken() {
+		tokens = append(tokens, struct{ name, token string }{"write token", c.WriteToken()})
+	}
+
+	results := make([]Result, 0, len(tokens))
+	for _, t := range tokens {
+		if t.token == "" {
+			results = append(results, Result{Name: t.name, Status: Fail, Message: "not configured", Hint: "Run `tfdr login` or set tf_team_token"})
+			continue
+		}
+		name, err := api.ValidateToken(t.token)
+		if err != nil {
+			results = append(results, Result{Name: t.name, Status: Fail, Message: err.Error(), Hint: "Run `tfdr login` or `tfdr auth rotate` to replace the token"})
+			continue
+		}
+		results = append(results, Result{Name: t.name, Status: OK, Message: "valid for " + name})
+	}
+	if warning := config.TokenAgeWarning(time.Now()); warning != "" {
+		results = append(results, Result{Name: "token age", Status: Warn, Message: warning, Hint: "Run `tfdr auth rotate`"})
+	}
+	return results
+}
+
+func checkCapabilities(capabilities map[api.Capability]bool) Result {
+	missing := api.CapabilityNames(capabilities, false)
+	if len(missing) == 0 {
+		return Result{Name: "capabilities", Status: OK, Message: "supports " + strings.Join(api.CapabilityNames(capabilities, true), ", ")}
+	}
+	return Result{
+		Name:    "capabilities",
+		Status:  Warn,
+		Message: "no support for " + strings.Join(missing, ", "),
+		Hint:    "Features that need them are turned off; upgrade TFE to use them",
+	}
+}
+
+func checkConfigPermissions(cfgFile string) Result {
+	r := Result{Name: "config file", Status: OK}
+	if cfgFile == "" {
+		r.Message = "no config file, using environment variables"
+		return r
+	}
+	info, err := os.Stat(cfgFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			r.Message = "no config file, using environment variables"
+			return r
+		}
+		r.Status = Fail
+		r.Message = err.Error()
+		return r
+	}
+	r.Message = fmt.Sprintf("%s (%s)", cfgFile, info.Mode().Perm())
+	if info.Mode().Perm()&0077 != 0 {
+		r.Status = Warn
+		r.Message += " is readable by other users and may contain API tokens"
+		r.Hint = "Run chmod 600 " + cfgFile
+	}
+	return r
+}
+
+func checkDiskSpace(dir string) Result {
+	if _, err := os.Stat(dir); err != nil {
+		dir = os.TempDir()
+	}
+	free, err := freeSpace(dir)
+	if err != nil {
+		return Result{Name: "disk space", Status: Warn, Message: err.Error()}
+	}
+	r := Result{Name: "disk space", Status: OK, Message: fmt.Sprintf("%d MB free in %s", free>>20, dir)}
+	if free < minFreeSpace {
+		r.Status = Warn
+		r.Hint = "Free up space for downloaded states and the inventory index"
+	}
+	return r
+}
@@ -0,0 +1,78 @@
+package doctor
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckProxy(t *testing.T) {
+	// net/http reads the proxy environment once per process, so only the
+	// environment the tests run in can be checked
+	apiURL, _ := url.Parse("https://app.terraform.io/api/v2/")
+	assert.Equal(t, OK, checkProxy(apiURL).Status)
+}
+
+func TestCheckDNS(t *testing.T) {
+	defer func(f func(string) ([]string, error)) { apiLookupHost = f }(apiLookupHost)
+
+	apiLookupHost = func(string) ([]string, error) { return []string{"10.0.0.1"}, nil }
+	assert.Equal(t, OK, checkDNS("app.terraform.io").Status)
+
+	apiLookupHost = func(string) ([]string, error) { return nil, errors.New("no such host") }
+	r := checkDNS("app.terraform.io")
+	assert.Equal(t, Fail, r.Status)
+	assert.NotEmpty(t, r.Hint)
+}
+
+func TestCheckClockSkew(t *testing.T) {
+	now := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, OK, checkClockSkew(now.Add(-30*time.Second), now).Status)
+	assert.Equal(t, Warn, checkClockSkew(now.Add(10*time.Minute), now).Status)
+	assert.Equal(t, Warn, checkClockSkew(now.Add(-10*time.Minute), now).Status)
+}
+
+func TestCheckConfigPermissions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "doctor")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cfgFile := filepath.Join(dir, "config.yaml")
+
+	assert.Equal(t, OK, checkConfigPermissions("").Status)
+	assert.Equal(t, OK, checkConfigPermissions(cfgFile).Status, "missing file is fine")
+
+	assert.NoError(t, ioutil.WriteFile(cfgFile, []byte("tf_team_token: x\n"), 0600))
+	assert.Equal(t, OK, checkConfigPermissions(cfgFile).Status)
+
+	assert.NoError(t, os.Chmod(cfgFile, 0644))
+	r := checkConfigPermissions(cfgFile)
+	assert.Equal(t, Warn, r.Status)
+	assert.Contains(t, r.Hint, "chmod 600")
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	defer func(min uint64) { minFreeSpace = min }(minFreeSpace)
+
+	minFreeSpace = 0
+	assert.Equal(t, OK, checkDiskSpace(os.TempDir()).Status)
+
+	minFreeSpace = 1 << 62
+	assert.Equal(t, Warn, checkDiskSpace("/does/not/exist").Status)
+}
+
+func TestCheckCapabilities(t *testing.T) {
+	r := checkCapabilities(map[api.Capability]bool{api.CapabilityTags: true, api.CapabilityProjects: true})
+	assert.Equal(t, OK, r.Status)
+	assert.Equal(t, "supports projects, workspace tags", r.Message)
+
+	r = checkCapabilities(map[api.Capability]bool{api.CapabilityTags: true, api.CapabilityProjects: false, api.CapabilityVariableSets: false})
+	assert.Equal(t, Warn, r.Status)
+	assert.Equal(t, "no support for projects, variable sets", r.Message)
+}
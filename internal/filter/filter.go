@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	"github.com/mupuri/go-tfdr/internal/models"
 	"github.com/mupuri/go-tfdr/internal/tfdrerrors"
@@ -28,13 +29,23 @@ func StateFilter(vs []models.Resource, f func(*models.Resource, *models.FilterCo
 
 // CopyResourceFilterFunc &
 var CopyResourceFilterFunc = func(resource *models.Resource, filterConfig *models.FilterConfig) *models.Resource {
+	result := copyResource(resource, filterConfig)
+	if result != nil {
+		result.Provider = rewriteProvider(result.Provider, filterConfig.ProviderRewrites)
+	}
+	return result
+}
+
+func copyResource(resource *models.Resource, filterConfig *models.FilterConfig) *models.Resource {
 	for _, globalResource := range filterConfig.GlobalResourceTypes {
 		if resource.Type == globalResource {
+			rewriteAttributes(resource, filterConfig.Rewrites)
 			return resource
 		}
 	}
 	for _, filter := range filterConfig.Filters {
 		if resource.Mode == "managed" && resource.Module == filter.FilterProperties.Module && resource.Name == filter.FilterProperties.Name && resource.Type == filter.FilterProperties.Type {
+			rewriteAttributes(resource, filterConfig.Rewrites)
 			if filter.NewProperties.Name != "" {
 				resource.Name = filter.NewProperties.Name
 			}
@@ -50,6 +61,34 @@ var CopyResourceFilterFunc = func(resource *models.Resource, filterConfig *model
 	return nil
 }
 
+// rewriteProvider replaces the source address prefix of a provider address
+// such as provider["registry.terraform.io/hashicorp/aws"] using the longest
+// matching rewrite, e.g. registry.terraform.io -> terraform.example.com
+func rewriteProvider(address string, rewrites map[string]string) string {
+	const prefix = "provider[\""
+	start := strings.Index(address, prefix)
+	if start < 0 || len(rewrites) == 0 {
+		return address
+	}
+	start += len(prefix)
+	end := strings.Index(address[start:], "\"")
+	if end < 0 {
+		return address
+	}
+	source := address[start : start+end]
+
+	match := ""
+	for from := range rewrites {
+		if (source == from || strings.HasPrefix(source, from+"/")) && len(from) > len(match) {
+			match = from
+		}
+	}
+	if match == "" {
+		return address
+	}
+	return address[:start] + rewrites[match] + source[len(match):] + address[start+end:]
+}
+
 // DeleteResourceFilterFunc &
 var DeleteResourceFilterFunc = func(resource *models.Resource, filterConfig *models.FilterConfig) *models.Resource {
 	for _, globalResource := range filterConfig.GlobalResourceTypes {
@@ -67,6 +106,16 @@ var DeleteResourceFilterFunc = func(resource *models.Resource, filterConfig *mod
 	return resource
 }
 
+// ReadVariables returns the terraform variables a filter config file sets on
+// new workspaces
+func ReadVariables(configFileName string) (map[string]interface{}, error) {
+	filterConfig, err := readFiltersFromFile(configFileName)
+	if err != nil {
+		return nil, tfdrerrors.ErrReadFilterFile{Err: err}
+	}
+	return filterConfig.Variables, nil
+}
+
 func readFiltersFromFile(configFileName string) (*models.FilterConfig, error) {
 	filterConfigFile, err := os.Open(configFileName)
 	if err != nil {
@@ -64,6 +64,40 @@ func (s *TestSuite) TestDeleteStateFilter() {
 	s.False(contains(fr, "module.test_module_2", "managed", "type_2"))
 }
 
+func (s *TestSuite) TestCopyStateFilterRewritesProvider() {
+	filterConfig := &models.FilterConfig{
+		GlobalResourceTypes: []string{"aws_iam_role"},
+		ProviderRewrites:    map[string]string{"registry.terraform.io": "terraform.example.com"},
+	}
+	resource := &models.Resource{
+		Mode:     "managed",
+		Type:     "aws_iam_role",
+		Name:     "role",
+		Provider: `provider["registry.terraform.io/hashicorp/aws"]`,
+	}
+
+	result := CopyResourceFilterFunc(resource, filterConfig)
+	s.Equal(`provider["terraform.example.com/hashicorp/aws"]`, result.Provider)
+}
+
+func (s *TestSuite) TestRewriteProvider() {
+	rewrites := map[string]string{
+		"registry.terraform.io":           "mirror.example.com",
+		"registry.terraform.io/hashicorp": "mirror.example.com/approved",
+	}
+	tests := map[string]string{
+		`provider["registry.terraform.io/hashicorp/aws"]`:            `provider["mirror.example.com/approved/aws"]`,
+		`provider["registry.terraform.io/hashicorp/aws"].west`:       `provider["mirror.example.com/approved/aws"].west`,
+		`module.a.provider["registry.terraform.io/datadog/datadog"]`: `module.a.provider["mirror.example.com/datadog/datadog"]`,
+		`provider["registry.terraform.io.evil.com/hashicorp/aws"]`:   `provider["registry.terraform.io.evil.com/hashicorp/aws"]`,
+		"provider.aws": "provider.aws",
+	}
+	for address, expected := range tests {
+		s.Equal(expected, rewriteProvider(address, rewrites), address)
+	}
+	s.Equal("provider.aws", rewriteProvider("provider.aws", nil))
+}
+
 func get(s []models.Resource, module string, mode string, typ string) models.Resource {
 	for _, a := range s {
 		if a.Module == module && a.Mode == mode && a.Type == typ {
@@ -0,0 +1,44 @@
+package filter
+
+import "github.com/mupuri/go-tfdr/internal/models"
+
+// TaintedStatus is the status terraform gives instances that failed to
+// create or were tainted by hand, which it replaces on the next apply
+const TaintedStatus = "tainted"
+
+// DropDeposed removes the deposed instances of resources, the leftovers of
+// create_before_destroy replacements that failed to destroy them. Terraform
+// destroys deposed instances on the next apply, which in a DR workspace
+// would destroy resources the primary still uses. It returns the number of
+// instances removed.
+func DropDeposed(resources []models.Resource) int {
+	dropped := 0
+	for r := range resources {
+		kept := resources[r].Instances[:0]
+		for _, i := range resources[r].Instances {
+			if i.Deposed != "" {
+				dropped++
+				continue
+			}
+			kept = append(kept, i)
+		}
+		resources[r].Instances = kept
+	}
+	return dropped
+}
+
+// Untaint clears the tainted status of instances, so the first apply in a DR
+// workspace doesn't replace them, and returns the number of instances
+// untainted
+func Untaint(resources []models.Resource) int {
+	untainted := 0
+	for r := range resources {
+		for i := range resources[r].Instances {
+			if resources[r].Instances[i].Status == TaintedStatus {
+				resources[r].Instances[i].Status = ""
+				untainted++
+			}
+		}
+	}
+	return untainted
+}
@@ -0,0 +1,36 @@
+package filter
+
+import (
+	"github.com/mupuri/go-tfdr/internal/models"
+)
+
+func instanceResources() []models.Resource {
+	return []models.Resource{
+		{
+			Type: "aws_instance",
+			Instances: []models.Instance{
+				{IndexKey: float64(0), Status: TaintedStatus},
+				{IndexKey: float64(1)},
+				{IndexKey: float64(1), Deposed: "00000001"},
+			},
+		},
+		{
+			Type:      "aws_launch_template",
+			Instances: []models.Instance{{Deposed: "00000002"}},
+		},
+	}
+}
+
+func (s *TestSuite) TestDropDeposed() {
+	resources := instanceResources()
+	s.Equal(2, DropDeposed(resources))
+	s.Equal([]models.Instance{{IndexKey: float64(0), Status: TaintedStatus}, {IndexKey: float64(1)}}, resources[0].Instances)
+	s.Empty(resources[1].Instances)
+}
+
+func (s *TestSuite) TestUntaint() {
+	resources := instanceResources()
+	s.Equal(1, Untaint(resources))
+	s.Equal("", resources[0].Instances[0].Status)
+	s.Equal("00000001", resources[0].Instances[2].Deposed, "deposed instances should be kept")
+}
@@ -0,0 +1,84 @@
+package filter
+
+import (
+	"strings"
+
+	"github.com/mupuri/go-tfdr/internal/models"
+)
+
+// Redact replaces the listed attributes of resources with placeholder and
+// returns the number of values replaced. Attributes are given as
+// <type>.<attribute>[.<nested>...], with * matching any resource type. Every
+// string inside a redacted map or list is replaced; other values are kept so
+// the state still matches the provider schema.
+func Redact(resources []models.Resource, attributes []string, placeholder string) int {
+	redacted := 0
+	for _, a := range attributes {
+		parts := strings.Split(a, ".")
+		if len(parts) < 2 {
+			continue
+		}
+		for r := range resources {
+			if parts[0] != "*" && parts[0] != resources[r].Type {
+				continue
+			}
+			for i := range resources[r].Instances {
+				redacted += redactPath(resources[r].Instances[i].Attributes, parts[1:], placeholder)
+			}
+		}
+	}
+	return redacted
+}
+
+func redactPath(v interface{}, path []string, placeholder string) int {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		e, ok := value[path[0]]
+		if !ok {
+			return 0
+		}
+		if len(path) > 1 {
+			return redactPath(e, path[1:], placeholder)
+		}
+		n := 0
+		value[path[0]], n = redactValue(e, placeholder)
+		return n
+	case []interface{}:
+		// Blocks are stored as lists of objects, the path applies to each
+		n := 0
+		for _, e := range value {
+			n += redactPath(e, path, placeholder)
+		}
+		return n
+	default:
+		return 0
+	}
+}
+
+func redactValue(v interface{}, placeholder string) (interface{}, int) {
+	switch value := v.(type) {
+	case string:
+		if value == "" {
+			return value, 0
+		}
+		return placeholder, 1
+	case map[string]interface{}:
+		n := 0
+		for k, e := range value {
+			var c int
+			value[k], c = redactValue(e, placeholder)
+			n += c
+		}
+		return value, n
+	case []interface{}:
+		n := 0
+		for i, e := range value {
+			var c int
+			value[i], c = redactValue(e, placeholder)
+			n += c
+		}
+		return value, n
+	default:
+		return v, 0
+	}
+}
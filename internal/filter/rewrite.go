@@ -0,0 +1,101 @@
+package filter
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/mupuri/go-tfdr/internal/models"
+)
+
+var (
+	arnPattern          = regexp.MustCompile(`arn:([^:\s"]*):([^:\s"]*):([^:\s"]*):([^:\s"]*):`)
+	subscriptionPattern = regexp.MustCompile(`(?i)/subscriptions/([0-9a-f-]{36})`)
+	projectPattern      = regexp.MustCompile(`projects/([a-z][a-z0-9-]{4,28}[a-z0-9])\b`)
+)
+
+// rewriteAttributes applies the failover rewrites to every string in the
+// attributes of a resource's instances. Account ids and regions are replaced
+// inside ARNs, subscription ids inside /subscriptions/ paths and project ids
+// inside projects/ paths. A string that equals a mapped id or region as a
+// whole is replaced too.
+func rewriteAttributes(resource *models.Resource, rewrites models.Rewrites) {
+	if len(rewrites.AWSAccountIDs)+len(rewrites.AzureSubscriptionIDs)+len(rewrites.GCPProjectIDs)+len(rewrites.Regions) == 0 {
+		return
+	}
+	for i := range resource.Instances {
+		for k, v := range resource.Instances[i].Attributes {
+			resource.Instances[i].Attributes[k] = rewriteValue(v, rewrites)
+		}
+	}
+}
+
+func rewriteValue(v interface{}, rewrites models.Rewrites) interface{} {
+	switch value := v.(type) {
+	case string:
+		return rewriteString(value, rewrites)
+	case map[string]interface{}:
+		for k, e := range value {
+			value[k] = rewriteValue(e, rewrites)
+		}
+		return value
+	case []interface{}:
+		for i, e := range value {
+			value[i] = rewriteValue(e, rewrites)
+		}
+		return value
+	default:
+		return v
+	}
+}
+
+func rewriteString(s string, rewrites models.Rewrites) string {
+	for _, m := range []map[string]string{rewrites.AWSAccountIDs, rewrites.AzureSubscriptionIDs, rewrites.GCPProjectIDs, rewrites.Regions} {
+		if to, ok := lookup(m, s); ok {
+			return to
+		}
+	}
+
+	if len(rewrites.AWSAccountIDs)+len(rewrites.Regions) > 0 {
+		s = arnPattern.ReplaceAllStringFunc(s, func(arn string) string {
+			parts := arnPattern.FindStringSubmatch(arn)
+			region, account := parts[3], parts[4]
+			if to, ok := rewrites.Regions[region]; ok {
+				region = to
+			}
+			if to, ok := rewrites.AWSAccountIDs[account]; ok {
+				account = to
+			}
+			return "arn:" + parts[1] + ":" + parts[2] + ":" + region + ":" + account + ":"
+		})
+	}
+	s = replaceGroup(s, subscriptionPattern, rewrites.AzureSubscriptionIDs)
+	s = replaceGroup(s, projectPattern, rewrites.GCPProjectIDs)
+	return s
+}
+
+// replaceGroup replaces the id captured by the pattern's first group
+func replaceGroup(s string, pattern *regexp.Regexp, m map[string]string) string {
+	if len(m) == 0 {
+		return s
+	}
+	return pattern.ReplaceAllStringFunc(s, func(match string) string {
+		id := pattern.FindStringSubmatch(match)[1]
+		if to, ok := lookup(m, id); ok {
+			return strings.Replace(match, id, to, 1)
+		}
+		return match
+	})
+}
+
+// lookup finds a mapping, ignoring case since azure ids are not case sensitive
+func lookup(m map[string]string, key string) (string, bool) {
+	if to, ok := m[key]; ok {
+		return to, true
+	}
+	for from, to := range m {
+		if strings.EqualFold(from, key) {
+			return to, true
+		}
+	}
+	return "", false
+}
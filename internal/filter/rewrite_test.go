@@ -0,0 +1,64 @@
+package filter
+
+import (
+	"github.com/mupuri/go-tfdr/internal/models"
+)
+
+var testRewrites = models.Rewrites{
+	AWSAccountIDs:        map[string]string{"111111111111": "222222222222"},
+	AzureSubscriptionIDs: map[string]string{"00000000-0000-0000-0000-000000000001": "00000000-0000-0000-0000-000000000002"},
+	GCPProjectIDs:        map[string]string{"prod-primary": "prod-dr"},
+	Regions:              map[string]string{"us-east-1": "us-west-2"},
+}
+
+func (s *TestSuite) TestRewriteString() {
+	tests := map[string]string{
+		"arn:aws:s3:::prod-logs":                   "arn:aws:s3:::prod-logs",
+		"arn:aws:iam::111111111111:role/deploy":    "arn:aws:iam::222222222222:role/deploy",
+		"arn:aws:sqs:us-east-1:111111111111:queue": "arn:aws:sqs:us-west-2:222222222222:queue",
+		"arn:aws:sqs:eu-west-1:333333333333:queue": "arn:aws:sqs:eu-west-1:333333333333:queue",
+		"111111111111":                             "222222222222",
+		"us-east-1":                                "us-west-2",
+		"us-east-1a":                               "us-east-1a",
+		"/SUBSCRIPTIONS/00000000-0000-0000-0000-000000000001/resourceGroups/rg": "/SUBSCRIPTIONS/00000000-0000-0000-0000-000000000002/resourceGroups/rg",
+		"projects/prod-primary/zones/us-central1-a/instances/web":               "projects/prod-dr/zones/us-central1-a/instances/web",
+		"projects/prod-primary-2/global/networks/default":                       "projects/prod-primary-2/global/networks/default",
+		"prod-primary": "prod-dr",
+		`{"Statement":[{"Resource":"arn:aws:s3:::b","Principal":{"AWS":"arn:aws:iam::111111111111:root"}}]}`: `{"Statement":[{"Resource":"arn:aws:s3:::b","Principal":{"AWS":"arn:aws:iam::222222222222:root"}}]}`,
+	}
+	for from, expected := range tests {
+		s.Equal(expected, rewriteString(from, testRewrites), from)
+	}
+}
+
+func (s *TestSuite) TestCopyStateFilterRewritesAttributes() {
+	filterConfig := &models.FilterConfig{
+		Filters: []models.Filter{{
+			FilterProperties: models.FilterProperties{Type: "aws_sqs_queue", Name: "jobs"},
+			NewProperties:    models.NewProperties{Attributes: map[string]interface{}{"region": "us-east-1"}},
+		}},
+		Rewrites: testRewrites,
+	}
+	resource := &models.Resource{
+		Mode: "managed",
+		Type: "aws_sqs_queue",
+		Name: "jobs",
+		Instances: []models.Instance{{
+			Attributes: map[string]interface{}{
+				"arn":    "arn:aws:sqs:us-east-1:111111111111:jobs",
+				"tags":   map[string]interface{}{"owner": "111111111111"},
+				"policy": []interface{}{"arn:aws:iam::111111111111:root"},
+				"count":  float64(1),
+			},
+		}},
+	}
+
+	result := CopyResourceFilterFunc(resource, filterConfig)
+	attributes := result.Instances[0].Attributes
+	s.Equal("arn:aws:sqs:us-west-2:222222222222:jobs", attributes["arn"])
+	s.Equal("222222222222", attributes["tags"].(map[string]interface{})["owner"])
+	s.Equal("arn:aws:iam::222222222222:root", attributes["policy"].([]interface{})[0])
+	s.Equal(float64(1), attributes["count"])
+	// explicit new_properties attributes are applied after the rewrites
+	s.Equal("us-east-1", attributes["region"])
+}
@@ -0,0 +1,44 @@
+package inventory
+
+import "sort"
+
+// Duplicate is a physical resource, identified by type and provider id, that
+// is managed from more than one workspace
+type Duplicate struct {
+	Type  string
+	ID    string
+	Items []Item
+}
+
+// Duplicates returns the managed resources whose id appears in the state of
+// more than one workspace, ordered by type and id
+func Duplicates(items []Item) []Duplicate {
+	type key struct{ typ, id string }
+	groups := make(map[key][]Item)
+	for _, i := range items {
+		if i.Mode != "managed" || i.ID == "" {
+			continue
+		}
+		k := key{i.Type, i.ID}
+		groups[k] = append(groups[k], i)
+	}
+
+	duplicates := make([]Duplicate, 0)
+	for k, group := range groups {
+		workspaces := make(map[string]bool)
+		for _, i := range group {
+			workspaces[i.Workspace] = true
+		}
+		if len(workspaces) > 1 {
+			duplicates = append(duplicates, Duplicate{Type: k.typ, ID: k.id, Items: group})
+		}
+	}
+
+	sort.Slice(duplicates, func(i, j int) bool {
+		if duplicates[i].Type != duplicates[j].Type {
+			return duplicates[i].Type < duplicates[j].Type
+		}
+		return duplicates[i].ID < duplicates[j].ID
+	})
+	return duplicates
+}
@@ -0,0 +1,27 @@
+package inventory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicates(t *testing.T) {
+	items := append(FromState("prod", testState()), FromState("dr", testState())...)
+	items = append(items, Item{Workspace: "prod", Mode: "managed", Type: "aws_instance", Name: "web", ID: "i-123"})
+
+	duplicates := Duplicates(items)
+	assert.Len(t, duplicates, 2)
+	assert.Equal(t, "aws_s3_bucket", duplicates[0].Type)
+	assert.Equal(t, "prod-logs-a", duplicates[0].ID)
+	assert.Equal(t, []Item{items[0], items[3]}, duplicates[0].Items)
+	assert.Equal(t, "prod-logs-b", duplicates[1].ID)
+}
+
+func TestDuplicatesWithinOneWorkspace(t *testing.T) {
+	items := []Item{
+		{Workspace: "prod", Mode: "managed", Type: "aws_iam_role", Name: "a", ID: "role"},
+		{Workspace: "prod", Mode: "managed", Type: "aws_iam_role", Name: "b", ID: "role"},
+	}
+	assert.Empty(t, Duplicates(items))
+}
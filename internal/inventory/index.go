@@ -0,0 +1,54 @@
+package inventory
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/config/file"
+)
+
+// Index is a cached inventory of an organization, saved next to the config
+// file so repeated searches don't have to download every state again
+type Index struct {
+	Organization string    `json:"organization"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Items        []Item    `json:"items"`
+}
+
+// IndexPath returns the default location of the cached index
+func IndexPath() string {
+	return filepath.Join(filepath.Dir(file.Path()), "inventory.json")
+}
+
+// LoadIndex reads a cached index, returning nil when there is none
+func LoadIndex(path string) (*Index, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var idx Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, err
+	}
+	return &idx, nil
+}
+
+// SaveIndex writes a cached index
+func SaveIndex(path string, idx *Index) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	return file.Write(path, data)
+}
+
+// Fresh reports whether the index is for the organization and younger than maxAge
+func (idx *Index) Fresh(organization string, maxAge time.Duration, now time.Time) bool {
+	return idx != nil && idx.Organization == organization && now.Sub(idx.UpdatedAt) < maxAge
+}
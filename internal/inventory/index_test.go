@@ -0,0 +1,42 @@
+package inventory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndexRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inventory")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "inventory.json")
+
+	idx, err := LoadIndex(path)
+	assert.NoError(t, err)
+	assert.Nil(t, idx)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	saved := &Index{Organization: "team", UpdatedAt: now, Items: FromState("prod", testState())}
+	assert.NoError(t, SaveIndex(path, saved))
+
+	idx, err = LoadIndex(path)
+	assert.NoError(t, err)
+	assert.Equal(t, saved, idx)
+}
+
+func TestIndexFresh(t *testing.T) {
+	now := time.Now()
+	idx := &Index{Organization: "team", UpdatedAt: now.Add(-30 * time.Minute)}
+
+	assert.True(t, idx.Fresh("team", time.Hour, now))
+	assert.False(t, idx.Fresh("team", 10*time.Minute, now))
+	assert.False(t, idx.Fresh("other", time.Hour, now))
+
+	var missing *Index
+	assert.False(t, missing.Fresh("team", time.Hour, now))
+}
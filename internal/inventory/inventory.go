@@ -0,0 +1,109 @@
+package inventory
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mupuri/go-tfdr/internal/models"
+)
+
+// Item is one resource instance found in a workspace state. An item with
+// Error set stands for a workspace whose state couldn't be read.
+type Item struct {
+	Workspace string `json:"workspace"`
+	Module    string `json:"module,omitempty"`
+	Mode      string `json:"mode"`
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Index     string `json:"index,omitempty"`
+	Provider  string `json:"provider"`
+	ID        string `json:"id,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+var csvHeader = []string{"workspace", "module", "mode", "type", "name", "index", "provider", "id", "error"}
+
+// FromState returns an item for every resource instance in a workspace state
+func FromState(workspace string, state *models.State) []Item {
+	items := make([]Item, 0)
+	if state == nil {
+		return items
+	}
+	for _, r := range state.Resources {
+		for _, i := range r.Instances {
+			item := Item{
+				Workspace: workspace,
+				Module:    r.Module,
+				Mode:      r.Mode,
+				Type:      r.Type,
+				Name:      r.Name,
+				Provider:  ProviderName(r.Provider),
+			}
+			if i.IndexKey != nil {
+				item.Index = fmt.Sprint(i.IndexKey)
+			}
+			if id, ok := i.Attributes["id"].(string); ok {
+				item.ID = id
+			}
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ProviderName shortens a provider address such as
+// provider["registry.terraform.io/hashicorp/aws"].west to aws
+func ProviderName(address string) string {
+	name := strings.TrimPrefix(address, "provider.")
+	name = strings.TrimPrefix(name, "provider[\"")
+	if i := strings.Index(name, "\""); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// Address returns the resource address of an item, e.g. module.a.aws_s3_bucket.logs
+func (i Item) Address() string {
+	addr := i.Type + "." + i.Name
+	if i.Mode == "data" {
+		addr = "data." + addr
+	}
+	if i.Module != "" {
+		addr = i.Module + "." + addr
+	}
+	if i.Index != "" {
+		addr = fmt.Sprintf("%s[%s]", addr, i.Index)
+	}
+	return addr
+}
+
+// WriteCSV writes items as CSV with a header row
+func WriteCSV(w io.Writer, items []Item) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, i := range items {
+		if err := cw.Write([]string{i.Workspace, i.Module, i.Mode, i.Type, i.Name, i.Index, i.Provider, i.ID, i.Error}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes items as an indented JSON array
+func WriteJSON(w io.Writer, items []Item) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(items)
+}
@@ -0,0 +1,67 @@
+package inventory
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mupuri/go-tfdr/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func testState() *models.State {
+	return &models.State{
+		Resources: []models.Resource{
+			{
+				Module:   "module.logs",
+				Mode:     "managed",
+				Type:     "aws_s3_bucket",
+				Name:     "logs",
+				Provider: `provider["registry.terraform.io/hashicorp/aws"].west`,
+				Instances: []models.Instance{
+					{IndexKey: "a", Attributes: map[string]interface{}{"id": "prod-logs-a"}},
+					{IndexKey: "b", Attributes: map[string]interface{}{"id": "prod-logs-b"}},
+				},
+			},
+			{
+				Mode:      "data",
+				Type:      "aws_caller_identity",
+				Name:      "current",
+				Provider:  "provider.aws",
+				Instances: []models.Instance{{}},
+			},
+		},
+	}
+}
+
+func TestFromState(t *testing.T) {
+	items := FromState("prod", testState())
+	assert.Equal(t, []Item{
+		{Workspace: "prod", Module: "module.logs", Mode: "managed", Type: "aws_s3_bucket", Name: "logs", Index: "a", Provider: "aws", ID: "prod-logs-a"},
+		{Workspace: "prod", Module: "module.logs", Mode: "managed", Type: "aws_s3_bucket", Name: "logs", Index: "b", Provider: "aws", ID: "prod-logs-b"},
+		{Workspace: "prod", Mode: "data", Type: "aws_caller_identity", Name: "current", Provider: "aws"},
+	}, items)
+}
+
+func TestFromNilState(t *testing.T) {
+	assert.Empty(t, FromState("prod", nil))
+}
+
+func TestAddress(t *testing.T) {
+	items := FromState("prod", testState())
+	assert.Equal(t, "module.logs.aws_s3_bucket.logs[a]", items[0].Address())
+	assert.Equal(t, "data.aws_caller_identity.current", items[2].Address())
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCSV(&buf, FromState("prod", testState())[:1])
+	assert.NoError(t, err)
+	assert.Equal(t, "workspace,module,mode,type,name,index,provider,id,error\nprod,module.logs,managed,aws_s3_bucket,logs,a,aws,prod-logs-a,\n", buf.String())
+}
+
+func TestWriteCSVWithError(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCSV(&buf, []Item{{Workspace: "dev", Error: "not found"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "workspace,module,mode,type,name,index,provider,id,error\ndev,,,,,,,,not found\n", buf.String())
+}
@@ -0,0 +1,80 @@
+package inventory
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Query matches inventory items. It is written as type.field~value for a
+// substring match or type.field=value for an exact match, e.g.
+// aws_s3_bucket.name~prod-logs. The type may be * or left out to match any
+// type, and a bare type such as aws_s3_bucket matches every resource of it.
+type Query struct {
+	Type  string
+	Field string
+	Value string
+	Exact bool
+}
+
+var queryFields = map[string]func(Item) string{
+	"workspace": func(i Item) string { return i.Workspace },
+	"module":    func(i Item) string { return i.Module },
+	"name":      func(i Item) string { return i.Name },
+	"index":     func(i Item) string { return i.Index },
+	"provider":  func(i Item) string { return i.Provider },
+	"id":        func(i Item) string { return i.ID },
+	"address":   func(i Item) string { return i.Address() },
+}
+
+// ParseQuery parses a search query
+func ParseQuery(query string) (Query, error) {
+	var q Query
+	op := strings.IndexAny(query, "~=")
+	if op < 0 {
+		if query == "" {
+			return q, fmt.Errorf("Empty search query")
+		}
+		q.Type = query
+		return q, nil
+	}
+
+	left := query[:op]
+	q.Exact = query[op] == '='
+	q.Value = query[op+1:]
+	if dot := strings.LastIndex(left, "."); dot >= 0 {
+		q.Type, q.Field = left[:dot], left[dot+1:]
+	} else {
+		q.Field = left
+	}
+	if _, ok := queryFields[q.Field]; !ok {
+		return q, fmt.Errorf("Unknown search field %q in query %q", q.Field, query)
+	}
+	return q, nil
+}
+
+// Match reports whether an item satisfies the query
+func (q Query) Match(i Item) bool {
+	if q.Type != "" && q.Type != "*" && q.Type != i.Type {
+		return false
+	}
+	if q.Field == "" {
+		return true
+	}
+	value := queryFields[q.Field](i)
+	if q.Exact {
+		return value == q.Value
+	}
+	return strings.Contains(strings.ToLower(value), strings.ToLower(q.Value))
+}
+
+// Search returns the items matching the query, leaving out workspaces whose
+// state couldn't be read
+func Search(items []Item, q Query) []Item {
+	matches := make([]Item, 0)
+	for _, i := range items {
+		if i.Error == "" && q.Match(i) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,46 @@
+package inventory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuery(t *testing.T) {
+	q, err := ParseQuery("aws_s3_bucket.name~prod-logs")
+	assert.NoError(t, err)
+	assert.Equal(t, Query{Type: "aws_s3_bucket", Field: "name", Value: "prod-logs"}, q)
+
+	q, err = ParseQuery("id=prod-logs-a")
+	assert.NoError(t, err)
+	assert.Equal(t, Query{Field: "id", Value: "prod-logs-a", Exact: true}, q)
+
+	q, err = ParseQuery("aws_s3_bucket")
+	assert.NoError(t, err)
+	assert.Equal(t, Query{Type: "aws_s3_bucket"}, q)
+
+	_, err = ParseQuery("aws_s3_bucket.colour~red")
+	assert.Error(t, err)
+
+	_, err = ParseQuery("")
+	assert.Error(t, err)
+}
+
+func TestSearch(t *testing.T) {
+	items := FromState("prod", testState())
+
+	q, _ := ParseQuery("aws_s3_bucket.id~PROD-LOGS")
+	assert.Len(t, Search(items, q), 2)
+
+	q, _ = ParseQuery("*.id=prod-logs-b")
+	assert.Equal(t, []Item{items[1]}, Search(items, q))
+
+	q, _ = ParseQuery("aws_caller_identity")
+	assert.Equal(t, []Item{items[2]}, Search(items, q))
+
+	q, _ = ParseQuery("aws_instance.name~logs")
+	assert.Empty(t, Search(items, q))
+
+	q, _ = ParseQuery("workspace=dev")
+	assert.Empty(t, Search(append(items, Item{Workspace: "dev", Error: "not found"}), q))
+}
@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"io/ioutil"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Fields are structured key/value pairs attached to log entries
+type Fields map[string]interface{}
+
+// Logger is the structured logger used by tfdr's internal packages. It is
+// passed to them, so tests can supply their own instead of the global logrus
+// logger.
+type Logger interface {
+	Tracef(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	// WithFields returns a logger that adds fields to every entry
+	WithFields(fields Fields) Logger
+	// TraceEnabled reports whether trace entries are written, so expensive
+	// trace output can be skipped
+	TraceEnabled() bool
+}
+
+type logrusLogger struct {
+	entry *logrus.Entry
+}
+
+// FromLogrus adapts a logrus logger
+func FromLogrus(l *logrus.Logger) Logger {
+	return logrusLogger{entry: logrus.NewEntry(l)}
+}
+
+// Default returns a logger writing through the standard logrus logger
+// configured by InitLogger
+func Default() Logger {
+	return FromLogrus(logrus.StandardLogger())
+}
+
+// Discard returns a logger that drops every entry
+func Discard() Logger {
+	l := logrus.New()
+	l.SetOutput(ioutil.Discard)
+	l.SetLevel(logrus.PanicLevel)
+	return FromLogrus(l)
+}
+
+func (l logrusLogger) Tracef(format string, args ...interface{}) { l.entry.Tracef(format, args...) }
+func (l logrusLogger) Debugf(format string, args ...interface{}) { l.entry.Debugf(format, args...) }
+func (l logrusLogger) Infof(format string, args ...interface{})  { l.entry.Infof(format, args...) }
+func (l logrusLogger) Warnf(format string, args ...interface{})  { l.entry.Warnf(format, args...) }
+func (l logrusLogger) Errorf(format string, args ...interface{}) { l.entry.Errorf(format, args...) }
+
+func (l logrusLogger) WithFields(fields Fields) Logger {
+	return logrusLogger{entry: l.entry.WithFields(logrus.Fields(fields))}
+}
+
+func (l logrusLogger) TraceEnabled() bool {
+	return l.entry.Logger.IsLevelEnabled(logrus.TraceLevel)
+}
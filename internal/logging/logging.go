@@ -1,7 +1,10 @@
 package logging
 
 import (
+	"os"
+
 	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/console"
 	"github.com/sirupsen/logrus"
 )
 
@@ -19,5 +22,7 @@ func InitLogger() {
 		PadLevelText:   true,
 		DisableQuote:   true,
 		DisableSorting: true,
+		ForceColors:    console.ColorsFor(os.Stderr),
+		DisableColors:  !console.ColorsFor(os.Stderr),
 	})
 }
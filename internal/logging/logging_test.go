@@ -1,12 +1,16 @@
 package logging
 
 import (
+	"bytes"
 	"os"
 	"testing"
 
 	"github.com/mupuri/go-tfdr/internal/config"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestInitLogger(t *testing.T) {
@@ -22,3 +26,39 @@ func TestDefault(t *testing.T) {
 	InitLogger()
 	assert.Equal(t, logrus.InfoLevel, logrus.GetLevel())
 }
+
+func TestFromLogrus(t *testing.T) {
+	l := logrus.New()
+	var out bytes.Buffer
+	l.SetOutput(&out)
+	l.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true, DisableQuote: true})
+	l.SetLevel(logrus.DebugLevel)
+
+	log := FromLogrus(l).WithFields(Fields{"request_id": "abc"})
+	log.Debugf("sent %d", 1)
+	log.Tracef("hidden")
+
+	assert.Equal(t, "level=debug msg=sent 1 request_id=abc\n", out.String())
+	assert.False(t, log.TraceEnabled())
+	l.SetLevel(logrus.TraceLevel)
+	assert.True(t, log.TraceEnabled())
+}
+
+func TestFromZap(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	log := FromZap(zap.New(core)).WithFields(Fields{"request_id": "abc"})
+	log.Infof("sent %d", 1)
+	log.Debugf("hidden")
+
+	entries := logs.AllUntimed()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "sent 1", entries[0].Message)
+	assert.Equal(t, map[string]interface{}{"request_id": "abc"}, entries[0].ContextMap())
+	assert.False(t, log.TraceEnabled())
+}
+
+func TestDiscard(t *testing.T) {
+	log := Discard()
+	log.Errorf("dropped")
+	assert.False(t, log.WithFields(Fields{"a": 1}).TraceEnabled())
+}
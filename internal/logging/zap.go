@@ -0,0 +1,34 @@
+package logging
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type zapLogger struct {
+	sugar *zap.SugaredLogger
+}
+
+// FromZap adapts a zap logger. zap has no trace level, so trace entries are
+// written at debug level.
+func FromZap(l *zap.Logger) Logger {
+	return zapLogger{sugar: l.Sugar()}
+}
+
+func (l zapLogger) Tracef(format string, args ...interface{}) { l.sugar.Debugf(format, args...) }
+func (l zapLogger) Debugf(format string, args ...interface{}) { l.sugar.Debugf(format, args...) }
+func (l zapLogger) Infof(format string, args ...interface{})  { l.sugar.Infof(format, args...) }
+func (l zapLogger) Warnf(format string, args ...interface{})  { l.sugar.Warnf(format, args...) }
+func (l zapLogger) Errorf(format string, args ...interface{}) { l.sugar.Errorf(format, args...) }
+
+func (l zapLogger) WithFields(fields Fields) Logger {
+	args := make([]interface{}, 0, len(fields)*2)
+	for k, v := range fields {
+		args = append(args, k, v)
+	}
+	return zapLogger{sugar: l.sugar.With(args...)}
+}
+
+func (l zapLogger) TraceEnabled() bool {
+	return l.sugar.Desugar().Core().Enabled(zapcore.DebugLevel)
+}
@@ -1,6 +1,11 @@
 package models
 
 type FilterConfig struct {
-	GlobalResourceTypes []string `json:"global_resource_types"`
-	Filters             []Filter `json:"filters"`
+	GlobalResourceTypes []string          `json:"global_resource_types"`
+	Filters             []Filter          `json:"filters"`
+	ProviderRewrites    map[string]string `json:"provider_rewrites"`
+	Rewrites            Rewrites          `json:"rewrites"`
+	// Variables are terraform variables set on the new workspace, e.g. the
+	// region it is restored to
+	Variables map[string]interface{} `json:"variables"`
 }
@@ -2,6 +2,8 @@ package models
 
 type Instance struct {
 	IndexKey      interface{}            `json:"index_key"`
+	Status        string                 `json:"status,omitempty"`
+	Deposed       string                 `json:"deposed,omitempty"`
 	SchemaVersion interface{}            `json:"schema_version"`
 	Attributes    map[string]interface{} `json:"attributes"`
 	Private       string                 `json:"private"`
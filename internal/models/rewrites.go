@@ -0,0 +1,8 @@
+package models
+
+type Rewrites struct {
+	AWSAccountIDs        map[string]string `json:"aws_account_ids"`
+	AzureSubscriptionIDs map[string]string `json:"azure_subscription_ids"`
+	GCPProjectIDs        map[string]string `json:"gcp_project_ids"`
+	Regions              map[string]string `json:"regions"`
+}
@@ -0,0 +1,199 @@
+package modules
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Module is a module call found in a terraform configuration
+type Module struct {
+	Name    string
+	File    string
+	Line    int
+	Source  string
+	Version string
+}
+
+var (
+	moduleBlock    = regexp.MustCompile(`^\s*module\s+"([^"]+)"\s*\{`)
+	sourceArg      = regexp.MustCompile(`^\s*source\s*=\s*"([^"]*)"`)
+	versionArg     = regexp.MustCompile(`^\s*version\s*=\s*"([^"]*)"`)
+	registrySource = regexp.MustCompile(`^([a-zA-Z0-9.-]+\.[a-zA-Z]+(:\d+)?/)?[0-9A-Za-z-_]+/[0-9A-Za-z-_]+/[0-9a-z]+(//.*)?$`)
+	exactVersion   = regexp.MustCompile(`^=?\s*v?\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?$`)
+)
+
+// Scan finds the module calls in the .tf files of a directory, following
+// local module sources into their own directories
+func Scan(dir string) ([]Module, error) {
+	found := make([]Module, 0)
+	seen := make(map[string]bool)
+	if err := scanDir(dir, &found, seen); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].File != found[j].File {
+			return found[i].File < found[j].File
+		}
+		return found[i].Line < found[j].Line
+	})
+	return found, nil
+}
+
+func scanDir(dir string, found *[]Module, seen map[string]bool) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if seen[abs] {
+		return nil
+	}
+	seen[abs] = true
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		modules, err := scanFile(f)
+		if err != nil {
+			return err
+		}
+		for _, m := range modules {
+			*found = append(*found, m)
+			if isLocal(m.Source) {
+				if err := scanDir(filepath.Join(dir, m.Source), found, seen); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func scanFile(fileName string) ([]Module, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read %s. Err: %v", fileName, err)
+	}
+
+	modules := make([]Module, 0)
+	var current *Module
+	depth := 0
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if current == nil {
+			if match := moduleBlock.FindStringSubmatch(text); match != nil {
+				current = &Module{Name: match[1], File: fileName, Line: line}
+				depth = braces(text)
+			}
+			continue
+		}
+		if depth == 1 {
+			if match := sourceArg.FindStringSubmatch(text); match != nil {
+				current.Source = match[1]
+			}
+			if match := versionArg.FindStringSubmatch(text); match != nil {
+				current.Version = match[1]
+			}
+		}
+		if depth += braces(text); depth <= 0 {
+			modules = append(modules, *current)
+			current = nil
+		}
+	}
+	return modules, scanner.Err()
+}
+
+// braces returns the change in block depth on a line, ignoring braces in
+// strings and comments
+func braces(line string) int {
+	n := 0
+	inString := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && inString:
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '#' || (c == '/' && i+1 < len(line) && line[i+1] == '/'):
+			return n
+		case c == '{':
+			n++
+		case c == '}':
+			n--
+		}
+	}
+	return n
+}
+
+func isLocal(source string) bool {
+	return strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
+}
+
+// Check returns the problems that would stop a module call from resolving
+// the same code in a DR environment: sources that are not pinned to a fixed
+// version, and sources hosted on one of the unreachable hosts
+func Check(m Module, unreachableHosts []string) []string {
+	problems := make([]string, 0)
+	if m.Source == "" {
+		return append(problems, "no source")
+	}
+	if isLocal(m.Source) {
+		return problems
+	}
+
+	if isVCS(m.Source) {
+		if !strings.Contains(m.Source, "ref=") {
+			problems = append(problems, "VCS module without a ref")
+		}
+	} else if registrySource.MatchString(m.Source) {
+		if m.Version == "" {
+			problems = append(problems, "registry module without a version")
+		} else if !exactVersion.MatchString(strings.TrimSpace(m.Version)) {
+			problems = append(problems, fmt.Sprintf("version %q is not pinned to a single release", m.Version))
+		}
+	}
+
+	host := sourceHost(m.Source)
+	for _, h := range unreachableHosts {
+		if strings.EqualFold(host, h) {
+			problems = append(problems, fmt.Sprintf("hosted on %s, which is unreachable in DR", host))
+		}
+	}
+	return problems
+}
+
+func isVCS(source string) bool {
+	return strings.HasPrefix(source, "git::") || strings.HasPrefix(source, "hg::") ||
+		strings.HasPrefix(source, "git@") || strings.HasPrefix(source, "github.com/") ||
+		strings.HasPrefix(source, "bitbucket.org/")
+}
+
+// sourceHost returns the host a module source is downloaded from
+func sourceHost(source string) string {
+	if i := strings.Index(source, "::"); i >= 0 {
+		source = source[i+2:]
+	}
+	if strings.HasPrefix(source, "git@") {
+		source = strings.TrimPrefix(source, "git@")
+		return strings.SplitN(source, ":", 2)[0]
+	}
+	if u, err := url.Parse(source); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	host := strings.SplitN(source, "/", 2)[0]
+	if !strings.Contains(host, ".") {
+		// registry shorthand such as hashicorp/consul/aws
+		return "registry.terraform.io"
+	}
+	return strings.SplitN(host, ":", 2)[0]
+}
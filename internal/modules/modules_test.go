@@ -0,0 +1,54 @@
+package modules
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScan(t *testing.T) {
+	found, err := Scan("testdata/config")
+	assert.NoError(t, err)
+
+	names := make([]string, 0)
+	for _, m := range found {
+		names = append(names, m.Name)
+	}
+	assert.Equal(t, []string{"vpc", "eks", "network", "dns", "subnets", "loop"}, names)
+	assert.Equal(t, "terraform-aws-modules/vpc/aws", found[0].Source)
+	assert.Equal(t, "2.44.0", found[0].Version)
+	assert.Equal(t, 1, found[0].Line)
+	assert.Equal(t, filepath.Join("testdata", "config", "network", "main.tf"), found[4].File)
+}
+
+func TestCheck(t *testing.T) {
+	unreachable := []string{"git.internal.example.com"}
+	tests := []struct {
+		module   Module
+		problems int
+	}{
+		{Module{Source: "terraform-aws-modules/vpc/aws", Version: "2.44.0"}, 0},
+		{Module{Source: "registry.terraform.io/terraform-aws-modules/vpc/aws", Version: "= 2.44.0"}, 0},
+		{Module{Source: "terraform-aws-modules/eks/aws", Version: "~> 12.0"}, 1},
+		{Module{Source: "terraform-aws-modules/eks/aws"}, 1},
+		{Module{Source: "./network"}, 0},
+		{Module{Source: "github.com/example/terraform-subnets"}, 1},
+		{Module{Source: "github.com/example/terraform-subnets?ref=v1.0.0"}, 0},
+		{Module{Source: "git::https://git.internal.example.com/infra/dns.git?ref=v1.2.0"}, 1},
+		{Module{Source: "git@git.internal.example.com:infra/dns.git"}, 2},
+		{Module{Source: "app.terraform.io/example/dns/aws", Version: "1.0.0"}, 0},
+		{Module{}, 1},
+	}
+	for _, test := range tests {
+		assert.Len(t, Check(test.module, unreachable), test.problems, test.module.Source)
+	}
+}
+
+func TestSourceHost(t *testing.T) {
+	assert.Equal(t, "registry.terraform.io", sourceHost("terraform-aws-modules/vpc/aws"))
+	assert.Equal(t, "app.terraform.io", sourceHost("app.terraform.io/example/dns/aws"))
+	assert.Equal(t, "git.internal.example.com", sourceHost("git::https://git.internal.example.com/infra/dns.git?ref=v1"))
+	assert.Equal(t, "git.internal.example.com", sourceHost("git@git.internal.example.com:infra/dns.git"))
+	assert.Equal(t, "github.com", sourceHost("github.com/example/terraform-subnets"))
+}
@@ -0,0 +1,25 @@
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "2.44.0"
+
+  tags = {
+    Name = "prod"
+  }
+}
+
+module "eks" {
+  source  = "terraform-aws-modules/eks/aws"
+  version = "~> 12.0"
+}
+
+# module "disabled" {
+#   source = "git::https://git.internal.example.com/disabled.git"
+# }
+
+module "network" {
+  source = "./network"
+}
+
+module "dns" {
+  source = "git::https://git.internal.example.com/infra/dns.git?ref=v1.2.0"
+}
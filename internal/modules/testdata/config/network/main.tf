@@ -0,0 +1,7 @@
+module "subnets" {
+  source = "github.com/example/terraform-subnets"
+}
+
+module "loop" {
+  source = "../network"
+}
@@ -0,0 +1,234 @@
+// Package notify sends the outcome of long running operations to people who
+// aren't watching the terminal.
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+)
+
+const (
+	defaultSubject = `[tfdr] {{.Operation}} {{if .Err}}failed{{else}}succeeded{{end}}`
+	defaultBody    = `tfdr {{.Operation}} {{if .Err}}failed: {{.Err}}{{else}}succeeded{{end}}
+Operation ID: {{.OperationID}}
+{{if .OnBehalfOf}}On behalf of: {{.OnBehalfOf}}
+{{end}}Started:  {{.Started.Format "2006-01-02 15:04:05 MST"}}
+Finished: {{.Finished.Format "2006-01-02 15:04:05 MST"}}
+{{if .Succeeded}}
+Succeeded:
+{{range .Succeeded}}  {{.}}
+{{end}}{{end}}{{if .Failed}}
+Failed:
+{{range .Failed}}  {{.Workspace}}: {{.Err}}
+{{end}}{{end}}{{if .APICalls}}
+API requests:
+{{range .APICalls}}  {{.Count}} {{.Method}} {{.Endpoint}} ({{.Status}})
+{{end}}{{end}}`
+)
+
+// Outcome is the data the subject and body templates are rendered with
+type Outcome struct {
+	Operation string
+	// OperationID matches the operation_id field of the operation's log lines
+	OperationID string
+	// OnBehalfOf is who the operation was run for, see api.SetOnBehalfOf
+	OnBehalfOf string
+	Started    time.Time
+	Finished   time.Time
+	Err        error
+	Succeeded  []string
+	Failed     []Failure
+	// APICalls are the requests the operation made to Terraform Cloud
+	APICalls []api.APICalls
+}
+
+// Failure is a workspace an operation failed on
+type Failure struct {
+	Workspace string
+	Err       error
+}
+
+// Email sends outcomes over SMTP
+type Email struct {
+	cfg     config.EmailNotification
+	subject *template.Template
+	body    *template.Template
+}
+
+// NewEmail checks the email settings and parses the templates
+func NewEmail(cfg config.EmailNotification) (*Email, error) {
+	if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return nil, fmt.Errorf("Email notifications need host, from and to")
+	}
+	switch cfg.TLS {
+	case "":
+		cfg.TLS = "starttls"
+	case "starttls", "tls", "none":
+	default:
+		return nil, fmt.Errorf("Invalid email tls setting %q, expected starttls, tls or none", cfg.TLS)
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+		if cfg.TLS == "tls" {
+			cfg.Port = 465
+		}
+	}
+	if cfg.Subject == "" {
+		cfg.Subject = defaultSubject
+	}
+	if cfg.Body == "" {
+		cfg.Body = defaultBody
+	}
+
+	subject, err := template.New("subject").Parse(cfg.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid email subject template. Error: %v", err)
+	}
+	body, err := template.New("body").Parse(cfg.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid email body template. Error: %v", err)
+	}
+	return &Email{cfg: cfg, subject: subject, body: body}, nil
+}
+
+// Handler returns an api event handler that emails the outcome of every
+// operation once it finishes. The email is sent before the handler
+// returns, so the process doesn't exit first.
+func (e *Email) Handler(onError func(error)) func(api.Event) {
+	var mu sync.Mutex
+	outcomes := make(map[string]*Outcome)
+	return func(ev api.Event) {
+		mu.Lock()
+		switch ev.Type {
+		case api.OperationStarted:
+			outcomes[ev.OperationID] = &Outcome{Operation: ev.Operation, OperationID: ev.OperationID, OnBehalfOf: ev.OnBehalfOf, Started: ev.Time}
+		case api.WorkspaceCompleted:
+			if o, ok := outcomes[ev.OperationID]; ok {
+				if ev.Err != nil {
+					o.Failed = append(o.Failed, Failure{Workspace: ev.Workspace, Err: ev.Err})
+				} else {
+					o.Succeeded = append(o.Succeeded, ev.Workspace)
+				}
+			}
+		case api.OperationFinished:
+			o, ok := outcomes[ev.OperationID]
+			if !ok {
+				break
+			}
+			delete(outcomes, ev.OperationID)
+			o.Finished = ev.Time
+			o.Err = ev.Err
+			o.APICalls = ev.APICalls
+			mu.Unlock()
+			// Send without the lock, other operations' events shouldn't
+			// wait for the mail server
+			if err := e.Send(o); err != nil {
+				onError(err)
+			}
+			return
+		}
+		mu.Unlock()
+	}
+}
+
+// Send renders the templates with o and sends the email
+func (e *Email) Send(o *Outcome) error {
+	msg, err := e.message(o)
+	if err != nil {
+		return err
+	}
+	return e.deliver(msg)
+}
+
+func (e *Email) message(o *Outcome) ([]byte, error) {
+	var subject, body bytes.Buffer
+	if err := e.subject.Execute(&subject, o); err != nil {
+		return nil, fmt.Errorf("Unable to render email subject. Error: %v", err)
+	}
+	if err := e.body.Execute(&body, o); err != nil {
+		return nil, fmt.Errorf("Unable to render email body. Error: %v", err)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.TrimSpace(strings.ReplaceAll(subject.String(), "\n", " ")))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body.String(), "\n", "\r\n"))
+	return msg.Bytes(), nil
+}
+
+// smtpTimeout bounds connecting to the mail server and the whole SMTP
+// conversation. Emails are sent while the operation finishes, so a mail
+// server that hangs must not hold up the CLI or the API server's queue.
+var smtpTimeout = 30 * time.Second
+
+func (e *Email) deliver(msg []byte) error {
+	addr := net.JoinHostPort(e.cfg.Host, strconv.Itoa(e.cfg.Port))
+	tlsConfig := &tls.Config{ServerName: e.cfg.Host}
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+
+	var conn net.Conn
+	var err error
+	if e.cfg.TLS == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to connect to %s. Error: %v", addr, err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(smtpTimeout)); err != nil {
+		conn.Close()
+		return err
+	}
+	c, err := smtp.NewClient(conn, e.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("Unable to talk to %s. Error: %v", addr, err)
+	}
+	defer c.Close()
+
+	if e.cfg.TLS == "starttls" {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("Unable to start TLS with %s. Error: %v", addr, err)
+		}
+	}
+	if e.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed. Error: %v", err)
+		}
+	}
+	if err := c.Mail(e.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range e.cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
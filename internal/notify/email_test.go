@@ -0,0 +1,143 @@
+package notify
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTP accepts a single message and returns what was sent
+func fakeSMTP(t *testing.T) (int, <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan string, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		var transcript strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			transcript.WriteString(line)
+			switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "DATA":
+				reply("354 go ahead")
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					transcript.WriteString(l)
+				}
+				reply("250 ok")
+			case "QUIT":
+				reply("221 bye")
+				received <- transcript.String()
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return l.Addr().(*net.TCPAddr).Port, received
+}
+
+func TestEmailHandler(t *testing.T) {
+	port, received := fakeSMTP(t)
+	e, err := NewEmail(config.EmailNotification{
+		Host: "127.0.0.1",
+		Port: port,
+		TLS:  "none",
+		From: "tfdr@example.com",
+		To:   []string{"oncall@example.com", "dr@example.com"},
+	})
+	assert.NoError(t, err)
+
+	h := e.Handler(func(err error) { t.Error(err) })
+	start := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	h(api.Event{Type: api.OperationStarted, Operation: "copy", Total: 2, Time: start, OnBehalfOf: "jane@example.com"})
+	h(api.Event{Type: api.WorkspaceCompleted, Operation: "copy", Workspace: "dr-east"})
+	h(api.Event{Type: api.WorkspaceCompleted, Operation: "copy", Workspace: "dr-west", Err: errors.New("locked")})
+	h(api.Event{Type: api.OperationFinished, Operation: "copy", Err: errors.New("Failed to copy state to 1 of 2 workspaces"), Time: start.Add(time.Minute),
+		APICalls: []api.APICalls{{Method: "GET", Endpoint: "workspaces/:id/current-state-version", Status: 200, Count: 2}}})
+
+	select {
+	case msg := <-received:
+		assert.Contains(t, msg, "RCPT TO:<oncall@example.com>")
+		assert.Contains(t, msg, "RCPT TO:<dr@example.com>")
+		assert.Contains(t, msg, "Subject: [tfdr] copy failed\r\n")
+		assert.Contains(t, msg, "Succeeded:\r\n  dr-east\r\n")
+		assert.Contains(t, msg, "  dr-west: locked\r\n")
+		assert.Contains(t, msg, "On behalf of: jane@example.com\r\n")
+		assert.Contains(t, msg, "Started:  2020-10-01 12:00:00 UTC")
+		assert.Contains(t, msg, "API requests:\r\n  2 GET workspaces/:id/current-state-version (200)\r\n")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no email sent")
+	}
+}
+
+func TestEmailTimesOut(t *testing.T) {
+	// A mail server that accepts connections and never answers
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	defer func(timeout time.Duration) { smtpTimeout = timeout }(smtpTimeout)
+	smtpTimeout = 100 * time.Millisecond
+
+	e, err := NewEmail(config.EmailNotification{Host: "127.0.0.1", Port: l.Addr().(*net.TCPAddr).Port, From: "a@example.com", To: []string{"b@example.com"}})
+	assert.NoError(t, err)
+	done := make(chan error, 1)
+	go func() { done <- e.deliver([]byte("Subject: test\r\n\r\ntest")) }()
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery to a hung mail server should time out")
+	}
+}
+
+func TestNewEmail(t *testing.T) {
+	_, err := NewEmail(config.EmailNotification{Host: "smtp.example.com", From: "a@example.com"})
+	assert.Error(t, err, "recipients are required")
+
+	_, err = NewEmail(config.EmailNotification{Host: "smtp.example.com", From: "a@example.com", To: []string{"b@example.com"}, TLS: "ssl"})
+	assert.Error(t, err)
+
+	_, err = NewEmail(config.EmailNotification{Host: "smtp.example.com", From: "a@example.com", To: []string{"b@example.com"}, Subject: "{{.Nope"})
+	assert.Error(t, err)
+
+	e, err := NewEmail(config.EmailNotification{Host: "smtp.example.com", From: "a@example.com", To: []string{"b@example.com"}, TLS: "tls"})
+	assert.NoError(t, err)
+	assert.Equal(t, 465, e.cfg.Port)
+}
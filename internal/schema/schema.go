@@ -0,0 +1,63 @@
+// Package schema builds JSON Schema documents for tfdr's input files from
+// the Go types they are read into, so the schemas can't drift from the code.
+package schema
+
+import (
+	"reflect"
+	"strings"
+)
+
+const draft = "http://json-schema.org/draft-07/schema#"
+
+// Schema is a JSON Schema document
+type Schema map[string]interface{}
+
+// Generate returns the schema of v's type, naming fields after their tag
+// key, e.g. yaml or json. Objects reject unknown properties, so typos in
+// keys are reported.
+func Generate(v interface{}, title string, tag string) Schema {
+	s := of(reflect.TypeOf(v), tag)
+	s["$schema"] = draft
+	s["title"] = title
+	return s
+}
+
+func of(t reflect.Type, tag string) Schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return of(t.Elem(), tag)
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return Schema{"type": "array", "items": of(t.Elem(), tag)}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": of(t.Elem(), tag)}
+	case reflect.Struct:
+		properties := Schema{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			name := strings.Split(f.Tag.Get(tag), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			properties[name] = of(f.Type, tag)
+		}
+		return Schema{"type": "object", "properties": properties, "additionalProperties": false}
+	default:
+		// interface{} values can be anything
+		return Schema{}
+	}
+}
@@ -0,0 +1,42 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type child struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+type parent struct {
+	Name     string                 `yaml:"name"`
+	Port     int                    `yaml:"port,omitempty"`
+	Ratio    float64                `yaml:"ratio"`
+	Tags     []string               `yaml:"tags"`
+	Children map[string]child       `yaml:"children"`
+	Child    *child                 `yaml:"child"`
+	Extra    map[string]interface{} `yaml:"extra"`
+	Skipped  string                 `yaml:"-"`
+	hidden   string
+}
+
+func TestGenerate(t *testing.T) {
+	childSchema := Schema{"type": "object", "properties": Schema{"enabled": Schema{"type": "boolean"}}, "additionalProperties": false}
+	assert.Equal(t, Schema{
+		"$schema": draft,
+		"title":   "test",
+		"type":    "object",
+		"properties": Schema{
+			"name":     Schema{"type": "string"},
+			"port":     Schema{"type": "integer"},
+			"ratio":    Schema{"type": "number"},
+			"tags":     Schema{"type": "array", "items": Schema{"type": "string"}},
+			"children": Schema{"type": "object", "additionalProperties": childSchema},
+			"child":    childSchema,
+			"extra":    Schema{"type": "object", "additionalProperties": Schema{}},
+		},
+		"additionalProperties": false,
+	}, Generate(parent{}, "test", "yaml"))
+}
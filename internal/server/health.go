@@ -0,0 +1,100 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+)
+
+// readyCacheTTL is how long readiness results are reused, so frequent probes
+// don't turn into a stream of Terraform Cloud API requests
+const readyCacheTTL = 30 * time.Second
+
+// Check is the result of one readiness check
+type Check struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+type healthResponse struct {
+	Status string  `json:"status"`
+	Checks []Check `json:"checks,omitempty"`
+}
+
+// readiness caches the results of the readiness checks
+type readiness struct {
+	mu      sync.Mutex
+	checked time.Time
+	checks  []Check
+}
+
+// handleHealth reports that the server is up. It has no dependencies so
+// Kubernetes only restarts tfdr when it stops responding.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// handleReady reports whether the server can run operations: the config is
+// valid, the tokens are accepted and the lock directory is writable
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	s.ready.mu.Lock()
+	if time.Since(s.ready.checked) > readyCacheTTL {
+		s.ready.checks = s.readyChecks()
+		s.ready.checked = time.Now()
+	}
+	checks := s.ready.checks
+	s.ready.mu.Unlock()
+
+	resp := healthResponse{Status: "ok", Checks: checks}
+	status := http.StatusOK
+	for _, c := range checks {
+		if !c.OK {
+			resp.Status = "failing"
+			status = http.StatusServiceUnavailable
+		}
+	}
+	writeJSON(w, status, resp)
+}
+
+func readyChecks() []Check {
+	c := config.GetConfig()
+	checks := []Check{newCheck("config", config.ValidateConfig())}
+	if !checks[0].OK {
+		return checks
+	}
+
+	_, err := api.ValidateToken(c.ReadToken())
+	checks = append(checks, newCheck("read token", err))
+	if c.WriteToken() != c.ReadToken() {
+		_, err = api.ValidateToken(c.WriteToken())
+		checks = append(checks, newCheck("write token", err))
+	}
+	return append(checks, newCheck("lock directory", checkWritable(c.LockDirectory())))
+}
+
+func newCheck(name string, err error) Check {
+	c := Check{Name: name, OK: err == nil}
+	if err != nil {
+		c.Error = err.Error()
+	}
+	return c
+}
+
+// checkWritable creates and removes a file in dir
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0770); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, ".ready-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	s := New(config.APIServer{Tokens: map[string]string{"portal": "secret"}}, logging.Discard())
+	defer s.Close()
+	h := s.Handler()
+
+	resp, data := request(t, h, "GET", "/healthz", "", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"status":"ok"}`, string(data))
+}
+
+func TestReady(t *testing.T) {
+	s := New(config.APIServer{Tokens: map[string]string{"portal": "secret"}}, logging.Discard())
+	defer s.Close()
+	calls := 0
+	tokenErr := errors.New("The token was rejected")
+	s.readyChecks = func() []Check {
+		calls++
+		return []Check{newCheck("config", nil), newCheck("read token", tokenErr)}
+	}
+	h := s.Handler()
+
+	resp, data := request(t, h, "GET", "/readyz", "", "")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	var ready healthResponse
+	assert.NoError(t, json.Unmarshal(data, &ready))
+	assert.Equal(t, "failing", ready.Status)
+	assert.Equal(t, []Check{{Name: "config", OK: true}, {Name: "read token", Error: "The token was rejected"}}, ready.Checks)
+
+	// Results are cached until the config is reloaded
+	tokenErr = nil
+	resp, _ = request(t, h, "GET", "/readyz", "", "")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, 1, calls)
+
+	s.SetConfig(config.APIServer{Tokens: map[string]string{"portal": "secret"}})
+	resp, _ = request(t, h, "GET", "/readyz", "", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, calls)
+}
+
+func TestCheckWritable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tfdr-ready")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, checkWritable(filepath.Join(dir, "locks")))
+	files, err := ioutil.ReadDir(filepath.Join(dir, "locks"))
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+
+	blocked := filepath.Join(dir, "file")
+	assert.NoError(t, ioutil.WriteFile(blocked, nil, 0600))
+	assert.Error(t, checkWritable(filepath.Join(blocked, "locks")))
+}
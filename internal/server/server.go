@@ -0,0 +1,394 @@
+// Package server exposes tfdr operations over an authenticated HTTP API, so
+// portals and chat bots can start restores without running the CLI.
+// Operations run one at a time in the background, as they would from the
+// CLI, and clients poll them for their status.
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+)
+
+// Statuses of an operation
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// queueSize is how many operations can wait to run
+const queueSize = 100
+
+// maxRequestBody limits the size of restore requests, filters included
+const maxRequestBody = 1 << 20
+
+// Finished operations are kept for finishedTTL, and at most maxFinished of
+// them, so a long running server doesn't keep every operation in memory
+const (
+	finishedTTL = 24 * time.Hour
+	maxFinished = 1000
+)
+
+// Operation is an operation started through the API
+type Operation struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	// Caller is the name of the token the operation was started with
+	Caller       string   `json:"caller"`
+	Source       string   `json:"source,omitempty"`
+	Destinations []string `json:"destinations,omitempty"`
+	// OperationID matches the operation_id field of tfdr's log lines
+	OperationID string            `json:"operation_id,omitempty"`
+	Created     time.Time         `json:"created"`
+	Started     *time.Time        `json:"started,omitempty"`
+	Finished    *time.Time        `json:"finished,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	Workspaces  []WorkspaceResult `json:"workspaces"`
+	// APICalls are the requests the operation made to Terraform Cloud
+	APICalls []api.APICalls `json:"api_calls,omitempty"`
+}
+
+// WorkspaceResult is the outcome of an operation on one workspace
+type WorkspaceResult struct {
+	Workspace string `json:"workspace"`
+	Error     string `json:"error,omitempty"`
+}
+
+// RestoreRequest is the body of POST /v1/restores. Filters has the layout of
+// a filters file.
+type RestoreRequest struct {
+	Source        string          `json:"source"`
+	Destinations  []string        `json:"destinations"`
+	Filters       json.RawMessage `json:"filters"`
+	CreateMissing bool            `json:"create_missing"`
+	Approvals     []string        `json:"approvals"`
+	ExecutionMode string          `json:"execution_mode"`
+	AgentPoolID   string          `json:"agent_pool_id"`
+	CleanDeposed  bool            `json:"clean_deposed"`
+	Untaint       bool            `json:"untaint"`
+	TagStatus     bool            `json:"tag_status"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+var errQueueFull = errors.New("too many operations are queued")
+
+// Server serves the API
+type Server struct {
+	mu         sync.Mutex
+	cfg        config.APIServer
+	log        logging.Logger
+	operations map[string]*Operation
+	running    *Operation
+	queue      chan func()
+	ready      readiness
+	// copyState and readyChecks are replaced in tests
+	copyState   func(source string, destinations []string, filterFile string, opts api.CopyOptions) error
+	readyChecks func() []Check
+	// stopEvents stops recording api events
+	stopEvents func()
+}
+
+// New returns a server with the api_server settings, logging to log, and
+// starts running queued operations
+func New(cfg config.APIServer, log logging.Logger) *Server {
+	s := &Server{
+		cfg:         cfg,
+		log:         log,
+		operations:  make(map[string]*Operation),
+		queue:       make(chan func(), queueSize),
+		copyState:   api.CopyTFStateToMany,
+		readyChecks: readyChecks,
+	}
+	s.stopEvents = api.AddEventHandler(s.record)
+	go func() {
+		for run := range s.queue {
+			run()
+		}
+	}()
+	return s
+}
+
+// SetConfig replaces the api_server settings, e.g. after the config is
+// reloaded
+func (s *Server) SetConfig(cfg config.APIServer) {
+	s.mu.Lock()
+	s.cfg = cfg
+	s.mu.Unlock()
+
+	// The tokens may have changed too
+	s.ready.mu.Lock()
+	s.ready.checked = time.Time{}
+	s.ready.mu.Unlock()
+}
+
+// Handler returns the API's routes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/restores", s.authenticated(s.handleRestores))
+	mux.HandleFunc("/v1/operations", s.authenticated(s.handleOperations))
+	mux.HandleFunc("/v1/operations/", s.authenticated(s.handleOperation))
+	mux.HandleFunc("/v1/webhooks/restores", s.handleWebhookRestore)
+	mux.HandleFunc("/v1/slack/commands", s.handleSlackCommand)
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.HandleFunc("/readyz", s.handleReady)
+	return mux
+}
+
+// authenticated rejects requests without one of the configured bearer tokens
+// and passes the token's name on to h
+func (s *Server) authenticated(h func(w http.ResponseWriter, r *http.Request, caller string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		s.mu.Lock()
+		caller := ""
+		for name, t := range s.cfg.Tokens {
+			if t != "" && subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+				caller = name
+			}
+		}
+		s.mu.Unlock()
+		if caller == "" {
+			writeError(w, http.StatusUnauthorized, errors.New("missing or unknown bearer token"))
+			return
+		}
+		h(w, r, caller)
+	}
+}
+
+func (s *Server) handleRestores(w http.ResponseWriter, r *http.Request, caller string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s is not allowed", r.Method))
+		return
+	}
+	var req RestoreRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+	s.startRestore(w, req, caller)
+}
+
+// startRestore queues a restore and answers with the queued operation
+func (s *Server) startRestore(w http.ResponseWriter, req RestoreRequest, caller string) {
+	if req.Source == "" || len(req.Destinations) == 0 || len(req.Filters) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("source, destinations and filters are required"))
+		return
+	}
+
+	op := newRestore(caller, req.Source, req.Destinations)
+	view, err := s.enqueue(op, func() error {
+		return s.restore(req)
+	})
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, view)
+}
+
+func newRestore(caller string, source string, destinations []string) *Operation {
+	return &Operation{
+		ID:           newID(),
+		Type:         "restore",
+		Status:       StatusQueued,
+		Caller:       caller,
+		Source:       source,
+		Destinations: destinations,
+		Created:      time.Now().UTC(),
+		Workspaces:   []WorkspaceResult{},
+	}
+}
+
+// enqueue queues f to run as the operation and returns a copy of it
+func (s *Server) enqueue(op *Operation, f func() error) (Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case s.queue <- func() { s.run(op, f) }:
+		s.evict(time.Now())
+		s.operations[op.ID] = op
+	default:
+		return Operation{}, errQueueFull
+	}
+	s.log.Infof("%s queued %s %s from %s to %s", op.Caller, op.Type, op.ID, op.Source, strings.Join(op.Destinations, ", "))
+	return *op, nil
+}
+
+// restore copies state with the request's filters written to a temporary
+// filters file
+func (s *Server) restore(req RestoreRequest) error {
+	f, err := ioutil.TempFile("", "tfdr-filters-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(req.Filters)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return s.copyState(req.Source, req.Destinations, f.Name(), api.CopyOptions{
+		CreateMissing: req.CreateMissing,
+		Approvals:     req.Approvals,
+		ExecutionMode: req.ExecutionMode,
+		AgentPoolID:   req.AgentPoolID,
+		CleanDeposed:  req.CleanDeposed,
+		Untaint:       req.Untaint,
+		TagStatus:     req.TagStatus,
+	})
+}
+
+// run runs an operation, recording its progress from the api package's
+// events while it is the running operation
+func (s *Server) run(op *Operation, f func() error) {
+	s.mu.Lock()
+	started := time.Now().UTC()
+	op.Status = StatusRunning
+	op.Started = &started
+	s.running = op
+	s.mu.Unlock()
+
+	err := f()
+
+	s.mu.Lock()
+	finished := time.Now().UTC()
+	op.Finished = &finished
+	op.Status = StatusSucceeded
+	if err != nil {
+		op.Status = StatusFailed
+		op.Error = err.Error()
+	}
+	s.running = nil
+	s.mu.Unlock()
+	s.log.Infof("Operation %s %s", op.ID, op.Status)
+}
+
+// evict forgets finished operations that are older than finishedTTL or
+// beyond the newest maxFinished. Callers must hold s.mu.
+func (s *Server) evict(now time.Time) {
+	finished := make([]*Operation, 0)
+	for id, op := range s.operations {
+		if op.Finished == nil {
+			continue
+		}
+		if now.Sub(*op.Finished) > finishedTTL {
+			delete(s.operations, id)
+			continue
+		}
+		finished = append(finished, op)
+	}
+	if len(finished) <= maxFinished {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].Finished.After(*finished[j].Finished) })
+	for _, op := range finished[maxFinished:] {
+		delete(s.operations, op.ID)
+	}
+}
+
+// Close stops the server from recording the progress of operations. Queued
+// operations still run.
+func (s *Server) Close() {
+	s.stopEvents()
+}
+
+func (s *Server) record(e api.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	op := s.running
+	if op == nil {
+		return
+	}
+	switch e.Type {
+	case api.OperationStarted:
+		if op.OperationID == "" {
+			op.OperationID = e.OperationID
+		}
+	case api.WorkspaceCompleted:
+		result := WorkspaceResult{Workspace: e.Workspace}
+		if e.Err != nil {
+			result.Error = e.Err.Error()
+		}
+		op.Workspaces = append(op.Workspaces, result)
+	case api.OperationFinished:
+		op.APICalls = append(op.APICalls, e.APICalls...)
+	}
+}
+
+func (s *Server) handleOperations(w http.ResponseWriter, r *http.Request, caller string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s is not allowed", r.Method))
+		return
+	}
+	s.mu.Lock()
+	ops := make([]Operation, 0, len(s.operations))
+	for _, op := range s.operations {
+		view := *op
+		view.Workspaces = append([]WorkspaceResult{}, op.Workspaces...)
+		ops = append(ops, view)
+	}
+	s.mu.Unlock()
+	sort.Slice(ops, func(i, j int) bool { return ops[i].Created.After(ops[j].Created) })
+	writeJSON(w, http.StatusOK, ops)
+}
+
+func (s *Server) handleOperation(w http.ResponseWriter, r *http.Request, caller string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s is not allowed", r.Method))
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/v1/operations/")
+	s.mu.Lock()
+	op, ok := s.operations[id]
+	var view Operation
+	if ok {
+		view = *op
+		view.Workspaces = append([]WorkspaceResult{}, op.Workspaces...)
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no operation %s", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	// Encoding only fails when the client has gone away, and there is
+	// nobody left to tell
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func request(t *testing.T, h http.Handler, method string, path string, token string, body string) (*http.Response, []byte) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	resp := w.Result()
+	data, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp, data
+}
+
+// waitFor polls an operation until it is no longer queued or running
+func waitFor(t *testing.T, h http.Handler, id string) Operation {
+	var op Operation
+	for i := 0; i < 100; i++ {
+		resp, data := request(t, h, "GET", "/v1/operations/"+id, "secret", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NoError(t, json.Unmarshal(data, &op))
+		if op.Status != StatusQueued && op.Status != StatusRunning {
+			return op
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("operation %s did not finish", id)
+	return op
+}
+
+func TestAuthentication(t *testing.T) {
+	s := New(config.APIServer{Tokens: map[string]string{"portal": "secret"}}, logging.Discard())
+	defer s.Close()
+	h := s.Handler()
+
+	resp, _ := request(t, h, "GET", "/v1/operations", "", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp, _ = request(t, h, "GET", "/v1/operations", "wrong", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp, data := request(t, h, "GET", "/v1/operations", "secret", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `[]`, string(data))
+}
+
+func TestRestore(t *testing.T) {
+	s := New(config.APIServer{Tokens: map[string]string{"portal": "secret"}}, logging.Discard())
+	defer s.Close()
+	var filters string
+	s.copyState = func(source string, destinations []string, filterFile string, opts api.CopyOptions) error {
+		data, err := ioutil.ReadFile(filterFile)
+		assert.NoError(t, err)
+		filters = string(data)
+		assert.Equal(t, "prod-app", source)
+		assert.True(t, opts.CreateMissing)
+		if destinations[0] == "dr-broken" {
+			return errors.New("new workspace state is not empty")
+		}
+		return nil
+	}
+	h := s.Handler()
+
+	resp, data := request(t, h, "POST", "/v1/restores", "secret",
+		`{"source":"prod-app","destinations":["dr-app"],"filters":{"global_resource_types":["aws_iam_role"]},"create_missing":true}`)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	var op Operation
+	assert.NoError(t, json.Unmarshal(data, &op))
+	assert.Equal(t, "portal", op.Caller)
+
+	op = waitFor(t, h, op.ID)
+	assert.Equal(t, StatusSucceeded, op.Status)
+	assert.JSONEq(t, `{"global_resource_types":["aws_iam_role"]}`, filters)
+
+	_, data = request(t, h, "POST", "/v1/restores", "secret",
+		`{"source":"prod-app","destinations":["dr-broken"],"filters":{},"create_missing":true}`)
+	assert.NoError(t, json.Unmarshal(data, &op))
+	op = waitFor(t, h, op.ID)
+	assert.Equal(t, StatusFailed, op.Status)
+	assert.Equal(t, "new workspace state is not empty", op.Error)
+
+	_, data = request(t, h, "GET", "/v1/operations", "secret", "")
+	var ops []Operation
+	assert.NoError(t, json.Unmarshal(data, &ops))
+	assert.Len(t, ops, 2)
+}
+
+func TestRestoreValidation(t *testing.T) {
+	s := New(config.APIServer{Tokens: map[string]string{"portal": "secret"}}, logging.Discard())
+	defer s.Close()
+	h := s.Handler()
+
+	resp, _ := request(t, h, "POST", "/v1/restores", "secret", `{"source":"prod-app"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = request(t, h, "GET", "/v1/restores", "secret", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	resp, _ = request(t, h, "GET", "/v1/operations/unknown", "secret", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	huge := `{"source":"prod-app","destinations":["dr-app"],"filters":"` + strings.Repeat("x", maxRequestBody) + `"}`
+	resp, _ = request(t, h, "POST", "/v1/restores", "secret", huge)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "oversized requests should be rejected")
+}
+
+func TestEvictFinishedOperations(t *testing.T) {
+	s := New(config.APIServer{}, logging.Discard())
+	defer s.Close()
+	now := time.Now()
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(-d)
+		return &t
+	}
+	s.operations["queued"] = &Operation{ID: "queued"}
+	s.operations["expired"] = &Operation{ID: "expired", Finished: at(finishedTTL + time.Minute)}
+	for i := 0; i <= maxFinished; i++ {
+		id := fmt.Sprintf("op-%d", i)
+		s.operations[id] = &Operation{ID: id, Finished: at(time.Duration(i) * time.Second)}
+	}
+
+	s.evict(now)
+	assert.Len(t, s.operations, maxFinished+1)
+	assert.Contains(t, s.operations, "queued", "unfinished operations should be kept")
+	assert.Contains(t, s.operations, "op-0")
+	assert.NotContains(t, s.operations, "expired")
+	assert.NotContains(t, s.operations, fmt.Sprintf("op-%d", maxFinished), "the oldest finished operations should be dropped")
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// slackMaxAge is how old a slash command's timestamp can be, so captured
+// requests can't be replayed
+const slackMaxAge = 5 * time.Minute
+
+// slackMaxBody limits the size of slash command requests
+const slackMaxBody = 1 << 20
+
+const slackUsage = "Usage: `/tfdr status [operation]`"
+
+type slackResponse struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// handleSlackCommand serves the /tfdr slash command. Slack signs its requests
+// with the app's signing secret instead of sending a bearer token.
+func (s *Server) handleSlackCommand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s is not allowed", r.Method))
+		return
+	}
+	s.mu.Lock()
+	slack := s.cfg.Slack
+	s.mu.Unlock()
+	if slack == nil || slack.SigningSecret == "" {
+		writeError(w, http.StatusNotFound, errors.New("the Slack command is not configured"))
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, slackMaxBody))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+	if err := verifySlackSignature(slack.SigningSecret, r.Header, body, time.Now()); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+
+	user := form.Get("user_id")
+	args := strings.Fields(form.Get("text"))
+	if len(args) == 0 {
+		writeJSON(w, http.StatusOK, slackResponse{"ephemeral", slackUsage})
+		return
+	}
+	switch args[0] {
+	case "status":
+		if !contains(slack.Viewers, user) {
+			writeJSON(w, http.StatusOK, slackResponse{"ephemeral", "You aren't allowed to see tfdr operations"})
+			return
+		}
+		writeJSON(w, http.StatusOK, slackResponse{"ephemeral", s.slackStatus(args[1:])})
+	default:
+		writeJSON(w, http.StatusOK, slackResponse{"ephemeral", slackUsage})
+	}
+}
+
+// verifySlackSignature checks the X-Slack-Signature header, an HMAC-SHA256
+// of the version, the request timestamp and the body
+func verifySlackSignature(secret string, header http.Header, body []byte, now time.Time) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("missing or invalid Slack request timestamp")
+	}
+	if age := now.Sub(time.Unix(sec, 0)); age > slackMaxAge || age < -slackMaxAge {
+		return errors.New("the Slack request timestamp is too old")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return errors.New("invalid Slack signature")
+	}
+	return nil
+}
+
+// slackStatus describes the given operation, or the latest operations
+func (s *Server) slackStatus(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(args) > 0 {
+		op, ok := s.operations[args[0]]
+		if !ok {
+			return fmt.Sprintf("No operation %s", args[0])
+		}
+		lines := []string{slackSummary(*op)}
+		for _, ws := range op.Workspaces {
+			if ws.Error != "" {
+				lines = append(lines, fmt.Sprintf("• %s: %s", ws.Workspace, ws.Error))
+			} else {
+				lines = append(lines, fmt.Sprintf("• %s: done", ws.Workspace))
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	ops := make([]Operation, 0, len(s.operations))
+	for _, op := range s.operations {
+		ops = append(ops, *op)
+	}
+	if len(ops) == 0 {
+		return "No operations have been started"
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].Created.After(ops[j].Created) })
+	if len(ops) > 5 {
+		ops = ops[:5]
+	}
+	lines := make([]string, 0, len(ops))
+	for _, op := range ops {
+		lines = append(lines, slackSummary(op))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func slackSummary(op Operation) string {
+	text := fmt.Sprintf("%s `%s` from %s to %s: *%s*", op.Type, op.ID, op.Source, strings.Join(op.Destinations, ", "), op.Status)
+	if op.Error != "" {
+		text += " - " + op.Error
+	}
+	return text
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func slackCommand(t *testing.T, h http.Handler, secret string, timestamp time.Time, form url.Values) (*http.Response, slackResponse) {
+	body := form.Encode()
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", ts, body)
+
+	req := httptest.NewRequest("POST", "/v1/slack/commands", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	resp := w.Result()
+	var msg slackResponse
+	if resp.StatusCode == http.StatusOK {
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&msg))
+	}
+	return resp, msg
+}
+
+func slackServer() *Server {
+	return New(config.APIServer{
+		Tokens: map[string]string{"portal": "secret"},
+		Slack: &config.Slack{
+			SigningSecret: "signing",
+			Viewers:       []string{"UVIEWER"},
+		},
+	}, logging.Discard())
+}
+
+func TestSlackSignature(t *testing.T) {
+	s := slackServer()
+	defer s.Close()
+	h := s.Handler()
+	form := url.Values{"user_id": {"UVIEWER"}, "text": {"status"}}
+
+	resp, _ := slackCommand(t, h, "wrong", time.Now(), form)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp, _ = slackCommand(t, h, "signing", time.Now().Add(-10*time.Minute), form)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp, msg := slackCommand(t, h, "signing", time.Now(), form)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "No operations have been started", msg.Text)
+
+	plain := New(config.APIServer{Tokens: map[string]string{"portal": "secret"}}, logging.Discard())
+	defer plain.Close()
+	h = plain.Handler()
+	resp, _ = slackCommand(t, h, "signing", time.Now(), form)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestSlackRoles(t *testing.T) {
+	s := slackServer()
+	defer s.Close()
+	h := s.Handler()
+
+	_, msg := slackCommand(t, h, "signing", time.Now(), url.Values{"user_id": {"USTRANGER"}, "text": {"status"}})
+	assert.Equal(t, "You aren't allowed to see tfdr operations", msg.Text)
+	_, msg = slackCommand(t, h, "signing", time.Now(), url.Values{"user_id": {"UVIEWER"}, "text": {"restore prod-app dr-app app"}})
+	assert.Equal(t, slackUsage, msg.Text, "restores can't be started from Slack")
+}
+
+func TestSlackStatus(t *testing.T) {
+	s := slackServer()
+	defer s.Close()
+	s.copyState = func(source string, destinations []string, filterFile string, opts api.CopyOptions) error {
+		return nil
+	}
+	h := s.Handler()
+
+	_, data := request(t, h, "POST", "/v1/restores", "secret",
+		`{"source":"prod-app","destinations":["dr-app","dr-app-2"],"filters":{"global_resource_types":["aws_iam_role"]}}`)
+	var op Operation
+	assert.NoError(t, json.Unmarshal(data, &op))
+	waitFor(t, h, op.ID)
+
+	_, msg := slackCommand(t, h, "signing", time.Now(), url.Values{"user_id": {"UVIEWER"}, "text": {"status"}})
+	assert.Contains(t, msg.Text, "restore `"+op.ID+"` from prod-app to dr-app, dr-app-2: *succeeded*")
+	_, msg = slackCommand(t, h, "signing", time.Now(), url.Values{"user_id": {"UVIEWER"}, "text": {"status " + op.ID}})
+	assert.Contains(t, msg.Text, "from prod-app to dr-app, dr-app-2: *succeeded*")
+	_, msg = slackCommand(t, h, "signing", time.Now(), url.Values{"user_id": {"UVIEWER"}, "text": {"status unknown"}})
+	assert.Equal(t, "No operation unknown", msg.Text)
+}
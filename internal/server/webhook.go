@@ -0,0 +1,86 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// webhookMaxAge is how old a signed webhook request can be, so captured
+// requests can't be replayed
+const webhookMaxAge = 5 * time.Minute
+
+// handleWebhookRestore starts a restore for incident tooling such as
+// PagerDuty or Rundeck. The body is a RestoreRequest, signed with one of the
+// api_server.webhooks secrets instead of sent with a bearer token.
+func (s *Server) handleWebhookRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s is not allowed", r.Method))
+		return
+	}
+	s.mu.Lock()
+	webhooks := s.cfg.Webhooks
+	s.mu.Unlock()
+	if len(webhooks) == 0 {
+		writeError(w, http.StatusNotFound, errors.New("no webhooks are configured"))
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+	caller, err := verifywebhookSignature(webhooks, r.Header, body, time.Now())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	var req RestoreRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+	s.startRestore(w, req, caller)
+}
+
+// verifyWebhookSignature checks the X-Tfdr-Signature header, an HMAC-SHA256
+// of the version, the X-Tfdr-Timestamp header and the body, and returns the
+// name of the webhook whose secret signed it
+func verifywebhookSignature(webhooks map[string]string, header http.Header, body []byte, now time.Time) (string, error) {
+	timestamp := header.Get("X-Tfdr-Timestamp")
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", errors.New("missing or invalid request timestamp")
+	}
+	if age := now.Sub(time.Unix(sec, 0)); age > webhookMaxAge || age < -webhookMaxAge {
+		return "", errors.New("the request timestamp is too old")
+	}
+	signature := []byte(header.Get("X-Tfdr-Signature"))
+	caller := ""
+	for name, secret := range webhooks {
+		if secret != "" && hmac.Equal([]byte(webhookSignature(secret, timestamp, body)), signature) {
+			caller = name
+		}
+	}
+	if caller == "" {
+		return "", errors.New("invalid request signature")
+	}
+	return caller, nil
+}
+
+// webhookSignature returns the X-Tfdr-Signature header for a webhook request
+// body sent at timestamp, in Unix seconds
+func webhookSignature(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v1:%s:", timestamp)
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func webhookRequest(t *testing.T, h http.Handler, secret string, timestamp time.Time, body string) (*http.Response, []byte) {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	req := httptest.NewRequest("POST", "/v1/webhooks/restores", strings.NewReader(body))
+	req.Header.Set("X-Tfdr-Timestamp", ts)
+	req.Header.Set("X-Tfdr-Signature", webhookSignature(secret, ts, []byte(body)))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	resp := w.Result()
+	data, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp, data
+}
+
+func TestWebhookRestore(t *testing.T) {
+	s := New(config.APIServer{
+		Tokens:   map[string]string{"portal": "secret"},
+		Webhooks: map[string]string{"pagerduty": "signing"},
+	}, logging.Discard())
+	defer s.Close()
+	s.copyState = func(source string, destinations []string, filterFile string, opts api.CopyOptions) error {
+		assert.Equal(t, "prod-app", source)
+		assert.Equal(t, []string{"dr-app"}, destinations)
+		return nil
+	}
+	h := s.Handler()
+	body := `{"source":"prod-app","destinations":["dr-app"],"filters":{"global_resource_types":["aws_iam_role"]}}`
+
+	resp, data := webhookRequest(t, h, "signing", time.Now(), body)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	var op Operation
+	assert.NoError(t, json.Unmarshal(data, &op))
+	assert.Equal(t, "pagerduty", op.Caller)
+	op = waitFor(t, h, op.ID)
+	assert.Equal(t, StatusSucceeded, op.Status)
+
+	resp, _ = webhookRequest(t, h, "wrong", time.Now(), body)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, _ = webhookRequest(t, h, "signing", time.Now().Add(-10*time.Minute), body)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "old requests should not be replayable")
+
+	resp, _ = webhookRequest(t, h, "signing", time.Now(), `{"source":"prod-app"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = request(t, h, "POST", "/v1/webhooks/restores", "secret", body)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "bearer tokens should not be accepted")
+}
+
+func TestWebhookNotConfigured(t *testing.T) {
+	s := New(config.APIServer{Tokens: map[string]string{"portal": "secret"}}, logging.Discard())
+	defer s.Close()
+
+	resp, _ := webhookRequest(t, s.Handler(), "", time.Now(), `{}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
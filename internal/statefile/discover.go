@@ -0,0 +1,85 @@
+package statefile
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// localWorkspacesDir holds the states of the terraform CLI's non default
+// workspaces, one directory per workspace
+const localWorkspacesDir = "terraform.tfstate.d"
+
+var invalidWorkspaceChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// Discover walks root and returns the files whose slash separated path
+// relative to root matches pattern. Pattern is a path.Match pattern in which
+// a ** element matches any number of directories. Hidden directories such as
+// .terraform are skipped.
+func Discover(root string, pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	files := make([]string, 0)
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if p != root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if matchGlob(strings.Split(pattern, "/"), strings.Split(filepath.ToSlash(rel), "/")) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func matchGlob(pattern []string, elems []string) bool {
+	if len(pattern) == 0 {
+		return len(elems) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(elems); i++ {
+			if matchGlob(pattern[1:], elems[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(elems) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], elems[0])
+	return ok && matchGlob(pattern[1:], elems[1:])
+}
+
+// WorkspaceName derives a workspace name from the slash separated path of a
+// state file relative to the directory it was discovered in, e.g.
+// network/prod/terraform.tfstate becomes network-prod. The states of local
+// CLI workspaces in terraform.tfstate.d are named after their directory and
+// workspace. States at the top level are named after the root directory.
+func WorkspaceName(root string, rel string) string {
+	dir := path.Dir(rel)
+	if dir == "." {
+		dir = filepath.Base(filepath.Clean(root))
+	}
+	elems := make([]string, 0)
+	for _, e := range strings.Split(dir, "/") {
+		if e != localWorkspacesDir {
+			elems = append(elems, e)
+		}
+	}
+	name := invalidWorkspaceChars.ReplaceAllString(strings.Join(elems, "-"), "-")
+	return strings.Trim(name, "-")
+}
@@ -0,0 +1,242 @@
+package statefile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sensitiveNames are attribute names masked even when the state doesn't mark
+// them sensitive, as states written before terraform 0.15 don't
+var sensitiveNames = regexp.MustCompile(`(?i)password|secret|token|private_key`)
+
+// Sensitive replaces a masked attribute value
+type Sensitive struct{}
+
+// MarshalJSON &
+func (Sensitive) MarshalJSON() ([]byte, error) {
+	return []byte(`"(sensitive value)"`), nil
+}
+
+// ShownInstance is a resource instance with its sensitive attributes masked
+type ShownInstance struct {
+	Address    string                 `json:"address"`
+	Mode       string                 `json:"mode"`
+	Type       string                 `json:"type"`
+	Name       string                 `json:"name"`
+	Status     string                 `json:"status,omitempty"`
+	Deposed    string                 `json:"deposed,omitempty"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// pathStep is a step of a path in an instance's sensitive_attributes, e.g.
+// {"type":"get_attr","value":"password"} or
+// {"type":"index","value":{"value":0,"type":"number"}}
+type pathStep struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+type stateShowJSON struct {
+	Resources []struct {
+		Module    string `json:"module"`
+		Mode      string `json:"mode"`
+		Type      string `json:"type"`
+		Name      string `json:"name"`
+		Instances []struct {
+			IndexKey            interface{}            `json:"index_key"`
+			Status              string                 `json:"status"`
+			Deposed             string                 `json:"deposed"`
+			Attributes          map[string]interface{} `json:"attributes"`
+			SensitiveAttributes [][]pathStep           `json:"sensitive_attributes"`
+		} `json:"instances"`
+	} `json:"resources"`
+}
+
+// Show returns the instances of a version 4 state matching address, which is
+// an instance address such as module.app.aws_instance.web[0], a resource
+// address without the index, or a module. An empty address matches every
+// instance.
+func Show(data []byte, address string) ([]ShownInstance, error) {
+	var state stateShowJSON
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&state); err != nil {
+		return nil, fmt.Errorf("Invalid state json. Err: %v", err)
+	}
+
+	shown := make([]ShownInstance, 0)
+	for _, r := range state.Resources {
+		resource := r.Type + "." + r.Name
+		if r.Mode == "data" {
+			resource = "data." + resource
+		}
+		if r.Module != "" {
+			resource = r.Module + "." + resource
+		}
+		for _, i := range r.Instances {
+			instance := resource + indexSuffix(i.IndexKey)
+			if address != "" && address != instance && address != resource && !strings.HasPrefix(instance, address+".") {
+				continue
+			}
+			for _, path := range i.SensitiveAttributes {
+				maskPath(i.Attributes, path)
+			}
+			maskNames(i.Attributes)
+			shown = append(shown, ShownInstance{
+				Address:    instance,
+				Mode:       r.Mode,
+				Type:       r.Type,
+				Name:       r.Name,
+				Status:     i.Status,
+				Deposed:    i.Deposed,
+				Attributes: i.Attributes,
+			})
+		}
+	}
+	if address != "" && len(shown) == 0 {
+		return nil, fmt.Errorf("No resource instance in the state matches %s", address)
+	}
+	return shown, nil
+}
+
+// indexSuffix returns the index part of an instance address, e.g. [0] or
+// ["a"]
+func indexSuffix(key interface{}) string {
+	switch k := key.(type) {
+	case nil:
+		return ""
+	case string:
+		return fmt.Sprintf("[%q]", k)
+	default:
+		return fmt.Sprintf("[%v]", k)
+	}
+}
+
+func maskPath(v interface{}, path []pathStep) interface{} {
+	if len(path) == 0 {
+		return Sensitive{}
+	}
+	var key interface{}
+	if err := json.Unmarshal(path[0].Value, &key); err != nil {
+		return v
+	}
+	if index, ok := key.(map[string]interface{}); ok {
+		key = index["value"]
+	}
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if k, ok := key.(string); ok {
+			if e, ok := value[k]; ok {
+				value[k] = maskPath(e, path[1:])
+			}
+		}
+	case []interface{}:
+		if n, ok := key.(float64); ok && int(n) >= 0 && int(n) < len(value) {
+			value[int(n)] = maskPath(value[int(n)], path[1:])
+		}
+	}
+	return v
+}
+
+// maskNames masks the non-empty strings of attributes named like secrets
+func maskNames(v interface{}) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, e := range value {
+			if s, ok := e.(string); ok && s != "" && sensitiveNames.MatchString(k) {
+				value[k] = Sensitive{}
+				continue
+			}
+			maskNames(e)
+		}
+	case []interface{}:
+		for _, e := range value {
+			maskNames(e)
+		}
+	}
+}
+
+// WriteInstance prints an instance's attributes in the style of terraform
+// state show. Null attributes are left out.
+func WriteInstance(w io.Writer, i ShownInstance) error {
+	header := "# " + i.Address + ":"
+	if i.Deposed != "" {
+		header += " (deposed object " + i.Deposed + ")"
+	}
+	if i.Status == "tainted" {
+		header += " (tainted)"
+	}
+	kind := "resource"
+	if i.Mode == "data" {
+		kind = "data"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n%s %q %q ", header, kind, i.Type, i.Name)
+	writeValue(&b, i.Attributes, 0, false)
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeValue(b *strings.Builder, v interface{}, depth int, quoteKeys bool) {
+	indent := strings.Repeat("    ", depth+1)
+	switch value := v.(type) {
+	case Sensitive:
+		b.WriteString("(sensitive value)")
+	case string:
+		b.WriteString(strconv.Quote(value))
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		width := 0
+		for k, e := range value {
+			if e == nil {
+				continue
+			}
+			keys = append(keys, k)
+			if quoteKeys {
+				k = strconv.Quote(k)
+			}
+			if len(k) > width {
+				width = len(k)
+			}
+		}
+		if len(keys) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		sort.Strings(keys)
+		b.WriteString("{\n")
+		for _, k := range keys {
+			name := k
+			if quoteKeys {
+				name = strconv.Quote(k)
+			}
+			fmt.Fprintf(b, "%s%-*s = ", indent, width, name)
+			// Objects inside attributes are maps such as tags, whose keys
+			// are quoted
+			writeValue(b, value[k], depth+1, true)
+			b.WriteString("\n")
+		}
+		b.WriteString(strings.Repeat("    ", depth) + "}")
+	case []interface{}:
+		if len(value) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteString("[\n")
+		for _, e := range value {
+			b.WriteString(indent)
+			writeValue(b, e, depth+1, false)
+			b.WriteString(",\n")
+		}
+		b.WriteString(strings.Repeat("    ", depth) + "]")
+	default:
+		fmt.Fprint(b, value)
+	}
+}
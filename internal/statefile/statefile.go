@@ -0,0 +1,153 @@
+package statefile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// Problem is a single lint finding for a state file
+type Problem struct {
+	Path    string
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Path == "" {
+		return p.Message
+	}
+	return fmt.Sprintf("%s: %s", p.Path, p.Message)
+}
+
+// Read loads a local state file
+func Read(fileName string) ([]byte, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read state file. Err: %v", err)
+	}
+	return data, nil
+}
+
+// Format returns the state with keys sorted, two space indentation and a
+// trailing newline, so the same state always serializes to the same bytes
+func Format(data []byte) ([]byte, error) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("Invalid state json. Err: %v", err)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Lint checks a state for the fields terraform needs to accept it on upload
+func Lint(data []byte) []Problem {
+	var state map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&state); err != nil {
+		return []Problem{{Message: fmt.Sprintf("invalid json: %v", err)}}
+	}
+
+	problems := make([]Problem, 0)
+	add := func(path string, format string, args ...interface{}) {
+		problems = append(problems, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if version, ok := intField(state, "version"); !ok {
+		add("version", "missing or not a number")
+	} else if version != 4 {
+		add("version", "unsupported state format version %d, expected 4", version)
+	}
+	if s, ok := state["terraform_version"].(string); !ok || s == "" {
+		add("terraform_version", "missing")
+	}
+	if serial, ok := intField(state, "serial"); !ok {
+		add("serial", "missing or not a number")
+	} else if serial < 1 {
+		add("serial", "must be at least 1, got %d", serial)
+	}
+	if s, ok := state["lineage"].(string); !ok || s == "" {
+		add("lineage", "missing")
+	}
+
+	resources, ok := state["resources"].([]interface{})
+	if !ok {
+		add("resources", "missing or not a list")
+		return problems
+	}
+
+	seen := make(map[string]int)
+	for i, r := range resources {
+		path := fmt.Sprintf("resources[%d]", i)
+		resource, ok := r.(map[string]interface{})
+		if !ok {
+			add(path, "not an object")
+			continue
+		}
+		for _, key := range []string{"mode", "type", "name", "provider"} {
+			if s, ok := resource[key].(string); !ok || s == "" {
+				add(path, "missing %s", key)
+			}
+		}
+		if mode, _ := resource["mode"].(string); mode != "" && mode != "managed" && mode != "data" {
+			add(path, "invalid mode %q", mode)
+		}
+
+		address := resourceAddress(resource)
+		if prev, ok := seen[address]; ok {
+			add(path, "duplicate resource %s (also resources[%d])", address, prev)
+		} else {
+			seen[address] = i
+		}
+
+		instances, ok := resource["instances"].([]interface{})
+		if !ok {
+			add(path, "missing instances")
+			continue
+		}
+		for j, inst := range instances {
+			instance, ok := inst.(map[string]interface{})
+			if !ok {
+				add(fmt.Sprintf("%s.instances[%d]", path, j), "not an object")
+				continue
+			}
+			if _, ok := instance["attributes"].(map[string]interface{}); !ok {
+				if _, flat := instance["attributes_flat"]; !flat {
+					add(fmt.Sprintf("%s.instances[%d]", path, j), "missing attributes")
+				}
+			}
+		}
+	}
+
+	return problems
+}
+
+func intField(m map[string]interface{}, key string) (int64, bool) {
+	n, ok := m[key].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	i, err := n.Int64()
+	return i, err == nil
+}
+
+func resourceAddress(resource map[string]interface{}) string {
+	address := fmt.Sprintf("%v.%v", resource["type"], resource["name"])
+	if resource["mode"] == "data" {
+		address = "data." + address
+	}
+	if module, _ := resource["module"].(string); module != "" {
+		address = module + "." + address
+	}
+	return address
+}
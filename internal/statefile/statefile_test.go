@@ -0,0 +1,185 @@
+package statefile
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TestSuite struct {
+	suite.Suite
+}
+
+func TestRunSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+func (s *TestSuite) TestFormatIsStable() {
+	data, err := Read("./testdata/valid.tfstate")
+	s.NoError(err)
+
+	formatted, err := Format(data)
+	s.NoError(err)
+	s.Equal(string(data), string(formatted))
+}
+
+func (s *TestSuite) TestFormatSortsKeys() {
+	formatted, err := Format([]byte(`{"version":4,"serial":10000000000000000001,"lineage":"a<b"}`))
+	s.NoError(err)
+	s.Equal("{\n  \"lineage\": \"a<b\",\n  \"serial\": 10000000000000000001,\n  \"version\": 4\n}\n", string(formatted))
+}
+
+func (s *TestSuite) TestFormatInvalidJSON() {
+	_, err := Format([]byte(`{"version":`))
+	s.Error(err)
+}
+
+func (s *TestSuite) TestLintValid() {
+	data, err := Read("./testdata/valid.tfstate")
+	s.NoError(err)
+	s.Empty(Lint(data))
+}
+
+func (s *TestSuite) TestLintInvalid() {
+	data, err := Read("./testdata/invalid.tfstate")
+	s.NoError(err)
+
+	problems := make([]string, 0)
+	for _, p := range Lint(data) {
+		problems = append(problems, p.String())
+	}
+	s.ElementsMatch([]string{
+		"version: unsupported state format version 3, expected 4",
+		"terraform_version: missing",
+		"serial: must be at least 1, got 0",
+		"lineage: missing",
+		"resources[1]: duplicate resource aws_s3_bucket.logs (also resources[0])",
+		"resources[1].instances[0]: missing attributes",
+		"resources[2]: missing provider",
+		"resources[2]: invalid mode \"resource\"",
+		"resources[2]: missing instances",
+	}, problems)
+}
+
+func (s *TestSuite) TestReadError() {
+	_, err := Read("./testdata/not-found.tfstate")
+	s.Error(err)
+}
+
+func (s *TestSuite) TestDiscover() {
+	root, err := ioutil.TempDir("", "tfdr-discover")
+	s.NoError(err)
+	defer os.RemoveAll(root)
+	for _, f := range []string{
+		"terraform.tfstate",
+		"network/prod/terraform.tfstate",
+		"network/prod/terraform.tfstate.backup",
+		"app/terraform.tfstate.d/staging/terraform.tfstate",
+		"app/.terraform/terraform.tfstate",
+	} {
+		s.NoError(os.MkdirAll(filepath.Join(root, filepath.Dir(f)), 0700))
+		s.NoError(ioutil.WriteFile(filepath.Join(root, f), []byte("{}"), 0600))
+	}
+
+	files, err := Discover(root, "**/terraform.tfstate")
+	s.NoError(err)
+	s.Equal([]string{
+		filepath.Join(root, "app/terraform.tfstate.d/staging/terraform.tfstate"),
+		filepath.Join(root, "network/prod/terraform.tfstate"),
+		filepath.Join(root, "terraform.tfstate"),
+	}, files)
+
+	files, err = Discover(root, "network/*/terraform.tfstate*")
+	s.NoError(err)
+	s.Len(files, 2)
+
+	_, err = Discover(root, "[")
+	s.Error(err)
+}
+
+func (s *TestSuite) TestWorkspaceName() {
+	s.Equal("network-prod", WorkspaceName("repos", "network/prod/terraform.tfstate"))
+	s.Equal("app-staging", WorkspaceName("repos", "app/terraform.tfstate.d/staging/terraform.tfstate"))
+	s.Equal("repos", WorkspaceName("/src/repos/", "terraform.tfstate"))
+	s.Equal("my-app-v1-0", WorkspaceName("repos", "my app/v1.0/terraform.tfstate"))
+}
+
+func (s *TestSuite) TestGetStats() {
+	data, err := Read("./testdata/stats.tfstate")
+	s.NoError(err)
+
+	stats, err := GetStats(data)
+	s.NoError(err)
+	s.Equal(len(data), stats.Size)
+	s.Equal("0.14.11", stats.TerraformVersion)
+	s.Equal(int64(7), stats.Serial)
+	s.Equal(3, stats.Resources)
+	s.Equal(1, stats.DataSources)
+	s.Equal(6, stats.Instances)
+	s.Equal(1, stats.Tainted)
+	s.Equal(1, stats.Deposed)
+	s.Equal(1, stats.Outputs)
+	s.Equal(2, stats.MaxModuleDepth)
+	s.Equal(map[string]int{"aws_vpc": 1, "aws_subnet": 3, "aws_region": 1, "random_id": 1}, stats.ByType)
+	s.Equal(map[string]int{"aws": 5, "random": 1}, stats.ByProvider)
+	s.Equal(map[string]int{"module.network": 1, "module.network.module.subnets[\"private\"]": 3, "": 2}, stats.ByModule)
+}
+
+func (s *TestSuite) TestGetStatsInvalidJSON() {
+	_, err := GetStats([]byte(`{"resources":`))
+	s.Error(err)
+}
+
+func (s *TestSuite) TestShow() {
+	data, err := Read("./testdata/show.tfstate")
+	s.NoError(err)
+
+	all, err := Show(data, "")
+	s.NoError(err)
+	addresses := make([]string, 0)
+	for _, i := range all {
+		addresses = append(addresses, i.Address)
+	}
+	s.Equal([]string{"aws_db_instance.main", "module.app.aws_instance.web[0]", "module.app.aws_instance.web[1]", "module.app.data.aws_ami.ubuntu"}, addresses)
+
+	for address, count := range map[string]int{"module.app": 3, "module.app.aws_instance.web": 2, "module.app.aws_instance.web[1]": 1} {
+		shown, err := Show(data, address)
+		s.NoError(err)
+		s.Len(shown, count, address)
+	}
+	_, err = Show(data, "module.ap")
+	s.EqualError(err, "No resource instance in the state matches module.ap")
+
+	shown, err := Show(data, "aws_db_instance.main")
+	s.NoError(err)
+	var out bytes.Buffer
+	s.NoError(WriteInstance(&out, shown[0]))
+	s.Equal(`# aws_db_instance.main:
+resource "aws_db_instance" "main" {
+    allocated_storage = 20
+    connection        = [
+        {
+            dsn  = (sensitive value)
+            user = "app"
+        },
+    ]
+    engine            = "postgres"
+    id                = "db-1"
+    password          = (sensitive value)
+    tags              = {
+        "Name" = "main"
+        "team" = "platform"
+    }
+}
+`, out.String())
+
+	shown, err = Show(data, "module.app.aws_instance.web[0]")
+	s.NoError(err)
+	out.Reset()
+	s.NoError(WriteInstance(&out, shown[0]))
+	s.Contains(out.String(), "# module.app.aws_instance.web[0]: (tainted)\n")
+}
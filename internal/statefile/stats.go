@@ -0,0 +1,98 @@
+package statefile
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mupuri/go-tfdr/internal/inventory"
+)
+
+// Stats summarizes the contents of a state. The per type, provider and
+// module counts are of resource instances; the root module is "".
+type Stats struct {
+	Size             int            `json:"size"`
+	TerraformVersion string         `json:"terraform_version"`
+	Serial           int64          `json:"serial"`
+	Resources        int            `json:"resources"`
+	DataSources      int            `json:"data_sources"`
+	Instances        int            `json:"instances"`
+	Tainted          int            `json:"tainted"`
+	Deposed          int            `json:"deposed"`
+	Outputs          int            `json:"outputs"`
+	MaxModuleDepth   int            `json:"max_module_depth"`
+	ByType           map[string]int `json:"by_type"`
+	ByProvider       map[string]int `json:"by_provider"`
+	ByModule         map[string]int `json:"by_module"`
+}
+
+// stateStatsJSON has the parts of a state file Stats reads, including the
+// instance status and deposed keys models.Instance leaves out
+type stateStatsJSON struct {
+	TerraformVersion string                 `json:"terraform_version"`
+	Serial           int64                  `json:"serial"`
+	Outputs          map[string]interface{} `json:"outputs"`
+	Resources        []struct {
+		Module    string `json:"module"`
+		Mode      string `json:"mode"`
+		Type      string `json:"type"`
+		Provider  string `json:"provider"`
+		Instances []struct {
+			Status  string `json:"status"`
+			Deposed string `json:"deposed"`
+		} `json:"instances"`
+	} `json:"resources"`
+}
+
+// GetStats returns the statistics of a version 4 state
+func GetStats(data []byte) (Stats, error) {
+	var state stateStatsJSON
+	if err := json.Unmarshal(data, &state); err != nil {
+		return Stats{}, fmt.Errorf("Invalid state json. Err: %v", err)
+	}
+
+	s := Stats{
+		Size:             len(data),
+		TerraformVersion: state.TerraformVersion,
+		Serial:           state.Serial,
+		Outputs:          len(state.Outputs),
+		ByType:           make(map[string]int),
+		ByProvider:       make(map[string]int),
+		ByModule:         make(map[string]int),
+	}
+	for _, r := range state.Resources {
+		if r.Mode == "data" {
+			s.DataSources++
+		} else {
+			s.Resources++
+		}
+		if depth := ModuleDepth(r.Module); depth > s.MaxModuleDepth {
+			s.MaxModuleDepth = depth
+		}
+		for _, i := range r.Instances {
+			s.Instances++
+			s.ByType[r.Type]++
+			s.ByProvider[inventory.ProviderName(r.Provider)]++
+			s.ByModule[r.Module]++
+			if i.Status == "tainted" {
+				s.Tainted++
+			}
+			if i.Deposed != "" {
+				s.Deposed++
+			}
+		}
+	}
+	return s, nil
+}
+
+// ModuleDepth returns how deeply a module address is nested, e.g. 2 for
+// module.network.module.subnets["a"]. The root module has depth 0.
+func ModuleDepth(module string) int {
+	depth := 0
+	for _, part := range strings.Split(module, ".") {
+		if part == "module" {
+			depth++
+		}
+	}
+	return depth
+}
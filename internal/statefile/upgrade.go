@@ -0,0 +1,284 @@
+package statefile
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version 3 is the state format written by terraform 0.11 and earlier,
+// version 4 the format read by terraform 0.12 and later
+type stateV3 struct {
+	Version          int        `json:"version"`
+	TerraformVersion string     `json:"terraform_version"`
+	Serial           int64      `json:"serial"`
+	Lineage          string     `json:"lineage"`
+	Modules          []moduleV3 `json:"modules"`
+}
+
+type moduleV3 struct {
+	Path      []string              `json:"path"`
+	Outputs   map[string]outputV3   `json:"outputs"`
+	Resources map[string]resourceV3 `json:"resources"`
+}
+
+type outputV3 struct {
+	Sensitive bool        `json:"sensitive"`
+	Type      string      `json:"type"`
+	Value     interface{} `json:"value"`
+}
+
+type resourceV3 struct {
+	Type      string       `json:"type"`
+	DependsOn []string     `json:"depends_on"`
+	Primary   *instanceV3  `json:"primary"`
+	Deposed   []instanceV3 `json:"deposed"`
+	Provider  string       `json:"provider"`
+}
+
+type instanceV3 struct {
+	ID         string                 `json:"id"`
+	Attributes map[string]string      `json:"attributes"`
+	Meta       map[string]interface{} `json:"meta"`
+	Tainted    bool                   `json:"tainted"`
+}
+
+type stateV4 struct {
+	Version          int                 `json:"version"`
+	TerraformVersion string              `json:"terraform_version"`
+	Serial           int64               `json:"serial"`
+	Lineage          string              `json:"lineage"`
+	Outputs          map[string]outputV4 `json:"outputs"`
+	Resources        []*resourceV4       `json:"resources"`
+}
+
+type outputV4 struct {
+	Value     interface{} `json:"value"`
+	Type      interface{} `json:"type"`
+	Sensitive bool        `json:"sensitive,omitempty"`
+}
+
+type resourceV4 struct {
+	Module    string        `json:"module,omitempty"`
+	Mode      string        `json:"mode"`
+	Type      string        `json:"type"`
+	Name      string        `json:"name"`
+	Provider  string        `json:"provider"`
+	Instances []*instanceV4 `json:"instances"`
+}
+
+type instanceV4 struct {
+	IndexKey       interface{}       `json:"index_key,omitempty"`
+	Status         string            `json:"status,omitempty"`
+	Deposed        string            `json:"deposed,omitempty"`
+	SchemaVersion  int               `json:"schema_version"`
+	AttributesFlat map[string]string `json:"attributes_flat"`
+	Dependencies   []string          `json:"dependencies,omitempty"`
+}
+
+// Upgrade converts a state between format versions. Only the upgrade from
+// version 3 to version 4 is supported. Instance attributes are kept in their
+// flat form; terraform converts them using the provider schema on the next
+// apply.
+func Upgrade(data []byte, from int, to int) ([]byte, error) {
+	if from != 3 || to != 4 {
+		return nil, fmt.Errorf("Unsupported state upgrade from version %d to %d, only 3 to 4 is supported", from, to)
+	}
+
+	var old stateV3
+	if err := json.Unmarshal(data, &old); err != nil {
+		return nil, fmt.Errorf("Invalid state json. Err: %v", err)
+	}
+	if old.Version != from {
+		return nil, fmt.Errorf("State is format version %d, not %d", old.Version, from)
+	}
+
+	state := stateV4{
+		Version:          4,
+		TerraformVersion: old.TerraformVersion,
+		Serial:           old.Serial,
+		Lineage:          old.Lineage,
+		Outputs:          make(map[string]outputV4),
+		Resources:        make([]*resourceV4, 0),
+	}
+
+	byAddress := make(map[string]*resourceV4)
+	for _, m := range old.Modules {
+		module := modulePath(m.Path)
+		if module == "" {
+			for name, o := range m.Outputs {
+				state.Outputs[name] = outputV4{Value: o.Value, Type: outputType(o.Type), Sensitive: o.Sensitive}
+			}
+		}
+
+		for key, r := range m.Resources {
+			mode, typ, name, index, err := parseResourceKey(key)
+			if err != nil {
+				return nil, err
+			}
+			address := strings.TrimPrefix(module+"."+mode+"."+typ+"."+name, ".")
+			resource, ok := byAddress[address]
+			if !ok {
+				resource = &resourceV4{
+					Module:    module,
+					Mode:      mode,
+					Type:      typ,
+					Name:      name,
+					Provider:  providerAddress(module, typ, r.Provider),
+					Instances: make([]*instanceV4, 0),
+				}
+				byAddress[address] = resource
+				state.Resources = append(state.Resources, resource)
+			}
+
+			dependencies := dependencyAddresses(module, r.DependsOn)
+			if r.Primary != nil {
+				resource.Instances = append(resource.Instances, upgradeInstance(*r.Primary, index, "", dependencies))
+			}
+			for i, d := range r.Deposed {
+				resource.Instances = append(resource.Instances, upgradeInstance(d, index, fmt.Sprintf("%08x", i+1), dependencies))
+			}
+		}
+	}
+
+	sort.Slice(state.Resources, func(i, j int) bool {
+		a, b := state.Resources[i], state.Resources[j]
+		if a.Module != b.Module {
+			return a.Module < b.Module
+		}
+		if a.Mode != b.Mode {
+			return a.Mode < b.Mode
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Name < b.Name
+	})
+	for _, r := range state.Resources {
+		sort.SliceStable(r.Instances, func(i, j int) bool {
+			a, _ := r.Instances[i].IndexKey.(int)
+			b, _ := r.Instances[j].IndexKey.(int)
+			return a < b
+		})
+	}
+
+	upgraded, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	return Format(upgraded)
+}
+
+func upgradeInstance(old instanceV3, index interface{}, deposed string, dependencies []string) *instanceV4 {
+	attributes := old.Attributes
+	if attributes == nil {
+		attributes = make(map[string]string)
+	}
+	if _, ok := attributes["id"]; !ok && old.ID != "" {
+		attributes["id"] = old.ID
+	}
+
+	instance := &instanceV4{
+		IndexKey:       index,
+		Deposed:        deposed,
+		AttributesFlat: attributes,
+		Dependencies:   dependencies,
+	}
+	if old.Tainted {
+		instance.Status = "tainted"
+	}
+	if v, ok := old.Meta["schema_version"]; ok {
+		instance.SchemaVersion, _ = strconv.Atoi(fmt.Sprint(v))
+	}
+	return instance
+}
+
+// modulePath turns a version 3 module path such as [root, net, vpc] into
+// module.net.module.vpc
+func modulePath(path []string) string {
+	parts := make([]string, 0, len(path))
+	for _, p := range path {
+		if p == "root" && len(parts) == 0 {
+			continue
+		}
+		parts = append(parts, "module."+p)
+	}
+	return strings.Join(parts, ".")
+}
+
+// parseResourceKey splits a version 3 resource key such as
+// data.aws_ami.ubuntu or aws_instance.web.2
+func parseResourceKey(key string) (mode, typ, name string, index interface{}, err error) {
+	parts := strings.Split(key, ".")
+	mode = "managed"
+	if parts[0] == "data" {
+		mode = "data"
+		parts = parts[1:]
+	}
+	switch len(parts) {
+	case 2:
+	case 3:
+		i, convErr := strconv.Atoi(parts[2])
+		if convErr != nil {
+			return "", "", "", nil, fmt.Errorf("Invalid resource key %q", key)
+		}
+		index = i
+	default:
+		return "", "", "", nil, fmt.Errorf("Invalid resource key %q", key)
+	}
+	return mode, parts[0], parts[1], index, nil
+}
+
+// providerAddress returns the version 4 provider address, defaulting to the
+// provider named by the resource type prefix as terraform 0.11 did
+func providerAddress(module string, typ string, provider string) string {
+	if provider == "" {
+		provider = "provider." + strings.SplitN(typ, "_", 2)[0]
+	}
+	if !strings.HasPrefix(provider, "provider.") {
+		provider = "provider." + provider
+	}
+	if module != "" {
+		provider = module + "." + provider
+	}
+	return provider
+}
+
+// dependencyAddresses converts version 3 depends_on entries such as
+// aws_instance.web.* into resource addresses within the module
+func dependencyAddresses(module string, dependsOn []string) []string {
+	dependencies := make([]string, 0, len(dependsOn))
+	for _, d := range dependsOn {
+		parts := strings.Split(d, ".")
+		if last := parts[len(parts)-1]; last == "*" || isNumber(last) {
+			parts = parts[:len(parts)-1]
+		}
+		address := strings.Join(parts, ".")
+		if module != "" {
+			address = module + "." + address
+		}
+		dependencies = append(dependencies, address)
+	}
+	sort.Strings(dependencies)
+	return dependencies
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+func outputType(typ string) interface{} {
+	switch typ {
+	case "string":
+		return "string"
+	case "list":
+		return []string{"list", "string"}
+	case "map":
+		return []string{"map", "string"}
+	default:
+		return "dynamic"
+	}
+}
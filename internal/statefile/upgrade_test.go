@@ -0,0 +1,60 @@
+package statefile
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpgrade(t *testing.T) {
+	data, err := Read("testdata/v3.tfstate")
+	assert.NoError(t, err)
+
+	upgraded, err := Upgrade(data, 3, 4)
+	assert.NoError(t, err)
+	assert.Empty(t, Lint(upgraded))
+
+	var state stateV4
+	assert.NoError(t, json.Unmarshal(upgraded, &state))
+	assert.Equal(t, 4, state.Version)
+	assert.Equal(t, int64(12), state.Serial)
+	assert.Equal(t, "0.11.14", state.TerraformVersion)
+	assert.Equal(t, "prod-logs", state.Outputs["bucket"].Value)
+	assert.Equal(t, []interface{}{"list", "string"}, state.Outputs["subnets"].Type)
+
+	assert.Len(t, state.Resources, 3)
+	bucket := state.Resources[1]
+	assert.Equal(t, "managed", bucket.Mode)
+	assert.Equal(t, "aws_s3_bucket", bucket.Type)
+	assert.Equal(t, "provider.aws", bucket.Provider)
+	assert.Equal(t, "prod", bucket.Instances[0].AttributesFlat["tags.env"])
+
+	identity := state.Resources[0]
+	assert.Equal(t, "data", identity.Mode)
+	assert.Equal(t, "provider.aws", identity.Provider)
+	assert.Equal(t, "2020-01-01", identity.Instances[0].AttributesFlat["id"])
+
+	web := state.Resources[2]
+	assert.Equal(t, "module.web", web.Module)
+	assert.Equal(t, "module.web.provider.aws.west", web.Provider)
+	assert.Len(t, web.Instances, 2)
+	assert.Equal(t, float64(0), web.Instances[0].IndexKey)
+	assert.Equal(t, float64(1), web.Instances[1].IndexKey)
+	assert.Equal(t, "tainted", web.Instances[1].Status)
+	assert.Equal(t, 1, web.Instances[0].SchemaVersion)
+	assert.Equal(t, []string{"module.web.aws_security_group.web"}, web.Instances[0].Dependencies)
+}
+
+func TestUpgradeUnsupportedVersions(t *testing.T) {
+	_, err := Upgrade([]byte(`{"version":2}`), 2, 4)
+	assert.Error(t, err)
+}
+
+func TestUpgradeVersionMismatch(t *testing.T) {
+	data, err := Read("testdata/valid.tfstate")
+	assert.NoError(t, err)
+
+	_, err = Upgrade(data, 3, 4)
+	assert.Error(t, err)
+}
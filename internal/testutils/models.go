@@ -20,6 +20,7 @@ type stateVersionData struct {
 type responseAttr struct {
 	HostedStateDownloadURL string `json:"hosted-state-download-url,omitempty"`
 	State                  string `json:"state,omitempty"`
+	Serial                 int64  `json:"serial,omitempty"`
 }
 
 type TfeTestWks struct {
@@ -29,3 +30,29 @@ type TfeTestWks struct {
 	CsvResponder    httpmock.Responder
 	SvPostResponder httpmock.Responder
 }
+
+type stateVersionList struct {
+	Data []stateVersionListData `json:"data"`
+	Meta listMeta               `json:"meta"`
+}
+
+type stateVersionListData struct {
+	ID         string           `json:"id"`
+	Typ        string           `json:"type"`
+	Attributes stateVersionAttr `json:"attributes"`
+}
+
+type stateVersionAttr struct {
+	Serial int64 `json:"serial"`
+}
+
+type listMeta struct {
+	Pagination listPagination `json:"pagination"`
+}
+
+type listPagination struct {
+	CurrentPage int `json:"current-page"`
+	NextPage    int `json:"next-page"`
+	TotalPages  int `json:"total-pages"`
+	TotalCount  int `json:"total-count"`
+}
@@ -89,6 +89,7 @@ func SetupWksMockHTTPResponses(wks *TfeTestWks) error {
 				fmt.Sprintf("https://app.terraform.io/api/v2/organizations/team/workspaces/%v", wks.Name),
 				NewResponder(wks.Name, "workspaces", ""),
 			)
+			RegisterLockResponders(wks.Name)
 			if wks.CsvResponder != nil {
 				httpmock.RegisterResponder(
 					"GET",
@@ -126,6 +127,44 @@ func SetupWksMockHTTPResponses(wks *TfeTestWks) error {
 	return nil
 }
 
+// RegisterLockResponders lets the workspace with the given id be locked and
+// unlocked, and reports it as locked by the same user afterwards
+func RegisterLockResponders(id string) {
+	httpmock.RegisterResponder(
+		"POST",
+		fmt.Sprintf("https://app.terraform.io/api/v2/workspaces/%v/actions/lock", id),
+		NewResponder(id, "workspaces", ""),
+	)
+	httpmock.RegisterResponder(
+		"POST",
+		fmt.Sprintf("https://app.terraform.io/api/v2/workspaces/%v/actions/unlock", id),
+		NewResponder(id, "workspaces", ""),
+	)
+	httpmock.RegisterResponder(
+		"GET",
+		fmt.Sprintf("https://app.terraform.io/api/v2/workspaces/%v", id),
+		NewLockStateResponder(id, true, "user-test"),
+	)
+}
+
+// NewLockStateResponder responds with a workspace's lock status and holder
+func NewLockStateResponder(id string, locked bool, lockedBy string) httpmock.Responder {
+	res := map[string]interface{}{
+		"data": map[string]interface{}{
+			"id":         id,
+			"type":       "workspaces",
+			"attributes": map[string]interface{}{"locked": locked},
+			"relationships": map[string]interface{}{
+				"locked-by": map[string]interface{}{
+					"data": map[string]interface{}{"id": lockedBy, "type": "users"},
+				},
+			},
+		},
+	}
+
+	return httpmock.NewJsonResponderOrPanic(200, res)
+}
+
 func DecodeStateFromBody(req *http.Request) (models.State, error) {
 	var sv stateVersion
 	if err := json.NewDecoder(req.Body).Decode(&sv); err != nil {
@@ -171,6 +210,28 @@ func newStateVersion(id, typ, hostedStateDownloadURL string) stateVersion {
 			HostedStateDownloadURL: hostedStateDownloadURL,
 		}
 	}
+	if typ == "state-versions" {
+		res.Data.Attributes.Serial = DefaultSerial
+	}
 
 	return res
 }
+
+// NewStateVersionListResponder responds with one page of state versions with
+// ids sv-<serial> for the given serials
+func NewStateVersionListResponder(serials []int64) httpmock.Responder {
+	res := stateVersionList{
+		Meta: listMeta{
+			Pagination: listPagination{CurrentPage: 1, TotalPages: 1, TotalCount: len(serials)},
+		},
+	}
+	for _, serial := range serials {
+		res.Data = append(res.Data, stateVersionListData{
+			ID:         fmt.Sprintf("sv-%d", serial),
+			Typ:        "state-versions",
+			Attributes: stateVersionAttr{Serial: serial},
+		})
+	}
+
+	return httpmock.NewJsonResponderOrPanic(200, res)
+}
@@ -9,3 +9,7 @@ type ErrReadFilterFile struct {
 func (errReadFilterFile ErrReadFilterFile) Error() string {
 	return fmt.Sprintf("Unable to get workspace. Err: %v", errReadFilterFile.Err)
 }
+
+func (errReadFilterFile ErrReadFilterFile) Unwrap() error {
+	return errReadFilterFile.Err
+}
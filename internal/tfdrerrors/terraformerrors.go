@@ -1,6 +1,9 @@
 package tfdrerrors
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type ErrDestinationNotEmpty struct{}
 
@@ -22,6 +25,10 @@ func (errReadState ErrReadState) Error() string {
 	return fmt.Sprintf("Unable to read origin state. Error: %v", errReadState.Err)
 }
 
+func (errReadState ErrReadState) Unwrap() error {
+	return errReadState.Err
+}
+
 type ErrGetWorkspace struct {
 	Err error
 }
@@ -30,6 +37,10 @@ func (errGetWorkspace ErrGetWorkspace) Error() string {
 	return fmt.Sprintf("Unable to get workspace. Error: %v", errGetWorkspace.Err)
 }
 
+func (errGetWorkspace ErrGetWorkspace) Unwrap() error {
+	return errGetWorkspace.Err
+}
+
 type ErrUnableToFilter struct {
 	Err error
 }
@@ -38,6 +49,10 @@ func (errUnableToFilter ErrUnableToFilter) Error() string {
 	return fmt.Sprintf("Unable to filter resources from state. Error: %v", errUnableToFilter.Err)
 }
 
+func (errUnableToFilter ErrUnableToFilter) Unwrap() error {
+	return errUnableToFilter.Err
+}
+
 type ErrUnableToCreateStateVersion struct {
 	Err error
 }
@@ -46,6 +61,10 @@ func (errUnableToCreateStateVersion ErrUnableToCreateStateVersion) Error() strin
 	return fmt.Sprintf("Unable to create new state version. Error: %v", errUnableToCreateStateVersion.Err)
 }
 
+func (errUnableToCreateStateVersion ErrUnableToCreateStateVersion) Unwrap() error {
+	return errUnableToCreateStateVersion.Err
+}
+
 type ErrUnableToGetStateVersion struct {
 	Err error
 }
@@ -54,6 +73,10 @@ func (errUnableToGetStateVersion ErrUnableToGetStateVersion) Error() string {
 	return fmt.Sprintf("Cannot get current state. Error: %v", errUnableToGetStateVersion.Err)
 }
 
+func (errUnableToGetStateVersion ErrUnableToGetStateVersion) Unwrap() error {
+	return errUnableToGetStateVersion.Err
+}
+
 type ErrUnableToDownloadState struct {
 	Err error
 }
@@ -61,3 +84,111 @@ type ErrUnableToDownloadState struct {
 func (errUnableToDownloadState ErrUnableToDownloadState) Error() string {
 	return fmt.Sprintf("Cannot download state. Error: %v", errUnableToDownloadState.Err)
 }
+
+func (errUnableToDownloadState ErrUnableToDownloadState) Unwrap() error {
+	return errUnableToDownloadState.Err
+}
+
+type ErrUnableToListStateVersions struct {
+	Err error
+}
+
+func (errUnableToListStateVersions ErrUnableToListStateVersions) Error() string {
+	return fmt.Sprintf("Cannot list state versions. Error: %v", errUnableToListStateVersions.Err)
+}
+
+func (errUnableToListStateVersions ErrUnableToListStateVersions) Unwrap() error {
+	return errUnableToListStateVersions.Err
+}
+
+type ErrStateVersionDeleteUnsupported struct{}
+
+func (ErrStateVersionDeleteUnsupported) Error() string {
+	return "state version deletion is not supported by this Terraform Cloud/Enterprise instance"
+}
+
+type ErrTokenRejected struct{}
+
+func (ErrTokenRejected) Error() string {
+	return "Terraform Cloud rejected the API token, it may have expired or been revoked. Run `tfdr login` or `tfdr auth rotate` to replace it"
+}
+
+type ErrTerraformVersionDowngrade struct {
+	WorkspaceVersion string
+	StateVersion     string
+}
+
+func (errTerraformVersionDowngrade ErrTerraformVersionDowngrade) Error() string {
+	return fmt.Sprintf("new workspace runs terraform %s which cannot read state written by terraform %s. Use --align-tf-version to update the workspace",
+		errTerraformVersionDowngrade.WorkspaceVersion, errTerraformVersionDowngrade.StateVersion)
+}
+
+type ErrDeadlineExceeded struct {
+	Remaining []string
+	// Failed is how many of the workspaces processed before the deadline failed
+	Failed int
+}
+
+func (e ErrDeadlineExceeded) Error() string {
+	msg := fmt.Sprintf("Deadline reached before %d workspaces were processed: %s", len(e.Remaining), strings.Join(e.Remaining, ", "))
+	if e.Failed > 0 {
+		msg += fmt.Sprintf(". %d workspaces failed before the deadline", e.Failed)
+	}
+	return msg
+}
+
+type ErrWorkspaceLocked struct {
+	Workspace string
+}
+
+func (e ErrWorkspaceLocked) Error() string {
+	return fmt.Sprintf("workspace %s is locked by someone else", e.Workspace)
+}
+
+type ErrWorkspaceLockLost struct {
+	Workspace string
+	Reason    string
+}
+
+func (e ErrWorkspaceLockLost) Error() string {
+	return fmt.Sprintf("lost the lock on workspace %s (%s), aborting before writing state", e.Workspace, e.Reason)
+}
+
+type ErrStateConflict struct {
+	Workspace string
+	Expected  int64
+	Actual    int64
+}
+
+func (e ErrStateConflict) Error() string {
+	describe := func(serial int64) string {
+		if serial < 0 {
+			return "no state"
+		}
+		return fmt.Sprintf("serial %d", serial)
+	}
+	return fmt.Sprintf("state of workspace %s changed while tfdr was working on it: expected %s, found %s. Someone else wrote state in the meantime, check the workspace's latest state before running again",
+		e.Workspace, describe(e.Expected), describe(e.Actual))
+}
+
+type ErrApprovalRequired struct {
+	Workspace string
+	Tag       string
+}
+
+func (e ErrApprovalRequired) Error() string {
+	return fmt.Sprintf("workspace %s is tagged %s, restoring to it needs an approval token from a second person, see tfdr approve", e.Workspace, e.Tag)
+}
+
+type ErrWorkspaceBusy struct {
+	Workspace string
+	Operation string
+	PID       int
+	Host      string
+	LockFile  string
+}
+
+func (e ErrWorkspaceBusy) Error() string {
+	return fmt.Sprintf("workspace %s is in use by tfdr %s (pid %d on %s). If that process is gone, remove %s",
+		e.Workspace, e.Operation, e.PID, e.Host, e.LockFile)
+}
@@ -0,0 +1,302 @@
+// Package tui is a keyboard driven terminal dashboard of an organization's
+// workspaces for operators who stay in a terminal during an incident. It
+// lists and filters workspaces a page at a time, shows their state on
+// demand, queues plan-only verification runs and follows the operations
+// tfdr runs.
+package tui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/eiannone/keyboard"
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/console"
+)
+
+// maxOperations is how many recent operation events are shown
+const maxOperations = 5
+
+// Actions are what the dashboard does for the operator. They are called on
+// their own goroutine.
+type Actions struct {
+	// List returns the names of the workspaces to show
+	List func() ([]string, error)
+	// Details describes the state of a workspace
+	Details func(name string) (string, error)
+	// Verify queues a plan-only run on a workspace and returns its outcome
+	Verify func(name string) (string, error)
+}
+
+// Dashboard is the dashboard's state. It is safe for concurrent use.
+type Dashboard struct {
+	mu         sync.Mutex
+	title      string
+	pageSize   int
+	actions    Actions
+	workspaces []string
+	listErr    error
+	filter     string
+	// filtering is set while the operator types a filter into input
+	filtering bool
+	input     string
+	cursor    int
+	details   map[string]string
+	verify    map[string]string
+	ops       []string
+	updates   chan struct{}
+}
+
+// New returns a dashboard showing pageSize workspaces at a time
+func New(title string, pageSize int, actions Actions) *Dashboard {
+	return &Dashboard{
+		title:    title,
+		pageSize: pageSize,
+		actions:  actions,
+		details:  make(map[string]string),
+		verify:   make(map[string]string),
+		updates:  make(chan struct{}, 1),
+	}
+}
+
+// Updates receives a value whenever an action changed the dashboard
+func (d *Dashboard) Updates() <-chan struct{} {
+	return d.updates
+}
+
+// Refresh reloads the workspace list in the background
+func (d *Dashboard) Refresh() {
+	d.async(func() {
+		names, err := d.actions.List()
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.listErr = err
+		if err == nil {
+			d.workspaces = names
+			if visible := d.visible(); d.cursor >= len(visible) {
+				d.cursor = 0
+			}
+		}
+	})
+}
+
+// HandleEvent records an operation event from the api package
+func (d *Dashboard) HandleEvent(e api.Event) {
+	var line string
+	switch e.Type {
+	case api.OperationStarted:
+		line = fmt.Sprintf("%s started", e.Operation)
+	case api.WorkspaceCompleted:
+		line = fmt.Sprintf("%s %s done", e.Operation, e.Workspace)
+		if e.Err != nil {
+			line = fmt.Sprintf("%s %s failed: %v", e.Operation, e.Workspace, e.Err)
+		}
+	case api.OperationFinished:
+		line = fmt.Sprintf("%s finished", e.Operation)
+		if e.Err != nil {
+			line = fmt.Sprintf("%s failed: %v", e.Operation, e.Err)
+		}
+	default:
+		return
+	}
+	d.mu.Lock()
+	d.ops = append(d.ops, e.Time.Format("15:04:05")+" "+line)
+	if len(d.ops) > maxOperations {
+		d.ops = d.ops[len(d.ops)-maxOperations:]
+	}
+	d.mu.Unlock()
+	d.notify()
+}
+
+// HandleKey acts on a key press and reports whether the dashboard should
+// close
+func (d *Dashboard) HandleKey(ch rune, key keyboard.Key) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.filtering {
+		switch {
+		case key == keyboard.KeyEnter:
+			d.filter = d.input
+			d.filtering = false
+			d.cursor = 0
+		case key == keyboard.KeyEsc:
+			d.filtering = false
+		case key == keyboard.KeyBackspace || key == keyboard.KeyBackspace2:
+			if d.input != "" {
+				d.input = d.input[:len(d.input)-1]
+			}
+		case key == keyboard.KeySpace:
+			d.input += " "
+		case ch != 0:
+			d.input += string(ch)
+		}
+		return false
+	}
+
+	visible := d.visible()
+	switch {
+	case ch == 'q' || key == keyboard.KeyCtrlC:
+		return true
+	case ch == 'j' || key == keyboard.KeyArrowDown:
+		if d.cursor < len(visible)-1 {
+			d.cursor++
+		}
+	case ch == 'k' || key == keyboard.KeyArrowUp:
+		if d.cursor > 0 {
+			d.cursor--
+		}
+	case ch == 'n' || key == keyboard.KeyPgdn:
+		if d.cursor+d.pageSize < len(visible) {
+			d.cursor = (d.cursor/d.pageSize + 1) * d.pageSize
+		}
+	case ch == 'p' || key == keyboard.KeyPgup:
+		if d.cursor >= d.pageSize {
+			d.cursor = (d.cursor/d.pageSize - 1) * d.pageSize
+		}
+	case ch == '/':
+		d.filtering = true
+		d.input = d.filter
+	case ch == 'r':
+		d.Refresh()
+	case key == keyboard.KeyEnter && len(visible) > 0:
+		name := visible[d.cursor]
+		d.details[name] = "loading..."
+		d.async(func() { d.setResult(d.details, name, d.actions.Details) })
+	case ch == 'v' && len(visible) > 0:
+		name := visible[d.cursor]
+		if d.verify[name] != "verifying..." {
+			d.verify[name] = "verifying..."
+			d.async(func() { d.setResult(d.verify, name, d.actions.Verify) })
+		}
+	}
+	return false
+}
+
+// setResult stores the outcome of an action on a workspace
+func (d *Dashboard) setResult(results map[string]string, name string, action func(string) (string, error)) {
+	result, err := action(name)
+	if err != nil {
+		result = "error: " + err.Error()
+	}
+	d.mu.Lock()
+	results[name] = result
+	d.mu.Unlock()
+}
+
+// async runs f in the background and notifies Updates when it is done
+func (d *Dashboard) async(f func()) {
+	go func() {
+		f()
+		d.notify()
+	}()
+}
+
+func (d *Dashboard) notify() {
+	select {
+	case d.updates <- struct{}{}:
+	default:
+	}
+}
+
+// visible returns the workspaces matching the filter. The caller holds mu.
+func (d *Dashboard) visible() []string {
+	if d.filter == "" {
+		return d.workspaces
+	}
+	names := make([]string, 0)
+	for _, name := range d.workspaces {
+		if strings.Contains(name, d.filter) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Render writes the current page of the dashboard
+func (d *Dashboard) Render(w io.Writer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	visible := d.visible()
+	pages := (len(visible) + d.pageSize - 1) / d.pageSize
+	if pages == 0 {
+		pages = 1
+	}
+	page := d.cursor / d.pageSize
+
+	fmt.Fprintln(w, console.Bold(d.title))
+	header := fmt.Sprintf("%d workspaces, page %d/%d", len(visible), page+1, pages)
+	if d.filter != "" {
+		header += fmt.Sprintf(", filter %q", d.filter)
+	}
+	fmt.Fprintln(w, header)
+	if d.listErr != nil {
+		fmt.Fprintln(w, console.Failure("Unable to list workspaces: "+d.listErr.Error()))
+	}
+	fmt.Fprintln(w)
+
+	end := (page + 1) * d.pageSize
+	if end > len(visible) {
+		end = len(visible)
+	}
+	for i := page * d.pageSize; i < end; i++ {
+		name := visible[i]
+		line := "  " + name
+		if i == d.cursor {
+			line = "> " + console.Bold(name)
+		}
+		if v, ok := d.verify[name]; ok {
+			line += "  [" + v + "]"
+		}
+		fmt.Fprintln(w, line)
+		if details, ok := d.details[name]; ok {
+			fmt.Fprintln(w, "    "+details)
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, console.Bold("Operations"))
+	if len(d.ops) == 0 {
+		fmt.Fprintln(w, "  none yet")
+	}
+	for _, op := range d.ops {
+		fmt.Fprintln(w, "  "+op)
+	}
+
+	fmt.Fprintln(w)
+	if d.filtering {
+		fmt.Fprintf(w, "Filter: %s_  (enter to apply, esc to cancel)\n", d.input)
+		return
+	}
+	fmt.Fprintln(w, "up/down or j/k move  n/p page  / filter  enter state  v verify  r refresh  q quit")
+}
+
+// Run shows the dashboard until the operator quits, redrawing it on every
+// key press and finished action
+func Run(d *Dashboard, out io.Writer) error {
+	keys, err := keyboard.GetKeys(10)
+	if err != nil {
+		return err
+	}
+	defer keyboard.Close()
+
+	d.Refresh()
+	for {
+		// Clear the screen and move to the top left corner
+		fmt.Fprint(out, "\033[H\033[2J")
+		d.Render(out)
+		select {
+		case e := <-keys:
+			if e.Err != nil {
+				return e.Err
+			}
+			if d.HandleKey(e.Rune, e.Key) {
+				return nil
+			}
+		case <-d.Updates():
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package tui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/eiannone/keyboard"
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/console"
+	"github.com/stretchr/testify/assert"
+)
+
+func testDashboard(t *testing.T) *Dashboard {
+	assert.NoError(t, console.SetColorMode(console.ColorNever))
+	names := make([]string, 0)
+	for i := 1; i <= 5; i++ {
+		names = append(names, fmt.Sprintf("prod-%d", i))
+	}
+	names = append(names, "dr-1")
+	d := New("tfdr: team", 2, Actions{
+		List: func() ([]string, error) { return names, nil },
+		Details: func(name string) (string, error) {
+			return "serial 3", nil
+		},
+		Verify: func(name string) (string, error) {
+			return "", errors.New("plan-only runs are not supported")
+		},
+	})
+	d.Refresh()
+	waitForUpdate(t, d)
+	return d
+}
+
+func waitForUpdate(t *testing.T, d *Dashboard) {
+	select {
+	case <-d.Updates():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the dashboard was not updated")
+	}
+}
+
+func render(d *Dashboard) string {
+	var buf bytes.Buffer
+	d.Render(&buf)
+	return buf.String()
+}
+
+func TestPaging(t *testing.T) {
+	d := testDashboard(t)
+	out := render(d)
+	assert.Contains(t, out, "6 workspaces, page 1/3")
+	assert.Contains(t, out, "> prod-1\n  prod-2\n")
+	assert.NotContains(t, out, "prod-3")
+
+	d.HandleKey('n', 0)
+	d.HandleKey(0, keyboard.KeyArrowDown)
+	out = render(d)
+	assert.Contains(t, out, "page 2/3")
+	assert.Contains(t, out, "  prod-3\n> prod-4\n")
+
+	d.HandleKey('n', 0)
+	d.HandleKey('n', 0)
+	assert.Contains(t, render(d), "page 3/3")
+	d.HandleKey('p', 0)
+	assert.Contains(t, render(d), "> prod-3\n")
+	assert.True(t, d.HandleKey('q', 0))
+}
+
+func TestFilter(t *testing.T) {
+	d := testDashboard(t)
+	d.HandleKey('/', 0)
+	for _, ch := range "dr-x" {
+		d.HandleKey(ch, 0)
+	}
+	d.HandleKey(0, keyboard.KeyBackspace2)
+	assert.Contains(t, render(d), "Filter: dr-_")
+	d.HandleKey(0, keyboard.KeyEnter)
+
+	out := render(d)
+	assert.Contains(t, out, `1 workspaces, page 1/1, filter "dr-"`)
+	assert.Contains(t, out, "> dr-1\n")
+	assert.NotContains(t, out, "prod-1")
+}
+
+func TestActions(t *testing.T) {
+	d := testDashboard(t)
+	d.HandleKey(0, keyboard.KeyEnter)
+	waitForUpdate(t, d)
+	d.HandleKey('v', 0)
+	waitForUpdate(t, d)
+
+	out := render(d)
+	assert.Contains(t, out, "> prod-1  [error: plan-only runs are not supported]\n    serial 3\n")
+}
+
+func TestOperations(t *testing.T) {
+	d := testDashboard(t)
+	at := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	d.HandleEvent(api.Event{Type: api.OperationStarted, Time: at, Operation: "copy"})
+	d.HandleEvent(api.Event{Type: api.RetryScheduled, Time: at, Operation: "copy"})
+	d.HandleEvent(api.Event{Type: api.WorkspaceCompleted, Time: at, Operation: "copy", Workspace: "dr-1", Err: errors.New("new workspace state is not empty")})
+	for i := 0; i < maxOperations; i++ {
+		d.HandleEvent(api.Event{Type: api.OperationFinished, Time: at, Operation: "verify"})
+	}
+
+	out := render(d)
+	assert.NotContains(t, out, "copy started", "only the latest operations should be shown")
+	assert.Contains(t, out, "15:04:05 verify finished")
+
+	d = testDashboard(t)
+	d.HandleEvent(api.Event{Type: api.WorkspaceCompleted, Time: at, Operation: "copy", Workspace: "dr-1", Err: errors.New("new workspace state is not empty")})
+	assert.Contains(t, render(d), "15:04:05 copy dr-1 failed: new workspace state is not empty")
+}
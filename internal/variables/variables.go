@@ -0,0 +1,256 @@
+// Package variables reads workspace variables whose values come from outside
+// Terraform Cloud. Sensitive values can't be read back from a workspace, so a
+// restored workspace gets them from here instead.
+package variables
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Categories of workspace variables
+const (
+	CategoryTerraform = "terraform"
+	CategoryEnv       = "env"
+)
+
+// Variable is a workspace variable and where its value comes from. Exactly
+// one of Value, Env, File, Vault and Sops is set. Vault is <path>#<field> of
+// a KV version 2 secret, read with VAULT_ADDR and VAULT_TOKEN. Sops is
+// <file>#<key> of a top level key of a SOPS encrypted file, decrypted with
+// the sops command.
+type Variable struct {
+	Key       string `yaml:"key"`
+	Category  string `yaml:"category,omitempty"`
+	Sensitive bool   `yaml:"sensitive,omitempty"`
+	HCL       bool   `yaml:"hcl,omitempty"`
+	Value     string `yaml:"value,omitempty"`
+	Env       string `yaml:"env,omitempty"`
+	File      string `yaml:"file,omitempty"`
+	Vault     string `yaml:"vault,omitempty"`
+	Sops      string `yaml:"sops,omitempty"`
+}
+
+type variablesFile struct {
+	Variables []Variable `yaml:"variables"`
+}
+
+// Load reads and checks a variables file
+func Load(path string) ([]Variable, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f variablesFile
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, fmt.Errorf("Unable to parse variables file %s. Error: %v", path, err)
+	}
+	for i := range f.Variables {
+		v := &f.Variables[i]
+		if v.Category == "" {
+			v.Category = CategoryTerraform
+		}
+		if err := v.valid(); err != nil {
+			return nil, err
+		}
+	}
+	return f.Variables, nil
+}
+
+func (v Variable) valid() error {
+	if v.Key == "" {
+		return fmt.Errorf("Variable without a key")
+	}
+	if v.Category != CategoryTerraform && v.Category != CategoryEnv {
+		return fmt.Errorf("Variable %s has category %q, expected terraform or env", v.Key, v.Category)
+	}
+	sources := 0
+	for _, s := range []string{v.Value, v.Env, v.File, v.Vault, v.Sops} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("Variable %s needs exactly one of value, env, file, vault and sops", v.Key)
+	}
+	if v.Vault != "" && !strings.Contains(v.Vault, "#") {
+		return fmt.Errorf("Variable %s has vault %q, expected <path>#<field>", v.Key, v.Vault)
+	}
+	if v.Sops != "" && !strings.Contains(v.Sops, "#") {
+		return fmt.Errorf("Variable %s has sops %q, expected <file>#<key>", v.Key, v.Sops)
+	}
+	return nil
+}
+
+// Resolve returns the variable's value from its source
+func (v Variable) Resolve() (string, error) {
+	switch {
+	case v.Env != "":
+		value, ok := os.LookupEnv(v.Env)
+		if !ok {
+			return "", fmt.Errorf("Variable %s: environment variable %s is not set", v.Key, v.Env)
+		}
+		return value, nil
+	case v.File != "":
+		data, err := ioutil.ReadFile(v.File)
+		if err != nil {
+			return "", fmt.Errorf("Variable %s: %v", v.Key, err)
+		}
+		return strings.TrimRight(string(data), "\n"), nil
+	case v.Vault != "":
+		value, err := readVault(v.Vault)
+		if err != nil {
+			return "", fmt.Errorf("Variable %s: %v", v.Key, err)
+		}
+		return value, nil
+	case v.Sops != "":
+		value, err := readSops(v.Sops)
+		if err != nil {
+			return "", fmt.Errorf("Variable %s: %v", v.Key, err)
+		}
+		return value, nil
+	default:
+		return v.Value, nil
+	}
+}
+
+var vaultClient = &http.Client{Timeout: 30 * time.Second}
+
+// readVault reads a field of a KV version 2 secret, given as <path>#<field>
+// where path includes the data/ segment, e.g. secret/data/dr/db#password
+func readVault(ref string) (string, error) {
+	addr := os.Getenv("VAULT_ADDR")
+	token := os.Getenv("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		return "", fmt.Errorf("VAULT_ADDR and VAULT_TOKEN are required to read %s", ref)
+	}
+	parts := strings.SplitN(ref, "#", 2)
+
+	req, err := http.NewRequest("GET", strings.TrimRight(addr, "/")+"/v1/"+strings.TrimLeft(parts[0], "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	resp, err := vaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Unexpected status reading %s from vault: %s", parts[0], resp.Status)
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return "", err
+	}
+	value, ok := secret.Data.Data[parts[1]]
+	if !ok {
+		return "", fmt.Errorf("Secret %s has no field %s", parts[0], parts[1])
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(value)
+	return string(b), err
+}
+
+// sopsCommand decrypts SOPS files. sops finds the keys to decrypt with
+// itself, e.g. from AWS credentials or SOPS_AGE_KEY_FILE.
+var (
+	sopsCommand = "sops"
+	sopsTimeout = 30 * time.Second
+)
+
+// readSops decrypts a top level key of a SOPS encrypted file, given as
+// <file>#<key>
+func readSops(ref string) (string, error) {
+	parts := strings.SplitN(ref, "#", 2)
+	key, err := json.Marshal(parts[1])
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sopsTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, sopsCommand, "--decrypt", "--extract", "["+string(key)+"]", parts[0])
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Unable to decrypt %s with sops. Error: %v %s", ref, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimRight(stdout.String(), "\n"), nil
+}
+
+// WithOverrides adds terraform variables with the given values to vars, in
+// key order. Strings, numbers and bools are set as they are and lists and
+// maps as HCL. A key can't be set both in vars and in values.
+func WithOverrides(vars []Variable, values map[string]interface{}) ([]Variable, error) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := append([]Variable{}, vars...)
+	for _, k := range keys {
+		for _, v := range vars {
+			if v.Key == k && v.Category == CategoryTerraform {
+				return nil, fmt.Errorf("Variable %s is set in both the variables file and the filters file", k)
+			}
+		}
+		v := Variable{Key: k, Category: CategoryTerraform}
+		switch value := values[k].(type) {
+		case nil:
+			return nil, fmt.Errorf("Variable %s has no value", k)
+		case string:
+			v.Value = value
+		default:
+			b, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("Variable %s: %v", k, err)
+			}
+			v.Value = string(b)
+			switch value.(type) {
+			case []interface{}, map[string]interface{}:
+				v.HCL = true
+			}
+		}
+		result = append(result, v)
+	}
+	return result, nil
+}
+
+// ResolveAll resolves every variable, returning copies whose Value holds the
+// resolved value, so sources are read once however many workspaces the
+// variables are written to
+func ResolveAll(vars []Variable) ([]Variable, error) {
+	resolved := make([]Variable, 0, len(vars))
+	for _, v := range vars {
+		value, err := v.Resolve()
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, Variable{Key: v.Key, Category: v.Category, Sensitive: v.Sensitive, HCL: v.HCL, Value: value})
+	}
+	return resolved, nil
+}
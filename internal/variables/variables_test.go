@@ -0,0 +1,143 @@
+package variables
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type VariablesSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (s *VariablesSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "variables")
+	s.NoError(err)
+	s.dir = dir
+}
+
+func (s *VariablesSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *VariablesSuite) TestLoad() {
+	vars, err := Load("./testdata/variables.yaml")
+	s.NoError(err)
+	s.Len(vars, 3)
+	s.Equal(CategoryTerraform, vars[0].Category, "category should default to terraform")
+	s.Equal(CategoryEnv, vars[1].Category)
+	s.True(vars[2].Sensitive)
+}
+
+func (s *VariablesSuite) TestLoadRejectsInvalidVariables() {
+	cases := map[string]string{
+		"no source":   "variables:\n  - key: region\n",
+		"two sources": "variables:\n  - key: region\n    value: a\n    env: B\n",
+		"category":    "variables:\n  - key: region\n    value: a\n    category: policy\n",
+		"vault ref":   "variables:\n  - key: region\n    vault: secret/data/dr\n",
+		"sops ref":    "variables:\n  - key: region\n    sops: secrets.enc.yaml\n",
+	}
+	for name, content := range cases {
+		path := filepath.Join(s.dir, "variables.yaml")
+		s.NoError(ioutil.WriteFile(path, []byte(content), 0600))
+		_, err := Load(path)
+		s.Error(err, name)
+	}
+}
+
+func (s *VariablesSuite) TestResolve() {
+	os.Setenv("TFDR_TEST_AWS_SECRET", "from-env")
+	defer os.Unsetenv("TFDR_TEST_AWS_SECRET")
+	path := filepath.Join(s.dir, "secret")
+	s.NoError(ioutil.WriteFile(path, []byte("from-file\n"), 0600))
+
+	value, err := Variable{Key: "a", Env: "TFDR_TEST_AWS_SECRET"}.Resolve()
+	s.NoError(err)
+	s.Equal("from-env", value)
+
+	value, err = Variable{Key: "b", File: path}.Resolve()
+	s.NoError(err)
+	s.Equal("from-file", value, "trailing newlines should be trimmed")
+
+	_, err = Variable{Key: "c", Env: "TFDR_TEST_UNSET"}.Resolve()
+	s.EqualError(err, "Variable c: environment variable TFDR_TEST_UNSET is not set")
+}
+
+func (s *VariablesSuite) TestResolveFromVault() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Equal("/v1/secret/data/dr/db", r.URL.Path)
+		s.Equal("vault-token", r.Header.Get("X-Vault-Token"))
+		_, _ = w.Write([]byte(`{"data":{"data":{"password":"from-vault","port":5432}}}`))
+	}))
+	defer server.Close()
+	os.Setenv("VAULT_ADDR", server.URL)
+	os.Setenv("VAULT_TOKEN", "vault-token")
+	defer os.Unsetenv("VAULT_ADDR")
+	defer os.Unsetenv("VAULT_TOKEN")
+
+	resolved, err := ResolveAll([]Variable{
+		{Key: "db_password", Category: CategoryTerraform, Sensitive: true, Vault: "secret/data/dr/db#password"},
+		{Key: "db_port", Category: CategoryTerraform, Vault: "secret/data/dr/db#port"},
+	})
+	s.NoError(err)
+	s.Equal("from-vault", resolved[0].Value)
+	s.Equal("", resolved[0].Vault)
+	s.True(resolved[0].Sensitive)
+	s.Equal("5432", resolved[1].Value)
+
+	_, err = Variable{Key: "x", Vault: "secret/data/dr/db#user"}.Resolve()
+	s.EqualError(err, "Variable x: Secret secret/data/dr/db has no field user")
+}
+
+func (s *VariablesSuite) TestResolveFromSops() {
+	defer func(command string) { sopsCommand = command }(sopsCommand)
+	// A stand-in for sops that records its arguments
+	sopsCommand = filepath.Join(s.dir, "sops")
+	script := "#!/bin/sh\necho \"$@\" > " + filepath.Join(s.dir, "args") + "\nprintf 'from-sops\\n'\n"
+	s.NoError(ioutil.WriteFile(sopsCommand, []byte(script), 0700))
+
+	value, err := Variable{Key: "db_password", Sops: "secrets.enc.yaml#db_password"}.Resolve()
+	s.NoError(err)
+	s.Equal("from-sops", value)
+	args, err := ioutil.ReadFile(filepath.Join(s.dir, "args"))
+	s.NoError(err)
+	s.Equal("--decrypt --extract [\"db_password\"] secrets.enc.yaml\n", string(args))
+
+	s.NoError(ioutil.WriteFile(sopsCommand, []byte("#!/bin/sh\necho 'Error: no key could decrypt the data' >&2\nexit 128\n"), 0700))
+	_, err = Variable{Key: "db_password", Sops: "secrets.enc.yaml#db_password"}.Resolve()
+	s.EqualError(err, "Variable db_password: Unable to decrypt secrets.enc.yaml#db_password with sops. Error: exit status 128 Error: no key could decrypt the data")
+}
+
+func (s *VariablesSuite) TestWithOverrides() {
+	vars := []Variable{{Key: "db_password", Category: CategoryTerraform, Sensitive: true, Env: "DB_PASSWORD"}}
+	result, err := WithOverrides(vars, map[string]interface{}{
+		"region":  "us-west-2",
+		"dr_mode": true,
+		"zones":   []interface{}{"us-west-2a", "us-west-2b"},
+		"nodes":   float64(3),
+	})
+	s.NoError(err)
+	s.Equal([]Variable{
+		vars[0],
+		{Key: "dr_mode", Category: CategoryTerraform, Value: "true"},
+		{Key: "nodes", Category: CategoryTerraform, Value: "3"},
+		{Key: "region", Category: CategoryTerraform, Value: "us-west-2"},
+		{Key: "zones", Category: CategoryTerraform, Value: `["us-west-2a","us-west-2b"]`, HCL: true},
+	}, result)
+
+	_, err = WithOverrides(vars, map[string]interface{}{"db_password": "x"})
+	s.EqualError(err, "Variable db_password is set in both the variables file and the filters file")
+
+	_, err = WithOverrides(nil, map[string]interface{}{"region": nil})
+	s.EqualError(err, "Variable region has no value")
+}
+
+func TestVariablesSuite(t *testing.T) {
+	suite.Run(t, new(VariablesSuite))
+}
@@ -1,9 +1,14 @@
 package main
 
-import "github.com/mupuri/go-tfdr/cmd"
+import (
+	"os"
 
-var version = "devbuild"
+	"github.com/mupuri/go-tfdr/cmd"
+	"github.com/mupuri/go-tfdr/version"
+)
 
 func main() {
-	cmd.Execute(version)
+	if err := cmd.Execute(version.Version()); err != nil {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,213 @@
+// Package acctest runs tfdr against a real Terraform Cloud or Enterprise
+// organization. Tests using it create disposable workspaces, run tfdr
+// operations on them and delete them afterwards.
+//
+// The tests only run when TFDR_ACC is set, since they create and delete
+// workspaces. TFDR_ACC_TOKEN must be a team token for a sandbox organization
+// named by TFDR_ACC_ORG, never a production one. The harness ignores
+// ~/.tfdr/config.yaml, so a developer's own tokens are never used.
+//
+// The package can be used from outside the tfdr module: tests build states
+// with State, Resource and Instance and run tfdr operations with CopyState
+// and DeleteStateResources.
+package acctest
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/internal/logging"
+	"github.com/mupuri/go-tfdr/internal/models"
+)
+
+// Environment variables read by the harness
+const (
+	EnvAcc   = "TFDR_ACC"
+	EnvToken = "TFDR_ACC_TOKEN"
+	EnvOrg   = "TFDR_ACC_ORG"
+)
+
+// State, Resource and Instance are the parts of a Terraform state tfdr
+// reads, for building the states workspaces are seeded with
+type (
+	State    = models.State
+	Resource = models.Resource
+	Instance = models.Instance
+)
+
+// CopyOptions are the options of CopyState, see `tfdr state copy`
+type CopyOptions = api.CopyOptions
+
+// WorkspacePrefix starts the name of every workspace the harness creates,
+// so leftovers from interrupted runs can be found and deleted with
+// `tfdr workspace delete -p tfdr-acc-`
+const WorkspacePrefix = "tfdr-acc-"
+
+// PreCheck skips the test unless acceptance tests are enabled, and fails it
+// when they are enabled without a token and organization. It then points
+// tfdr at the sandbox organization, with every token set to TFDR_ACC_TOKEN
+// and no config file.
+func PreCheck(t testing.TB) {
+	t.Helper()
+	if os.Getenv(EnvAcc) == "" {
+		t.Skipf("Acceptance tests skipped unless %s is set", EnvAcc)
+	}
+	token, org := os.Getenv(EnvToken), os.Getenv(EnvOrg)
+	if token == "" || org == "" {
+		t.Fatalf("%s and %s must be set for acceptance tests", EnvToken, EnvOrg)
+	}
+
+	dir, err := ioutil.TempDir("", "tfdr-acc")
+	if err != nil {
+		t.Fatalf("Unable to create config directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for _, env := range []string{"TF_TEAM_TOKEN", "TF_READ_TOKEN", "TF_WRITE_TOKEN"} {
+		os.Setenv(env, token)
+	}
+	os.Setenv("TF_ORG_NAME", org)
+	// A config file that doesn't exist, so only the settings above apply
+	config.InitConfig(filepath.Join(dir, "config.yaml"))
+	logging.InitLogger()
+}
+
+// CopyState copies the resources selected by the filters file from the
+// source workspace's state to each destination, like `tfdr state copy`
+func CopyState(source string, destinations []string, filtersFile string, opts CopyOptions) error {
+	return api.CopyTFStateToMany(source, destinations, filtersFile, opts)
+}
+
+// DeleteStateResources removes the resources selected by the filters file
+// from the workspace's state, like `tfdr state delete`
+func DeleteStateResources(workspace string, filtersFile string) error {
+	return api.DeleteTFStateResources(workspace, filtersFile)
+}
+
+// Client returns a go-tfe client for the sandbox organization
+func Client(t testing.TB) *tfe.Client {
+	t.Helper()
+	client, err := tfe.NewClient(&tfe.Config{Token: os.Getenv(EnvToken)})
+	if err != nil {
+		t.Fatalf("Unable to create tfe client: %v", err)
+	}
+	return client
+}
+
+// Workspace creates an empty workspace and deletes it when the test ends.
+// It returns the workspace's name.
+func Workspace(t testing.TB, name string) string {
+	t.Helper()
+	client := Client(t)
+	org := os.Getenv(EnvOrg)
+	fullName := fmt.Sprintf("%s%s-%s", WorkspacePrefix, name, randomSuffix())
+
+	_, err := client.Workspaces.Create(context.Background(), org, tfe.WorkspaceCreateOptions{Name: tfe.String(fullName)})
+	if err != nil {
+		t.Fatalf("Unable to create workspace %s: %v", fullName, err)
+	}
+	t.Cleanup(func() {
+		if err := client.Workspaces.Delete(context.Background(), org, fullName); err != nil {
+			t.Errorf("Unable to delete workspace %s, delete it by hand: %v", fullName, err)
+		}
+	})
+	return fullName
+}
+
+// SeedState uploads state as the workspace's current state
+func SeedState(t testing.TB, workspace string, state *State) {
+	t.Helper()
+	client := Client(t)
+	ctx := context.Background()
+
+	ws, err := client.Workspaces.Read(ctx, os.Getenv(EnvOrg), workspace)
+	if err != nil {
+		t.Fatalf("Unable to read workspace %s: %v", workspace, err)
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatalf("Unable to encode state: %v", err)
+	}
+
+	if _, err := client.Workspaces.Lock(ctx, ws.ID, tfe.WorkspaceLockOptions{Reason: tfe.String("tfdr acceptance test")}); err != nil {
+		t.Fatalf("Unable to lock workspace %s: %v", workspace, err)
+	}
+	defer func() {
+		if _, err := client.Workspaces.Unlock(ctx, ws.ID); err != nil {
+			t.Errorf("Unable to unlock workspace %s: %v", workspace, err)
+		}
+	}()
+	_, err = client.StateVersions.Create(ctx, ws.ID, tfe.StateVersionCreateOptions{
+		Lineage: tfe.String(state.Lineage),
+		MD5:     tfe.String(fmt.Sprintf("%x", md5.Sum(data))),
+		Serial:  tfe.Int64(state.Serial),
+		State:   tfe.String(base64.StdEncoding.EncodeToString(data)),
+	})
+	if err != nil {
+		t.Fatalf("Unable to upload state to workspace %s: %v", workspace, err)
+	}
+}
+
+// CurrentState returns the workspace's current state, or nil if it has none
+func CurrentState(t testing.TB, workspace string) *State {
+	t.Helper()
+	client := Client(t)
+	ctx := context.Background()
+
+	ws, err := client.Workspaces.Read(ctx, os.Getenv(EnvOrg), workspace)
+	if err != nil {
+		t.Fatalf("Unable to read workspace %s: %v", workspace, err)
+	}
+	sv, err := client.StateVersions.Current(ctx, ws.ID)
+	if err == tfe.ErrResourceNotFound {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("Unable to read current state version of %s: %v", workspace, err)
+	}
+	data, err := client.StateVersions.Download(ctx, sv.DownloadURL)
+	if err != nil {
+		t.Fatalf("Unable to download state of %s: %v", workspace, err)
+	}
+	var state models.State
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("Unable to decode state of %s: %v", workspace, err)
+	}
+	return &state
+}
+
+// ResourceAddresses returns <type>.<name> of every resource in state, with
+// the module prefixed when set
+func ResourceAddresses(state *State) []string {
+	addresses := make([]string, 0, len(state.Resources))
+	for _, r := range state.Resources {
+		address := r.Type + "." + r.Name
+		if r.Module != "" {
+			address = r.Module + "." + address
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+var random = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+func randomSuffix() string {
+	const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, 8)
+	for i := range b {
+		b[i] = letters[random.Intn(len(letters))]
+	}
+	return string(b)
+}
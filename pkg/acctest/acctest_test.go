@@ -0,0 +1,55 @@
+package acctest_test
+
+import (
+	"testing"
+
+	"github.com/mupuri/go-tfdr/pkg/acctest"
+	"github.com/stretchr/testify/assert"
+)
+
+func sourceState() *acctest.State {
+	instance := func(id string) []acctest.Instance {
+		return []acctest.Instance{{Attributes: map[string]interface{}{"id": id}}}
+	}
+	return &acctest.State{
+		Version:          4,
+		TerraformVersion: "0.13.4",
+		Serial:           1,
+		Lineage:          "tfdr-acc",
+		Resources: []acctest.Resource{
+			{Mode: "managed", Type: "null_resource", Name: "global", Provider: `provider["registry.terraform.io/hashicorp/null"]`, Instances: instance("1")},
+			{Module: "module.app", Mode: "managed", Type: "random_id", Name: "suffix", Provider: `provider["registry.terraform.io/hashicorp/random"]`, Instances: instance("abc")},
+			{Module: "module.app", Mode: "managed", Type: "random_id", Name: "other", Provider: `provider["registry.terraform.io/hashicorp/random"]`, Instances: instance("def")},
+		},
+	}
+}
+
+func TestAccCopyState(t *testing.T) {
+	acctest.PreCheck(t)
+	source := acctest.Workspace(t, "copy-source")
+	dest := acctest.Workspace(t, "copy-dest")
+	acctest.SeedState(t, source, sourceState())
+
+	err := acctest.CopyState(source, []string{dest}, "./testdata/filters.json", acctest.CopyOptions{})
+	assert.NoError(t, err)
+
+	state := acctest.CurrentState(t, dest)
+	if assert.NotNil(t, state, "destination should have state after the copy") {
+		assert.ElementsMatch(t, []string{"null_resource.global", "module.app.random_id.dr_suffix"}, acctest.ResourceAddresses(state))
+	}
+}
+
+func TestAccDeleteStateResources(t *testing.T) {
+	acctest.PreCheck(t)
+	workspace := acctest.Workspace(t, "delete")
+	acctest.SeedState(t, workspace, sourceState())
+
+	err := acctest.DeleteStateResources(workspace, "./testdata/filters.json")
+	assert.NoError(t, err)
+
+	state := acctest.CurrentState(t, workspace)
+	if assert.NotNil(t, state) {
+		assert.Equal(t, []string{"module.app.random_id.other"}, acctest.ResourceAddresses(state))
+		assert.Equal(t, int64(2), state.Serial)
+	}
+}
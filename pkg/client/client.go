@@ -0,0 +1,103 @@
+// Package client lists Terraform Cloud and Enterprise resources for programs
+// embedding tfdr, with the same pagination, retries and rate limit handling
+// tfdr uses itself, so they don't have to page through results on their own.
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/mupuri/go-tfdr/internal/api"
+)
+
+// Stop can be returned by the function passed to Each to end the walk early
+// without an error
+var Stop = errors.New("stop")
+
+// Config selects the TFE instance and organization a Client talks to
+type Config struct {
+	// Address of the TFE instance, Terraform Cloud when empty
+	Address      string
+	Token        string
+	Organization string
+}
+
+// Client lists resources of one organization
+type Client struct {
+	tfe          *tfe.Client
+	organization string
+}
+
+// New returns a client for cfg.Organization
+func New(cfg Config) (*Client, error) {
+	if cfg.Organization == "" {
+		return nil, fmt.Errorf("Client needs an organization")
+	}
+	c, err := api.NewTFEClient(cfg.Address, cfg.Token)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{tfe: c, organization: cfg.Organization}, nil
+}
+
+// Workspaces returns the workspaces of the client's organization
+func (c *Client) Workspaces() *Workspaces {
+	return &Workspaces{client: c}
+}
+
+// Workspaces lists workspaces
+type Workspaces struct {
+	client *Client
+}
+
+// List returns an iterator over every workspace of the organization
+func (w *Workspaces) List(ctx context.Context) *WorkspaceIterator {
+	return &WorkspaceIterator{client: w.client, ctx: ctx}
+}
+
+// Search returns an iterator over the workspaces whose name contains search
+func (w *Workspaces) Search(ctx context.Context, search string) *WorkspaceIterator {
+	return &WorkspaceIterator{client: w.client, ctx: ctx, search: search}
+}
+
+// WorkspaceIterator walks a workspace listing page by page
+type WorkspaceIterator struct {
+	client *Client
+	ctx    context.Context
+	search string
+}
+
+// Each calls fn with every workspace in turn, reading the next page when
+// needed. Pages that fail with server or network errors are retried. It
+// stops at the first error fn returns and returns it, unless it is Stop.
+func (it *WorkspaceIterator) Each(fn func(*tfe.Workspace) error) error {
+	options := tfe.WorkspaceListOptions{}
+	if it.search != "" {
+		options.Search = tfe.String(it.search)
+	}
+	var fnErr error
+	err := api.EachPage(func(page tfe.ListOptions) (*tfe.Pagination, error) {
+		options.ListOptions = page
+		wl, err := it.client.tfe.Workspaces.List(it.ctx, it.client.organization, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, w := range wl.Items {
+			// Errors of fn end the walk instead of being retried like a
+			// failed page
+			if fnErr = fn(w); fnErr != nil {
+				return nil, api.ErrStopPaging
+			}
+		}
+		return wl.Pagination, nil
+	})
+	if fnErr == Stop {
+		return nil
+	}
+	if fnErr != nil {
+		return fnErr
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/go-tfe"
+	"github.com/mupuri/go-tfdr/pkg/client"
+	"github.com/stretchr/testify/assert"
+)
+
+// workspaceServer serves two pages of two workspaces each
+func workspaceServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/api/v2/organizations/org/workspaces", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page[number]"))
+		next := page + 1
+		if next > 2 {
+			next = 0
+		}
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		fmt.Fprintf(w, `{"data":[{"id":"ws-%[1]da","type":"workspaces","attributes":{"name":"ws%[1]da"}},{"id":"ws-%[1]db","type":"workspaces","attributes":{"name":"ws%[1]db"}}],`+
+			`"meta":{"pagination":{"current-page":%[1]d,"next-page":%[2]d,"total-pages":2,"total-count":4}}}`, page, next)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestWorkspacesEach(t *testing.T) {
+	server := workspaceServer()
+	defer server.Close()
+
+	c, err := client.New(client.Config{Address: server.URL, Token: "token", Organization: "org"})
+	assert.NoError(t, err)
+
+	names := make([]string, 0)
+	err = c.Workspaces().List(context.Background()).Each(func(w *tfe.Workspace) error {
+		names = append(names, w.Name)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ws1a", "ws1b", "ws2a", "ws2b"}, names)
+}
+
+func TestWorkspacesEachStop(t *testing.T) {
+	server := workspaceServer()
+	defer server.Close()
+
+	c, err := client.New(client.Config{Address: server.URL, Token: "token", Organization: "org"})
+	assert.NoError(t, err)
+
+	calls := 0
+	err = c.Workspaces().List(context.Background()).Each(func(w *tfe.Workspace) error {
+		calls++
+		return client.Stop
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	failed := fmt.Errorf("failed")
+	err = c.Workspaces().List(context.Background()).Each(func(w *tfe.Workspace) error {
+		return failed
+	})
+	assert.Equal(t, failed, err)
+}
+
+func TestNewNeedsOrganization(t *testing.T) {
+	_, err := client.New(client.Config{Token: "token"})
+	assert.Error(t, err)
+}
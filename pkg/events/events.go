@@ -0,0 +1,37 @@
+// Package events reports the progress of long running tfdr operations, such
+// as state copies and workspace deletes, to programs embedding tfdr. They
+// can build their own progress UIs and metrics on it instead of scraping
+// log output.
+package events
+
+import (
+	"github.com/mupuri/go-tfdr/internal/api"
+)
+
+// Type identifies a progress event
+type Type = api.EventType
+
+// Progress events, see the api package for when each is sent
+const (
+	OperationStarted   = api.OperationStarted
+	WorkspaceCompleted = api.WorkspaceCompleted
+	RetryScheduled     = api.RetryScheduled
+	OperationFinished  = api.OperationFinished
+)
+
+// Event reports the progress of an operation. Err is set on
+// WorkspaceCompleted and OperationFinished events when the workspace or
+// operation failed.
+type Event = api.Event
+
+// APICalls counts the requests of an operation to one endpoint, sent with
+// OperationFinished
+type APICalls = api.APICalls
+
+// Subscribe calls h with every progress event until the returned function
+// is called. Events are delivered from whichever goroutine produced them,
+// possibly several at once, so h must be safe for concurrent use and should
+// return quickly.
+func Subscribe(h func(Event)) (unsubscribe func()) {
+	return api.AddEventHandler(h)
+}
@@ -0,0 +1,35 @@
+package events_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/mupuri/go-tfdr/internal/api"
+	"github.com/mupuri/go-tfdr/internal/config"
+	"github.com/mupuri/go-tfdr/pkg/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tfdr-events")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	config.InitConfig(filepath.Join(dir, "config.yaml"))
+
+	var mu sync.Mutex
+	var got []events.Type
+	unsubscribe := events.Subscribe(func(e events.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, e.Type)
+	})
+
+	assert.NoError(t, api.PushLocalStates(nil, false, nil))
+	unsubscribe()
+	assert.NoError(t, api.PushLocalStates(nil, false, nil))
+
+	assert.Equal(t, []events.Type{events.OperationStarted, events.OperationFinished}, got)
+}
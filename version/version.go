@@ -0,0 +1,45 @@
+// Package version reports the tfdr build. The values are set at build time
+// with -ldflags, for example
+//
+//	-X github.com/mupuri/go-tfdr/version.version=1.2.3
+//	-X github.com/mupuri/go-tfdr/version.commit=abc1234
+//	-X github.com/mupuri/go-tfdr/version.date=2020-10-01T00:00:00Z
+package version
+
+import "runtime"
+
+// APILevel is bumped whenever a command, flag or output format changes in a
+// way that breaks scripts. Automation can refuse to run against a tfdr with
+// a level it was not written for.
+const APILevel = 1
+
+var (
+	version = "devbuild"
+	commit  = "unknown"
+	date    = "unknown"
+)
+
+// Info describes a tfdr build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+	APILevel  int    `json:"api_level"`
+}
+
+// Version returns the tfdr release, or devbuild for local builds
+func Version() string {
+	return version
+}
+
+// Get returns the full build information
+func Get() Info {
+	return Info{
+		Version:   version,
+		Commit:    commit,
+		Date:      date,
+		GoVersion: runtime.Version(),
+		APILevel:  APILevel,
+	}
+}